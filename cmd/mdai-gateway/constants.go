@@ -6,8 +6,47 @@ const (
 	httpPortEnvVarKey = "HTTP_PORT"
 	defaultHTTPPort   = "8081"
 
+	trustedProxiesEnvVarKey = "TRUSTED_PROXIES"
+
+	hubSyncTimeout = 30 * time.Second
+
+	proposalTTLEnvVarKey  = "PROPOSAL_TTL"
+	proposalSweepInterval = time.Minute
+
+	freezeReleaseInterval = 30 * time.Second
+
+	alertDeduperBackendEnvVarKey    = "ALERT_DEDUP_BACKEND"
+	alertDeduperTTLEnvVarKey        = "ALERT_DEDUP_TTL"
+	alertDeduperMaxEntriesEnvVarKey = "ALERT_DEDUP_MAX_ENTRIES"
+	alertDeduperJanitorInterval     = time.Minute
+
+	alertPushResolveTimeoutEnvVarKey = "ALERT_PUSH_RESOLVE_TIMEOUT"
+	alertPushSweepInterval           = 15 * time.Second
+
+	alertStateRetentionEnvVarKey     = "ALERT_STATE_RETENTION"
+	alertStateStalenessEnvVarKey     = "ALERT_STATE_STALENESS"
+	alertStateHistoryLengthEnvVarKey = "ALERT_STATE_HISTORY_LENGTH"
+
+	auditRetentionEnvVarKey    = "VALKEY_AUDIT_STREAM_RETENTION"
+	auditRetentionMsEnvVarKey  = "VALKEY_AUDIT_STREAM_EXPIRY_MS" // deprecated, still honoured by data-core
+	defaultAuditRetention      = 30 * 24 * time.Hour
+	auditRetentionTrimInterval = time.Minute
+
+	auditSinkFilePathEnvVarKey       = "AUDIT_SINK_FILE_PATH"
+	auditSinkFileMaxSizeMBEnvVarKey  = "AUDIT_SINK_FILE_MAX_SIZE_MB"
+	auditSinkFileMaxBackupsEnvVarKey = "AUDIT_SINK_FILE_MAX_BACKUPS"
+	defaultAuditSinkFileMaxSizeMB    = 100
+	defaultAuditSinkFileMaxBackups   = 5
+	auditSinkOTLPEnabledEnvVarKey    = "AUDIT_SINK_OTLP_ENABLED"
+	otelSDKDisabledEnvVarKey         = "OTEL_SDK_DISABLED"
+	auditSinkWebhookURLEnvVarKey     = "AUDIT_SINK_WEBHOOK_URL"
+	auditSinkWebhookTokenEnvVarKey   = "AUDIT_SINK_WEBHOOK_TOKEN"
+	auditSinkWebhookTimeoutEnvVarKey = "AUDIT_SINK_WEBHOOK_TIMEOUT"
+	auditSinkCloseTimeout            = 10 * time.Second
+
 	defaultReadHeaderTimeout = 5 * time.Second
 	defaultReadTimeout       = 10 * time.Second
 	defaultWriteTimeout      = 10 * time.Second
 	defaultIdleTimeout       = 120 * time.Second
+	serverShutdownTimeout    = 15 * time.Second
 )
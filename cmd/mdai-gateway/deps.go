@@ -3,15 +3,27 @@ package main
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/decisiveai/mdai-data-core/audit"
 	datacorepublisher "github.com/decisiveai/mdai-data-core/eventing/publisher"
+	"github.com/decisiveai/mdai-data-core/helpers"
 	datacorekube "github.com/decisiveai/mdai-data-core/kube"
 	"github.com/decisiveai/mdai-data-core/service"
 	"github.com/decisiveai/mdai-data-core/valkey"
 	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/alertpush"
+	"github.com/decisiveai/mdai-gateway/internal/alertstate"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/freeze"
+	"github.com/decisiveai/mdai-gateway/internal/hubs"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
 	"github.com/decisiveai/mdai-gateway/internal/opamp"
+	"github.com/decisiveai/mdai-gateway/internal/proposals"
 	"github.com/decisiveai/mdai-gateway/internal/server"
+	"github.com/decisiveai/mdai-gateway/internal/webhooks"
+	valkeygo "github.com/valkey-io/valkey-go"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
@@ -28,6 +40,9 @@ func initDependencies(ctx context.Context) (deps server.HandlerDeps, cleanup fun
 	}
 
 	auditAdapter := audit.NewAuditAdapter(app, valkeyClient)
+	retention := auditRetention(app)
+	auditChain := auditutils.NewChain(valkeyClient, retention)
+	auditSinks := initAuditSinks(ctx, app, auditChain)
 
 	publisher, err := datacorepublisher.NewPublisher(ctx, app, publisherClientName)
 	if err != nil {
@@ -39,25 +54,59 @@ func initDependencies(ctx context.Context) (deps server.HandlerDeps, cleanup fun
 		app.Fatal("failed to start config map controller", zap.Error(err))
 	}
 
-	deduper := adapter.NewDeduper()
+	// Only used to check the variables alerts refer to, so the gateway can do without it. Alerts of
+	// hubs that are not loaded are not checked.
+	hubRegistry := hubs.NewRegistry(app)
+	if err := watchHubs(ctx, app, hubRegistry); err != nil {
+		app.Warn("failed to watch hubs", zap.Error(err))
+	}
+
+	deduper := newAlertDeduper(app, valkeyClient)
+
+	// Without the webhook sources the gateway still receives Alertmanager and Grafana alerts.
+	webhookSources := webhooks.NewRegistry(app)
+	if err := watchWebhookSources(ctx, app, webhookSources); err != nil {
+		app.Warn("failed to watch webhook sources", zap.Error(err))
+	}
 
-	opampServer, err := opamp.NewOpAMPControlServer(app, auditAdapter, publisher)
+	opampServer, err := opamp.NewOpAMPControlServer(app, auditSinks, publisher)
 	if err != nil {
 		app.Fatal("failed to start OpAMP server", zap.Error(err))
 	}
 
+	proposalTTL := proposals.DefaultTTL
+	if value := helpers.GetEnvVariableWithDefault(proposalTTLEnvVarKey, ""); value != "" {
+		if proposalTTL, err = time.ParseDuration(value); err != nil {
+			app.Fatal("invalid proposal TTL", zap.String("value", value), zap.Error(err))
+		}
+	}
+
 	deps = server.HandlerDeps{
 		Logger:              app,
 		ValkeyClient:        valkeyClient,
 		EventPublisher:      publisher,
 		ConfigMapController: cmController,
+		Hubs:                hubRegistry,
 		AuditAdapter:        auditAdapter,
+		AuditInserter:       auditSinks,
+		AuditSinks:          auditSinks,
+		AuditRetention:      retention,
 		Deduper:             deduper,
+		Webhooks:            webhookSources,
+		AlertPush:           alertpush.NewTracker(valkeyClient, alertPushResolveTimeout(app)),
+		AlertStates:         alertstate.NewStore(valkeyClient, alertStateRetention(app), alertStateStaleness(app), envInt(app, alertStateHistoryLengthEnvVarKey, alertstate.DefaultHistoryLength)),
 		OpAMPServer:         opampServer,
+		Proposals:           proposals.NewStore(valkeyClient, proposalTTL),
+		Freezes:             freeze.NewStore(valkeyClient),
 	}
 
 	cleanup = func() {
 		app.Info("Closing client connections...")
+		sinksCtx, cancel := context.WithTimeout(context.Background(), auditSinkCloseTimeout)
+		if err := auditSinks.Close(sinksCtx); err != nil {
+			app.Error("Failed to close audit sinks", zap.Error(err))
+		}
+		cancel()
 		valkeyClient.Close()
 		_ = publisher.Close()
 		cmController.Stop()
@@ -68,6 +117,130 @@ func initDependencies(ctx context.Context) (deps server.HandlerDeps, cleanup fun
 	return deps, cleanup
 }
 
+// newAlertDeduper returns the alert deduper selected by ALERT_DEDUP_BACKEND. The memory backend is
+// the default; the valkey backend shares its state across replicas and restarts.
+func newAlertDeduper(logger *zap.Logger, client valkeygo.Client) adapter.Deduper { //nolint:ireturn
+	ttl := alertDeduperTTL(logger)
+	switch backend := helpers.GetEnvVariableWithDefault(alertDeduperBackendEnvVarKey, adapter.DeduperBackendMemory); backend {
+	case adapter.DeduperBackendMemory:
+		return adapter.NewMemoryDeduper(ttl, envInt(logger, alertDeduperMaxEntriesEnvVarKey, adapter.DefaultDeduperMaxEntries))
+	case adapter.DeduperBackendValkey:
+		return adapter.NewValkeyDeduper(client, ttl)
+	default:
+		logger.Fatal("invalid alert deduplication backend", zap.String("value", backend))
+		return nil
+	}
+}
+
+// alertDeduperTTL reads how long the gateway remembers an alert fingerprint it has not seen.
+func alertDeduperTTL(logger *zap.Logger) time.Duration {
+	value := helpers.GetEnvVariableWithDefault(alertDeduperTTLEnvVarKey, "")
+	if value == "" {
+		return adapter.DefaultDeduperTTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		logger.Fatal("invalid alert deduplication TTL", zap.String("value", value), zap.Error(err))
+	}
+	return ttl
+}
+
+// alertPushResolveTimeout reads how long an alert pushed without an end time stays firing.
+func alertPushResolveTimeout(logger *zap.Logger) time.Duration {
+	value := helpers.GetEnvVariableWithDefault(alertPushResolveTimeoutEnvVarKey, "")
+	if value == "" {
+		return alertpush.DefaultResolveTimeout
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		logger.Fatal("invalid alert push resolve timeout", zap.String("value", value), zap.Error(err))
+	}
+	return timeout
+}
+
+// trustedProxies reads the proxies allowed to set the identity headers. None are trusted by
+// default.
+func trustedProxies(logger *zap.Logger) identity.TrustedProxies {
+	value := helpers.GetEnvVariableWithDefault(trustedProxiesEnvVarKey, "")
+	proxies, err := identity.ParseTrustedProxies(value)
+	if err != nil {
+		logger.Fatal("invalid trusted proxies", zap.String("value", value), zap.Error(err))
+	}
+	return proxies
+}
+
+// alertStateRetention reads how long the state and history of a resolved alert are kept.
+func alertStateRetention(logger *zap.Logger) time.Duration {
+	value := helpers.GetEnvVariableWithDefault(alertStateRetentionEnvVarKey, "")
+	if value == "" {
+		return alertstate.DefaultRetention
+	}
+
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention <= 0 {
+		logger.Fatal("invalid alert state retention", zap.String("value", value), zap.Error(err))
+	}
+	return retention
+}
+
+// alertStateStaleness reads how long the state and history of a firing alert are kept after its
+// last recorded state.
+func alertStateStaleness(logger *zap.Logger) time.Duration {
+	value := helpers.GetEnvVariableWithDefault(alertStateStalenessEnvVarKey, "")
+	if value == "" {
+		return alertstate.DefaultStaleness
+	}
+
+	staleness, err := time.ParseDuration(value)
+	if err != nil || staleness <= 0 {
+		logger.Fatal("invalid alert state staleness", zap.String("value", value), zap.Error(err))
+	}
+	return staleness
+}
+
+// auditRetention reads the audit stream retention the same way the data-core audit adapter does,
+// e.g. "30d" or "72h", falling back to the deprecated retention in milliseconds, so that chained
+// records are trimmed like the others.
+func auditRetention(logger *zap.Logger) time.Duration {
+	if value := helpers.GetEnvVariableWithDefault(auditRetentionEnvVarKey, ""); value != "" {
+		retention, err := auditutils.ParseRetention(value)
+		if err != nil || retention < 0 {
+			logger.Fatal("invalid audit stream retention", zap.String("value", value), zap.Error(err))
+		}
+		return retention
+	}
+
+	if value := helpers.GetEnvVariableWithDefault(auditRetentionMsEnvVarKey, ""); value != "" {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ms < 0 {
+			logger.Fatal("invalid deprecated audit stream retention", zap.String("value", value), zap.Error(err))
+		}
+		logger.Warn(auditRetentionMsEnvVarKey+" is deprecated; use "+auditRetentionEnvVarKey, zap.Duration("retention", time.Duration(ms)*time.Millisecond))
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultAuditRetention
+}
+
+func watchWebhookSources(ctx context.Context, logger *zap.Logger, registry *webhooks.Registry) error {
+	clientset, err := datacorekube.NewK8sClient(logger)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	return registry.Watch(ctx, clientset, corev1.NamespaceAll)
+}
+
+func watchHubs(ctx context.Context, logger *zap.Logger, registry *hubs.Registry) error {
+	client, err := datacorekube.NewK8sDynamicClient(logger)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes dynamic client: %w", err)
+	}
+
+	return registry.Watch(ctx, client, corev1.NamespaceAll, hubSyncTimeout)
+}
+
 func startConfigMapController(
 	logger *zap.Logger,
 	clientset kubernetes.Interface,
@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/decisiveai/mdai-data-core/helpers"
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
 	"github.com/decisiveai/mdai-gateway/internal/server"
 	"go.uber.org/zap"
 )
@@ -12,19 +17,42 @@ import (
 const serviceName = "github.com/decisiveai/mdai-gateway"
 
 func main() {
-	ctx := context.Background()
+	// The context ends on SIGINT or SIGTERM, which stops the background loops and the server.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 
-	deps, cleanup := initDependencies(ctx)
+	var code int
+	if len(os.Args) > 1 && os.Args[1] == verifyAuditCommand {
+		code = runVerifyAudit(ctx, os.Args[2:], os.Stdout, os.Stderr)
+	} else {
+		code = runServer(ctx)
+	}
+	stop()
+	os.Exit(code)
+}
+
+// runServer serves the gateway until ctx ends and returns the process exit code.
+func runServer(ctx context.Context) int {
+	// Only the background loops stop with ctx; requests still being served during the shutdown
+	// keep their dependencies.
+	serveCtx := context.WithoutCancel(ctx)
+	deps, cleanup := initDependencies(serveCtx)
 	defer cleanup()
 
-	router := server.NewRouter(ctx, deps)
+	router := server.NewRouter(serveCtx, deps)
+	go server.RunProposalSweeper(ctx, deps, proposalSweepInterval)
+	go server.RunFreezeReleaser(ctx, deps, freezeReleaseInterval)
+	go server.RunAlertPushSweeper(ctx, deps, alertPushSweepInterval)
+	go server.RunAuditRetentionTrimmer(ctx, deps, auditRetentionTrimInterval)
+	if deduper, ok := deps.Deduper.(*adapter.MemoryDeduper); ok {
+		go deduper.RunJanitor(ctx, alertDeduperJanitorInterval)
+	}
 
 	httpPort := helpers.GetEnvVariableWithDefault(httpPortEnvVarKey, defaultHTTPPort)
 	deps.Logger.Info("Starting server", zap.String("address", ":"+httpPort))
 
 	httpServer := &http.Server{
 		Addr:              ":" + httpPort,
-		Handler:           router,
+		Handler:           trustedProxies(deps.Logger).StripUntrusted(router),
 		ReadHeaderTimeout: defaultReadHeaderTimeout,
 		ReadTimeout:       defaultReadTimeout,
 		WriteTimeout:      defaultWriteTimeout,
@@ -32,5 +60,20 @@ func main() {
 		ConnContext:       deps.OpAMPServer.ConnContext,
 	}
 
-	deps.Logger.Fatal("failed to start server", zap.Error(httpServer.ListenAndServe()))
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- httpServer.ListenAndServe() }()
+
+	select {
+	case err := <-serverErr:
+		deps.Logger.Error("failed to start server", zap.Error(err))
+		return 1
+	case <-ctx.Done():
+		deps.Logger.Info("Shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			deps.Logger.Error("failed to shut down server", zap.Error(err))
+		}
+		return 0
+	}
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/decisiveai/mdai-data-core/helpers"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/auditsink"
+	"go.uber.org/zap"
+)
+
+// initAuditSinks wraps the Valkey audit writer in a fan-out to the sinks configured by
+// environment. Misconfigured sinks stop the gateway; failing writes only affect their sink.
+func initAuditSinks(ctx context.Context, logger *zap.Logger, primary auditutils.Inserter) *auditsink.FanOut {
+	fanOut := auditsink.NewFanOut(logger, primary)
+
+	if path := helpers.GetEnvVariableWithDefault(auditSinkFilePathEnvVarKey, ""); path != "" {
+		maxSizeMB := envInt(logger, auditSinkFileMaxSizeMBEnvVarKey, defaultAuditSinkFileMaxSizeMB)
+		maxBackups := envInt(logger, auditSinkFileMaxBackupsEnvVarKey, defaultAuditSinkFileMaxBackups)
+		sink, err := auditsink.NewFileSink(path, int64(maxSizeMB)<<20, maxBackups)
+		if err != nil {
+			logger.Fatal("failed to open audit file sink", zap.String("path", path), zap.Error(err))
+		}
+		fanOut.Add("file", sink, auditsink.DefaultQueueSize, auditsink.DefaultTimeout)
+		logger.Info("Writing audit records to file", zap.String("path", path))
+	}
+
+	if envBool(logger, auditSinkOTLPEnabledEnvVarKey) {
+		if envBool(logger, otelSDKDisabledEnvVarKey) {
+			logger.Warn("OTLP audit sink is enabled but the OpenTelemetry SDK is disabled, not exporting audit records")
+		} else {
+			sink, err := auditsink.NewOTLPSink(ctx)
+			if err != nil {
+				logger.Fatal("failed to create OTLP audit sink", zap.Error(err))
+			}
+			fanOut.Add("otlp", sink, auditsink.DefaultQueueSize, auditsink.DefaultTimeout)
+			logger.Info("Exporting audit records over OTLP")
+		}
+	}
+
+	if url := helpers.GetEnvVariableWithDefault(auditSinkWebhookURLEnvVarKey, ""); url != "" {
+		timeout := auditsink.DefaultTimeout
+		if value := helpers.GetEnvVariableWithDefault(auditSinkWebhookTimeoutEnvVarKey, ""); value != "" {
+			var err error
+			if timeout, err = time.ParseDuration(value); err != nil || timeout <= 0 {
+				logger.Fatal("invalid audit webhook timeout", zap.String("value", value), zap.Error(err))
+			}
+		}
+		token := helpers.GetEnvVariableWithDefault(auditSinkWebhookTokenEnvVarKey, "")
+		sink := auditsink.NewWebhookSink(&http.Client{Timeout: timeout}, url, token)
+		fanOut.Add("webhook", sink, auditsink.DefaultQueueSize, timeout)
+		logger.Info("Posting audit records to webhook", zap.String("url", url))
+	}
+
+	return fanOut
+}
+
+func envInt(logger *zap.Logger, key string, defaultValue int) int {
+	value := helpers.GetEnvVariableWithDefault(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logger.Fatal("invalid integer environment variable", zap.String("key", key), zap.String("value", value), zap.Error(err))
+	}
+	return n
+}
+
+func envBool(logger *zap.Logger, key string) bool {
+	value := helpers.GetEnvVariableWithDefault(key, "")
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Fatal("invalid boolean environment variable", zap.String("key", key), zap.String("value", value), zap.Error(err))
+	}
+	return b
+}
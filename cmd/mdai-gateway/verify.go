@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/decisiveai/mdai-data-core/valkey"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"go.uber.org/zap"
+)
+
+const verifyAuditCommand = "verify-audit"
+
+// Exit codes of the verify-audit command.
+const (
+	verifyExitIntact = 0
+	verifyExitBroken = 1
+	verifyExitError  = 2
+)
+
+// runVerifyAudit verifies the hash chain of a range of the audit stream, prints the result as
+// JSON and returns the exit code.
+func runVerifyAudit(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(verifyAuditCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	start := flags.String("start", "-", "first stream ID or millisecond timestamp to verify")
+	end := flags.String("end", "+", "last stream ID or millisecond timestamp to verify")
+	if err := flags.Parse(args); err != nil {
+		return verifyExitError
+	}
+
+	from, to, err := auditutils.VerifyRangeFromValues(url.Values{"start": {*start}, "end": {*end}})
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return verifyExitError
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return verifyExitError
+	}
+	defer logger.Sync() //nolint:errcheck
+
+	client, err := valkey.Init(ctx, logger, valkey.NewConfig())
+	if err != nil {
+		logger.Error("failed to initialize valkey client", zap.Error(err))
+		return verifyExitError
+	}
+	defer client.Close()
+
+	verification, err := auditutils.Verify(ctx, client, from, to)
+	if err != nil {
+		logger.Error("failed to verify audit chain", zap.Error(err))
+		return verifyExitError
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(verification); err != nil {
+		return verifyExitError
+	}
+	if !verification.Intact {
+		return verifyExitBroken
+	}
+	return verifyExitIntact
+}
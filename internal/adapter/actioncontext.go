@@ -0,0 +1,86 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const (
+	ActionContextAnnotation  = "action_context"
+	RelevantLabelsAnnotation = "relevant_labels"
+)
+
+// ActionContext is the action_context annotation: the actions to take, keyed by the alert status
+// ("firing" or "resolved") that triggers them.
+type ActionContext map[string]AlertAction
+
+type AlertAction struct {
+	VariableUpdate *VariableUpdate `json:"variableUpdate,omitempty"`
+}
+
+// VariableUpdate changes a hub variable, e.g. adds the relevant label values to a set.
+type VariableUpdate struct {
+	VariableRef string `json:"variableRef"`
+	Operation   string `json:"operation"`
+}
+
+// AlertAnnotations holds the parsed action_context and relevant_labels annotations of an alert.
+type AlertAnnotations struct {
+	ActionContext  ActionContext
+	RelevantLabels []string
+}
+
+// ParseAlertAnnotations parses the action_context and relevant_labels annotations. Variable
+// references are checked against declared unless it is nil. Every problem found is returned; the
+// parts that could be parsed are returned as well.
+func ParseAlertAnnotations(annotations map[string]string, declared []string) (AlertAnnotations, error) {
+	var (
+		parsed AlertAnnotations
+		errs   []error
+	)
+
+	if raw, ok := annotations[ActionContextAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &parsed.ActionContext); err != nil {
+			errs = append(errs, fmt.Errorf("%s is not valid JSON: %w", ActionContextAnnotation, err))
+		}
+		for _, status := range slices.Sorted(maps.Keys(parsed.ActionContext)) {
+			errs = append(errs, validateAction(status, parsed.ActionContext[status], declared)...)
+		}
+	}
+
+	if raw, ok := annotations[RelevantLabelsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &parsed.RelevantLabels); err != nil {
+			errs = append(errs, fmt.Errorf("%s must be a JSON list of label names: %w", RelevantLabelsAnnotation, err))
+		}
+		if slices.Contains(parsed.RelevantLabels, "") {
+			errs = append(errs, fmt.Errorf("%s contains an empty label name", RelevantLabelsAnnotation))
+		}
+	}
+
+	return parsed, errors.Join(errs...)
+}
+
+func validateAction(status string, action AlertAction, declared []string) []error {
+	prefix := ActionContextAnnotation + "." + status
+	var errs []error
+	if !strings.EqualFold(status, "firing") && !strings.EqualFold(status, "resolved") {
+		errs = append(errs, fmt.Errorf("%s: unknown alert status, expected firing or resolved", prefix))
+	}
+	update := action.VariableUpdate
+	if update == nil {
+		return append(errs, fmt.Errorf("%s: variableUpdate is required", prefix))
+	}
+	if update.VariableRef == "" {
+		errs = append(errs, fmt.Errorf("%s: variableRef is required", prefix))
+	} else if declared != nil && !slices.Contains(declared, update.VariableRef) {
+		errs = append(errs, fmt.Errorf("%s: variable %q is not declared by the hub", prefix, update.VariableRef))
+	}
+	if update.Operation == "" {
+		errs = append(errs, fmt.Errorf("%s: operation is required", prefix))
+	}
+	return errs
+}
@@ -0,0 +1,104 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlertAnnotations(t *testing.T) {
+	declared := []string{"service_list"}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		declared    []string
+		want        AlertAnnotations
+		wantErrs    []string
+	}{
+		{
+			name:        "none",
+			annotations: map[string]string{"alert_name": "top_talkers"},
+			declared:    declared,
+		},
+		{
+			name: "valid",
+			annotations: map[string]string{
+				ActionContextAnnotation:  `{"firing":{"variableUpdate":{"variableRef":"service_list","operation":"mdai/add_element"}},"resolved":{"variableUpdate":{"variableRef":"service_list","operation":"mdai/remove_element"}}}`,
+				RelevantLabelsAnnotation: `["service_name"]`,
+			},
+			declared: declared,
+			want: AlertAnnotations{
+				ActionContext: ActionContext{
+					"firing":   {VariableUpdate: &VariableUpdate{VariableRef: "service_list", Operation: "mdai/add_element"}},
+					"resolved": {VariableUpdate: &VariableUpdate{VariableRef: "service_list", Operation: "mdai/remove_element"}},
+				},
+				RelevantLabels: []string{"service_name"},
+			},
+		},
+		{
+			name:        "undeclared variable",
+			annotations: map[string]string{ActionContextAnnotation: `{"firing":{"variableUpdate":{"variableRef":"services","operation":"mdai/set"}}}`},
+			declared:    declared,
+			want: AlertAnnotations{ActionContext: ActionContext{
+				"firing": {VariableUpdate: &VariableUpdate{VariableRef: "services", Operation: "mdai/set"}},
+			}},
+			wantErrs: []string{`action_context.firing: variable "services" is not declared by the hub`},
+		},
+		{
+			name:        "unknown variables",
+			annotations: map[string]string{ActionContextAnnotation: `{"firing":{"variableUpdate":{"variableRef":"services","operation":"mdai/set"}}}`},
+			want: AlertAnnotations{ActionContext: ActionContext{
+				"firing": {VariableUpdate: &VariableUpdate{VariableRef: "services", Operation: "mdai/set"}},
+			}},
+		},
+		{
+			name:        "incomplete actions",
+			annotations: map[string]string{ActionContextAnnotation: `{"pending":{"variableUpdate":{"variableRef":"service_list"}},"resolved":{}}`},
+			declared:    declared,
+			want: AlertAnnotations{ActionContext: ActionContext{
+				"pending":  {VariableUpdate: &VariableUpdate{VariableRef: "service_list"}},
+				"resolved": {},
+			}},
+			wantErrs: []string{
+				"action_context.pending: unknown alert status, expected firing or resolved",
+				"action_context.pending: operation is required",
+				"action_context.resolved: variableUpdate is required",
+			},
+		},
+		{
+			name: "malformed",
+			annotations: map[string]string{
+				ActionContextAnnotation:  `{"firing":`,
+				RelevantLabelsAnnotation: `service_name`,
+			},
+			declared: declared,
+			wantErrs: []string{
+				"action_context is not valid JSON: unexpected end of JSON input",
+				"relevant_labels must be a JSON list of label names: invalid character 's' looking for beginning of value",
+			},
+		},
+		{
+			name:        "empty label",
+			annotations: map[string]string{RelevantLabelsAnnotation: `["service_name",""]`},
+			want:        AlertAnnotations{RelevantLabels: []string{"service_name", ""}},
+			wantErrs:    []string{"relevant_labels contains an empty label name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAlertAnnotations(tt.annotations, tt.declared)
+			assert.Equal(t, tt.want, got)
+			if tt.wantErrs == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tt.wantErrs {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
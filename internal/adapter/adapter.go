@@ -1,12 +1,22 @@
 package adapter
 
-import "github.com/decisiveai/mdai-data-core/eventing"
+import (
+	"context"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+)
 
 type EventAdapter interface {
-	ToMdaiEvents() ([]EventPerSubject, int, error)
+	ToMdaiEvents(ctx context.Context) ([]EventPerSubject, int, error)
 }
 
 type EventPerSubject struct {
 	Event   eventing.MdaiEvent
 	Subject eventing.MdaiEventSubject
+	// AuditFields are extra fields written to the audit record of the event, e.g. the hub a
+	// value was promoted from.
+	AuditFields map[string]string
+	// AuditPayload, when set, replaces the event payload in the audit record, e.g. with
+	// sensitive values redacted.
+	AuditPayload string
 }
@@ -1,31 +1,199 @@
 package adapter
 
 import (
+	"container/list"
+	"context"
 	"sync"
 	"time"
 )
 
-type Deduper struct {
-	mu   sync.Mutex
-	last map[string]time.Time // TODO add TTL, default 12h equal to the Alertmanager configuration
+const (
+	// DefaultDeduperTTL matches the Alertmanager resolve and repeat configuration: an alert that
+	// has not been seen for this long is forgotten.
+	DefaultDeduperTTL = 12 * time.Hour
+	// DefaultDeduperMaxEntries bounds the number of fingerprints kept.
+	DefaultDeduperMaxEntries = 100_000
+
+	// Deduper backends.
+	DeduperBackendMemory = "memory"
+	DeduperBackendValkey = "valkey"
+)
+
+// Deduper remembers the last change time of each alert fingerprint, so that alert states older
+// than the last one seen can be skipped.
+type Deduper interface {
+	// UpdateIfNewer stores changeTime for the fingerprint if it is strictly newer than the stored
+	// time. It returns whether it did and the time stored afterwards.
+	UpdateIfNewer(ctx context.Context, fingerprint string, changeTime time.Time) (bool, time.Time, error)
+	// PeekLast returns the stored time of the fingerprint.
+	PeekLast(ctx context.Context, fingerprint string) (time.Time, bool, error)
+	// Forget removes the fingerprint if its stored time is still changeTime, so that a retry of
+	// an alert that could not be published is not skipped as stale.
+	Forget(ctx context.Context, fingerprint string, changeTime time.Time) error
+	Stats(ctx context.Context) (DeduperStats, error)
+}
+
+var (
+	_ Deduper = (*MemoryDeduper)(nil)
+	_ Deduper = (*ValkeyDeduper)(nil)
+)
+
+// MemoryDeduper is a Deduper in process memory. Entries expire once their fingerprint has not
+// been seen for the TTL, and the least recently seen entry is evicted when the deduper is full.
+type MemoryDeduper struct {
+	mu         sync.Mutex
+	last       map[string]*list.Element
+	lru        *list.List // front is the most recently seen entry
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	evictions   uint64
+	expirations uint64
+}
+
+type deduperEntry struct {
+	fingerprint string
+	changeTime  time.Time
+	seenAt      time.Time
+}
+
+// DeduperStats describes the state of a deduper. The entry counts are only known for the memory
+// backend.
+type DeduperStats struct {
+	Backend     string `json:"backend"`
+	TTL         string `json:"ttl"`
+	Entries     int    `json:"entries,omitempty"`
+	MaxEntries  int    `json:"maxEntries,omitempty"`
+	Evictions   uint64 `json:"evictions,omitempty"`
+	Expirations uint64 `json:"expirations,omitempty"`
 }
 
-func NewDeduper() *Deduper { return &Deduper{last: make(map[string]time.Time)} }
+// NewMemoryDeduper returns a deduper that forgets fingerprints after ttl and keeps at most
+// maxEntries. Non-positive values select the defaults.
+func NewMemoryDeduper(ttl time.Duration, maxEntries int) *MemoryDeduper {
+	if ttl <= 0 {
+		ttl = DefaultDeduperTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultDeduperMaxEntries
+	}
+	return &MemoryDeduper{
+		last:       make(map[string]*list.Element),
+		lru:        list.New(),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+func (d *MemoryDeduper) UpdateIfNewer(_ context.Context, fingerprint string, changeTime time.Time) (bool, time.Time, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if elem, ok := d.lookup(fingerprint, now); ok {
+		entry := elem.Value.(*deduperEntry) //nolint:forcetypeassert
+		entry.seenAt = now
+		d.lru.MoveToFront(elem)
+		if !changeTime.After(entry.changeTime) {
+			return false, entry.changeTime, nil
+		}
+		entry.changeTime = changeTime
+		return true, changeTime, nil
+	}
+
+	d.last[fingerprint] = d.lru.PushFront(&deduperEntry{fingerprint: fingerprint, changeTime: changeTime, seenAt: now})
+	for d.lru.Len() > d.maxEntries {
+		d.remove(d.lru.Back())
+		d.evictions++
+	}
+	return true, changeTime, nil
+}
 
-// UpdateIfNewer checks the stored time for key and, if changeTime is strictly newer.
-func (d *Deduper) UpdateIfNewer(fingerprint string, changeTime time.Time) (bool, time.Time) {
+func (d *MemoryDeduper) PeekLast(_ context.Context, fingerprint string) (time.Time, bool, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	if prev, ok := d.last[fingerprint]; ok && !changeTime.After(prev) {
-		return false, prev
+	elem, ok := d.lookup(fingerprint, d.now())
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	return elem.Value.(*deduperEntry).changeTime, true, nil //nolint:forcetypeassert
+}
+
+func (d *MemoryDeduper) Forget(_ context.Context, fingerprint string, changeTime time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elem, ok := d.last[fingerprint]; ok && elem.Value.(*deduperEntry).changeTime.Equal(changeTime) { //nolint:forcetypeassert
+		d.remove(elem)
+	}
+	return nil
+}
+
+// lookup returns the entry of fingerprint unless it is missing or expired. Expired entries are
+// removed.
+func (d *MemoryDeduper) lookup(fingerprint string, now time.Time) (*list.Element, bool) {
+	elem, ok := d.last[fingerprint]
+	if !ok {
+		return nil, false
+	}
+	if d.expired(elem, now) {
+		d.remove(elem)
+		d.expirations++
+		return nil, false
+	}
+	return elem, true
+}
+
+func (d *MemoryDeduper) expired(elem *list.Element, now time.Time) bool {
+	return now.Sub(elem.Value.(*deduperEntry).seenAt) >= d.ttl //nolint:forcetypeassert
+}
+
+func (d *MemoryDeduper) remove(elem *list.Element) {
+	d.lru.Remove(elem)
+	delete(d.last, elem.Value.(*deduperEntry).fingerprint) //nolint:forcetypeassert
+}
+
+// Expire removes the entries that have not been seen for the TTL and returns how many it removed.
+func (d *MemoryDeduper) Expire() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	removed := 0
+	// Entries are ordered by when they were last seen, so the expired ones are at the back.
+	for elem := d.lru.Back(); elem != nil && d.expired(elem, now); elem = d.lru.Back() {
+		d.remove(elem)
+		removed++
+	}
+	d.expirations += uint64(removed) //nolint:gosec
+	return removed
+}
+
+// RunJanitor removes expired entries every interval until ctx is done.
+func (d *MemoryDeduper) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Expire()
+		}
 	}
-	d.last[fingerprint] = changeTime
-	return true, changeTime
 }
 
-func (d *Deduper) PeekLast(key string) (time.Time, bool) {
+func (d *MemoryDeduper) Stats(_ context.Context) (DeduperStats, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	t, ok := d.last[key]
-	return t, ok
+	return DeduperStats{
+		Backend:     DeduperBackendMemory,
+		Entries:     d.lru.Len(),
+		MaxEntries:  d.maxEntries,
+		TTL:         d.ttl.String(),
+		Evictions:   d.evictions,
+		Expirations: d.expirations,
+	}, nil
 }
@@ -1,149 +1,425 @@
 package adapter
 
 import (
+	"context"
 	"math/rand"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/decisiveai/mdai-gateway/internal/valkeytest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
 )
 
-func isNewer(d *Deduper, fingerprint string, changeTime time.Time) bool {
-	updated, _ := d.UpdateIfNewer(fingerprint, changeTime)
+func isNewer(t *testing.T, d Deduper, fingerprint string, changeTime time.Time) bool {
+	t.Helper()
+	updated, _, err := d.UpdateIfNewer(t.Context(), fingerprint, changeTime)
+	require.NoError(t, err)
 	return updated
 }
 
+func peekLast(t *testing.T, d Deduper, fingerprint string) (time.Time, bool) {
+	t.Helper()
+	last, ok, err := d.PeekLast(t.Context(), fingerprint)
+	require.NoError(t, err)
+	return last, ok
+}
+
+// deduperBackends returns a constructor for each Deduper implementation, so that every test of
+// the deduper contract runs against all of them.
+func deduperBackends() map[string]func(t *testing.T) Deduper {
+	return map[string]func(t *testing.T) Deduper{
+		DeduperBackendMemory: func(*testing.T) Deduper { return NewMemoryDeduper(0, 0) },
+		DeduperBackendValkey: func(t *testing.T) Deduper { return NewValkeyDeduper(valkeytest.New(t), 0) },
+	}
+}
+
 func TestDeduper_IsNewer_Basic(t *testing.T) {
 	t.Parallel()
 
-	deduper := NewDeduper()
-	key := "fp1"
-	first := time.Now()
-	later := first.Add(time.Nanosecond)
-	earlier := first.Add(-time.Nanosecond)
+	for name, newDeduper := range deduperBackends() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
 
-	// First time for a key -> true
-	assert.True(t, isNewer(deduper, key, first))
+			deduper := newDeduper(t)
+			key := "fp1"
+			first := time.Now()
+			later := first.Add(time.Nanosecond)
+			earlier := first.Add(-time.Nanosecond)
 
-	// Equal timestamp -> false
-	assert.False(t, isNewer(deduper, key, first))
+			// First time for a key -> true
+			assert.True(t, isNewer(t, deduper, key, first))
 
-	// Older timestamp -> false
-	assert.False(t, isNewer(deduper, key, earlier))
+			// Equal timestamp -> false
+			assert.False(t, isNewer(t, deduper, key, first))
 
-	// UpdateIfNewer timestamp -> true
-	assert.True(t, isNewer(deduper, key, later))
+			// Older timestamp -> false
+			assert.False(t, isNewer(t, deduper, key, earlier))
 
-	// After newer is set, older again -> false
-	assert.False(t, isNewer(deduper, key, first))
+			// UpdateIfNewer timestamp -> true
+			assert.True(t, isNewer(t, deduper, key, later))
+
+			// After newer is set, older again -> false
+			assert.False(t, isNewer(t, deduper, key, first))
+		})
+	}
 }
 
 func TestDeduper_IsNewer_PerKeyIsolation(t *testing.T) {
 	t.Parallel()
 
-	deduper := NewDeduper()
-	baseTime := time.Now()
+	for name, newDeduper := range deduperBackends() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			deduper := newDeduper(t)
+			baseTime := time.Now()
 
-	keyA := "fpA"
-	keyB := "fpB"
+			keyA := "fpA"
+			keyB := "fpB"
 
-	// Set keyA at +10ms
-	at10ms := baseTime.Add(10 * time.Millisecond)
-	assert.True(t, isNewer(deduper, keyA, at10ms))
+			// Set keyA at +10ms
+			at10ms := baseTime.Add(10 * time.Millisecond)
+			assert.True(t, isNewer(t, deduper, keyA, at10ms))
 
-	// Set keyB at +5ms (independent) -> true
-	at5ms := baseTime.Add(5 * time.Millisecond)
-	assert.True(t, isNewer(deduper, keyB, at5ms))
+			// Set keyB at +5ms (independent) -> true
+			at5ms := baseTime.Add(5 * time.Millisecond)
+			assert.True(t, isNewer(t, deduper, keyB, at5ms))
 
-	// keyA older than last -> false
-	at9ms := baseTime.Add(9 * time.Millisecond)
-	assert.False(t, isNewer(deduper, keyA, at9ms))
+			// keyA older than last -> false
+			at9ms := baseTime.Add(9 * time.Millisecond)
+			assert.False(t, isNewer(t, deduper, keyA, at9ms))
 
-	// keyA newer than last -> true
-	at11ms := baseTime.Add(11 * time.Millisecond)
-	assert.True(t, isNewer(deduper, keyA, at11ms))
+			// keyA newer than last -> true
+			at11ms := baseTime.Add(11 * time.Millisecond)
+			assert.True(t, isNewer(t, deduper, keyA, at11ms))
 
-	// keyB equal -> false
-	assert.False(t, isNewer(deduper, keyB, at5ms))
+			// keyB equal -> false
+			assert.False(t, isNewer(t, deduper, keyB, at5ms))
+		})
+	}
 }
 
 func TestDeduper_ZeroTime(t *testing.T) {
 	t.Parallel()
 
-	deduper := NewDeduper()
-	key := "zero-time-key"
+	for name, newDeduper := range deduperBackends() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			deduper := newDeduper(t)
+			key := "zero-time-key"
 
-	// First zero -> true (we accept zero as "first seen")
-	assert.True(t, isNewer(deduper, key, time.Time{}))
+			// First zero -> true (we accept zero as "first seen")
+			assert.True(t, isNewer(t, deduper, key, time.Time{}))
 
-	// Second zero -> false
-	assert.False(t, isNewer(deduper, key, time.Time{}))
+			// Second zero -> false
+			assert.False(t, isNewer(t, deduper, key, time.Time{}))
 
-	// Non-zero after zero -> true
-	assert.True(t, isNewer(deduper, key, time.Now()))
+			// Non-zero after zero -> true
+			assert.True(t, isNewer(t, deduper, key, time.Now()))
+		})
+	}
 }
 
-func TestDeduper_Concurrent(t *testing.T) {
+func TestDeduper_Forget(t *testing.T) {
 	t.Parallel()
 
-	deduper := NewDeduper()
-	key := "concurrent-key"
+	for name, newDeduper := range deduperBackends() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
 
-	const numUpdates = 1000
-	start := time.Now().Truncate(time.Microsecond)
+			deduper := newDeduper(t)
+			first := time.Now()
+			later := first.Add(time.Second)
 
-	timestamps := make([]time.Time, numUpdates)
-	for i := range numUpdates {
-		timestamps[i] = start.Add(time.Duration(i) * time.Nanosecond)
-	}
+			require.True(t, isNewer(t, deduper, "fp", first))
+			require.True(t, isNewer(t, deduper, "fp", later))
 
-	randomOrder := rand.New(rand.NewSource(42)).Perm(numUpdates) //nolint:gosec
+			// A time that is no longer stored leaves the entry alone.
+			require.NoError(t, deduper.Forget(t.Context(), "fp", first))
+			stored, ok := peekLast(t, deduper, "fp")
+			require.True(t, ok)
+			assert.True(t, later.Equal(stored))
 
-	var waitGroup sync.WaitGroup
-	waitGroup.Add(numUpdates)
-	for _, index := range randomOrder {
-		ts := timestamps[index]
-		go func(ts time.Time) {
-			defer waitGroup.Done()
-			_, _ = deduper.UpdateIfNewer(key, ts)
-		}(ts)
+			require.NoError(t, deduper.Forget(t.Context(), "fp", later))
+			_, ok = peekLast(t, deduper, "fp")
+			assert.False(t, ok)
+			assert.True(t, isNewer(t, deduper, "fp", later))
+
+			require.NoError(t, deduper.Forget(t.Context(), "unknown", later))
+		})
 	}
-	waitGroup.Wait()
+}
+
+func TestDeduper_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	for name, newDeduper := range deduperBackends() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
 
-	// Final stored value must be the max (last element of timestamps).
-	lastSeen, exists := deduper.PeekLast(key)
+			deduper := newDeduper(t)
+			key := "concurrent-key"
 
-	require.True(t, exists, "expected key to be present after updates")
-	require.Equal(t, timestamps[numUpdates-1], lastSeen)
+			const numUpdates = 1000
+			start := time.Now().Truncate(time.Microsecond)
+
+			timestamps := make([]time.Time, numUpdates)
+			for i := range numUpdates {
+				timestamps[i] = start.Add(time.Duration(i) * time.Nanosecond)
+			}
+
+			randomOrder := rand.New(rand.NewSource(42)).Perm(numUpdates) //nolint:gosec
+
+			var waitGroup sync.WaitGroup
+			waitGroup.Add(numUpdates)
+			for _, index := range randomOrder {
+				ts := timestamps[index]
+				go func(ts time.Time) {
+					defer waitGroup.Done()
+					_, _, _ = deduper.UpdateIfNewer(t.Context(), key, ts)
+				}(ts)
+			}
+			waitGroup.Wait()
+
+			// Final stored value must be the max (last element of timestamps).
+			lastSeen, exists := peekLast(t, deduper, key)
+
+			require.True(t, exists, "expected key to be present after updates")
+			require.True(t, timestamps[numUpdates-1].Equal(lastSeen), "got %s", lastSeen)
+		})
+	}
 }
 
 func TestDeduper_Concurrent_MultipleKeys(t *testing.T) {
-	deduper := NewDeduper()
-	const n = 500
-	base := time.Unix(0, 0)
-
-	keys := []string{"k1", "k2", "k3"}
-	var wg sync.WaitGroup
-	wg.Add(len(keys) * n)
-
-	for _, k := range keys {
-		// different permutations per key
-		perm := rand.New(rand.NewSource(int64(len(k)))).Perm(n) //nolint:gosec
-		for _, idx := range perm {
-			ts := base.Add(time.Duration(idx) * time.Nanosecond)
-			go func(key string, ts time.Time) {
-				defer wg.Done()
-				_, _ = deduper.UpdateIfNewer(key, ts)
-			}(k, ts)
-		}
+	for name, newDeduper := range deduperBackends() {
+		t.Run(name, func(t *testing.T) {
+			deduper := newDeduper(t)
+			const n = 500
+			base := time.Unix(0, 0)
+
+			keys := []string{"k1", "k2", "k3"}
+			var wg sync.WaitGroup
+			wg.Add(len(keys) * n)
+
+			for _, k := range keys {
+				// different permutations per key
+				perm := rand.New(rand.NewSource(int64(len(k)))).Perm(n) //nolint:gosec
+				for _, idx := range perm {
+					ts := base.Add(time.Duration(idx) * time.Nanosecond)
+					go func(key string, ts time.Time) {
+						defer wg.Done()
+						_, _, _ = deduper.UpdateIfNewer(t.Context(), key, ts)
+					}(k, ts)
+				}
+			}
+			wg.Wait()
+
+			for _, k := range keys {
+				lastSeen, ok := peekLast(t, deduper, k)
+				require.True(t, ok)
+				require.True(t, base.Add(time.Duration(n-1)*time.Nanosecond).Equal(lastSeen), "got %s", lastSeen)
+			}
+		})
 	}
-	wg.Wait()
+}
+
+func TestValkeyDeduper_Script(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+	deduper := NewValkeyDeduper(client, time.Hour)
+	changeTime := time.Unix(1759276800, 5)
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		return cmd[0] == "EVALSHA" && cmd[2] == "1" && cmd[3] == "alert/dedup/fp" && cmd[4] == "1759276800" && cmd[5] == "5" && cmd[6] == "3600000"
+	})).Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyInt64(0), valkeymock.ValkeyBlobString("1759276801:0"))))
 
-	for _, k := range keys {
-		lastSeen, ok := deduper.PeekLast(k)
-		require.True(t, ok)
-		require.Equal(t, base.Add(time.Duration(n-1)*time.Nanosecond), lastSeen)
+	updated, last, err := deduper.UpdateIfNewer(t.Context(), "fp", changeTime)
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, time.Unix(1759276801, 0).UTC(), last)
+}
+
+func TestValkeyDeduper_Scripts(t *testing.T) {
+	t.Parallel()
+
+	client := valkeytest.New(t)
+	deduper := NewValkeyDeduper(client, time.Hour)
+	first := time.Unix(1759276800, 500)
+
+	updated, last, err := deduper.UpdateIfNewer(t.Context(), "fp", first)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, first.UTC(), last)
+
+	// Equal and older times, down to the nanosecond and across the second, keep the stored time.
+	for _, changeTime := range []time.Time{first, first.Add(-time.Nanosecond), first.Add(-time.Second), time.Unix(1759276799, 999999999)} {
+		updated, last, err = deduper.UpdateIfNewer(t.Context(), "fp", changeTime)
+		require.NoError(t, err)
+		assert.False(t, updated, changeTime)
+		assert.Equal(t, first.UTC(), last, changeTime)
+	}
+	stored, err := client.Do(t.Context(), client.B().Get().Key("alert/dedup/fp").Build()).ToString()
+	require.NoError(t, err)
+	assert.Equal(t, "1759276800:500", stored)
+
+	// A rejected update still refreshes the TTL.
+	pttl, err := client.Do(t.Context(), client.B().Pttl().Key("alert/dedup/fp").Build()).AsInt64()
+	require.NoError(t, err)
+	assert.Positive(t, pttl)
+	assert.LessOrEqual(t, pttl, time.Hour.Milliseconds())
+
+	// Times before 1970 have negative seconds but positive nanoseconds.
+	negative := time.Unix(-10, 5)
+	updated, last, err = deduper.UpdateIfNewer(t.Context(), "old", negative)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, negative.UTC(), last)
+	for changeTime, want := range map[time.Time]bool{
+		negative:                      false,
+		time.Unix(-11, 999999999):     false,
+		negative.Add(time.Nanosecond): true,
+		time.Unix(0, 0):               true,
+	} {
+		_, err := client.Do(t.Context(), client.B().Set().Key("alert/dedup/old").Value("-10:5").Build()).ToString()
+		require.NoError(t, err)
+		updated, _, err = deduper.UpdateIfNewer(t.Context(), "old", changeTime)
+		require.NoError(t, err)
+		assert.Equal(t, want, updated, changeTime)
 	}
+
+	// Forget only deletes the time it was given.
+	require.NoError(t, deduper.Forget(t.Context(), "fp", first.Add(time.Nanosecond)))
+	_, ok, err := deduper.PeekLast(t.Context(), "fp")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, deduper.Forget(t.Context(), "fp", first))
+	_, ok, err = deduper.PeekLast(t.Context(), "fp")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestValkeyDeduper_Errors(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+	deduper := NewValkeyDeduper(client, 0)
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "EVALSHA" })).
+		Return(valkeymock.ErrorResult(assert.AnError))
+	_, _, err := deduper.UpdateIfNewer(t.Context(), "fp", time.Now())
+	require.ErrorIs(t, err, assert.AnError)
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "alert/dedup/fp")).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString("garbage")))
+	_, _, err = deduper.PeekLast(t.Context(), "fp")
+	require.EqualError(t, err, `invalid stored alert change time "garbage"`)
+
+	stats, err := deduper.Stats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, DeduperStats{Backend: DeduperBackendValkey, TTL: "12h0m0s"}, stats)
+}
+
+// fakeClock is a settable clock for the deduper.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestDeduper(ttl time.Duration, maxEntries int) (*MemoryDeduper, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	deduper := NewMemoryDeduper(ttl, maxEntries)
+	deduper.now = clock.Now
+	return deduper, clock
+}
+
+func memoryStats(t *testing.T, d *MemoryDeduper) DeduperStats {
+	t.Helper()
+	stats, err := d.Stats(t.Context())
+	require.NoError(t, err)
+	return stats
+}
+
+func TestMemoryDeduper_TTL(t *testing.T) {
+	t.Parallel()
+
+	deduper, clock := newTestDeduper(time.Hour, 10)
+	changeTime := clock.now.Add(-time.Minute)
+
+	assert.True(t, isNewer(t, deduper, "fp", changeTime))
+
+	// A repeated notification keeps the entry alive.
+	clock.now = clock.now.Add(50 * time.Minute)
+	assert.False(t, isNewer(t, deduper, "fp", changeTime))
+	clock.now = clock.now.Add(50 * time.Minute)
+	_, ok := peekLast(t, deduper, "fp")
+	assert.True(t, ok)
+
+	// Unseen for the TTL, the fingerprint is forgotten.
+	clock.now = clock.now.Add(time.Hour)
+	_, ok = peekLast(t, deduper, "fp")
+	assert.False(t, ok)
+	assert.True(t, isNewer(t, deduper, "fp", changeTime))
+	assert.Equal(t, uint64(1), memoryStats(t, deduper).Expirations)
+}
+
+func TestMemoryDeduper_MaxEntries(t *testing.T) {
+	t.Parallel()
+
+	deduper, clock := newTestDeduper(time.Hour, 2)
+	changeTime := clock.now
+
+	assert.True(t, isNewer(t, deduper, "a", changeTime))
+	assert.True(t, isNewer(t, deduper, "b", changeTime))
+	// Seeing "a" again makes "b" the least recently seen entry.
+	assert.False(t, isNewer(t, deduper, "a", changeTime))
+	assert.True(t, isNewer(t, deduper, "c", changeTime))
+
+	_, ok := peekLast(t, deduper, "b")
+	assert.False(t, ok)
+	_, ok = peekLast(t, deduper, "a")
+	assert.True(t, ok)
+	assert.Equal(t, DeduperStats{Backend: DeduperBackendMemory, Entries: 2, MaxEntries: 2, TTL: "1h0m0s", Evictions: 1}, memoryStats(t, deduper))
+}
+
+func TestMemoryDeduper_Expire(t *testing.T) {
+	t.Parallel()
+
+	deduper, clock := newTestDeduper(time.Hour, 10)
+	assert.True(t, isNewer(t, deduper, "old", clock.now))
+	clock.now = clock.now.Add(30 * time.Minute)
+	assert.True(t, isNewer(t, deduper, "new", clock.now))
+
+	clock.now = clock.now.Add(45 * time.Minute)
+	assert.Equal(t, 1, deduper.Expire())
+	assert.Equal(t, 1, memoryStats(t, deduper).Entries)
+	_, ok := peekLast(t, deduper, "new")
+	assert.True(t, ok)
+}
+
+func TestMemoryDeduper_RunJanitor(t *testing.T) {
+	t.Parallel()
+
+	deduper, clock := newTestDeduper(time.Hour, 10)
+	assert.True(t, isNewer(t, deduper, "fp", clock.now))
+	clock.now = clock.now.Add(2 * time.Hour)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		deduper.RunJanitor(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return memoryStats(t, deduper).Entries == 0 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestNewMemoryDeduper_Defaults(t *testing.T) {
+	t.Parallel()
+
+	stats := memoryStats(t, NewMemoryDeduper(0, -1))
+	assert.Equal(t, DefaultDeduperMaxEntries, stats.MaxEntries)
+	assert.Equal(t, DefaultDeduperTTL.String(), stats.TTL)
 }
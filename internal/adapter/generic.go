@@ -0,0 +1,55 @@
+package adapter
+
+import (
+	"strings"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/prometheus/alertmanager/template"
+	"go.uber.org/zap"
+)
+
+// WebhookEventSourcePrefix prefixes the name of a generic webhook source in the source of its
+// events.
+const WebhookEventSourcePrefix = "webhook:"
+
+// GenericAlert is an item of a generic webhook, mapped to an alert.
+type GenericAlert struct {
+	template.Alert
+
+	// Fields are the payload fields extracted from the item.
+	Fields map[string]any
+	// Err is why the item could not be mapped; such items are invalid.
+	Err error
+}
+
+// GenericAlertWrapper turns the items of a generic webhook into events exactly like
+// PromAlertWrapper turns Alertmanager alerts, on the same subjects. The events have the source
+// "webhook:<source>" and carry the extracted fields in their payload.
+type GenericAlertWrapper struct {
+	*PromAlertWrapper
+}
+
+var _ EventAdapter = (*GenericAlertWrapper)(nil)
+
+func NewGenericAlertWrapper(source string, items []GenericAlert, l *zap.Logger, d Deduper) *GenericAlertWrapper {
+	alerts := make(template.Alerts, len(items))
+	errs := make([]error, len(items))
+	for i, item := range items {
+		alerts[i] = item.Alert
+		errs[i] = item.Err
+	}
+
+	w := NewPromAlertWrapper(template.Data{Receiver: source, Alerts: alerts}, l, d)
+	w.source = WebhookEventSourcePrefix + source
+	w.alertErrs = errs
+	w.extendPayload = func(i int, payload *AlertPayload) {
+		payload.Fields = items[i].Fields
+	}
+	return &GenericAlertWrapper{PromAlertWrapper: w}
+}
+
+// IsAlertSource reports whether events of source carry an AlertPayload.
+func IsAlertSource(source string) bool {
+	return source == eventing.PrometheusAlertsEventSource || source == GrafanaAlertsEventSource ||
+		strings.HasPrefix(source, WebhookEventSourcePrefix)
+}
@@ -0,0 +1,51 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGenericAlertToMdaiEvents(t *testing.T) {
+	startsAt := time.Date(2025, 8, 8, 15, 56, 10, 0, time.UTC)
+	annotations := template.KV{HubName: "mdaihub-sample", AlertName: "error_rate"}
+	items := []GenericAlert{
+		{
+			Alert:  template.Alert{Status: "firing", Annotations: annotations, StartsAt: startsAt, Fingerprint: "inc-1"},
+			Fields: map[string]any{"service": "checkout"},
+		},
+		{
+			Alert: template.Alert{Status: "firing", Annotations: annotations, StartsAt: startsAt, Fingerprint: "inc-2"},
+			Err:   errors.New("time: cannot parse"),
+		},
+	}
+
+	wrapped := NewGenericAlertWrapper("incidents", items, zap.NewNop(), NewMemoryDeduper(0, 0))
+	events, skipped, err := wrapped.ToMdaiEvents(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 0, skipped)
+	require.Len(t, events, 1)
+
+	event := events[0]
+	assert.Equal(t, "error_rate.firing", event.Event.Name)
+	assert.Equal(t, "webhook:incidents", event.Event.Source)
+	assert.True(t, IsAlertSource(event.Event.Source))
+	assert.Equal(t, eventing.MdaiEventSubject{Type: eventing.AlertEventType, Path: "mdaihub-sample.inc-1"}, event.Subject)
+
+	var payload AlertPayload
+	require.NoError(t, json.Unmarshal([]byte(event.Event.Payload), &payload))
+	assert.Equal(t, map[string]any{"service": "checkout"}, payload.Fields)
+
+	results := wrapped.Results()
+	require.Len(t, results, 2)
+	assert.Empty(t, results[0].Status)
+	assert.Equal(t, AlertInvalid, results[1].Status)
+	assert.Equal(t, "time: cannot parse", results[1].Reason)
+}
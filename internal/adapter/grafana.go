@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"github.com/prometheus/alertmanager/template"
+	"go.uber.org/zap"
+)
+
+// GrafanaAlertsEventSource is the source of events made from Grafana alerts.
+const GrafanaAlertsEventSource = "grafana"
+
+// GrafanaMessage is the payload of the Grafana unified alerting webhook contact point. It extends
+// the Alertmanager webhook payload.
+type GrafanaMessage struct {
+	Receiver          string         `json:"receiver"`
+	Status            string         `json:"status"`
+	OrgID             int64          `json:"orgId"`
+	Alerts            []GrafanaAlert `json:"alerts"`
+	GroupLabels       template.KV    `json:"groupLabels"`
+	CommonLabels      template.KV    `json:"commonLabels"`
+	CommonAnnotations template.KV    `json:"commonAnnotations"`
+	ExternalURL       string         `json:"externalURL"`
+	Version           string         `json:"version"`
+	GroupKey          string         `json:"groupKey"`
+	TruncatedAlerts   int            `json:"truncatedAlerts"`
+	Title             string         `json:"title"`
+	State             string         `json:"state"`
+	Message           string         `json:"message"`
+}
+
+// GrafanaAlert is an alert of a GrafanaMessage.
+type GrafanaAlert struct {
+	template.Alert
+
+	SilenceURL   string `json:"silenceURL"`
+	DashboardURL string `json:"dashboardURL"`
+	PanelURL     string `json:"panelURL"`
+	ImageURL     string `json:"imageURL"`
+	// Values are the results of the queries and expressions of the rule, keyed by their ref ID.
+	Values map[string]float64 `json:"values"`
+	// ValueString describes the values together with their labels.
+	ValueString string `json:"valueString"`
+}
+
+// GrafanaAlertWrapper turns Grafana alerts into events exactly like PromAlertWrapper turns
+// Alertmanager alerts, on the same subjects. The events have the grafana source and carry the
+// values of the alert in their payload.
+type GrafanaAlertWrapper struct {
+	*PromAlertWrapper
+}
+
+var _ EventAdapter = (*GrafanaAlertWrapper)(nil)
+
+func NewGrafanaAlertWrapper(msg GrafanaMessage, l *zap.Logger, d Deduper) *GrafanaAlertWrapper {
+	alerts := make(template.Alerts, len(msg.Alerts))
+	for i, alert := range msg.Alerts {
+		alerts[i] = alert.Alert
+	}
+	data := template.Data{
+		Receiver:          msg.Receiver,
+		Status:            msg.Status,
+		Alerts:            alerts,
+		GroupLabels:       msg.GroupLabels,
+		CommonLabels:      msg.CommonLabels,
+		CommonAnnotations: msg.CommonAnnotations,
+		ExternalURL:       msg.ExternalURL,
+	}
+
+	w := NewPromAlertWrapper(data, l, d)
+	w.source = GrafanaAlertsEventSource
+	w.extendPayload = func(i int, payload *AlertPayload) {
+		payload.Values = msg.Alerts[i].Values
+		payload.ValueString = msg.Alerts[i].ValueString
+	}
+	return &GrafanaAlertWrapper{PromAlertWrapper: w}
+}
@@ -0,0 +1,75 @@
+package adapter
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func readGrafanaMessage(t *testing.T, fileName string) GrafanaMessage {
+	t.Helper()
+	body, err := os.ReadFile(fileName) //nolint:gosec
+	require.NoError(t, err)
+	var msg GrafanaMessage
+	require.NoError(t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+func TestGrafanaAlertToMdaiEvents(t *testing.T) {
+	msg := readGrafanaMessage(t, "../../testdata/grafana_alert_firing.json")
+	require.Len(t, msg.Alerts, 2)
+	assert.Equal(t, int64(1), msg.OrgID)
+
+	wrapped := NewGrafanaAlertWrapper(msg, zap.NewNop(), NewMemoryDeduper(0, 0))
+	events, skipped, err := wrapped.ToMdaiEvents(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 0, skipped)
+	require.Len(t, events, 2)
+
+	event := events[0]
+	assert.Equal(t, "top_talkers.firing", event.Event.Name)
+	assert.Equal(t, GrafanaAlertsEventSource, event.Event.Source)
+	assert.Equal(t, "9c5d7f5ea1e7e0b2", event.Event.SourceID)
+	assert.Equal(t, "mdaihub-sample", event.Event.HubName)
+	assert.True(t, time.Date(2025, 8, 8, 15, 56, 10, 0, time.UTC).Equal(event.Event.Timestamp))
+	// Same subject as an Alertmanager alert with this fingerprint.
+	assert.Equal(t, eventing.MdaiEventSubject{Type: eventing.AlertEventType, Path: "mdaihub-sample.9c5d7f5ea1e7e0b2"}, event.Subject)
+
+	var payload AlertPayload
+	require.NoError(t, json.Unmarshal([]byte(event.Event.Payload), &payload))
+	assert.Equal(t, "firing", payload.Status)
+	assert.Equal(t, "57296478.69", payload.Value)
+	assert.Equal(t, map[string]float64{"A": 57296478.69, "C": 1}, payload.Values)
+	assert.Equal(t, msg.Alerts[0].ValueString, payload.ValueString)
+	assert.Equal(t, "service1234", payload.Labels["mdai_service"])
+
+	require.NoError(t, json.Unmarshal([]byte(events[1].Event.Payload), &payload))
+	assert.Equal(t, map[string]float64{"A": 260712855, "C": 1}, payload.Values)
+}
+
+func TestGrafanaAlert_Deduplication(t *testing.T) {
+	deduper := NewMemoryDeduper(0, 0)
+	resolved := readGrafanaMessage(t, "../../testdata/grafana_alert_resolved.json")
+	firing := readGrafanaMessage(t, "../../testdata/grafana_alert_firing.json")
+
+	events, skipped, err := NewGrafanaAlertWrapper(resolved, zap.NewNop(), deduper).ToMdaiEvents(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 0, skipped)
+	require.Len(t, events, 1)
+	assert.Equal(t, "top_talkers.resolved", events[0].Event.Name)
+
+	// The firing notification arrives late: its first alert is older than the resolution.
+	wrapped := NewGrafanaAlertWrapper(firing, zap.NewNop(), deduper)
+	events, skipped, err = wrapped.ToMdaiEvents(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, skipped)
+	require.Len(t, events, 1)
+	assert.Equal(t, "1f3e8b7c66a0d4e9", events[0].Event.SourceID)
+	assert.Equal(t, AlertSkipped, wrapped.Results()[0].Status)
+}
@@ -1,6 +1,7 @@
 package adapter
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,57 +10,193 @@ import (
 
 	"github.com/decisiveai/mdai-data-core/eventing"
 	"github.com/decisiveai/mdai-data-core/eventing/config"
+	"github.com/decisiveai/mdai-gateway/internal/alertstate"
 	"github.com/google/uuid"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
 	"go.uber.org/zap"
 )
 
 var ErrMissingFingerprint = errors.New("alert fingerprint is required")
 
 const (
-	hubName      = "hub_name"
-	currentValue = "current_value"
+	HubName      = "hub_name"
+	CurrentValue = "current_value"
 	AlertName    = "alert_name"
 )
 
+// Alert result statuses.
+const (
+	AlertPublished = "published"
+	// AlertHeld marks alerts queued because their hub is frozen.
+	AlertHeld    = "held"
+	AlertSkipped = "skipped"
+	AlertInvalid = "invalid"
+	AlertFailed  = "failed"
+)
+
+// AlertResult is the outcome of a single alert of a notification. ToMdaiEvents leaves Status
+// empty for the alerts it turned into events; the caller sets it once it knows what became of
+// the event.
+type AlertResult struct {
+	Fingerprint string `json:"fingerprint,omitempty"`
+	AlertName   string `json:"alertName,omitempty"`
+	HubName     string `json:"hubName,omitempty"`
+	// FingerprintComputed is set when the sender left out the fingerprint.
+	FingerprintComputed bool      `json:"fingerprintComputed,omitempty"`
+	Status              string    `json:"status"`
+	Reason              string    `json:"reason,omitempty"`
+	ChangeTime          time.Time `json:"changeTime,omitzero"`
+	// EventID is the ID of the event made from the alert.
+	EventID string `json:"-"`
+}
+
 type PromAlertWrapper struct {
 	*template.Data
 
-	Logger  *zap.Logger
-	deduper *Deduper
+	Logger *zap.Logger
+	// HubConfig returns the alert settings of a hub. When nil, every hub gets the zero HubConfig.
+	HubConfig func(hubName string) HubConfig
+
+	deduper Deduper
+	skipped []SkippedAlert
+	results []AlertResult
+	// source is the source of the events, and extendPayload adds what other senders know about
+	// the alert at index i to its payload.
+	source        string
+	extendPayload func(i int, payload *AlertPayload)
+	// alertErrs holds, at the index of an alert, why another sender could not map it.
+	alertErrs []error
+}
+
+// Ways of handling alerts with invalid action_context or relevant_labels annotations.
+const (
+	// AnnotationsFlag publishes the alert with the problems listed in its event.
+	AnnotationsFlag = "flag"
+	// AnnotationsReject reports the alert as invalid.
+	AnnotationsReject = "reject"
+)
+
+// HubConfig is what the adapter needs to know about the hub of an alert.
+type HubConfig struct {
+	// ComputeFingerprint derives the fingerprint of alerts that arrive without one from their
+	// labels. Otherwise, such alerts are invalid.
+	ComputeFingerprint bool
+	// InvalidAnnotations is AnnotationsFlag (the default) or AnnotationsReject.
+	InvalidAnnotations string
+	// Variables are the variables declared by the hub. Nil when they are unknown, in which case
+	// variable references are not checked.
+	Variables []string
+	// Err is set when the settings of the hub cannot be read. Its alerts then fail, so that the
+	// sender retries them.
+	Err error
+}
+
+// SkippedAlert is an alert that was not turned into an event because a newer state of it was
+// already seen.
+type SkippedAlert struct {
+	Fingerprint string
+	AlertName   string
+	HubName     string
+	Status      string
+	ChangeTime  time.Time
+	LastUpdate  time.Time
 }
 
 var _ EventAdapter = (*PromAlertWrapper)(nil)
 
-func NewPromAlertWrapper(v template.Data, l *zap.Logger, d *Deduper) *PromAlertWrapper {
-	return &PromAlertWrapper{Data: &v, Logger: l, deduper: d}
+func NewPromAlertWrapper(v template.Data, l *zap.Logger, d Deduper) *PromAlertWrapper {
+	return &PromAlertWrapper{Data: &v, Logger: l, deduper: d, source: eventing.PrometheusAlertsEventSource}
 }
 
-func (w *PromAlertWrapper) ToMdaiEvents() ([]EventPerSubject, int, error) {
+// ToMdaiEvents turns each alert into an event on its own. Alerts without a fingerprint or that do
+// not make a valid event are left out as invalid, alerts of hubs whose settings cannot be read as
+// failed, and alerts older than the last state seen are skipped; Results reports all of them.
+func (w *PromAlertWrapper) ToMdaiEvents(ctx context.Context) ([]EventPerSubject, int, error) {
 	skipped := 0
+	w.skipped = nil
+	w.results = make([]AlertResult, 0, len(w.Alerts))
 	alerts := w.Alerts // we don't need sorting within the same payload since it's deduplicated by fingerprint
 
 	eventsPerSubject := make([]EventPerSubject, 0, len(alerts))
-	for _, alert := range alerts {
+	for i, alert := range alerts {
+		result := AlertResult{
+			Fingerprint: alert.Fingerprint,
+			AlertName:   alert.Annotations[AlertName],
+			HubName:     alert.Annotations[HubName],
+			ChangeTime:  changeTime(alert),
+		}
+		if i < len(w.alertErrs) && w.alertErrs[i] != nil {
+			result.Status, result.Reason = AlertInvalid, w.alertErrs[i].Error()
+			w.results = append(w.results, result)
+			continue
+		}
+
+		var hub HubConfig
+		if w.HubConfig != nil {
+			hub = w.HubConfig(alert.Annotations[HubName])
+		}
+		if hub.Err != nil {
+			result.Status, result.Reason = AlertFailed, hub.Err.Error()
+			w.results = append(w.results, result)
+			continue
+		}
+		if alert.Fingerprint == "" && len(alert.Labels) > 0 && hub.ComputeFingerprint {
+			alert.Fingerprint = LabelsFingerprint(alert.Labels)
+			result.Fingerprint, result.FingerprintComputed = alert.Fingerprint, true
+		}
 		if alert.Fingerprint == "" {
-			return nil, 0, fmt.Errorf("%w (name=%q status=%s)", ErrMissingFingerprint,
-				alert.Annotations[AlertName], alert.Status)
+			result.Status, result.Reason = AlertInvalid, ErrMissingFingerprint.Error()
+			w.results = append(w.results, result)
+			continue
+		}
+		annotations, annotationsErr := ParseAlertAnnotations(alert.Annotations, hub.Variables)
+		if annotationsErr != nil && hub.InvalidAnnotations == AnnotationsReject {
+			result.Status, result.Reason = AlertInvalid, annotationsErr.Error()
+			w.results = append(w.results, result)
+			continue
+		}
+		if annotationsErr != nil {
+			result.Reason = annotationsErr.Error()
+		}
+
+		payload := newAlertPayload(alert, result.FingerprintComputed, annotations, annotationsErr)
+		if w.extendPayload != nil {
+			w.extendPayload(i, &payload)
+		}
+		event, err := w.toMdaiEvent(alert, payload)
+		if err != nil {
+			result.Status, result.Reason = AlertInvalid, err.Error()
+			w.results = append(w.results, result)
+			continue
 		}
-		changeTime := changeTime(alert)
-		if isNewer, lastTime := w.deduper.UpdateIfNewer(alert.Fingerprint, changeTime); !isNewer {
+
+		isNewer, lastTime, err := w.deduper.UpdateIfNewer(ctx, alert.Fingerprint, result.ChangeTime)
+		if err != nil {
+			// Publishing a duplicate is better than losing an alert the deduper cannot vouch for.
+			w.Logger.Warn("Failed to deduplicate alert, publishing it", zap.String("alert_name", alert.Annotations[AlertName]), zap.Error(err))
+			isNewer = true
+		}
+		if !isNewer {
 			skipped++
+			w.skipped = append(w.skipped, SkippedAlert{
+				Fingerprint: alert.Fingerprint,
+				AlertName:   alert.Annotations[AlertName],
+				HubName:     alert.Annotations[HubName],
+				Status:      alert.Status,
+				ChangeTime:  result.ChangeTime,
+				LastUpdate:  lastTime,
+			})
 			w.Logger.Info(
 				"Skipping stale alert",
 				zap.String("alert_name", alert.Annotations[AlertName]),
 				zap.Time("last_update", lastTime),
-				zap.Time("this_change", changeTime),
+				zap.Time("this_change", result.ChangeTime),
 			)
+			result.Status, result.Reason = AlertSkipped, "not newer than the state seen at "+lastTime.Format(time.RFC3339Nano)
+			w.results = append(w.results, result)
 			continue
 		}
-		event, err := w.toMdaiEvent(alert)
-		if err != nil {
-			return nil, 0, err
-		}
 
 		subj := subjectFromAlert(alert, event.HubName)
 		w.Logger.Debug("subject for alert", zap.String("alert_name", alert.Annotations[AlertName]), zap.String("subject", subj.String()))
@@ -68,13 +205,64 @@ func (w *PromAlertWrapper) ToMdaiEvents() ([]EventPerSubject, int, error) {
 			Event:   event,
 			Subject: subj,
 		}
+		if result.FingerprintComputed || annotationsErr != nil {
+			eventPerSubject.AuditFields = make(map[string]string, 2)
+		}
+		if result.FingerprintComputed {
+			eventPerSubject.AuditFields["fingerprint_computed"] = "true"
+		}
+		if annotationsErr != nil {
+			eventPerSubject.AuditFields["annotation_errors"] = annotationsErr.Error()
+		}
 
 		eventsPerSubject = append(eventsPerSubject, eventPerSubject)
+		result.EventID = event.ID
+		w.results = append(w.results, result)
 	}
 
 	return eventsPerSubject, skipped, nil
 }
 
+// Results returns the outcome of each alert of the last call to ToMdaiEvents, in the order of the
+// alerts.
+func (w *PromAlertWrapper) Results() []AlertResult {
+	return w.results
+}
+
+// Skipped returns the alerts the last call to ToMdaiEvents skipped as stale.
+func (w *PromAlertWrapper) Skipped() []SkippedAlert {
+	return w.skipped
+}
+
+// AlertState returns the state of the alert at index i as of the last call to ToMdaiEvents.
+func (w *PromAlertWrapper) AlertState(i int) alertstate.State {
+	alert := w.Alerts[i]
+	state := alertstate.State{
+		Fingerprint: w.results[i].Fingerprint,
+		AlertName:   alert.Annotations[AlertName],
+		HubName:     alert.Annotations[HubName],
+		Status:      alert.Status,
+		Source:      w.source,
+		Labels:      alert.Labels,
+		Annotations: alert.Annotations,
+		FiringSince: alert.StartsAt,
+	}
+	if !state.Firing() {
+		state.ResolvedAt = alert.EndsAt
+	}
+	return state
+}
+
+// LabelsFingerprint returns the fingerprint Prometheus and Alertmanager give an alert with these
+// labels.
+func LabelsFingerprint(labels map[string]string) string {
+	set := make(model.LabelSet, len(labels))
+	for name, value := range labels {
+		set[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return set.Fingerprint().String()
+}
+
 // subjectFromAlert creates a subject from an alert. Prefix has to be added later at eventing package.
 func subjectFromAlert(alert template.Alert, hubName string) eventing.MdaiEventSubject {
 	return eventing.MdaiEventSubject{
@@ -83,20 +271,48 @@ func subjectFromAlert(alert template.Alert, hubName string) eventing.MdaiEventSu
 	}
 }
 
-func (w *PromAlertWrapper) toMdaiEvent(alert template.Alert) (eventing.MdaiEvent, error) {
-	annotations := alert.Annotations
+// AlertPayload is the payload of alert events.
+type AlertPayload struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Status      string            `json:"status"`
+	// Value is the current_value annotation.
+	Value string `json:"value,omitempty"`
+	// FingerprintComputed is set when the fingerprint was derived from the labels because the
+	// sender left it out.
+	FingerprintComputed bool `json:"fingerprintComputed,omitempty"`
+	// ActionContext and RelevantLabels are the parsed action_context and relevant_labels
+	// annotations.
+	ActionContext  ActionContext `json:"actionContext,omitempty"`
+	RelevantLabels []string      `json:"relevantLabels,omitempty"`
+	// AnnotationErrors lists the problems with those annotations of a flagged alert.
+	AnnotationErrors []string `json:"annotationErrors,omitempty"`
+	// Values and ValueString are the query results of Grafana alerts.
+	Values      map[string]float64 `json:"values,omitempty"`
+	ValueString string             `json:"valueString,omitempty"`
+	// Fields are the fields extracted from a generic webhook item.
+	Fields map[string]any `json:"fields,omitempty"`
+}
 
-	payload := struct {
-		Labels      map[string]string `json:"labels"`
-		Annotations map[string]string `json:"annotations,omitempty"`
-		Status      string            `json:"status"`
-		Value       string            `json:"value,omitempty"`
-	}{
+func newAlertPayload(alert template.Alert, fingerprintComputed bool, parsed AlertAnnotations, annotationsErr error) AlertPayload {
+	payload := AlertPayload{
 		Labels:      alert.Labels,
 		Annotations: alert.Annotations,
 		Status:      alert.Status,
-		Value:       alert.Annotations[currentValue],
+		Value:       alert.Annotations[CurrentValue],
+
+		FingerprintComputed: fingerprintComputed,
+		ActionContext:       parsed.ActionContext,
+		RelevantLabels:      parsed.RelevantLabels,
+	}
+	if annotationsErr != nil {
+		payload.AnnotationErrors = strings.Split(annotationsErr.Error(), "\n")
 	}
+	return payload
+}
+
+func (w *PromAlertWrapper) toMdaiEvent(alert template.Alert, payload AlertPayload) (eventing.MdaiEvent, error) {
+	annotations := alert.Annotations
 
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
@@ -111,17 +327,18 @@ func (w *PromAlertWrapper) toMdaiEvent(alert template.Alert) (eventing.MdaiEvent
 
 	event := eventing.MdaiEvent{
 		Name:          alert.Annotations[AlertName] + "." + alert.Status,
-		Source:        eventing.PrometheusAlertsEventSource,
+		Source:        w.source,
 		SourceID:      alert.Fingerprint,
 		Timestamp:     changeTime(alert),
-		HubName:       annotations[hubName],
+		HubName:       annotations[HubName],
 		Payload:       string(payloadJSON),
 		CorrelationID: correlationID,
 	}
 	event.ApplyDefaults()
 
 	if err := event.Validate(); err != nil {
-		w.Logger.Error("Failed to validate MdaiEvent", zap.Error(err), zap.Inline(&event))
+		w.Logger.Error("Failed to validate MdaiEvent", zap.Error(err),
+			zap.String("name", event.Name), zap.String("hubName", event.HubName), zap.String("sourceId", event.SourceID))
 		return eventing.MdaiEvent{}, err
 	}
 
@@ -7,7 +7,10 @@ import (
 
 	"github.com/decisiveai/mdai-data-core/eventing"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 )
 
@@ -67,13 +70,13 @@ func TestPrometheusAlertToMdaiEvents(t *testing.T) {
 		},
 	}
 
-	deduper := NewDeduper() // shared across subtests is fine since fingerprints differ
+	deduper := NewMemoryDeduper(0, 0) // shared across subtests is fine since fingerprints differ
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			input := template.Data{Alerts: tt.alerts}
 			wrappedInput := NewPromAlertWrapper(input, zap.NewNop(), deduper)
 
-			events, skipped, err := wrappedInput.ToMdaiEvents()
+			events, skipped, err := wrappedInput.ToMdaiEvents(t.Context())
 			require.NoError(t, err)
 			require.Equal(t, 0, skipped)
 			require.Len(t, events, len(tt.alerts))
@@ -131,7 +134,7 @@ func TestPrometheusAlertToMdaiEvents(t *testing.T) {
 	}
 }
 
-// Verifies that an alert without a fingerprint is rejected with ErrMissingFingerprint.
+// Verifies that an alert without a fingerprint is reported as invalid with ErrMissingFingerprint.
 func TestPrometheusAlertWithoutFingerprint(t *testing.T) {
 	now := time.Now()
 
@@ -148,13 +151,16 @@ func TestPrometheusAlertWithoutFingerprint(t *testing.T) {
 	}
 
 	input := template.Data{Alerts: []template.Alert{alert}}
-	deduper := NewDeduper() // shared/global in real server wiring
+	deduper := NewMemoryDeduper(0, 0) // shared/global in real server wiring
 	wrapped := NewPromAlertWrapper(input, zap.NewNop(), deduper)
 
-	events, skipped, err := wrapped.ToMdaiEvents()
-	require.ErrorIs(t, err, ErrMissingFingerprint)
+	events, skipped, err := wrapped.ToMdaiEvents(t.Context())
+	require.NoError(t, err)
 	require.Empty(t, events)
 	require.Equal(t, 0, skipped)
+	require.Len(t, wrapped.Results(), 1)
+	assert.Equal(t, AlertInvalid, wrapped.Results()[0].Status)
+	assert.Equal(t, ErrMissingFingerprint.Error(), wrapped.Results()[0].Reason)
 }
 
 func TestLatePrometheusAlert(t *testing.T) {
@@ -186,10 +192,151 @@ func TestLatePrometheusAlert(t *testing.T) {
 	}
 
 	input := template.Data{Alerts: alerts}
-	deduper := NewDeduper() // shared/global in real server wiring
+	deduper := NewMemoryDeduper(0, 0) // shared/global in real server wiring
 	wrapped := NewPromAlertWrapper(input, zap.NewNop(), deduper)
 
-	_, skipped, err := wrapped.ToMdaiEvents()
+	_, skipped, err := wrapped.ToMdaiEvents(t.Context())
 	require.NoError(t, err)
 	require.Equal(t, 1, skipped)
+	require.Equal(t, []SkippedAlert{{
+		Fingerprint: "abc123",
+		AlertName:   "DiskUsageHigh",
+		HubName:     "prod-cluster",
+		Status:      "firing",
+		ChangeTime:  now.Add(-2 * time.Minute),
+		LastUpdate:  now.Add(-1 * time.Minute),
+	}}, wrapped.Skipped())
+}
+
+func TestPrometheusAlert_DeduperUnavailable(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+	client.EXPECT().Do(gomock.Any(), gomock.Any()).Return(valkeymock.ErrorResult(assert.AnError))
+
+	input := template.Data{Alerts: []template.Alert{{
+		Annotations: template.KV{"alert_name": "DiskUsageHigh", "hub_name": "prod-cluster"},
+		Status:      "firing",
+		StartsAt:    time.Now(),
+		Fingerprint: "abc123",
+	}}}
+	wrapped := NewPromAlertWrapper(input, zap.NewNop(), NewValkeyDeduper(client, 0))
+
+	// The alert is published rather than lost.
+	events, skipped, err := wrapped.ToMdaiEvents(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 0, skipped)
+	require.Len(t, events, 1)
+}
+
+func TestPrometheusAlert_ComputedFingerprint(t *testing.T) {
+	alert := template.Alert{
+		Annotations: template.KV{"alert_name": "DiskUsageHigh", "hub_name": "prod-cluster"},
+		Labels:      template.KV{"alertname": "DiskUsageHigh", "severity": "critical"},
+		Status:      "firing",
+		StartsAt:    time.Now(),
+	}
+	input := template.Data{Alerts: []template.Alert{alert, alert}}
+	input.Alerts[1].Annotations = template.KV{"alert_name": "DiskUsageHigh", "hub_name": "other-cluster"}
+
+	wrapped := NewPromAlertWrapper(input, zap.NewNop(), NewMemoryDeduper(0, 0))
+	wrapped.HubConfig = func(hubName string) HubConfig { return HubConfig{ComputeFingerprint: hubName == "prod-cluster"} }
+
+	events, _, err := wrapped.ToMdaiEvents(t.Context())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	// The fingerprint Alertmanager gives the same labels.
+	const fingerprint = "27700f085fc1d2fc"
+	assert.Equal(t, fingerprint, events[0].Event.SourceID)
+	assert.Equal(t, "prod-cluster."+fingerprint, events[0].Subject.Path)
+	assert.Equal(t, map[string]string{"fingerprint_computed": "true"}, events[0].AuditFields)
+	var payload AlertPayload
+	require.NoError(t, json.Unmarshal([]byte(events[0].Event.Payload), &payload))
+	assert.True(t, payload.FingerprintComputed)
+
+	results := wrapped.Results()
+	require.Len(t, results, 2)
+	assert.Equal(t, fingerprint, results[0].Fingerprint)
+	assert.True(t, results[0].FingerprintComputed)
+	// Hubs that do not compute fingerprints still reject the alert.
+	assert.Equal(t, AlertInvalid, results[1].Status)
+	assert.Equal(t, ErrMissingFingerprint.Error(), results[1].Reason)
+}
+
+func TestPrometheusAlert_AlertState(t *testing.T) {
+	startsAt := time.Date(2025, 8, 8, 15, 0, 0, 0, time.UTC)
+	input := template.Data{Alerts: []template.Alert{{
+		Annotations: template.KV{"alert_name": "DiskUsageHigh", "hub_name": "prod-cluster"},
+		Labels:      template.KV{"alertname": "DiskUsageHigh"},
+		Status:      "resolved",
+		StartsAt:    startsAt,
+		EndsAt:      startsAt.Add(time.Hour),
+	}}}
+	wrapped := NewPromAlertWrapper(input, zap.NewNop(), NewMemoryDeduper(0, 0))
+	wrapped.HubConfig = func(string) HubConfig { return HubConfig{ComputeFingerprint: true} }
+
+	_, _, err := wrapped.ToMdaiEvents(t.Context())
+	require.NoError(t, err)
+
+	state := wrapped.AlertState(0)
+	assert.Equal(t, LabelsFingerprint(input.Alerts[0].Labels), state.Fingerprint)
+	assert.Equal(t, "DiskUsageHigh", state.AlertName)
+	assert.Equal(t, "prod-cluster", state.HubName)
+	assert.Equal(t, eventing.PrometheusAlertsEventSource, state.Source)
+	assert.False(t, state.Firing())
+	assert.Equal(t, startsAt, state.FiringSince)
+	assert.Equal(t, startsAt.Add(time.Hour), state.ChangeTime())
+}
+
+func TestLabelsFingerprint(t *testing.T) {
+	a := LabelsFingerprint(map[string]string{"alertname": "DiskUsageHigh", "severity": "critical"})
+	b := LabelsFingerprint(map[string]string{"severity": "critical", "alertname": "DiskUsageHigh"})
+	assert.Equal(t, a, b)
+	assert.Len(t, a, 16)
+	assert.NotEqual(t, a, LabelsFingerprint(map[string]string{"alertname": "DiskUsageHigh", "severity": "warning"}))
+}
+
+func TestPrometheusAlert_InvalidAnnotations(t *testing.T) {
+	alert := template.Alert{
+		Annotations: template.KV{
+			"alert_name":      "top_talkers",
+			"hub_name":        "flagging",
+			"action_context":  `{"firing":{"variableUpdate":{"variableRef":"services","operation":"mdai/set"}}}`,
+			"relevant_labels": `["service_name"]`,
+		},
+		Status:      "firing",
+		StartsAt:    time.Now(),
+		Fingerprint: "fp-1",
+	}
+	rejected := alert
+	rejected.Annotations = template.KV{"alert_name": "top_talkers", "hub_name": "rejecting", "action_context": alert.Annotations["action_context"]}
+	rejected.Fingerprint = "fp-2"
+	input := template.Data{Alerts: []template.Alert{alert, rejected}}
+
+	wrapped := NewPromAlertWrapper(input, zap.NewNop(), NewMemoryDeduper(0, 0))
+	wrapped.HubConfig = func(hubName string) HubConfig {
+		config := HubConfig{InvalidAnnotations: AnnotationsFlag, Variables: []string{"service_list"}}
+		if hubName == "rejecting" {
+			config.InvalidAnnotations = AnnotationsReject
+		}
+		return config
+	}
+
+	events, _, err := wrapped.ToMdaiEvents(t.Context())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	const reason = `action_context.firing: variable "services" is not declared by the hub`
+	var payload AlertPayload
+	require.NoError(t, json.Unmarshal([]byte(events[0].Event.Payload), &payload))
+	assert.Equal(t, ActionContext{"firing": {VariableUpdate: &VariableUpdate{VariableRef: "services", Operation: "mdai/set"}}}, payload.ActionContext)
+	assert.Equal(t, []string{"service_name"}, payload.RelevantLabels)
+	assert.Equal(t, []string{reason}, payload.AnnotationErrors)
+	assert.Equal(t, reason, events[0].AuditFields["annotation_errors"])
+
+	results := wrapped.Results()
+	require.Len(t, results, 2)
+	assert.Empty(t, results[0].Status)
+	assert.Equal(t, reason, results[0].Reason)
+	assert.Equal(t, AlertInvalid, results[1].Status)
+	assert.Equal(t, reason, results[1].Reason)
 }
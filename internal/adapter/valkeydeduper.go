@@ -0,0 +1,123 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+const deduperKeyPrefix = "alert/dedup/"
+
+// updateIfNewerScript stores the change time of a fingerprint unless the stored one is the same or
+// newer, and returns whether it did and the time stored afterwards. Times are "seconds:nanos"
+// since they do not fit the precision of Lua numbers as nanoseconds. Every call refreshes the TTL,
+// so a fingerprint expires once it has not been seen for the TTL.
+var updateIfNewerScript = valkey.NewLuaScript(`
+local sec, nsec = tonumber(ARGV[1]), tonumber(ARGV[2])
+local prev = redis.call('GET', KEYS[1])
+if prev then
+	local psec, pnsec = string.match(prev, '^(-?%d+):(%d+)$')
+	psec, pnsec = tonumber(psec), tonumber(pnsec)
+	if psec and (sec < psec or (sec == psec and nsec <= pnsec)) then
+		redis.call('PEXPIRE', KEYS[1], ARGV[3])
+		return {0, prev}
+	end
+end
+local value = ARGV[1] .. ':' .. ARGV[2]
+redis.call('SET', KEYS[1], value, 'PX', ARGV[3])
+return {1, value}
+`)
+
+// forgetScript deletes the key of a fingerprint if it still holds the given change time.
+var forgetScript = valkey.NewLuaScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// ValkeyDeduper is a Deduper that keeps each fingerprint under its own key in Valkey, so that the
+// state survives restarts and is shared by all replicas. The comparison runs in a script, which
+// makes concurrent updates of the same fingerprint from several replicas safe.
+type ValkeyDeduper struct {
+	client valkey.Client
+	ttl    time.Duration
+}
+
+// NewValkeyDeduper returns a deduper whose keys expire once their fingerprint has not been seen
+// for ttl. A non-positive ttl selects the default.
+func NewValkeyDeduper(client valkey.Client, ttl time.Duration) *ValkeyDeduper {
+	if ttl <= 0 {
+		ttl = DefaultDeduperTTL
+	}
+	return &ValkeyDeduper{client: client, ttl: ttl}
+}
+
+func (d *ValkeyDeduper) UpdateIfNewer(ctx context.Context, fingerprint string, changeTime time.Time) (bool, time.Time, error) {
+	args := []string{
+		strconv.FormatInt(changeTime.Unix(), 10),
+		strconv.Itoa(changeTime.Nanosecond()),
+		strconv.FormatInt(d.ttl.Milliseconds(), 10),
+	}
+	result, err := updateIfNewerScript.Exec(ctx, d.client, []string{deduperKeyPrefix + fingerprint}, args).ToArray()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if len(result) != 2 {
+		return false, time.Time{}, fmt.Errorf("unexpected deduper script result of length %d", len(result))
+	}
+	updated, err := result[0].AsInt64()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	value, err := result[1].ToString()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	stored, err := parseDeduperTime(value)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	return updated == 1, stored, nil
+}
+
+func (d *ValkeyDeduper) PeekLast(ctx context.Context, fingerprint string) (time.Time, bool, error) {
+	value, err := d.client.Do(ctx, d.client.B().Get().Key(deduperKeyPrefix+fingerprint).Build()).ToString()
+	if valkey.IsValkeyNil(err) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	stored, err := parseDeduperTime(value)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return stored, true, nil
+}
+
+func (d *ValkeyDeduper) Forget(ctx context.Context, fingerprint string, changeTime time.Time) error {
+	return forgetScript.Exec(ctx, d.client, []string{deduperKeyPrefix + fingerprint}, []string{formatDeduperTime(changeTime)}).Error()
+}
+
+func (d *ValkeyDeduper) Stats(_ context.Context) (DeduperStats, error) {
+	return DeduperStats{Backend: DeduperBackendValkey, TTL: d.ttl.String()}, nil
+}
+
+func formatDeduperTime(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10) + ":" + strconv.Itoa(t.Nanosecond())
+}
+
+func parseDeduperTime(value string) (time.Time, error) {
+	secs, nanos, ok := strings.Cut(value, ":")
+	sec, secErr := strconv.ParseInt(secs, 10, 64)
+	nsec, nsecErr := strconv.ParseInt(nanos, 10, 64)
+	if !ok || secErr != nil || nsecErr != nil {
+		return time.Time{}, fmt.Errorf("invalid stored alert change time %q", value)
+	}
+	return time.Unix(sec, nsec).UTC(), nil
+}
@@ -0,0 +1,93 @@
+// Package alertpush implements the alerts API of Alertmanager v2, so that Prometheus can push its
+// alerts to the gateway without an Alertmanager in between.
+package alertpush
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/common/model"
+)
+
+// Receiver is the receiver of the notifications made from pushed alerts.
+const Receiver = "alerts-api"
+
+// PostableAlert is an alert of a POST /api/v2/alerts request.
+type PostableAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt,omitzero"`
+	EndsAt       time.Time         `json:"endsAt,omitzero"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Validate checks the alert the way Alertmanager does.
+func (a PostableAlert) Validate() error {
+	if len(a.Labels) == 0 {
+		return errors.New("at least one label pair required")
+	}
+	if err := labelSet(a.Labels).Validate(); err != nil {
+		return err
+	}
+	if err := labelSet(a.Annotations).Validate(); err != nil {
+		return fmt.Errorf("invalid annotations: %w", err)
+	}
+	if !a.StartsAt.IsZero() && !a.EndsAt.IsZero() && a.EndsAt.Before(a.StartsAt) {
+		return errors.New("start time must be before end time")
+	}
+	return nil
+}
+
+func labelSet(labels map[string]string) model.LabelSet {
+	set := make(model.LabelSet, len(labels))
+	for name, value := range labels {
+		set[model.LabelName(name)] = model.LabelValue(value)
+	}
+	return set
+}
+
+// Group groups alerts into one notification per hub, named by their hub_name annotation, like an
+// Alertmanager route grouping by hub. Notifications are ordered by hub and keep the order of
+// their alerts.
+func Group(alerts template.Alerts) []template.Data {
+	byHub := make(map[string]template.Alerts)
+	for _, alert := range alerts {
+		hubName := alert.Annotations[adapter.HubName]
+		byHub[hubName] = append(byHub[hubName], alert)
+	}
+
+	groups := make([]template.Data, 0, len(byHub))
+	for _, hubName := range slices.Sorted(maps.Keys(byHub)) {
+		alerts := byHub[hubName]
+		status := "resolved"
+		if len(alerts.Firing()) > 0 {
+			status = "firing"
+		}
+		groups = append(groups, template.Data{
+			Receiver:          Receiver,
+			Status:            status,
+			Alerts:            alerts,
+			GroupLabels:       template.KV{adapter.HubName: hubName},
+			CommonLabels:      common(alerts, func(a template.Alert) template.KV { return a.Labels }),
+			CommonAnnotations: common(alerts, func(a template.Alert) template.KV { return a.Annotations }),
+		})
+	}
+	return groups
+}
+
+// common returns the pairs that all alerts share.
+func common(alerts template.Alerts, pairs func(template.Alert) template.KV) template.KV {
+	shared := maps.Clone(pairs(alerts[0]))
+	for _, alert := range alerts[1:] {
+		maps.DeleteFunc(shared, func(name, value string) bool { return pairs(alert)[name] != value })
+	}
+	if shared == nil {
+		shared = template.KV{}
+	}
+	return shared
+}
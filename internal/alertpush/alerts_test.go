@@ -0,0 +1,73 @@
+package alertpush
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostableAlertValidate(t *testing.T) {
+	start := time.Date(2025, 8, 8, 15, 56, 10, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		alert   PostableAlert
+		wantErr string
+	}{
+		{name: "valid", alert: PostableAlert{Labels: map[string]string{"alertname": "top_talkers"}, StartsAt: start}},
+		{name: "no labels", alert: PostableAlert{}, wantErr: "at least one label pair required"},
+		{name: "invalid label name", alert: PostableAlert{Labels: map[string]string{"\xff": "x"}}, wantErr: "invalid name"},
+		{
+			name:    "invalid annotation value",
+			alert:   PostableAlert{Labels: map[string]string{"alertname": "top_talkers"}, Annotations: map[string]string{"summary": "\xff"}},
+			wantErr: "invalid annotations",
+		},
+		{
+			name:    "ends before start",
+			alert:   PostableAlert{Labels: map[string]string{"alertname": "top_talkers"}, StartsAt: start, EndsAt: start.Add(-time.Second)},
+			wantErr: "start time must be before end time",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.alert.Validate()
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	alert := func(hubName, service, status string) template.Alert {
+		return template.Alert{
+			Status:      status,
+			Labels:      template.KV{"alertname": "top_talkers", "service": service},
+			Annotations: template.KV{adapter.HubName: hubName},
+		}
+	}
+
+	groups := Group(template.Alerts{
+		alert("second", "cart", "resolved"),
+		alert("first", "checkout", "resolved"),
+		alert("second", "checkout", "firing"),
+	})
+	require.Len(t, groups, 2)
+
+	assert.Equal(t, Receiver, groups[0].Receiver)
+	assert.Equal(t, "resolved", groups[0].Status)
+	assert.Equal(t, template.KV{adapter.HubName: "first"}, groups[0].GroupLabels)
+	assert.Equal(t, template.KV{"alertname": "top_talkers", "service": "checkout"}, groups[0].CommonLabels)
+
+	assert.Equal(t, "firing", groups[1].Status)
+	assert.Len(t, groups[1].Alerts, 2)
+	assert.Equal(t, "cart", groups[1].Alerts[0].Labels["service"])
+	assert.Equal(t, template.KV{"alertname": "top_talkers"}, groups[1].CommonLabels)
+	assert.Equal(t, template.KV{adapter.HubName: "second"}, groups[1].CommonAnnotations)
+}
@@ -0,0 +1,296 @@
+package alertpush
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/valkey-io/valkey-go"
+)
+
+const (
+	// DefaultResolveTimeout is how long an alert pushed without an end time stays firing when it is
+	// not pushed again, like the resolve_timeout of Alertmanager.
+	DefaultResolveTimeout = 5 * time.Minute
+
+	// The keys share a hash tag so that the scripts can update both on a cluster.
+	alertsKey = "{alert/push}/alerts"
+	endsKey   = "{alert/push}/ends"
+
+	// maxUpdateAttempts bounds how often an update is retried when other replicas keep changing
+	// the same alerts.
+	maxUpdateAttempts = 10
+)
+
+// setScript stores each given alert unless its fingerprint no longer holds the value the alert was
+// computed from, and returns 1 for each alert it stored and 0 for the others. Arguments come in
+// fours: the fingerprint, the value read before ("" if none), the value to store ("" to delete the
+// alert) and the end time of the alert in Unix milliseconds.
+var setScript = valkey.NewLuaScript(`
+local applied = {}
+for i = 1, #ARGV, 4 do
+	local fingerprint, expected, value = ARGV[i], ARGV[i + 1], ARGV[i + 2]
+	if (redis.call('HGET', KEYS[1], fingerprint) or '') == expected then
+		if value == '' then
+			redis.call('HDEL', KEYS[1], fingerprint)
+			redis.call('ZREM', KEYS[2], fingerprint)
+		else
+			redis.call('HSET', KEYS[1], fingerprint, value)
+			redis.call('ZADD', KEYS[2], ARGV[i + 3], fingerprint)
+		end
+		applied[#applied + 1] = 1
+	else
+		applied[#applied + 1] = 0
+	end
+end
+return applied
+`)
+
+// claimScript deletes the alerts whose end time in Unix milliseconds is not after ARGV[1] and
+// returns them, so that each expired alert is claimed by a single replica.
+var claimScript = valkey.NewLuaScript(`
+local claimed = {}
+for _, fingerprint in ipairs(redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])) do
+	local value = redis.call('HGET', KEYS[1], fingerprint)
+	if value then
+		claimed[#claimed + 1] = value
+	end
+	redis.call('HDEL', KEYS[1], fingerprint)
+	redis.call('ZREM', KEYS[2], fingerprint)
+end
+return claimed
+`)
+
+// Tracker keeps the firing pushed alerts until they end. Prometheus pushes every firing alert
+// again at each evaluation with an end time a few evaluations ahead; an alert that is not pushed
+// again before its end time resolves, e.g. when the Prometheus that raised it is gone.
+//
+// The alerts are kept in Valkey, in a hash by fingerprint and a sorted set of their end times, so
+// that they survive restarts and every replica sees the alerts pushed to the others. Alerts are
+// updated with a compare-and-set script and retried when another replica changed them meanwhile.
+type Tracker struct {
+	client         valkey.Client
+	resolveTimeout time.Duration
+	now            func() time.Time
+}
+
+func NewTracker(client valkey.Client, resolveTimeout time.Duration) *Tracker {
+	if resolveTimeout <= 0 {
+		resolveTimeout = DefaultResolveTimeout
+	}
+	return &Tracker{client: client, resolveTimeout: resolveTimeout, now: time.Now}
+}
+
+// Put merges the pushed alerts into the tracked ones and returns those that changed state as the
+// alerts of a notification, in the order they were pushed, together with the number of alerts that
+// did not. The fingerprint is computed from the labels, the start time defaults to the time of
+// receipt and the end time to the resolve timeout after it. An alert is resolved once its end time
+// has passed; a firing alert overlapping the one tracked for its fingerprint keeps the earlier
+// start time. An alert that was already firing with the same start time only has its end time
+// extended, so that the repeated pushes of an alert are not processed again.
+func (t *Tracker) Put(ctx context.Context, alerts []PostableAlert) (changed template.Alerts, unchanged int, err error) {
+	now := t.now()
+
+	fingerprints := make([]string, len(alerts))
+	for i, a := range alerts {
+		fingerprints[i] = adapter.LabelsFingerprint(a.Labels)
+	}
+	merged := make(template.Alerts, len(alerts))
+	same := make([]bool, len(alerts))
+	err = t.update(ctx, fingerprints, func(i int, tracked *template.Alert) *template.Alert {
+		a := alerts[i]
+		alert := template.Alert{
+			Labels:       maps.Clone(a.Labels),
+			Annotations:  maps.Clone(a.Annotations),
+			StartsAt:     a.StartsAt,
+			EndsAt:       a.EndsAt,
+			GeneratorURL: a.GeneratorURL,
+			Fingerprint:  fingerprints[i],
+		}
+		if alert.Annotations == nil {
+			alert.Annotations = template.KV{}
+		}
+		if alert.StartsAt.IsZero() {
+			alert.StartsAt = cmp.Or(alert.EndsAt, now)
+		}
+		if alert.EndsAt.IsZero() {
+			alert.EndsAt = now.Add(t.resolveTimeout)
+		}
+		if tracked != nil && !alert.StartsAt.After(tracked.EndsAt) && tracked.StartsAt.Before(alert.StartsAt) {
+			alert.StartsAt = tracked.StartsAt
+		}
+
+		if alert.EndsAt.After(now) {
+			alert.Status = "firing"
+			merged[i], same[i] = alert, tracked != nil && tracked.StartsAt.Equal(alert.StartsAt)
+			return &alert
+		}
+		alert.Status = "resolved"
+		merged[i], same[i] = alert, false
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changed = make(template.Alerts, 0, len(alerts))
+	for i, alert := range merged {
+		switch {
+		case same[i]:
+			unchanged++
+		case alert.Status == "firing":
+			changed = append(changed, notified(alert))
+		default:
+			changed = append(changed, alert)
+		}
+	}
+	return changed, unchanged, nil
+}
+
+// Forget stops tracking firing alerts, e.g. when they could not be processed, so that their next
+// push is processed again. Alerts that started again since are kept.
+func (t *Tracker) Forget(ctx context.Context, alerts template.Alerts) error {
+	return t.update(ctx, alertFingerprints(alerts), func(i int, tracked *template.Alert) *template.Alert {
+		if tracked != nil && tracked.StartsAt.Equal(alerts[i].StartsAt) {
+			return nil
+		}
+		return tracked
+	})
+}
+
+// Expired stops tracking the alerts whose end time has passed and returns them resolved, ordered
+// by fingerprint. Each expired alert is returned to a single caller, whichever replica it runs on.
+func (t *Tracker) Expired(ctx context.Context) (template.Alerts, error) {
+	now := strconv.FormatInt(t.now().UnixMilli(), 10)
+	values, err := claimScript.Exec(ctx, t.client, []string{alertsKey, endsKey}, []string{now}).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := make(template.Alerts, 0, len(values))
+	for _, value := range values {
+		var alert template.Alert
+		if err := json.Unmarshal([]byte(value), &alert); err != nil {
+			return nil, fmt.Errorf("invalid tracked pushed alert: %w", err)
+		}
+		alert.Status = "resolved"
+		expired = append(expired, alert)
+	}
+	slices.SortFunc(expired, func(a, b template.Alert) int { return cmp.Compare(a.Fingerprint, b.Fingerprint) })
+	return expired, nil
+}
+
+// Restore tracks expired alerts again, e.g. when their resolution could not be published, so that
+// the next sweep returns them again. Alerts pushed since they expired are kept.
+func (t *Tracker) Restore(ctx context.Context, alerts template.Alerts) error {
+	return t.update(ctx, alertFingerprints(alerts), func(i int, tracked *template.Alert) *template.Alert {
+		if tracked != nil {
+			return tracked
+		}
+		alert := alerts[i]
+		alert.Status = "firing"
+		return &alert
+	})
+}
+
+// Len returns how many firing alerts are tracked.
+func (t *Tracker) Len(ctx context.Context) (int, error) {
+	n, err := t.client.Do(ctx, t.client.B().Hlen().Key(alertsKey).Build()).AsInt64()
+	return int(n), err
+}
+
+// update replaces the alert tracked for each fingerprint, nil if there is none, with the alert
+// change returns for it, nil to stop tracking it. When another replica changed some of the alerts
+// meanwhile, change is called again for those with the alerts they hold now.
+func (t *Tracker) update(ctx context.Context, fingerprints []string, change func(i int, tracked *template.Alert) *template.Alert) error {
+	pending := make([]int, len(fingerprints))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt == maxUpdateAttempts {
+			return fmt.Errorf("pushed alerts kept changing concurrently for %d attempts", maxUpdateAttempts)
+		}
+
+		fields := make([]string, len(pending))
+		for j, i := range pending {
+			fields[j] = fingerprints[i]
+		}
+		values, err := t.client.Do(ctx, t.client.B().Hmget().Key(alertsKey).Field(fields...).Build()).ToArray()
+		if err != nil {
+			return err
+		}
+
+		var args []string
+		var written []int
+		for j, i := range pending {
+			stored, err := values[j].ToString()
+			if err != nil && !valkey.IsValkeyNil(err) {
+				return err
+			}
+			var tracked *template.Alert
+			if stored != "" {
+				tracked = &template.Alert{}
+				if err := json.Unmarshal([]byte(stored), tracked); err != nil {
+					return fmt.Errorf("invalid tracked pushed alert %s: %w", fingerprints[i], err)
+				}
+			}
+
+			next := change(i, tracked)
+			if next == tracked {
+				continue
+			}
+			value, endsAt := "", "0"
+			if next != nil {
+				doc, err := json.Marshal(next)
+				if err != nil {
+					return err
+				}
+				value, endsAt = string(doc), strconv.FormatInt(next.EndsAt.UnixMilli(), 10)
+			}
+			args = append(args, fingerprints[i], stored, value, endsAt)
+			written = append(written, i)
+		}
+		if len(written) == 0 {
+			return nil
+		}
+
+		applied, err := setScript.Exec(ctx, t.client, []string{alertsKey, endsKey}, args).AsIntSlice()
+		if err != nil {
+			return err
+		}
+		if len(applied) != len(written) {
+			return fmt.Errorf("unexpected pushed alert script result of length %d", len(applied))
+		}
+		pending = pending[:0]
+		for k, i := range written {
+			if applied[k] == 0 {
+				pending = append(pending, i)
+			}
+		}
+	}
+	return nil
+}
+
+func alertFingerprints(alerts template.Alerts) []string {
+	fingerprints := make([]string, len(alerts))
+	for i, alert := range alerts {
+		fingerprints[i] = alert.Fingerprint
+	}
+	return fingerprints
+}
+
+// notified returns a firing alert as Alertmanager notifies it, without the end time that only
+// bounds how long it stays firing.
+func notified(alert template.Alert) template.Alert {
+	alert.EndsAt = time.Time{}
+	return alert
+}
@@ -0,0 +1,159 @@
+package alertpush
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/valkeytest"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+)
+
+// newTestTracker returns a tracker on client whose clock reads *now.
+func newTestTracker(client valkey.Client, resolveTimeout time.Duration, now *time.Time) *Tracker {
+	tracker := NewTracker(client, resolveTimeout)
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func trackedLen(t *testing.T, tracker *Tracker) int {
+	t.Helper()
+	n, err := tracker.Len(t.Context())
+	require.NoError(t, err)
+	return n
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2025, 8, 8, 16, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(valkeytest.New(t), time.Minute, &now)
+
+	labels := map[string]string{"alertname": "top_talkers", "service": "checkout"}
+	annotations := map[string]string{adapter.HubName: "mdaihub-sample"}
+	fingerprint := adapter.LabelsFingerprint(labels)
+	activeAt := now.Add(-10 * time.Minute)
+
+	// Prometheus pushes firing alerts with an end time a few evaluations ahead.
+	pushed, unchanged, err := tracker.Put(t.Context(), []PostableAlert{{Labels: labels, Annotations: annotations, StartsAt: activeAt, EndsAt: now.Add(4 * time.Minute)}})
+	require.NoError(t, err)
+	require.Len(t, pushed, 1)
+	assert.Zero(t, unchanged)
+	assert.Equal(t, template.Alert{
+		Status:      "firing",
+		Labels:      labels,
+		Annotations: annotations,
+		StartsAt:    activeAt,
+		Fingerprint: fingerprint,
+	}, pushed[0])
+	assert.Equal(t, 1, trackedLen(t, tracker))
+
+	// A push without times overlaps the tracked alert and keeps its start time, so the alert has
+	// not changed and is only extended.
+	now = now.Add(time.Minute)
+	pushed, unchanged, err = tracker.Put(t.Context(), []PostableAlert{{Labels: labels, Annotations: annotations}})
+	require.NoError(t, err)
+	assert.Empty(t, pushed)
+	assert.Equal(t, 1, unchanged)
+	assert.Equal(t, 1, trackedLen(t, tracker))
+
+	// A forgotten alert is processed again at its next push.
+	require.NoError(t, tracker.Forget(t.Context(), template.Alerts{{Fingerprint: fingerprint, StartsAt: activeAt}}))
+	assert.Equal(t, 0, trackedLen(t, tracker))
+	pushed, _, err = tracker.Put(t.Context(), []PostableAlert{{Labels: labels, Annotations: annotations, StartsAt: activeAt}})
+	require.NoError(t, err)
+	require.Len(t, pushed, 1)
+	assert.Equal(t, activeAt, pushed[0].StartsAt)
+
+	// Only the alert with the start time forgotten is forgotten.
+	require.NoError(t, tracker.Forget(t.Context(), template.Alerts{{Fingerprint: fingerprint, StartsAt: now}}))
+	assert.Equal(t, 1, trackedLen(t, tracker))
+
+	// An end time in the past resolves the alert.
+	resolvedAt := now.Add(-time.Second)
+	pushed, _, err = tracker.Put(t.Context(), []PostableAlert{{Labels: labels, Annotations: annotations, StartsAt: activeAt, EndsAt: resolvedAt}})
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", pushed[0].Status)
+	assert.Equal(t, resolvedAt, pushed[0].EndsAt)
+	assert.Equal(t, 0, trackedLen(t, tracker))
+}
+
+func TestTrackerExpired(t *testing.T) {
+	now := time.Date(2025, 8, 8, 16, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(valkeytest.New(t), time.Minute, &now)
+
+	_, _, err := tracker.Put(t.Context(), []PostableAlert{
+		{Labels: map[string]string{"alertname": "a"}},
+		{Labels: map[string]string{"alertname": "b"}, EndsAt: now.Add(time.Hour)},
+	})
+	require.NoError(t, err)
+	expired, err := tracker.Expired(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	// The alert pushed without an end time resolves after the resolve timeout.
+	now = now.Add(time.Minute)
+	expired, err = tracker.Expired(t.Context())
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "a", expired[0].Labels["alertname"])
+	assert.Equal(t, "resolved", expired[0].Status)
+	assert.Equal(t, now, expired[0].EndsAt)
+	assert.Equal(t, 1, trackedLen(t, tracker))
+
+	require.NoError(t, tracker.Restore(t.Context(), expired))
+	assert.Equal(t, 2, trackedLen(t, tracker))
+	expired, err = tracker.Expired(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, expired, 1)
+}
+
+func TestTrackerReplicas(t *testing.T) {
+	now := time.Date(2025, 8, 8, 16, 0, 0, 0, time.UTC)
+	client := valkeytest.New(t)
+	first := newTestTracker(client, time.Minute, &now)
+	second := newTestTracker(client, time.Minute, &now)
+
+	alert := PostableAlert{Labels: map[string]string{"alertname": "top_talkers"}, StartsAt: now}
+	pushed, _, err := first.Put(t.Context(), []PostableAlert{alert})
+	require.NoError(t, err)
+	require.Len(t, pushed, 1)
+
+	// The alert pushed to one replica is not processed again when it is pushed to another.
+	pushed, unchanged, err := second.Put(t.Context(), []PostableAlert{alert})
+	require.NoError(t, err)
+	assert.Empty(t, pushed)
+	assert.Equal(t, 1, unchanged)
+
+	// Once it expired, a single replica resolves it.
+	now = now.Add(time.Minute)
+	expired, err := second.Expired(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, expired, 1)
+	expired, err = first.Expired(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+}
+
+func TestTrackerConcurrentPuts(t *testing.T) {
+	client := valkeytest.New(t)
+	now := time.Date(2025, 8, 8, 16, 0, 0, 0, time.UTC)
+	alerts := []PostableAlert{{Labels: map[string]string{"alertname": "top_talkers"}, StartsAt: now}}
+
+	// Replicas receiving the same alert at once process it once between them.
+	const replicas = 8
+	results := make(chan int, replicas)
+	for range replicas {
+		go func() {
+			pushed, _, err := newTestTracker(client, time.Minute, &now).Put(t.Context(), alerts)
+			assert.NoError(t, err)
+			results <- len(pushed)
+		}()
+	}
+	processed := 0
+	for range replicas {
+		processed += <-results
+	}
+	assert.Equal(t, 1, processed)
+}
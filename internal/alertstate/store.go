@@ -0,0 +1,293 @@
+// Package alertstate keeps the current state of every alert the gateway processed and the
+// transitions that led to it.
+package alertstate
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+const (
+	// DefaultRetention is how long the state and history of a resolved alert are kept.
+	DefaultRetention = 7 * 24 * time.Hour
+	// DefaultStaleness is how long the state and history of a firing alert are kept after its
+	// last recorded state, e.g. when its resolution never arrives.
+	DefaultStaleness = 7 * 24 * time.Hour
+	// DefaultHistoryLength bounds the number of transitions kept per alert.
+	DefaultHistoryLength = 100
+
+	stateKeyPrefix   = "alert/state/"
+	historyKeyPrefix = "alert/history/"
+	activeKey        = "alert/active"
+)
+
+// State is the current state of an alert.
+type State struct {
+	Fingerprint string            `json:"fingerprint"`
+	AlertName   string            `json:"alertName,omitempty"`
+	HubName     string            `json:"hubName"`
+	Status      string            `json:"status"`
+	Source      string            `json:"source"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// FiringSince is when the alert started firing, and ResolvedAt when it resolved.
+	FiringSince time.Time `json:"firingSince,omitzero"`
+	ResolvedAt  time.Time `json:"resolvedAt,omitzero"`
+	// LastUpdate is when the gateway recorded the state.
+	LastUpdate time.Time `json:"lastUpdate"`
+}
+
+// Firing reports whether the alert is firing, i.e. not resolved.
+func (s State) Firing() bool {
+	return !strings.EqualFold(s.Status, "resolved")
+}
+
+// ChangeTime returns when the alert entered its status.
+func (s State) ChangeTime() time.Time {
+	if s.Firing() {
+		return s.FiringSince
+	}
+	return s.ResolvedAt
+}
+
+// Transition is a change of status of an alert.
+type Transition struct {
+	Status     string    `json:"status"`
+	ChangeTime time.Time `json:"changeTime"`
+	Source     string    `json:"source"`
+	// EventID is the ID of the event published for the transition.
+	EventID    string    `json:"eventId,omitempty"`
+	RecordedAt time.Time `json:"recordedAt"`
+}
+
+// Filter selects active alerts. Empty fields match every alert.
+type Filter struct {
+	HubName   string
+	AlertName string
+}
+
+func (f Filter) matches(state State) bool {
+	return (f.HubName == "" || state.HubName == f.HubName) && (f.AlertName == "" || state.AlertName == f.AlertName)
+}
+
+// recordScript appends a transition to the history of an alert and makes its state the current one
+// unless the current state changed at the same time or later, e.g. when replicas process the
+// states of an alert out of order. Change times are "seconds:nanos" as in the alert deduper. The
+// firing alerts are also kept in the active hash. The state and history of a firing alert expire
+// once no state was recorded for it for the staleness, and those of a resolved alert after the
+// retention.
+var recordScript = valkey.NewLuaScript(`
+local sec, nsec = tonumber(ARGV[2]), tonumber(ARGV[3])
+local newer = 1
+local prev = redis.call('HGET', KEYS[1], 'changeTime')
+if prev then
+	local psec, pnsec = string.match(prev, '^(-?%d+):(%d+)$')
+	psec, pnsec = tonumber(psec), tonumber(pnsec)
+	if psec and (sec < psec or (sec == psec and nsec <= pnsec)) then
+		newer = 0
+	end
+end
+if newer == 1 then
+	redis.call('HSET', KEYS[1], 'changeTime', ARGV[2] .. ':' .. ARGV[3], 'state', ARGV[4])
+	if ARGV[5] == '1' then
+		redis.call('HSET', KEYS[3], ARGV[1], ARGV[4])
+	else
+		redis.call('HDEL', KEYS[3], ARGV[1])
+	end
+end
+redis.call('RPUSH', KEYS[2], ARGV[6])
+redis.call('LTRIM', KEYS[2], -tonumber(ARGV[7]), -1)
+local ttl = ARGV[8]
+if redis.call('HEXISTS', KEYS[3], ARGV[1]) == 1 then
+	ttl = ARGV[9]
+end
+redis.call('PEXPIRE', KEYS[1], ttl)
+redis.call('PEXPIRE', KEYS[2], ttl)
+return newer
+`)
+
+// pruneScript removes stale firing alerts from the active hash, whose fields do not expire with
+// the state of their alert. The keys are the active hash followed by the state and history key of
+// each alert, and the arguments the fingerprint and the state read as stale of each alert. An
+// alert is only removed if no state was recorded for it meanwhile. Its state and history are
+// deleted as well, for the alerts recorded before firing states expired.
+var pruneScript = valkey.NewLuaScript(`
+for i = 1, #ARGV, 2 do
+	if redis.call('HGET', KEYS[1], ARGV[i]) == ARGV[i + 1] then
+		redis.call('HDEL', KEYS[1], ARGV[i])
+		if redis.call('HGET', KEYS[i + 1], 'state') == ARGV[i + 1] then
+			redis.call('DEL', KEYS[i + 1], KEYS[i + 2])
+		end
+	end
+end
+return 0
+`)
+
+// Store keeps the current state of each alert in a Valkey hash per fingerprint, the states of the
+// firing alerts in one hash by fingerprint and the transitions of each alert in a list.
+type Store struct {
+	client        valkey.Client
+	retention     time.Duration
+	staleness     time.Duration
+	historyLength int
+	now           func() time.Time
+}
+
+// NewStore returns a store that keeps resolved alerts for retention, firing alerts for staleness
+// after their last recorded state and at most historyLength transitions per alert. Non-positive
+// values select the defaults.
+func NewStore(client valkey.Client, retention, staleness time.Duration, historyLength int) *Store {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if staleness <= 0 {
+		staleness = DefaultStaleness
+	}
+	if historyLength <= 0 {
+		historyLength = DefaultHistoryLength
+	}
+	return &Store{client: client, retention: retention, staleness: staleness, historyLength: historyLength, now: time.Now}
+}
+
+// Record adds the transition of an alert to state, published as the event eventID. It
+// returns whether state became the current state of the alert.
+func (s *Store) Record(ctx context.Context, state State, eventID string) (bool, error) {
+	state.LastUpdate = s.now().UTC()
+	doc, err := json.Marshal(state)
+	if err != nil {
+		return false, err
+	}
+	transition, err := json.Marshal(Transition{
+		Status:     state.Status,
+		ChangeTime: state.ChangeTime(),
+		Source:     state.Source,
+		EventID:    eventID,
+		RecordedAt: state.LastUpdate,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	changeTime := state.ChangeTime()
+	firing := "0"
+	if state.Firing() {
+		firing = "1"
+	}
+	keys := []string{stateKeyPrefix + state.Fingerprint, historyKeyPrefix + state.Fingerprint, activeKey}
+	args := []string{
+		state.Fingerprint,
+		strconv.FormatInt(changeTime.Unix(), 10),
+		strconv.Itoa(changeTime.Nanosecond()),
+		string(doc),
+		firing,
+		string(transition),
+		strconv.Itoa(s.historyLength),
+		strconv.FormatInt(s.retention.Milliseconds(), 10),
+		strconv.FormatInt(s.staleness.Milliseconds(), 10),
+	}
+	current, err := recordScript.Exec(ctx, s.client, keys, args).AsInt64()
+	if err != nil {
+		return false, err
+	}
+	return current == 1, nil
+}
+
+// Active returns the firing alerts matching filter, ordered by hub, alert name and the time they
+// started firing. Alerts whose last state was recorded more than the staleness ago are left out
+// and pruned.
+func (s *Store) Active(ctx context.Context, filter Filter) ([]State, error) {
+	docs, err := s.client.Do(ctx, s.client.B().Hgetall().Key(activeKey).Build()).AsStrMap()
+	if err != nil {
+		return nil, err
+	}
+
+	staleBefore := s.now().Add(-s.staleness)
+	states := make([]State, 0, len(docs))
+	stale := make(map[string]string)
+	for fingerprint, doc := range docs {
+		var state State
+		if err := json.Unmarshal([]byte(doc), &state); err != nil {
+			return nil, err
+		}
+		if state.LastUpdate.Before(staleBefore) {
+			stale[fingerprint] = doc
+			continue
+		}
+		if filter.matches(state) {
+			states = append(states, state)
+		}
+	}
+	if err := s.prune(ctx, stale); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(states, func(a, b State) int {
+		return cmp.Or(
+			cmp.Compare(a.HubName, b.HubName),
+			cmp.Compare(a.AlertName, b.AlertName),
+			a.FiringSince.Compare(b.FiringSince),
+			cmp.Compare(a.Fingerprint, b.Fingerprint),
+		)
+	})
+	return states, nil
+}
+
+// prune removes the stale states, by fingerprint, from the active alerts.
+func (s *Store) prune(ctx context.Context, stale map[string]string) error {
+	if len(stale) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, 1+2*len(stale))
+	args := make([]string, 0, 2*len(stale))
+	keys = append(keys, activeKey)
+	for _, fingerprint := range slices.Sorted(maps.Keys(stale)) {
+		keys = append(keys, stateKeyPrefix+fingerprint, historyKeyPrefix+fingerprint)
+		args = append(args, fingerprint, stale[fingerprint])
+	}
+	return pruneScript.Exec(ctx, s.client, keys, args).Error()
+}
+
+// Get returns the current state of an alert.
+func (s *Store) Get(ctx context.Context, fingerprint string) (State, bool, error) {
+	doc, err := s.client.Do(ctx, s.client.B().Hget().Key(stateKeyPrefix+fingerprint).Field("state").Build()).ToString()
+	if valkey.IsValkeyNil(err) {
+		return State{}, false, nil
+	}
+	if err != nil {
+		return State{}, false, err
+	}
+
+	var state State
+	if err := json.Unmarshal([]byte(doc), &state); err != nil {
+		return State{}, false, err
+	}
+	return state, true, nil
+}
+
+// History returns the transitions of an alert ordered by change time.
+func (s *Store) History(ctx context.Context, fingerprint string) ([]Transition, error) {
+	docs, err := s.client.Do(ctx, s.client.B().Lrange().Key(historyKeyPrefix+fingerprint).Start(0).Stop(-1).Build()).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := make([]Transition, 0, len(docs))
+	for _, doc := range docs {
+		var t Transition
+		if err := json.Unmarshal([]byte(doc), &t); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	// Transitions are appended as they are processed, which may be out of order across replicas.
+	slices.SortStableFunc(transitions, func(a, b Transition) int { return a.ChangeTime.Compare(b.ChangeTime) })
+	return transitions, nil
+}
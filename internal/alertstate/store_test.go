@@ -0,0 +1,152 @@
+package alertstate
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+var testNow = time.Date(2025, 8, 8, 16, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) (*Store, *valkeymock.Client) {
+	t.Helper()
+
+	client := valkeymock.NewClient(gomock.NewController(t))
+	store := NewStore(client, time.Hour, 2*time.Hour, 10)
+	store.now = func() time.Time { return testNow }
+	return store, client
+}
+
+func stateDocument(t *testing.T, state State) valkey.ValkeyMessage {
+	t.Helper()
+
+	doc, err := json.Marshal(state)
+	require.NoError(t, err)
+	return valkeymock.ValkeyBlobString(string(doc))
+}
+
+func TestStore_Record(t *testing.T) {
+	store, client := newTestStore(t)
+	firingSince := time.Date(2025, 8, 8, 15, 56, 10, 500, time.UTC)
+
+	var cmds [][]string
+	client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		// EVALSHA sha 3 state history active fingerprint sec nsec state firing transition length retention staleness
+		if cmd[0] != "EVALSHA" || cmd[2] != "3" || cmd[3] != "alert/state/fp-1" || cmd[4] != "alert/history/fp-1" || cmd[5] != "alert/active" {
+			return false
+		}
+		cmds = append(cmds, cmd)
+		return true
+	})).Return(valkeymock.Result(valkeymock.ValkeyInt64(1))).Times(2)
+
+	current, err := store.Record(t.Context(), State{
+		Fingerprint: "fp-1",
+		AlertName:   "top_talkers",
+		HubName:     "mdaihub-sample",
+		Status:      "firing",
+		Source:      "prometheus",
+		Labels:      map[string]string{"service": "checkout"},
+		FiringSince: firingSince,
+	}, "event-1")
+	require.NoError(t, err)
+	assert.True(t, current)
+
+	require.Len(t, cmds, 1)
+	assert.Equal(t, []string{"fp-1", "1754668570", "500"}, cmds[0][6:9])
+	assert.JSONEq(t, `{"fingerprint":"fp-1","alertName":"top_talkers","hubName":"mdaihub-sample","status":"firing","source":"prometheus",
+		"labels":{"service":"checkout"},"firingSince":"2025-08-08T15:56:10.0000005Z","lastUpdate":"2025-08-08T16:00:00Z"}`, cmds[0][9])
+	assert.Equal(t, "1", cmds[0][10])
+	assert.JSONEq(t, `{"status":"firing","changeTime":"2025-08-08T15:56:10.0000005Z","source":"prometheus","eventId":"event-1",
+		"recordedAt":"2025-08-08T16:00:00Z"}`, cmds[0][11])
+	assert.Equal(t, []string{"10", "3600000", "7200000"}, cmds[0][12:])
+
+	resolvedAt := firingSince.Add(time.Hour)
+	_, err = store.Record(t.Context(), State{
+		Fingerprint: "fp-1",
+		HubName:     "mdaihub-sample",
+		Status:      "resolved",
+		FiringSince: firingSince,
+		ResolvedAt:  resolvedAt,
+	}, "event-2")
+	require.NoError(t, err)
+	require.Len(t, cmds, 2)
+	assert.Equal(t, "1754672170", cmds[1][7])
+	assert.Equal(t, "0", cmds[1][10])
+}
+
+func TestStore_Active(t *testing.T) {
+	store, client := newTestStore(t)
+	since := testNow.Add(-time.Hour)
+	stale := stateDocument(t, State{Fingerprint: "fp-5", AlertName: "top_talkers", HubName: "mdaihub-sample", Status: "firing",
+		FiringSince: since.Add(-3 * time.Hour), LastUpdate: since.Add(-time.Hour - time.Second)})
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "alert/active")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+			"fp-3": stateDocument(t, State{Fingerprint: "fp-3", AlertName: "top_talkers", HubName: "mdaihub-sample", Status: "firing", FiringSince: since, LastUpdate: since}),
+			"fp-2": stateDocument(t, State{Fingerprint: "fp-2", AlertName: "top_talkers", HubName: "mdaihub-sample", Status: "firing", FiringSince: since.Add(-time.Minute), LastUpdate: since}),
+			"fp-1": stateDocument(t, State{Fingerprint: "fp-1", AlertName: "disk_usage", HubName: "mdaihub-sample", Status: "firing", FiringSince: since, LastUpdate: since}),
+			"fp-4": stateDocument(t, State{Fingerprint: "fp-4", AlertName: "top_talkers", HubName: "mdaihub-second", Status: "firing", FiringSince: since, LastUpdate: since}),
+			// Its resolution never arrived and no state was recorded for longer than the staleness.
+			"fp-5": stale,
+		}))).Times(2)
+	staleDoc, err := stale.ToString()
+	require.NoError(t, err)
+	// The stale alert is pruned unless its state was recorded again meanwhile.
+	client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		// EVALSHA sha 3 active state history fingerprint state
+		return cmd[0] == "EVALSHA" && slices.Equal(cmd[2:], []string{"3", "alert/active", "alert/state/fp-5", "alert/history/fp-5", "fp-5", staleDoc})
+	}, "EVALSHA prune fp-5")).Return(valkeymock.Result(valkeymock.ValkeyInt64(0))).Times(2)
+
+	active, err := store.Active(t.Context(), Filter{HubName: "mdaihub-sample"})
+	require.NoError(t, err)
+	fingerprints := make([]string, len(active))
+	for i, state := range active {
+		fingerprints[i] = state.Fingerprint
+	}
+	assert.Equal(t, []string{"fp-1", "fp-2", "fp-3"}, fingerprints)
+
+	active, err = store.Active(t.Context(), Filter{AlertName: "top_talkers", HubName: "mdaihub-second"})
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "fp-4", active[0].Fingerprint)
+}
+
+func TestStore_Get(t *testing.T) {
+	store, client := newTestStore(t)
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("HGET", "alert/state/fp-1", "state")).
+		Return(valkeymock.Result(stateDocument(t, State{Fingerprint: "fp-1", Status: "resolved"})))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("HGET", "alert/state/fp-2", "state")).
+		Return(valkeymock.Result(valkeymock.ValkeyNil()))
+
+	state, found, err := store.Get(t.Context(), "fp-1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.False(t, state.Firing())
+
+	_, found, err = store.Get(t.Context(), "fp-2")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestStore_History(t *testing.T) {
+	store, client := newTestStore(t)
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("LRANGE", "alert/history/fp-1", "0", "-1")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(
+			valkeymock.ValkeyBlobString(`{"status":"firing","changeTime":"2025-08-08T15:00:00Z"}`),
+			valkeymock.ValkeyBlobString(`{"status":"firing","changeTime":"2025-08-08T17:00:00Z"}`),
+			// Recorded by another replica after the newer state.
+			valkeymock.ValkeyBlobString(`{"status":"resolved","changeTime":"2025-08-08T16:00:00Z"}`),
+		)))
+
+	transitions, err := store.History(t.Context(), "fp-1")
+	require.NoError(t, err)
+	require.Len(t, transitions, 3)
+	assert.Equal(t, "resolved", transitions[1].Status)
+	assert.Equal(t, time.Date(2025, 8, 8, 17, 0, 0, 0, time.UTC), transitions[2].ChangeTime)
+}
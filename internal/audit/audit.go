@@ -9,11 +9,27 @@ import (
 	"go.uber.org/zap"
 )
 
+// Audit record types. Records of published events have no type.
+const (
+	// VariableProposalType marks the audit records of the approval workflow of protected variables.
+	VariableProposalType = "variable_proposal"
+	// RequestRejectedType marks requests refused with a client error before anything was published.
+	RequestRejectedType = "request_rejected"
+	// AlertSkippedType marks alerts dropped because a newer state of the alert was already seen.
+	AlertSkippedType = "alert_skipped"
+	// PublishBatchFailedType marks batches of events of which some could not be published.
+	PublishBatchFailedType = "publish_batch_failed"
+	// FreezeChangedType marks the creation and deletion of freeze windows.
+	FreezeChangedType = "freeze_changed"
+)
+
 type Inserter interface {
 	InsertAuditLogEventFromMap(ctx context.Context, eventMap map[string]string) error
 }
 
-func RecordAuditEventFromMdaiEvent(ctx context.Context, logger *zap.Logger, auditAdapter Inserter, event eventing.MdaiEvent, success bool) error {
+// RecordAuditEventFromMdaiEvent writes an audit record for a published event. Extra fields are
+// added to the record but never replace the fields derived from the event itself.
+func RecordAuditEventFromMdaiEvent(ctx context.Context, logger *zap.Logger, auditAdapter Inserter, event eventing.MdaiEvent, success bool, extra map[string]string) error {
 	eventMap := map[string]string{
 		"id":              event.ID,
 		"name":            event.Name,
@@ -25,6 +41,27 @@ func RecordAuditEventFromMdaiEvent(ctx context.Context, logger *zap.Logger, audi
 		"hub_name":        event.HubName,
 		"publish_success": strconv.FormatBool(success),
 	}
+	for k, v := range extra {
+		if _, exists := eventMap[k]; !exists {
+			eventMap[k] = v
+		}
+	}
 	logger.Info("AUDIT: Published event from Prometheus alert", zap.String("mdai-logstream", "audit"), zap.Any("mdaiEvent", eventMap))
 	return auditAdapter.InsertAuditLogEventFromMap(ctx, eventMap)
 }
+
+// RecordAuditRecord writes an audit record that does not stem from a published event. The record
+// is tagged with recordType and the current time.
+func RecordAuditRecord(ctx context.Context, logger *zap.Logger, auditAdapter Inserter, recordType string, fields map[string]string) error {
+	record := map[string]string{
+		"type":      recordType,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	for k, v := range fields {
+		if _, exists := record[k]; !exists {
+			record[k] = v
+		}
+	}
+	logger.Info("AUDIT: Recorded "+recordType, zap.String("mdai-logstream", "audit"), zap.Any("record", record))
+	return auditAdapter.InsertAuditLogEventFromMap(ctx, record)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/decisiveai/mdai-data-core/eventing"
 	"github.com/decisiveai/mdai-gateway/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
@@ -43,7 +44,7 @@ func TestRecordAuditEventFromMdaiEvent(t *testing.T) {
 
 	mockAudit.On("InsertAuditLogEventFromMap", t.Context(), expectedMap).Return(nil).Once()
 
-	err := RecordAuditEventFromMdaiEvent(t.Context(), logger, mockAudit, event, true)
+	err := RecordAuditEventFromMdaiEvent(t.Context(), logger, mockAudit, event, true, nil)
 	require.NoError(t, err)
 
 	mockAudit.AssertExpectations(t)
@@ -62,3 +63,46 @@ func TestRecordAuditEventFromMdaiEvent(t *testing.T) {
 	assert.Equal(t, "event_name", eventMap["name"])
 	assert.Equal(t, "true", eventMap["publish_success"])
 }
+
+func TestRecordAuditEventFromMdaiEvent_Extra(t *testing.T) {
+	mockAudit := &mocks.MockAuditAdapter{}
+	event := eventing.MdaiEvent{
+		ID:        "id1",
+		Name:      "var.add",
+		Timestamp: time.Date(2025, 7, 19, 12, 0, 0, 0, time.UTC),
+		Payload:   "{}",
+		Source:    eventing.ManualVariablesEventSource,
+		HubName:   "prod",
+	}
+
+	mockAudit.On("InsertAuditLogEventFromMap", t.Context(), mock.MatchedBy(func(m map[string]string) bool {
+		return m["promoted_from_hub"] == "staging" && m["hub_name"] == "prod"
+	})).Return(nil).Once()
+
+	extra := map[string]string{
+		"promoted_from_hub": "staging",
+		"hub_name":          "must-not-override",
+	}
+	err := RecordAuditEventFromMdaiEvent(t.Context(), zap.NewNop(), mockAudit, event, true, extra)
+	require.NoError(t, err)
+
+	mockAudit.AssertExpectations(t)
+}
+
+func TestRecordAuditRecord(t *testing.T) {
+	mockAudit := &mocks.MockAuditAdapter{}
+	mockAudit.On("InsertAuditLogEventFromMap", t.Context(), mock.MatchedBy(func(record map[string]string) bool {
+		_, err := time.Parse(time.RFC3339, record["timestamp"])
+		return err == nil &&
+			record["type"] == VariableProposalType &&
+			record["action"] == "proposed"
+	})).Return(nil).Once()
+
+	err := RecordAuditRecord(t.Context(), zap.NewNop(), mockAudit, VariableProposalType, map[string]string{
+		"action": "proposed",
+		"type":   "ignored",
+	})
+	require.NoError(t, err)
+
+	mockAudit.AssertExpectations(t)
+}
@@ -0,0 +1,270 @@
+package audit
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datacoreaudit "github.com/decisiveai/mdai-data-core/audit"
+	"github.com/valkey-io/valkey-go"
+)
+
+const (
+	// HashField holds the hash of a chained record.
+	HashField = "hash"
+	// PrevHashField holds the hash of the record written before it.
+	PrevHashField = "prev_hash"
+
+	chainHeadKey     = "audit/chain/head"
+	maxChainAttempts = 16
+)
+
+var ErrChainContention = errors.New("audit chain head kept moving, record not written")
+
+// appendScript adds a record to the stream only if the chain head is still the hash the record
+// was linked to, and otherwise returns the current head. Checking the head, appending and moving
+// the head in one script keeps the stream order and the chain order identical across replicas.
+// A retention policy stored in Valkey replaces the writer's retention, so that every replica
+// trims alike.
+var appendScript = valkey.NewLuaScript(`
+local head = redis.call('GET', KEYS[2]) or ''
+if head ~= ARGV[1] then
+	return {0, head}
+end
+local minid = ARGV[3]
+local policy = redis.call('HMGET', KEYS[3], 'max_age_ms', 'max_len')
+if policy[1] then
+	local now = redis.call('TIME')
+	minid = string.format('%.0f', tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000) - tonumber(policy[1]))
+end
+local id = redis.call('XADD', KEYS[1], 'MINID', minid, '*', unpack(ARGV, 4))
+if policy[2] and tonumber(policy[2]) > 0 then
+	redis.call('XTRIM', KEYS[1], 'MAXLEN', policy[2])
+end
+redis.call('SET', KEYS[2], ARGV[2])
+return {1, id}
+`)
+
+// Chain writes audit records as a hash chain. Every record carries the hash of the record
+// written before it and the hash of its own content, so editing or deleting a record breaks
+// the chain from that record on.
+type Chain struct {
+	client    valkey.Client
+	retention time.Duration
+	now       func() time.Time
+
+	mu   sync.Mutex
+	head string
+}
+
+var _ Inserter = (*Chain)(nil)
+
+// NewChain returns a chain that trims records older than retention from the stream, unless a
+// retention policy is stored in Valkey.
+func NewChain(client valkey.Client, retention time.Duration) *Chain {
+	return &Chain{client: client, retention: retention, now: time.Now}
+}
+
+// InsertAuditLogEventFromMap links the record to the chain head and appends it. When another
+// writer moved the head in the meantime, the record is linked to the new head and retried.
+func (c *Chain) InsertAuditLogEventFromMap(ctx context.Context, fields map[string]string) error {
+	record := maps.Clone(fields)
+	delete(record, HashField)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	minID := strconv.FormatInt(c.now().Add(-c.retention).UnixMilli(), 10)
+	for range maxChainAttempts {
+		record[PrevHashField] = c.head
+		hash := RecordHash(record)
+
+		args := []string{c.head, hash, minID}
+		for _, field := range slices.Sorted(maps.Keys(record)) {
+			args = append(args, field, record[field])
+		}
+		args = append(args, HashField, hash)
+
+		result, err := appendScript.Exec(ctx, c.client, []string{datacoreaudit.MdaiHubEventHistoryStreamName, chainHeadKey, retentionPolicyKey}, args).ToArray()
+		if err != nil {
+			return err
+		}
+		if len(result) != 2 {
+			return errors.New("unexpected reply from audit chain script")
+		}
+		appended, err := result[0].AsInt64()
+		if err != nil {
+			return err
+		}
+		if appended == 1 {
+			c.head = hash
+			return nil
+		}
+		if c.head, err = result[1].ToString(); err != nil {
+			return err
+		}
+	}
+	return ErrChainContention
+}
+
+// RecordHash returns the hex encoded SHA-256 hash of a record's canonical content: the JSON
+// object of all fields but the hash itself, with sorted keys. The previous hash is part of the
+// content.
+func RecordHash(fields map[string]string) string {
+	content := maps.Clone(fields)
+	delete(content, HashField)
+
+	canonical, _ := json.Marshal(content) //nolint:errchkjson // string maps always encode
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainBreak is the first record at which the chain does not hold.
+type ChainBreak struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// Verification is the result of walking a range of the audit stream.
+type Verification struct {
+	Intact bool `json:"intact"`
+	// Checked counts the chained records verified before a break.
+	Checked int `json:"checked"`
+	// Unchained counts records without a hash, e.g. written before chaining was enabled or by
+	// other writers of the stream.
+	Unchained int         `json:"unchained"`
+	First     string      `json:"first,omitempty"`
+	Last      string      `json:"last,omitempty"`
+	Break     *ChainBreak `json:"break,omitempty"`
+}
+
+var streamBoundPattern = regexp.MustCompile(`^(\d+(-\d+)?|-|\+)$`)
+
+// VerifyRangeFromValues parses the start and end query parameters of a verification. Both are
+// inclusive stream IDs or millisecond timestamps and default to the whole stream.
+func VerifyRangeFromValues(values url.Values) (start, end string, err error) { //nolint:nonamedreturns
+	start, end = "-", "+"
+	if v := values.Get("start"); v != "" {
+		start = v
+	}
+	if v := values.Get("end"); v != "" {
+		end = v
+	}
+	for _, bound := range []string{start, end} {
+		if !streamBoundPattern.MatchString(bound) {
+			return "", "", fmt.Errorf("invalid stream ID %q", bound)
+		}
+	}
+	return start, end, nil
+}
+
+// Verify walks the stream from start to end, both inclusive stream IDs or "-" and "+", and
+// reports the first record whose hash does not match its content or whose previous hash does
+// not match the chained record before it. The first chained record of the range is trusted to
+// link to records before the range. A range that reaches the end of the stream must also end at
+// the chain head, so that deleting the last records breaks the chain too.
+func Verify(ctx context.Context, client valkey.Client, start, end string) (Verification, error) {
+	var (
+		v       Verification
+		prev    string
+		scanned string
+	)
+	for range maxChainAttempts {
+		for record, err := range scanRange(ctx, client, start, end, false) {
+			if err != nil {
+				return Verification{}, err
+			}
+			scanned = record.ID
+
+			hash, chained := record.Fields[HashField]
+			if !chained {
+				v.Unchained++
+				continue
+			}
+
+			switch {
+			case RecordHash(record.Fields) != hash:
+				v.Break = &ChainBreak{ID: record.ID, Reason: "hash does not match the record content"}
+			case v.Checked > 0 && record.Fields[PrevHashField] != prev:
+				v.Break = &ChainBreak{ID: record.ID, Reason: "previous hash does not match the preceding record " + v.Last}
+			}
+			if v.Break != nil {
+				return v, nil
+			}
+
+			if v.First == "" {
+				v.First = record.ID
+			}
+			v.Last = record.ID
+			v.Checked++
+			prev = hash
+		}
+
+		// The head is read before the last ID: records appended in between show up as a newer
+		// last ID and are verified first, rather than as a head the scan never reached.
+		head, err := client.Do(ctx, client.B().Get().Key(chainHeadKey).Build()).ToString()
+		if err != nil && !valkey.IsValkeyNil(err) {
+			return Verification{}, err
+		}
+		tail, err := lastStreamID(ctx, client)
+		if err != nil {
+			return Verification{}, err
+		}
+		switch {
+		case end != "+" && compareStreamIDs(end, tail) < 0:
+			// The range ends before the stream does, so the head is not its concern.
+		case scanned != "" && compareStreamIDs(tail, scanned) > 0:
+			start = "(" + scanned
+			continue
+		case scanned == "" && tail != "" && start == "-":
+			continue
+		case v.Checked == 0 && start != "-":
+			// A range past the last chained record has nothing to link the head to.
+		case head != prev:
+			v.Break = &ChainBreak{ID: v.Last, Reason: "chain head does not match the last record, records after it are missing"}
+			return v, nil
+		}
+		v.Intact = true
+		return v, nil
+	}
+	return Verification{}, ErrChainContention
+}
+
+// lastStreamID returns the ID of the newest record of the audit stream, or "" if it is empty.
+func lastStreamID(ctx context.Context, client valkey.Client) (string, error) {
+	entries, err := client.Do(ctx, client.B().Xrevrange().Key(datacoreaudit.MdaiHubEventHistoryStreamName).End("+").Start("-").Count(1).Build()).AsXRange()
+	if err != nil || len(entries) == 0 {
+		return "", err
+	}
+	return entries[0].ID, nil
+}
+
+// compareStreamIDs compares two stream IDs as Valkey orders them. An ID without a sequence
+// number is an inclusive end bound, so it sorts after every ID of its millisecond.
+func compareStreamIDs(a, b string) int {
+	parse := func(id string) (uint64, uint64) {
+		ms, seq, found := strings.Cut(id, "-")
+		m, _ := strconv.ParseUint(ms, 10, 64)
+		if !found {
+			return m, math.MaxUint64
+		}
+		n, _ := strconv.ParseUint(seq, 10, 64)
+		return m, n
+	}
+	am, as := parse(a)
+	bm, bs := parse(b)
+	return cmp.Or(cmp.Compare(am, bm), cmp.Compare(as, bs))
+}
@@ -0,0 +1,294 @@
+package audit
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+// chainedEntries links records the way Chain writes them and returns them as stream entries.
+func chainedEntries(records ...map[string]string) []map[string]string {
+	prev := ""
+	chained := make([]map[string]string, 0, len(records))
+	for _, record := range records {
+		record = maps.Clone(record)
+		record[PrevHashField] = prev
+		record[HashField] = RecordHash(record)
+		prev = record[HashField]
+		chained = append(chained, record)
+	}
+	return chained
+}
+
+// flatten returns the fields and values of a record in field order.
+func flatten(record map[string]string) []string {
+	kv := make([]string, 0, 2*len(record))
+	for _, field := range slices.Sorted(maps.Keys(record)) {
+		kv = append(kv, field, record[field])
+	}
+	return kv
+}
+
+func entriesReply(records ...map[string]string) valkey.ValkeyResult {
+	entries := make([]valkey.ValkeyMessage, 0, len(records))
+	for i, record := range records {
+		entries = append(entries, streamEntry(strconv.Itoa(i+1)+"-0", flatten(record)...))
+	}
+	return valkeymock.Result(valkeymock.ValkeyArray(entries...))
+}
+
+func TestChain_Insert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := valkeymock.NewClient(ctrl)
+	chain := NewChain(client, time.Hour)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	chain.now = func() time.Time { return now }
+	minID := strconv.FormatInt(now.Add(-time.Hour).UnixMilli(), 10)
+
+	var appended []string
+	gomock.InOrder(
+		// Another replica moved the head since this one last wrote.
+		client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+			return cmd[0] == "EVALSHA" && cmd[2] == "3" && cmd[3] == "mdai_hub_event_history" && cmd[4] == chainHeadKey && cmd[5] == retentionPolicyKey && cmd[6] == ""
+		})).Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyInt64(0), valkeymock.ValkeyBlobString("head-1")))),
+		client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+			return cmd[0] == "EVALSHA" && cmd[6] == "head-1"
+		})).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			appended = cmd.Commands()
+			return valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyInt64(1), valkeymock.ValkeyBlobString("1-0")))
+		}),
+	)
+
+	require.NoError(t, chain.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.add", "hub_name": "prod"}))
+
+	record := map[string]string{"name": "var.add", "hub_name": "prod", PrevHashField: "head-1"}
+	hash := RecordHash(record)
+	assert.Equal(t, []string{
+		"head-1", hash, minID,
+		"hub_name", "prod", "name", "var.add", PrevHashField, "head-1", HashField, hash,
+	}, appended[6:])
+	assert.Equal(t, hash, chain.head)
+}
+
+func TestChain_InsertContention(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := valkeymock.NewClient(ctrl)
+	chain := NewChain(client, time.Hour)
+
+	attempt := 0
+	client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "EVALSHA" })).
+		DoAndReturn(func(_ any, _ valkey.Completed) valkey.ValkeyResult {
+			attempt++
+			return valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyInt64(0), valkeymock.ValkeyBlobString("head-"+strconv.Itoa(attempt))))
+		}).Times(maxChainAttempts)
+
+	require.ErrorIs(t, chain.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.add"}), ErrChainContention)
+}
+
+func TestRecordHash(t *testing.T) {
+	record := map[string]string{"name": "var.add", PrevHashField: ""}
+	hash := RecordHash(record)
+	assert.Len(t, hash, 64)
+
+	record[HashField] = hash
+	assert.Equal(t, hash, RecordHash(record), "the hash field is not part of the content")
+
+	record[PrevHashField] = "other"
+	assert.NotEqual(t, hash, RecordHash(record), "the previous hash is part of the content")
+}
+
+func expectChainTail(client *valkeymock.Client, head, tail string) {
+	headReply := valkeymock.Result(valkeymock.ValkeyNil())
+	if head != "" {
+		headReply = valkeymock.Result(valkeymock.ValkeyBlobString(head))
+	}
+	tailReply := valkeymock.Result(valkeymock.ValkeyArray())
+	if tail != "" {
+		tailReply = valkeymock.Result(valkeymock.ValkeyArray(streamEntry(tail)))
+	}
+	gomock.InOrder(
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", chainHeadKey)).Return(headReply),
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("XREVRANGE", "mdai_hub_event_history", "+", "-", "COUNT", "1")).Return(tailReply),
+	)
+}
+
+func TestVerify(t *testing.T) {
+	records := chainedEntries(
+		map[string]string{"name": "var.add", "hub_name": "prod"},
+		map[string]string{"name": "var.remove", "hub_name": "prod"},
+		map[string]string{"type": RequestRejectedType, "reason": "Int expected"},
+	)
+	tampered := maps.Clone(records[1])
+	tampered["hub_name"] = "staging"
+	unchained := map[string]string{"name": "alert.firing"}
+
+	tests := []struct {
+		name     string
+		entries  []map[string]string
+		head     string
+		expected Verification
+	}{
+		{
+			name:     "intact",
+			entries:  records,
+			head:     records[2][HashField],
+			expected: Verification{Intact: true, Checked: 3, First: "1-0", Last: "3-0"},
+		},
+		{
+			name:     "edited record",
+			entries:  []map[string]string{records[0], tampered, records[2]},
+			head:     records[2][HashField],
+			expected: Verification{Checked: 1, First: "1-0", Last: "1-0", Break: &ChainBreak{ID: "2-0", Reason: "hash does not match the record content"}},
+		},
+		{
+			name:     "deleted record",
+			entries:  []map[string]string{records[0], records[2]},
+			head:     records[2][HashField],
+			expected: Verification{Checked: 1, First: "1-0", Last: "1-0", Break: &ChainBreak{ID: "2-0", Reason: "previous hash does not match the preceding record 1-0"}},
+		},
+		{
+			name:    "deleted last record",
+			entries: records[:2],
+			head:    records[2][HashField],
+			expected: Verification{Checked: 2, First: "1-0", Last: "2-0", Break: &ChainBreak{
+				ID: "2-0", Reason: "chain head does not match the last record, records after it are missing",
+			}},
+		},
+		{
+			name:     "deleted records",
+			head:     records[2][HashField],
+			expected: Verification{Break: &ChainBreak{Reason: "chain head does not match the last record, records after it are missing"}},
+		},
+		{
+			name:     "range starting mid chain",
+			entries:  records[1:],
+			head:     records[2][HashField],
+			expected: Verification{Intact: true, Checked: 2, First: "1-0", Last: "2-0"},
+		},
+		{
+			name:     "unchained records",
+			entries:  []map[string]string{unchained, records[0], unchained, records[1], unchained},
+			head:     records[1][HashField],
+			expected: Verification{Intact: true, Checked: 2, Unchained: 3, First: "2-0", Last: "4-0"},
+		},
+		{
+			name:     "empty stream",
+			expected: Verification{Intact: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := valkeymock.NewClient(ctrl)
+			client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "+", "COUNT", strconv.Itoa(scanBatch))).
+				Return(entriesReply(tt.entries...))
+			// The head is read only once the whole stream checked out.
+			if tt.expected.Break == nil || tt.expected.Break.ID == tt.expected.Last {
+				tail := ""
+				if len(tt.entries) > 0 {
+					tail = strconv.Itoa(len(tt.entries)) + "-0"
+				}
+				expectChainTail(client, tt.head, tail)
+			}
+
+			v, err := Verify(t.Context(), client, "-", "+")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestVerify_Range(t *testing.T) {
+	records := chainedEntries(
+		map[string]string{"name": "var.add", "hub_name": "prod"},
+		map[string]string{"name": "var.remove", "hub_name": "prod"},
+	)
+
+	t.Run("ending before the stream", func(t *testing.T) {
+		client := valkeymock.NewClient(gomock.NewController(t))
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "1", "COUNT", strconv.Itoa(scanBatch))).
+			Return(entriesReply(records[0]))
+		expectChainTail(client, "later-head", "2-0")
+
+		v, err := Verify(t.Context(), client, "-", "1")
+		require.NoError(t, err)
+		assert.Equal(t, Verification{Intact: true, Checked: 1, First: "1-0", Last: "1-0"}, v)
+	})
+
+	t.Run("appended while verifying", func(t *testing.T) {
+		client := valkeymock.NewClient(gomock.NewController(t))
+		gomock.InOrder(
+			client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "+", "COUNT", strconv.Itoa(scanBatch))).
+				Return(entriesReply(records[0])),
+			client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", chainHeadKey)).
+				Return(valkeymock.Result(valkeymock.ValkeyBlobString(records[1][HashField]))),
+			client.EXPECT().Do(gomock.Any(), valkeymock.Match("XREVRANGE", "mdai_hub_event_history", "+", "-", "COUNT", "1")).
+				Return(valkeymock.Result(valkeymock.ValkeyArray(streamEntry("2-0")))),
+			client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "(1-0", "+", "COUNT", strconv.Itoa(scanBatch))).
+				Return(valkeymock.Result(valkeymock.ValkeyArray(streamEntry("2-0", flatten(records[1])...)))),
+		)
+		expectChainTail(client, records[1][HashField], "2-0")
+
+		v, err := Verify(t.Context(), client, "-", "+")
+		require.NoError(t, err)
+		assert.Equal(t, Verification{Intact: true, Checked: 2, First: "1-0", Last: "2-0"}, v)
+	})
+}
+
+func TestVerify_DeletedLastRecord(t *testing.T) {
+	client, err := valkey.NewClient(valkey.ClientOption{InitAddress: []string{miniredis.RunT(t).Addr()}, DisableCache: true})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	ctx := t.Context()
+
+	var ids []string
+	for _, record := range chainedEntries(
+		map[string]string{"name": "var.add", "hub_name": "prod"},
+		map[string]string{"name": "var.remove", "hub_name": "prod"},
+	) {
+		id, err := client.Do(ctx, client.B().Xadd().Key("mdai_hub_event_history").Id("*").FieldValue().
+			FieldValueIter(maps.All(record)).Build()).ToString()
+		require.NoError(t, err)
+		ids = append(ids, id)
+		require.NoError(t, client.Do(ctx, client.B().Set().Key(chainHeadKey).Value(record[HashField]).Build()).Error())
+	}
+
+	v, err := Verify(ctx, client, "-", "+")
+	require.NoError(t, err)
+	assert.True(t, v.Intact)
+
+	require.NoError(t, client.Do(ctx, client.B().Xdel().Key("mdai_hub_event_history").Id(ids[1]).Build()).Error())
+
+	v, err = Verify(ctx, client, "-", "+")
+	require.NoError(t, err)
+	assert.False(t, v.Intact)
+	assert.Equal(t, 1, v.Checked)
+	require.NotNil(t, v.Break)
+	assert.Equal(t, ids[0], v.Break.ID)
+}
+
+func TestVerifyRangeFromValues(t *testing.T) {
+	start, end, err := VerifyRangeFromValues(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, "-", start)
+	assert.Equal(t, "+", end)
+
+	start, end, err = VerifyRangeFromValues(url.Values{"start": {"1759276800000-1"}, "end": {"1759363200000"}})
+	require.NoError(t, err)
+	assert.Equal(t, "1759276800000-1", start)
+	assert.Equal(t, "1759363200000", end)
+
+	_, _, err = VerifyRangeFromValues(url.Values{"start": {"yesterday"}})
+	require.EqualError(t, err, `invalid stream ID "yesterday"`)
+}
@@ -0,0 +1,155 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"maps"
+
+	"github.com/decisiveai/mdai-gateway/internal/cloudevents"
+)
+
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	// FormatCloudEvents is a JSON array of CloudEvents, see cloudevents.FromAuditRecord.
+	FormatCloudEvents = "cloudevents"
+)
+
+// csvColumn is a field exported as its own CSV column under header.
+type csvColumn struct {
+	header, field string
+}
+
+// csvColumns are the fields exported as their own CSV columns, in order. Every other field is
+// exported in the trailing "fields" column as a JSON object.
+var csvColumns = []csvColumn{
+	{"timestamp", "timestamp"},
+	{"hub_name", "hub_name"},
+	{"type", "type"},
+	{"name", "name"},
+	{"source", "source"},
+	{"source_id", "sourceId"}, // as written by RecordAuditEventFromMdaiEvent
+	{"correlation_id", "correlation_id"},
+	{"publish_success", "publish_success"},
+	{"payload", "payload"},
+}
+
+// RecordWriter encodes audit records one at a time.
+type RecordWriter interface {
+	Write(record Record) error
+	// Flush writes any buffered data to the underlying writer.
+	Flush() error
+	// Close ends the export and flushes it.
+	Close() error
+}
+
+func NewRecordWriter(w io.Writer, format string) RecordWriter {
+	switch format {
+	case FormatCSV:
+		return &csvRecordWriter{w: csv.NewWriter(w)}
+	case FormatCloudEvents:
+		return &cloudEventsRecordWriter{w: w}
+	default:
+		return &ndjsonRecordWriter{enc: json.NewEncoder(w)}
+	}
+}
+
+type ndjsonRecordWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonRecordWriter) Write(record Record) error { return n.enc.Encode(record) }
+
+func (n *ndjsonRecordWriter) Flush() error { return nil }
+
+func (n *ndjsonRecordWriter) Close() error { return nil }
+
+// cloudEventsRecordWriter writes a CloudEvents JSON batch, one event per line.
+type cloudEventsRecordWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (c *cloudEventsRecordWriter) Write(record Record) error {
+	event, err := cloudevents.FromAuditRecord(record.ID, record.Fields)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if !c.written {
+		sep = "[\n"
+	}
+	c.written = true
+	_, err = c.w.Write(append([]byte(sep), data...))
+	return err
+}
+
+func (c *cloudEventsRecordWriter) Flush() error { return nil }
+
+func (c *cloudEventsRecordWriter) Close() error {
+	end := "\n]\n"
+	if !c.written {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(c.w, end)
+	return err
+}
+
+type csvRecordWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvRecordWriter) Write(record Record) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	row := make([]string, 0, len(csvColumns)+2)
+	row = append(row, record.ID)
+	rest := maps.Clone(record.Fields)
+	for _, column := range csvColumns {
+		row = append(row, record.Fields[column.field])
+		delete(rest, column.field)
+	}
+
+	extra := ""
+	if len(rest) > 0 {
+		b, err := json.Marshal(rest)
+		if err != nil {
+			return err
+		}
+		extra = string(b)
+	}
+	return c.w.Write(append(row, extra))
+}
+
+// Flush also writes the header, so that an export without records is still a valid CSV file.
+func (c *csvRecordWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRecordWriter) Close() error { return c.Flush() }
+
+func (c *csvRecordWriter) writeHeader() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+	header := make([]string, 0, len(csvColumns)+2)
+	header = append(header, "id")
+	for _, column := range csvColumns {
+		header = append(header, column.header)
+	}
+	return c.w.Write(append(header, "fields"))
+}
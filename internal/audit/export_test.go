@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportRecords = []Record{
+	{ID: "1-0", Fields: map[string]string{"hub_name": "prod", "name": "var.add", "payload": `{"a":"b,c"}`, "reason": "incident"}},
+	{ID: "2-0", Fields: map[string]string{"type": "variable_proposal"}},
+}
+
+func TestRecordWriter_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewRecordWriter(&buf, FormatNDJSON)
+	for _, record := range exportRecords {
+		require.NoError(t, w.Write(record))
+	}
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t,
+		`{"id":"1-0","fields":{"hub_name":"prod","name":"var.add","payload":"{\"a\":\"b,c\"}","reason":"incident"}}`+"\n"+
+			`{"id":"2-0","fields":{"type":"variable_proposal"}}`+"\n",
+		buf.String())
+}
+
+func TestRecordWriter_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewRecordWriter(&buf, FormatCSV)
+	for _, record := range exportRecords {
+		require.NoError(t, w.Write(record))
+	}
+	require.NoError(t, w.Flush())
+
+	assert.Equal(t,
+		"id,timestamp,hub_name,type,name,source,source_id,correlation_id,publish_success,payload,fields\n"+
+			`1-0,,prod,,var.add,,,,,"{""a"":""b,c""}","{""reason"":""incident""}"`+"\n"+
+			"2-0,,,variable_proposal,,,,,,,\n",
+		buf.String())
+}
+
+func TestRecordWriter_CSVSourceID(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewRecordWriter(&buf, FormatCSV)
+	require.NoError(t, w.Write(Record{ID: "1-0", Fields: map[string]string{"name": "top_talkers.firing", "sourceId": "fp-1"}}))
+	require.NoError(t, w.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	// The source_id column holds the sourceId field rather than the trailing fields.
+	assert.Equal(t, "1-0,,,,top_talkers.firing,,fp-1,,,,", lines[1])
+}
+
+func TestRecordWriter_CSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewRecordWriter(&buf, FormatCSV).Flush())
+
+	assert.Equal(t, "id,timestamp,hub_name,type,name,source,source_id,correlation_id,publish_success,payload,fields\n", buf.String())
+}
+
+func TestRecordWriter_CloudEvents(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewRecordWriter(&buf, FormatCloudEvents)
+	for _, record := range exportRecords {
+		require.NoError(t, w.Write(record))
+	}
+	require.NoError(t, w.Close())
+
+	assert.JSONEq(t, `[
+		{"specversion":"1.0","id":"1-0","source":"/mdai-gateway/audit","type":"ai.mydecisive.audit.event","subject":"prod",
+		 "datacontenttype":"application/json","data":{"hub_name":"prod","name":"var.add","payload":"{\"a\":\"b,c\"}","reason":"incident"}},
+		{"specversion":"1.0","id":"2-0","source":"/mdai-gateway/audit","type":"ai.mydecisive.audit.variable_proposal",
+		 "datacontenttype":"application/json","data":{"type":"variable_proposal"}}
+	]`, buf.String())
+}
+
+func TestRecordWriter_CloudEventsEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, NewRecordWriter(&buf, FormatCloudEvents).Close())
+
+	assert.Equal(t, "[]\n", buf.String())
+}
@@ -0,0 +1,206 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	datacoreaudit "github.com/decisiveai/mdai-data-core/audit"
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/valkey-io/valkey-go"
+)
+
+// Sources of variable changes.
+const (
+	// ChangeSourceManualAPI marks changes made through the manual variables API, including
+	// promotions, imports and approved proposals.
+	ChangeSourceManualAPI = "manual_api"
+	// ChangeSourceAlert marks changes the operator made in response to an alert.
+	ChangeSourceAlert = "alert"
+	// ChangeSourceOpAMPReplay marks replay completions reported by OpAMP agents.
+	ChangeSourceOpAMPReplay = "opamp_replay"
+
+	replayCompleteEventName = "replay-complete"
+)
+
+// VariableChange is an operation applied to a variable, as recorded in the audit stream.
+type VariableChange struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	// Operation is "add" or "remove". Operations the gateway does not know are passed through.
+	Operation        string          `json:"operation"`
+	Data             json.RawMessage `json:"data,omitempty"`
+	CorrelationID    string          `json:"correlationId,omitempty"`
+	Actor            string          `json:"actor,omitempty"`
+	AgentInstanceUID string          `json:"agentInstanceUid,omitempty"`
+	PromotedFrom     string          `json:"promotedFrom,omitempty"`
+	Reason           string          `json:"reason,omitempty"`
+}
+
+// VariableHistoryQuery is a page request over the changes of a hub variable, oldest first.
+type VariableHistoryQuery struct {
+	HubName  string
+	Variable string
+	// Since and Until bound the time range; zero values leave it open.
+	Since time.Time
+	Until time.Time
+	// Cursor is the stream ID the previous page ended at.
+	Cursor string
+	Limit  int
+}
+
+// VariableHistory is one page of the changes of a variable. NextCursor is empty on the last page.
+type VariableHistory struct {
+	Changes    []VariableChange
+	NextCursor string
+	// StreamStart is the time of the oldest record the stream holds. It is only set when the page
+	// starts at the beginning of the stream, and stays zero when the stream is empty.
+	StreamStart time.Time
+}
+
+// FromStart reports whether the page starts at the beginning of the stream.
+func (q VariableHistoryQuery) FromStart() bool {
+	return q.Since.IsZero() && q.Cursor == ""
+}
+
+// Run returns one page of the variable's changes. Like Query.Run, it reads at most maxScanned
+// stream entries and returns the changes found so far with a cursor to continue from. Events that
+// failed to publish are left out since they never changed the variable.
+//
+// The operator's records of the changes it applies carry no hub name. They are attributed to the
+// hub of the event with the same correlation ID, and left out when that event is not on the page.
+func (q VariableHistoryQuery) Run(ctx context.Context, client valkey.Client) (VariableHistory, error) {
+	history := VariableHistory{Changes: []VariableChange{}}
+	correlatedHubs := make(map[string]string)
+	scanned := 0
+	for record, err := range Scan(ctx, client, Filter{Since: q.Since, Until: q.Until}, q.Cursor, false) {
+		if err != nil {
+			return VariableHistory{}, err
+		}
+		if scanned == 0 && q.FromStart() {
+			history.StreamStart = streamIDTime(record.ID)
+		}
+		scanned++
+
+		recordHub := record.Fields["hub_name"]
+		correlationID := record.Fields["correlation_id"]
+		switch {
+		case recordHub != "" && correlationID != "":
+			correlatedHubs[correlationID] = recordHub
+		case recordHub == "" && isOperatorChange(record.Fields):
+			recordHub = correlatedHubs[correlationID]
+		}
+		if recordHub == q.HubName {
+			if change, ok := variableChange(record, q.Variable); ok {
+				if len(history.Changes) == q.Limit {
+					// A further change exists, so the page is not the last one.
+					history.NextCursor = history.Changes[len(history.Changes)-1].ID
+					return history, nil
+				}
+				history.Changes = append(history.Changes, change)
+			}
+		}
+		if scanned == maxScanned {
+			history.NextCursor = record.ID
+			return history, nil
+		}
+	}
+
+	if scanned == 0 && q.FromStart() {
+		// Nothing was written up to Until; the oldest record may still be later.
+		oldest, err := client.Do(ctx, client.B().Xrange().Key(datacoreaudit.MdaiHubEventHistoryStreamName).Start("-").End("+").Count(1).Build()).AsXRange()
+		if err != nil {
+			return VariableHistory{}, err
+		}
+		if len(oldest) > 0 {
+			history.StreamStart = streamIDTime(oldest[0].ID)
+		}
+	}
+	return history, nil
+}
+
+// isOperatorChange reports whether the fields are those of a variable change the operator applied.
+// The data-core handler adapter records them without a type, naming the variable in target.
+func isOperatorChange(fields map[string]string) bool {
+	return fields["type"] == "" && fields["source"] == "" && fields["target"] != "" && fields["operation"] != ""
+}
+
+// variableChange extracts the change of varName from a record of a published variable event or
+// of an operator variable update.
+func variableChange(record Record, varName string) (VariableChange, bool) {
+	fields := record.Fields
+	change := VariableChange{
+		ID:               record.ID,
+		Timestamp:        streamIDTime(record.ID),
+		CorrelationID:    fields["correlation_id"],
+		Actor:            fields["actor_user"],
+		AgentInstanceUID: fields["agent_instance_uid"],
+		PromotedFrom:     fields["promoted_from"],
+		Reason:           fields["reason"],
+	}
+
+	switch {
+	case isOperatorChange(fields):
+		if fields["target"] != varName {
+			return VariableChange{}, false
+		}
+		change.Source = ChangeSourceAlert
+		change.Operation = operatorOperation(fields["operation"])
+		change.Data, _ = json.Marshal(fields["variable"])
+	case fields["type"] == "":
+		if fields["source"] != eventing.ManualVariablesEventSource || fields["publish_success"] != "true" {
+			return VariableChange{}, false
+		}
+		var payload struct {
+			VariableRef string          `json:"variableRef"`
+			Operation   string          `json:"operation"`
+			Data        json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(fields["payload"]), &payload); err != nil || payload.VariableRef != varName {
+			return VariableChange{}, false
+		}
+		change.Source = ChangeSourceManualAPI
+		if fields["name"] == replayCompleteEventName || change.AgentInstanceUID != "" {
+			change.Source = ChangeSourceOpAMPReplay
+		}
+		change.Operation = payload.Operation
+		change.Data = payload.Data
+	case fields["type"] == datacoreaudit.VariableUpdated:
+		if fields["variable_ref"] != varName {
+			return VariableChange{}, false
+		}
+		change.Source = ChangeSourceAlert
+		change.Operation = operatorOperation(fields["operation"])
+		change.Data, _ = json.Marshal(fields["variable"])
+	default:
+		return VariableChange{}, false
+	}
+	return change, true
+}
+
+// operatorOperation maps the operations the operator records, such as "Add element to set" or
+// "Set map entry", to the commands of the manual variables API. The data-core handler adapter
+// records the removal of a map entry as "Remove element from set" with the field as the value.
+func operatorOperation(op string) string {
+	switch lower := strings.ToLower(op); {
+	case strings.HasPrefix(lower, "add"), strings.HasPrefix(lower, "set"):
+		return "add"
+	case strings.HasPrefix(lower, "remove"), strings.HasPrefix(lower, "delete"):
+		return "remove"
+	default:
+		return op
+	}
+}
+
+// streamIDTime returns the time encoded in a stream ID, which is when the record was written.
+func streamIDTime(id string) time.Time {
+	ms, _, _ := strings.Cut(id, "-")
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(n).UTC()
+}
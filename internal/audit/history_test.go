@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-data-core/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestVariableHistoryQuery(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+	until := time.UnixMilli(5000)
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "5000", "COUNT", strconv.Itoa(scanBatch))).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(
+			streamEntry("1000-0", "hub_name", "prod", "source", "manual_variables_api", "name", "var.add", "publish_success", "true",
+				"correlation_id", "c1", "actor_user", "alice", "reason", "rollout",
+				"payload", `{"variableRef":"service_list","dataType":"set","operation":"add","data":["a","b"]}`),
+			streamEntry("1500-0", "hub_name", "prod", "source", "manual_variables_api", "name", "var.add", "publish_success", "false",
+				"payload", `{"variableRef":"service_list","dataType":"set","operation":"add","data":["z"]}`),
+			streamEntry("2000-0", "hub_name", "prod", "source", "manual_variables_api", "name", "var.add", "publish_success", "true",
+				"payload", `{"variableRef":"other","dataType":"set","operation":"add","data":["x"]}`),
+			streamEntry("2500-0", "hub_name", "staging", "source", "manual_variables_api", "name", "var.add", "publish_success", "true",
+				"payload", `{"variableRef":"service_list","dataType":"set","operation":"add","data":["y"]}`),
+			streamEntry("3000-0", "hub_name", "prod", "type", "variable_updated", "event", "action", "operation", "remove_element",
+				"variable_ref", "service_list", "variable", "a"),
+			streamEntry("3500-0", "hub_name", "prod", "type", "request_rejected", "payload", `{"variableRef":"service_list"}`),
+			streamEntry("4000-0", "hub_name", "prod", "source", "manual_variables_api", "name", "replay-complete", "publish_success", "true",
+				"agent_instance_uid", "agent-1",
+				"payload", `{"variableRef":"service_list","dataType":"string","operation":"add","data":"done"}`),
+		)))
+
+	history, err := VariableHistoryQuery{HubName: "prod", Variable: "service_list", Until: until, Limit: DefaultLimit}.Run(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1000).UTC(), history.StreamStart)
+	assert.Empty(t, history.NextCursor)
+	assert.Equal(t, []VariableChange{
+		{
+			ID:            "1000-0",
+			Timestamp:     time.UnixMilli(1000).UTC(),
+			Source:        ChangeSourceManualAPI,
+			Operation:     "add",
+			Data:          json.RawMessage(`["a","b"]`),
+			CorrelationID: "c1",
+			Actor:         "alice",
+			Reason:        "rollout",
+		},
+		{
+			ID:        "3000-0",
+			Timestamp: time.UnixMilli(3000).UTC(),
+			Source:    ChangeSourceAlert,
+			Operation: "remove",
+			Data:      json.RawMessage(`"a"`),
+		},
+		{
+			ID:               "4000-0",
+			Timestamp:        time.UnixMilli(4000).UTC(),
+			Source:           ChangeSourceOpAMPReplay,
+			Operation:        "add",
+			Data:             json.RawMessage(`"done"`),
+			AgentInstanceUID: "agent-1",
+		},
+	}, history.Changes)
+}
+
+func TestVariableHistoryQuery_Pages(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+	added := func(id, element string) valkey.ValkeyMessage {
+		return streamEntry(id, "hub_name", "prod", "source", "manual_variables_api", "name", "var.add", "publish_success", "true",
+			"payload", `{"variableRef":"service_list","dataType":"set","operation":"add","data":["`+element+`"]}`)
+	}
+
+	gomock.InOrder(
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "(1000-0", "+", "COUNT", strconv.Itoa(scanBatch))).
+			Return(valkeymock.Result(valkeymock.ValkeyArray(added("2000-0", "b"), added("3000-0", "c"), added("4000-0", "d")))),
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "500", "COUNT", strconv.Itoa(scanBatch))).
+			Return(valkeymock.Result(valkeymock.ValkeyArray())),
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "+", "COUNT", "1")).
+			Return(valkeymock.Result(valkeymock.ValkeyArray(added("1000-0", "a")))),
+	)
+
+	query := VariableHistoryQuery{HubName: "prod", Variable: "service_list", Cursor: "1000-0", Limit: 2}
+	history, err := query.Run(t.Context(), client)
+	require.NoError(t, err)
+	assert.False(t, query.FromStart())
+	assert.Len(t, history.Changes, 2)
+	assert.Equal(t, "3000-0", history.NextCursor)
+	assert.True(t, history.StreamStart.IsZero())
+
+	history, err = VariableHistoryQuery{HubName: "prod", Variable: "service_list", Until: time.UnixMilli(500), Limit: 2}.Run(t.Context(), client)
+	require.NoError(t, err)
+	assert.Empty(t, history.Changes)
+	assert.Equal(t, time.UnixMilli(1000).UTC(), history.StreamStart)
+}
+
+// operatorEntry is a stream entry of a variable change as the data-core handler adapter records it.
+func operatorEntry(id string, action handlers.StoreVariableAction) valkey.ValkeyMessage {
+	var kv []string
+	for k, v := range action.ToSequence() {
+		kv = append(kv, k, v)
+	}
+	return streamEntry(id, kv...)
+}
+
+func TestVariableHistoryQuery_OperatorRecords(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "+", "COUNT", strconv.Itoa(scanBatch))).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(
+			streamEntry("1000-0", "hub_name", "prod", "source", "prometheus", "name", "NoisyService", "publish_success", "true",
+				"correlation_id", "c1"),
+			streamEntry("1100-0", "hub_name", "staging", "source", "prometheus", "name", "NoisyService", "publish_success", "true",
+				"correlation_id", "c2"),
+			operatorEntry("2000-0", handlers.StoreVariableAction{EventId: "e1", Operation: "Add element to set", Target: "service_list",
+				VariableRef: "checkout", Variable: "checkout", CorrelationId: "c1"}),
+			operatorEntry("2100-0", handlers.StoreVariableAction{EventId: "e2", Operation: "Add element to set", Target: "service_list",
+				VariableRef: "billing", Variable: "billing", CorrelationId: "c2"}),
+			operatorEntry("2200-0", handlers.StoreVariableAction{EventId: "e3", Operation: "Add element to set", Target: "service_list",
+				VariableRef: "unknown", Variable: "unknown", CorrelationId: "trimmed"}),
+			operatorEntry("3000-0", handlers.StoreVariableAction{EventId: "e4", Operation: "Remove element from set", Target: "service_list",
+				VariableRef: "checkout", Variable: "checkout", CorrelationId: "c1"}),
+			operatorEntry("3100-0", handlers.StoreVariableAction{EventId: "e5", Operation: "Set string value", Target: "other",
+				VariableRef: "x", Variable: "x", CorrelationId: "c1"}),
+		)))
+
+	history, err := VariableHistoryQuery{HubName: "prod", Variable: "service_list", Limit: DefaultLimit}.Run(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, []VariableChange{
+		{
+			ID:            "2000-0",
+			Timestamp:     time.UnixMilli(2000).UTC(),
+			Source:        ChangeSourceAlert,
+			Operation:     "add",
+			Data:          json.RawMessage(`"checkout"`),
+			CorrelationID: "c1",
+		},
+		{
+			ID:            "3000-0",
+			Timestamp:     time.UnixMilli(3000).UTC(),
+			Source:        ChangeSourceAlert,
+			Operation:     "remove",
+			Data:          json.RawMessage(`"checkout"`),
+			CorrelationID: "c1",
+		},
+	}, history.Changes)
+}
+
+func TestOperatorOperation(t *testing.T) {
+	for op, expected := range map[string]string{
+		"Add element to set":      "add",
+		"Remove element from set": "remove",
+		"Set map entry":           "add",
+		"Set string value":        "add",
+		"add_element":             "add",
+		"remove_element":          "remove",
+		"rename":                  "rename",
+	} {
+		assert.Equal(t, expected, operatorOperation(op), op)
+	}
+}
+
+func TestStreamIDTime(t *testing.T) {
+	assert.Equal(t, time.UnixMilli(1759276800000).UTC(), streamIDTime("1759276800000-3"))
+	assert.True(t, streamIDTime("bogus").IsZero())
+}
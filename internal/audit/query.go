@@ -0,0 +1,268 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	datacoreaudit "github.com/decisiveai/mdai-data-core/audit"
+	"github.com/valkey-io/valkey-go"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+
+	// maxScanned bounds the stream entries a single query reads. A query that hits the bound
+	// returns what it found with a cursor to continue from.
+	maxScanned = 10000
+	scanBatch  = 200
+)
+
+var streamIDPattern = regexp.MustCompile(`^\d+-\d+$`)
+
+// IsStreamID reports whether s is a stream entry ID such as "1700000000000-0".
+func IsStreamID(s string) bool {
+	return streamIDPattern.MatchString(s)
+}
+
+// Record is a single audit stream entry.
+type Record struct {
+	ID     string            `json:"id"`
+	Fields map[string]string `json:"fields"`
+}
+
+// EventRecordType selects the records of published events, which carry no type field, in
+// Filter.Type.
+const EventRecordType = "event"
+
+// Filter selects audit records. Zero fields match everything.
+type Filter struct {
+	// Type is a record type such as RequestRejectedType, or EventRecordType.
+	Type           string
+	HubName        string
+	Source         string
+	NamePrefix     string
+	CorrelationID  string
+	PublishSuccess *bool
+	// Actor is the authenticated user that caused the event.
+	Actor string
+	// RemoteAddr is the client address of an anonymous actor.
+	RemoteAddr string
+	// AgentInstanceUID is the OpAMP agent that reported the event.
+	AgentInstanceUID string
+	Since            time.Time
+	Until            time.Time
+	// Text is matched case-insensitively against the payload.
+	Text string
+}
+
+func (f Filter) Matches(fields map[string]string) bool {
+	recordType := fields["type"]
+	if recordType == "" {
+		recordType = EventRecordType
+	}
+
+	switch {
+	case f.Type != "" && recordType != f.Type,
+		f.HubName != "" && fields["hub_name"] != f.HubName,
+		f.Source != "" && fields["source"] != f.Source,
+		f.NamePrefix != "" && !strings.HasPrefix(fields["name"], f.NamePrefix),
+		f.CorrelationID != "" && fields["correlation_id"] != f.CorrelationID,
+		f.PublishSuccess != nil && fields["publish_success"] != strconv.FormatBool(*f.PublishSuccess),
+		f.Actor != "" && fields["actor_user"] != f.Actor,
+		f.RemoteAddr != "" && !matchesRemoteAddr(fields["actor_remote_addr"], f.RemoteAddr),
+		f.AgentInstanceUID != "" && fields["agent_instance_uid"] != f.AgentInstanceUID,
+		f.Text != "" && !strings.Contains(strings.ToLower(fields["payload"]), strings.ToLower(f.Text)):
+		return false
+	}
+	return true
+}
+
+// matchesRemoteAddr matches a recorded host:port address against either a full address or just
+// its host.
+func matchesRemoteAddr(recorded, want string) bool {
+	if recorded == want {
+		return true
+	}
+	host, _, err := net.SplitHostPort(recorded)
+	return err == nil && host == want
+}
+
+// Query is a page request over the audit stream.
+type Query struct {
+	Filter
+	// Cursor is the stream ID the previous page ended at.
+	Cursor     string
+	Limit      int
+	Descending bool
+}
+
+// Page is one page of query results. NextCursor is empty on the last page.
+type Page struct {
+	Records    []Record `json:"records"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// FilterFromValues parses the filter query parameters shared by the audit endpoints.
+func FilterFromValues(values url.Values) (Filter, error) {
+	f := Filter{
+		Type:             values.Get("type"),
+		HubName:          values.Get("hub_name"),
+		Source:           values.Get("source"),
+		NamePrefix:       values.Get("name_prefix"),
+		CorrelationID:    values.Get("correlation_id"),
+		Actor:            values.Get("actor"),
+		RemoteAddr:       values.Get("remote_addr"),
+		AgentInstanceUID: values.Get("agent_instance_uid"),
+		Text:             values.Get("q"),
+	}
+
+	if v := values.Get("publish_success"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid publish_success %q", v)
+		}
+		f.PublishSuccess = &b
+	}
+
+	var err error
+	if f.Since, err = parseTime(values, "since"); err != nil {
+		return f, err
+	}
+	if f.Until, err = parseTime(values, "until"); err != nil {
+		return f, err
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Until.Before(f.Since) {
+		return f, errors.New("until must not be before since")
+	}
+	return f, nil
+}
+
+// QueryFromValues parses the query parameters of GET /v2/audit.
+func QueryFromValues(values url.Values) (Query, error) {
+	filter, err := FilterFromValues(values)
+	if err != nil {
+		return Query{}, err
+	}
+	q := Query{Filter: filter, Limit: DefaultLimit, Descending: true}
+
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 || q.Limit > MaxLimit {
+			return q, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+	}
+
+	switch order := values.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		q.Descending = false
+	default:
+		return q, fmt.Errorf("invalid order %q, expected asc or desc", order)
+	}
+
+	if q.Cursor = values.Get("cursor"); q.Cursor != "" && !streamIDPattern.MatchString(q.Cursor) {
+		return q, fmt.Errorf("invalid cursor %q", q.Cursor)
+	}
+	return q, nil
+}
+
+func parseTime(values url.Values, key string) (time.Time, error) {
+	v := values.Get(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, expected RFC 3339", key, v)
+	}
+	return t, nil
+}
+
+// Run returns one page of the records matching the query.
+func (q Query) Run(ctx context.Context, client valkey.Client) (Page, error) {
+	page := Page{Records: make([]Record, 0, q.Limit)}
+	scanned := 0
+	for record, err := range Scan(ctx, client, q.Filter, q.Cursor, q.Descending) {
+		if err != nil {
+			return Page{}, err
+		}
+		scanned++
+
+		if q.Filter.Matches(record.Fields) {
+			if len(page.Records) == q.Limit {
+				// A further match exists, so the page is not the last one.
+				page.NextCursor = page.Records[len(page.Records)-1].ID
+				return page, nil
+			}
+			page.Records = append(page.Records, record)
+		}
+		if scanned == maxScanned {
+			page.NextCursor = record.ID
+			return page, nil
+		}
+	}
+	return page, nil
+}
+
+// Scan iterates over the audit stream in the given order, starting after cursor and bounded by
+// the filter's time range. Only the time range is applied; callers match the other criteria.
+func Scan(ctx context.Context, client valkey.Client, filter Filter, cursor string, descending bool) iter.Seq2[Record, error] {
+	start, end := "-", "+"
+	if !filter.Since.IsZero() {
+		start = strconv.FormatInt(filter.Since.UnixMilli(), 10)
+	}
+	if !filter.Until.IsZero() {
+		end = strconv.FormatInt(filter.Until.UnixMilli(), 10)
+	}
+	if cursor != "" {
+		if descending {
+			end = "(" + cursor
+		} else {
+			start = "(" + cursor
+		}
+	}
+	return scanRange(ctx, client, start, end, descending)
+}
+
+// scanRange iterates over the stream entries between start and end in batches.
+func scanRange(ctx context.Context, client valkey.Client, start, end string, descending bool) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		for {
+			var cmd valkey.Completed
+			if descending {
+				cmd = client.B().Xrevrange().Key(datacoreaudit.MdaiHubEventHistoryStreamName).End(end).Start(start).Count(scanBatch).Build()
+			} else {
+				cmd = client.B().Xrange().Key(datacoreaudit.MdaiHubEventHistoryStreamName).Start(start).End(end).Count(scanBatch).Build()
+			}
+
+			entries, err := client.Do(ctx, cmd).AsXRange()
+			if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			for _, entry := range entries {
+				if !yield(Record{ID: entry.ID, Fields: entry.FieldValues}, nil) {
+					return
+				}
+			}
+			if len(entries) < scanBatch {
+				return
+			}
+
+			last := "(" + entries[len(entries)-1].ID
+			if descending {
+				end = last
+			} else {
+				start = last
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package audit
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func streamEntry(id string, kv ...string) valkey.ValkeyMessage {
+	fields := make([]valkey.ValkeyMessage, 0, len(kv))
+	for _, s := range kv {
+		fields = append(fields, valkeymock.ValkeyBlobString(s))
+	}
+	return valkeymock.ValkeyArray(valkeymock.ValkeyBlobString(id), valkeymock.ValkeyArray(fields...))
+}
+
+func TestQueryFromValues(t *testing.T) {
+	q, err := QueryFromValues(url.Values{
+		"hub_name":        {"prod"},
+		"name_prefix":     {"var."},
+		"publish_success": {"false"},
+		"actor":           {"alice"},
+		"since":           {"2026-10-01T00:00:00Z"},
+		"until":           {"2026-10-02T00:00:00Z"},
+		"limit":           {"10"},
+		"order":           {"asc"},
+		"cursor":          {"1759276800000-1"},
+	})
+	require.NoError(t, err)
+
+	notPublished := false
+	assert.Equal(t, Query{
+		Filter: Filter{
+			HubName:        "prod",
+			NamePrefix:     "var.",
+			PublishSuccess: &notPublished,
+			Actor:          "alice",
+			Since:          time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			Until:          time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+		},
+		Cursor: "1759276800000-1",
+		Limit:  10,
+	}, q)
+
+	q, err = QueryFromValues(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLimit, q.Limit)
+	assert.True(t, q.Descending)
+
+	for param, value := range map[string]string{
+		"limit":           "0",
+		"order":           "sideways",
+		"cursor":          "abc",
+		"since":           "yesterday",
+		"publish_success": "maybe",
+	} {
+		_, err := QueryFromValues(url.Values{param: {value}})
+		require.Error(t, err, param)
+	}
+
+	_, err = QueryFromValues(url.Values{"since": {"2026-10-02T00:00:00Z"}, "until": {"2026-10-01T00:00:00Z"}})
+	require.EqualError(t, err, "until must not be before since")
+}
+
+func TestFilter_Matches(t *testing.T) {
+	fields := map[string]string{
+		"hub_name":        "prod",
+		"source":          "manual_variables_api",
+		"name":            "var.add",
+		"correlation_id":  "promote-1",
+		"publish_success": "true",
+		"payload":         `{"variableRef":"Service_List"}`,
+	}
+	published := true
+	failed := false
+
+	assert.True(t, Filter{}.Matches(fields))
+	assert.True(t, Filter{HubName: "prod", Source: "manual_variables_api", NamePrefix: "var.", CorrelationID: "promote-1", PublishSuccess: &published, Text: "service_list"}.Matches(fields))
+	assert.False(t, Filter{HubName: "staging"}.Matches(fields))
+	assert.False(t, Filter{NamePrefix: "alert."}.Matches(fields))
+	assert.False(t, Filter{PublishSuccess: &failed}.Matches(fields))
+	assert.False(t, Filter{Text: "filter"}.Matches(fields))
+}
+
+func TestFilter_MatchesType(t *testing.T) {
+	published := map[string]string{"name": "var.add", "publish_success": "true"}
+	rejected := map[string]string{"type": RequestRejectedType, "reason": "Int expected"}
+
+	assert.True(t, Filter{Type: EventRecordType}.Matches(published))
+	assert.False(t, Filter{Type: EventRecordType}.Matches(rejected))
+	assert.True(t, Filter{Type: RequestRejectedType}.Matches(rejected))
+	assert.False(t, Filter{Type: RequestRejectedType}.Matches(published))
+	assert.False(t, Filter{Type: AlertSkippedType}.Matches(rejected))
+}
+
+func TestFilter_MatchesActor(t *testing.T) {
+	userFields := map[string]string{"actor_user": "alice"}
+	anonymousFields := map[string]string{"actor_remote_addr": "10.0.0.7:52114"}
+	agentFields := map[string]string{"agent_instance_uid": "0198f1b2-7c3a-7d4e-8f00-112233445566"}
+
+	assert.True(t, Filter{Actor: "alice"}.Matches(userFields))
+	assert.False(t, Filter{Actor: "bob"}.Matches(userFields))
+	assert.False(t, Filter{Actor: "alice"}.Matches(anonymousFields))
+
+	assert.True(t, Filter{RemoteAddr: "10.0.0.7:52114"}.Matches(anonymousFields))
+	assert.True(t, Filter{RemoteAddr: "10.0.0.7"}.Matches(anonymousFields))
+	assert.False(t, Filter{RemoteAddr: "10.0.0.8"}.Matches(anonymousFields))
+	assert.False(t, Filter{RemoteAddr: "10.0.0.7"}.Matches(userFields))
+
+	assert.True(t, Filter{AgentInstanceUID: "0198f1b2-7c3a-7d4e-8f00-112233445566"}.Matches(agentFields))
+	assert.False(t, Filter{AgentInstanceUID: "0198f1b2-7c3a-7d4e-8f00-112233445566"}.Matches(userFields))
+}
+
+func TestQuery_Run(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XREVRANGE", "mdai_hub_event_history", "(30-0", "-", "COUNT", strconv.Itoa(scanBatch))).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(
+			streamEntry("20-0", "hub_name", "prod"),
+			streamEntry("15-0", "hub_name", "staging"),
+			streamEntry("10-0", "hub_name", "prod"),
+			streamEntry("5-0", "hub_name", "prod"),
+		)))
+
+	page, err := Query{Filter: Filter{HubName: "prod"}, Cursor: "30-0", Limit: 2, Descending: true}.Run(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, Page{
+		Records: []Record{
+			{ID: "20-0", Fields: map[string]string{"hub_name": "prod"}},
+			{ID: "10-0", Fields: map[string]string{"hub_name": "prod"}},
+		},
+		NextCursor: "10-0",
+	}, page)
+}
+
+func TestQuery_RunLastPage(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+	since := time.UnixMilli(1000)
+	until := time.UnixMilli(2000)
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "1000", "2000", "COUNT", strconv.Itoa(scanBatch))).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(
+			streamEntry("1500-0", "name", "var.add"),
+		)))
+
+	page, err := Query{Filter: Filter{Since: since, Until: until}, Limit: 2}.Run(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, Page{Records: []Record{{ID: "1500-0", Fields: map[string]string{"name": "var.add"}}}}, page)
+}
+
+func TestScan_Batches(t *testing.T) {
+	client := valkeymock.NewClient(gomock.NewController(t))
+
+	first := make([]valkey.ValkeyMessage, 0, scanBatch)
+	for i := range scanBatch {
+		first = append(first, streamEntry(strconv.Itoa(i+1)+"-0"))
+	}
+	gomock.InOrder(
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "+", "COUNT", strconv.Itoa(scanBatch))).
+			Return(valkeymock.Result(valkeymock.ValkeyArray(first...))),
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "("+strconv.Itoa(scanBatch)+"-0", "+", "COUNT", strconv.Itoa(scanBatch))).
+			Return(valkeymock.Result(valkeymock.ValkeyArray(streamEntry("999-0")))),
+	)
+
+	count := 0
+	for record, err := range Scan(t.Context(), client, Filter{}, "", false) {
+		require.NoError(t, err)
+		count++
+		if count == scanBatch+1 {
+			assert.Equal(t, "999-0", record.ID)
+		}
+	}
+	assert.Equal(t, scanBatch+1, count)
+}
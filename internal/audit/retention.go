@@ -0,0 +1,262 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	datacoreaudit "github.com/decisiveai/mdai-data-core/audit"
+	"github.com/valkey-io/valkey-go"
+)
+
+const (
+	// AdminScope lets a caller trim the audit stream and change its retention policy.
+	AdminScope = "audit:admin"
+
+	// AuditTrimmedType marks on-demand trims of the audit stream.
+	AuditTrimmedType = "audit_trimmed"
+	// RetentionChangedType marks changes of the audit retention policy.
+	RetentionChangedType = "retention_changed"
+
+	retentionPolicyKey = "audit/retention"
+
+	policyMaxAgeField    = "max_age_ms"
+	policyMaxLenField    = "max_len"
+	policyUpdatedByField = "updated_by"
+	policyUpdatedAtField = "updated_at"
+)
+
+// ParseRetention parses a retention the way the data-core audit adapter does: a number of days
+// such as "30d" or "2.5d", or a Go duration such as "72h", in any case.
+func ParseRetention(value string) (time.Duration, error) {
+	lower := strings.ToLower(value)
+	if days, ok := strings.CutSuffix(lower, "d"); ok {
+		n, err := strconv.ParseFloat(strings.TrimSpace(days), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention %q", value)
+		}
+		d, err := time.ParseDuration(strconv.FormatFloat(n*24, 'f', -1, 64) + "h")
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention %q", value)
+		}
+		return d, nil
+	}
+	d, err := time.ParseDuration(lower)
+	if err != nil {
+		return 0, fmt.Errorf("invalid retention %q", value)
+	}
+	return d, nil
+}
+
+// FormatRetention renders whole days as "30d" and anything else as a Go duration.
+func FormatRetention(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d > 0 && d%day == 0 {
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	}
+	return d.String()
+}
+
+// RetentionPolicy bounds the audit stream. Records older than MaxAge are trimmed as records are
+// written; with MaxLen set, only the newest MaxLen records are kept.
+type RetentionPolicy struct {
+	MaxAge time.Duration
+	MaxLen int64
+	// Default is set when no policy is stored and the deployment's retention applies.
+	Default   bool
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+// RetentionPolicyUpdate is the body of a policy change.
+type RetentionPolicyUpdate struct {
+	MaxAge string `json:"maxAge"`
+	MaxLen int64  `json:"maxLen"`
+}
+
+// Parse validates the update and returns the policy it sets.
+func (u RetentionPolicyUpdate) Parse() (RetentionPolicy, error) {
+	if u.MaxAge == "" {
+		return RetentionPolicy{}, errors.New("maxAge is required")
+	}
+	maxAge, err := ParseRetention(u.MaxAge)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	if maxAge <= 0 {
+		return RetentionPolicy{}, errors.New("maxAge must be positive")
+	}
+	if u.MaxLen < 0 {
+		return RetentionPolicy{}, errors.New("maxLen must not be negative")
+	}
+	return RetentionPolicy{MaxAge: maxAge, MaxLen: u.MaxLen}, nil
+}
+
+// GetRetentionPolicy returns the policy stored in Valkey, or the deployment's retention when none
+// is stored.
+func GetRetentionPolicy(ctx context.Context, client valkey.Client, defaultMaxAge time.Duration) (RetentionPolicy, error) {
+	fields, err := client.Do(ctx, client.B().Hgetall().Key(retentionPolicyKey).Build()).AsStrMap()
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	if len(fields) == 0 {
+		return RetentionPolicy{MaxAge: defaultMaxAge, Default: true}, nil
+	}
+
+	maxAgeMs, err := strconv.ParseInt(fields[policyMaxAgeField], 10, 64)
+	if err != nil {
+		return RetentionPolicy{}, fmt.Errorf("invalid stored audit retention: %w", err)
+	}
+	policy := RetentionPolicy{MaxAge: time.Duration(maxAgeMs) * time.Millisecond, UpdatedBy: fields[policyUpdatedByField]}
+	if v := fields[policyMaxLenField]; v != "" {
+		if policy.MaxLen, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return RetentionPolicy{}, fmt.Errorf("invalid stored audit length limit: %w", err)
+		}
+	}
+	if v := fields[policyUpdatedAtField]; v != "" {
+		policy.UpdatedAt, _ = time.Parse(time.RFC3339, v)
+	}
+	return policy, nil
+}
+
+// SetRetentionPolicy stores the policy, which every gateway applies from its next write on.
+func SetRetentionPolicy(ctx context.Context, client valkey.Client, policy RetentionPolicy) error {
+	cmd := client.B().Hset().Key(retentionPolicyKey).FieldValue().
+		FieldValue(policyMaxAgeField, strconv.FormatInt(policy.MaxAge.Milliseconds(), 10)).
+		FieldValue(policyMaxLenField, strconv.FormatInt(policy.MaxLen, 10)).
+		FieldValue(policyUpdatedByField, policy.UpdatedBy).
+		FieldValue(policyUpdatedAtField, policy.UpdatedAt.UTC().Format(time.RFC3339)).
+		Build()
+	return client.Do(ctx, cmd).Error()
+}
+
+// ResetRetentionPolicy removes the stored policy, so the deployment's retention applies again.
+func ResetRetentionPolicy(ctx context.Context, client valkey.Client) error {
+	return client.Do(ctx, client.B().Del().Key(retentionPolicyKey).Build()).Error()
+}
+
+// StreamStats describes the audit stream.
+type StreamStats struct {
+	Length      int64   `json:"length"`
+	MemoryBytes int64   `json:"memoryBytes"`
+	Oldest      *Record `json:"oldest,omitempty"`
+	Newest      *Record `json:"newest,omitempty"`
+}
+
+// Stats returns the length, the estimated memory usage and the oldest and newest records of the
+// audit stream.
+func Stats(ctx context.Context, client valkey.Client) (StreamStats, error) {
+	var stats StreamStats
+	var err error
+
+	if stats.Length, err = client.Do(ctx, client.B().Xlen().Key(datacoreaudit.MdaiHubEventHistoryStreamName).Build()).AsInt64(); err != nil {
+		return StreamStats{}, err
+	}
+	stats.MemoryBytes, err = client.Do(ctx, client.B().MemoryUsage().Key(datacoreaudit.MdaiHubEventHistoryStreamName).Build()).AsInt64()
+	if err != nil && !valkey.IsValkeyNil(err) {
+		return StreamStats{}, err
+	}
+	if stats.Length == 0 {
+		return stats, nil
+	}
+
+	oldest, err := client.Do(ctx, client.B().Xrange().Key(datacoreaudit.MdaiHubEventHistoryStreamName).Start("-").End("+").Count(1).Build()).AsXRange()
+	if err != nil {
+		return StreamStats{}, err
+	}
+	newest, err := client.Do(ctx, client.B().Xrevrange().Key(datacoreaudit.MdaiHubEventHistoryStreamName).End("+").Start("-").Count(1).Build()).AsXRange()
+	if err != nil {
+		return StreamStats{}, err
+	}
+	if len(oldest) > 0 {
+		stats.Oldest = &Record{ID: oldest[0].ID, Fields: oldest[0].FieldValues}
+	}
+	if len(newest) > 0 {
+		stats.Newest = &Record{ID: newest[0].ID, Fields: newest[0].FieldValues}
+	}
+	return stats, nil
+}
+
+// TrimRequest is the body of an on-demand trim. Exactly one of OlderThan and MaxLen is set.
+type TrimRequest struct {
+	OlderThan string `json:"olderThan,omitempty"`
+	MaxLen    *int64 `json:"maxLen,omitempty"`
+}
+
+// Validate returns an InvalidTrimError when the request cannot be carried out.
+func (req TrimRequest) Validate() error {
+	_, err := req.olderThan()
+	return err
+}
+
+func (req TrimRequest) olderThan() (time.Duration, error) {
+	switch {
+	case req.OlderThan != "" && req.MaxLen != nil:
+		return 0, InvalidTrimError("set either olderThan or maxLen, not both")
+	case req.OlderThan != "":
+		age, err := ParseRetention(req.OlderThan)
+		if err != nil {
+			return 0, InvalidTrimError(err.Error())
+		}
+		if age <= 0 {
+			return 0, InvalidTrimError("olderThan must be positive")
+		}
+		return age, nil
+	case req.MaxLen != nil:
+		if *req.MaxLen < 1 {
+			return 0, InvalidTrimError("maxLen must be positive")
+		}
+		return 0, nil
+	default:
+		return 0, InvalidTrimError("set olderThan or maxLen")
+	}
+}
+
+// Trim removes the records older than OlderThan, or all but the newest MaxLen records, and returns
+// how many were removed.
+func Trim(ctx context.Context, client valkey.Client, req TrimRequest, now time.Time) (int64, error) {
+	age, err := req.olderThan()
+	if err != nil {
+		return 0, err
+	}
+
+	var cmd valkey.Completed
+	if req.MaxLen != nil {
+		cmd = client.B().Xtrim().Key(datacoreaudit.MdaiHubEventHistoryStreamName).Maxlen().Threshold(strconv.FormatInt(*req.MaxLen, 10)).Build()
+	} else {
+		minID := strconv.FormatInt(now.Add(-age).UnixMilli(), 10)
+		cmd = client.B().Xtrim().Key(datacoreaudit.MdaiHubEventHistoryStreamName).Minid().Threshold(minID).Build()
+	}
+	return client.Do(ctx, cmd).AsInt64()
+}
+
+// applyPolicyScript trims the stream by the stored retention policy, if there is one, and returns
+// how many records were removed. Like appendScript, it measures the age by the Valkey clock.
+var applyPolicyScript = valkey.NewLuaScript(`
+local policy = redis.call('HMGET', KEYS[2], 'max_age_ms', 'max_len')
+if not policy[1] then
+	return 0
+end
+local now = redis.call('TIME')
+local minid = string.format('%.0f', tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000) - tonumber(policy[1]))
+local removed = redis.call('XTRIM', KEYS[1], 'MINID', minid)
+if policy[2] and tonumber(policy[2]) > 0 then
+	removed = removed + redis.call('XTRIM', KEYS[1], 'MAXLEN', policy[2])
+end
+return removed
+`)
+
+// ApplyRetentionPolicy trims the stream by the stored retention policy and returns how many
+// records were removed. Writers apply the policy only as they write, so this keeps a stream that
+// is seldom written to within the policy. It does nothing when no policy is stored.
+func ApplyRetentionPolicy(ctx context.Context, client valkey.Client) (int64, error) {
+	return applyPolicyScript.Exec(ctx, client, []string{datacoreaudit.MdaiHubEventHistoryStreamName, retentionPolicyKey}, nil).AsInt64()
+}
+
+// InvalidTrimError reports a trim request that cannot be carried out.
+type InvalidTrimError string
+
+func (e InvalidTrimError) Error() string { return "invalid trim: " + string(e) }
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestParseRetention(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"30d":  30 * 24 * time.Hour,
+		"2.5D": 60 * time.Hour,
+		"72h":  72 * time.Hour,
+		"90M":  90 * time.Minute,
+	} {
+		d, err := ParseRetention(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, d, value)
+	}
+
+	_, err := ParseRetention("a week")
+	require.EqualError(t, err, `invalid retention "a week"`)
+	_, err = ParseRetention("xd")
+	require.EqualError(t, err, `invalid retention "xd"`)
+
+	assert.Equal(t, "30d", FormatRetention(30*24*time.Hour))
+	assert.Equal(t, "36h0m0s", FormatRetention(36*time.Hour))
+}
+
+func TestRetentionPolicyUpdate_Parse(t *testing.T) {
+	policy, err := RetentionPolicyUpdate{MaxAge: "7d", MaxLen: 1000}.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, RetentionPolicy{MaxAge: 7 * 24 * time.Hour, MaxLen: 1000}, policy)
+
+	_, err = RetentionPolicyUpdate{}.Parse()
+	require.EqualError(t, err, "maxAge is required")
+	_, err = RetentionPolicyUpdate{MaxAge: "0d"}.Parse()
+	require.EqualError(t, err, "maxAge must be positive")
+	_, err = RetentionPolicyUpdate{MaxAge: "1d", MaxLen: -1}.Parse()
+	require.EqualError(t, err, "maxLen must not be negative")
+}
+
+func TestGetRetentionPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := valkeymock.NewClient(ctrl)
+	gomock.InOrder(
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", retentionPolicyKey)).
+			Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{}))),
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", retentionPolicyKey)).
+			Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+				policyMaxAgeField:    valkeymock.ValkeyBlobString("604800000"),
+				policyMaxLenField:    valkeymock.ValkeyBlobString("1000"),
+				policyUpdatedByField: valkeymock.ValkeyBlobString("alice"),
+				policyUpdatedAtField: valkeymock.ValkeyBlobString("2026-10-18T12:00:00Z"),
+			}))),
+	)
+
+	policy, err := GetRetentionPolicy(t.Context(), client, 30*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, RetentionPolicy{MaxAge: 30 * 24 * time.Hour, Default: true}, policy)
+
+	policy, err = GetRetentionPolicy(t.Context(), client, 30*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, RetentionPolicy{
+		MaxAge:    7 * 24 * time.Hour,
+		MaxLen:    1000,
+		UpdatedBy: "alice",
+		UpdatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}, policy)
+}
+
+func TestSetRetentionPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := valkeymock.NewClient(ctrl)
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("HSET", retentionPolicyKey,
+		policyMaxAgeField, "604800000", policyMaxLenField, "0",
+		policyUpdatedByField, "alice", policyUpdatedAtField, "2026-10-18T12:00:00Z",
+	)).Return(valkeymock.Result(valkeymock.ValkeyInt64(4)))
+
+	require.NoError(t, SetRetentionPolicy(t.Context(), client, RetentionPolicy{
+		MaxAge:    7 * 24 * time.Hour,
+		UpdatedBy: "alice",
+		UpdatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}))
+}
+
+func TestStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := valkeymock.NewClient(ctrl)
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XLEN", "mdai_hub_event_history")).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(2)))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("MEMORY", "USAGE", "mdai_hub_event_history")).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(4096)))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", "mdai_hub_event_history", "-", "+", "COUNT", "1")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(streamEntry("1-0", "name", "var.add"))))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XREVRANGE", "mdai_hub_event_history", "+", "-", "COUNT", "1")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(streamEntry("2-0", "name", "var.remove"))))
+
+	stats, err := Stats(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, StreamStats{
+		Length:      2,
+		MemoryBytes: 4096,
+		Oldest:      &Record{ID: "1-0", Fields: map[string]string{"name": "var.add"}},
+		Newest:      &Record{ID: "2-0", Fields: map[string]string{"name": "var.remove"}},
+	}, stats)
+}
+
+func TestTrim(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	ctrl := gomock.NewController(t)
+	client := valkeymock.NewClient(ctrl)
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XTRIM", "mdai_hub_event_history", "MINID", strconv.FormatInt(now.Add(-7*24*time.Hour).UnixMilli(), 10))).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(12)))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XTRIM", "mdai_hub_event_history", "MAXLEN", "100")).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(3)))
+
+	removed, err := Trim(t.Context(), client, TrimRequest{OlderThan: "7d"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), removed)
+
+	maxLen := int64(100)
+	removed, err = Trim(t.Context(), client, TrimRequest{MaxLen: &maxLen}, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), removed)
+
+	zero := int64(0)
+	for req, msg := range map[*TrimRequest]string{
+		{}:                                 "invalid trim: set olderThan or maxLen",
+		{OlderThan: "7d", MaxLen: &maxLen}: "invalid trim: set either olderThan or maxLen, not both",
+		{OlderThan: "soon"}:                `invalid trim: invalid retention "soon"`,
+		{MaxLen: &zero}:                    "invalid trim: maxLen must be positive",
+	} {
+		_, err := Trim(t.Context(), client, *req, now)
+		var invalid InvalidTrimError
+		require.ErrorAs(t, err, &invalid)
+		assert.EqualError(t, err, msg)
+	}
+}
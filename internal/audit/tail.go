@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	datacoreaudit "github.com/decisiveai/mdai-data-core/audit"
+	"github.com/valkey-io/valkey-go"
+)
+
+// emptyStreamID is the ID before the first entry of a stream.
+const emptyStreamID = "0-0"
+
+// ValidateStreamID checks an ID a client wants to resume reading after.
+func ValidateStreamID(id string) error {
+	if !streamIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid stream ID %q", id)
+	}
+	return nil
+}
+
+// LastID returns the ID of the newest audit record, so that reading after it yields only
+// records written from now on.
+func LastID(ctx context.Context, client valkey.Client) (string, error) {
+	entries, err := client.Do(ctx, client.B().Xrevrange().Key(datacoreaudit.MdaiHubEventHistoryStreamName).End("+").Start("-").Count(1).Build()).AsXRange()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return emptyStreamID, nil
+	}
+	return entries[0].ID, nil
+}
+
+// ReadAfter waits up to block for records newer than after and returns at most count of them,
+// oldest first. It returns no records when none were written in time.
+func ReadAfter(ctx context.Context, client valkey.Client, after string, block time.Duration, count int64) ([]Record, error) {
+	cmd := client.B().Xread().Count(count).Block(block.Milliseconds()).Streams().Key(datacoreaudit.MdaiHubEventHistoryStreamName).Id(after).Build()
+	streams, err := client.Do(ctx, cmd).AsXRead()
+	if valkey.IsValkeyNil(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := streams[datacoreaudit.MdaiHubEventHistoryStreamName]
+	records := make([]Record, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, Record{ID: entry.ID, Fields: entry.FieldValues})
+	}
+	return records, nil
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLastID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := valkeymock.NewClient(ctrl)
+	lastEntry := valkeymock.Match("XREVRANGE", "mdai_hub_event_history", "+", "-", "COUNT", "1")
+
+	client.EXPECT().Do(gomock.Any(), lastEntry).Return(valkeymock.Result(valkeymock.ValkeyArray(streamEntry("7-1", "name", "var.add"))))
+	id, err := LastID(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "7-1", id)
+
+	client.EXPECT().Do(gomock.Any(), lastEntry).Return(valkeymock.Result(valkeymock.ValkeyArray()))
+	id, err = LastID(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "0-0", id)
+}
+
+func TestReadAfter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := valkeymock.NewClient(ctrl)
+	read := valkeymock.Match("XREAD", "COUNT", "10", "BLOCK", "500", "STREAMS", "mdai_hub_event_history", "7-1")
+
+	client.EXPECT().Do(gomock.Any(), read).Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+		"mdai_hub_event_history": valkeymock.ValkeyArray(streamEntry("8-0", "name", "var.add"), streamEntry("9-0", "name", "var.remove")),
+	})))
+	records, err := ReadAfter(t.Context(), client, "7-1", 500*time.Millisecond, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Record{
+		{ID: "8-0", Fields: map[string]string{"name": "var.add"}},
+		{ID: "9-0", Fields: map[string]string{"name": "var.remove"}},
+	}, records)
+
+	client.EXPECT().Do(gomock.Any(), read).Return(valkeymock.Result(valkeymock.ValkeyNil()))
+	records, err = ReadAfter(t.Context(), client, "7-1", 500*time.Millisecond, 10)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestValidateStreamID(t *testing.T) {
+	require.NoError(t, ValidateStreamID("1759276800000-0"))
+	require.EqualError(t, ValidateStreamID("$"), `invalid stream ID "$"`)
+}
@@ -0,0 +1,205 @@
+// Package auditsink writes audit records to destinations besides the Valkey audit stream.
+package auditsink
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/audit"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultQueueSize is the number of records a sink may fall behind before records are dropped.
+	DefaultQueueSize = 1000
+	// DefaultTimeout bounds a single write to a sink.
+	DefaultTimeout = 5 * time.Second
+
+	primarySinkName = "valkey"
+)
+
+var (
+	errQueueFull = errors.New("queue full, record dropped")
+	errClosed    = errors.New("fan-out closed, record dropped")
+)
+
+// Sink is a destination for audit records.
+type Sink interface {
+	audit.Inserter
+	// Close flushes and releases the sink.
+	Close(ctx context.Context) error
+}
+
+// Status reports the writes to one sink since the gateway started.
+type Status struct {
+	Name          string     `json:"name"`
+	Written       int64      `json:"written"`
+	Failed        int64      `json:"failed"`
+	Dropped       int64      `json:"dropped"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty"`
+}
+
+type stats struct {
+	mu     sync.Mutex
+	status Status
+}
+
+func (s *stats) record(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		s.status.Written++
+		return
+	}
+	s.status.Failed++
+	s.fail(err)
+}
+
+func (s *stats) drop(reason error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.Dropped++
+	s.fail(reason)
+}
+
+func (s *stats) fail(err error) {
+	now := time.Now().UTC()
+	s.status.LastError = err.Error()
+	s.status.LastFailureAt = &now
+}
+
+func (s *stats) snapshot() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+type secondary struct {
+	sink    Sink
+	queue   chan map[string]string
+	stats   *stats
+	timeout time.Duration
+}
+
+// FanOut writes every audit record to a primary inserter and to any number of secondary sinks.
+// The primary write is synchronous and its error is returned. Each secondary sink is fed from its
+// own queue by its own worker, so a slow or failing sink neither delays requests nor affects the
+// other sinks; its failures are logged and counted.
+type FanOut struct {
+	logger       *zap.Logger
+	primary      audit.Inserter
+	primaryStats *stats
+	secondaries  []*secondary
+	wg           sync.WaitGroup
+
+	// mu guards closed and the queues: writers hold it shared while they enqueue, Close holds
+	// it exclusively while it closes the queues.
+	mu     sync.RWMutex
+	closed bool
+}
+
+var _ audit.Inserter = (*FanOut)(nil)
+
+func NewFanOut(logger *zap.Logger, primary audit.Inserter) *FanOut {
+	return &FanOut{
+		logger:       logger,
+		primary:      primary,
+		primaryStats: &stats{status: Status{Name: primarySinkName}},
+	}
+}
+
+// Add starts feeding sink under name. It must not be called once records are being written.
+func (f *FanOut) Add(name string, sink Sink, queueSize int, timeout time.Duration) {
+	s := &secondary{
+		sink:    sink,
+		queue:   make(chan map[string]string, queueSize),
+		stats:   &stats{status: Status{Name: name}},
+		timeout: timeout,
+	}
+	f.secondaries = append(f.secondaries, s)
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for record := range s.queue {
+			ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+			err := s.sink.InsertAuditLogEventFromMap(ctx, record)
+			cancel()
+
+			s.stats.record(err)
+			if err != nil {
+				f.logger.Error("Failed to write audit record to sink", zap.String("sink", name), zap.Error(err))
+			}
+		}
+	}()
+}
+
+func (f *FanOut) InsertAuditLogEventFromMap(ctx context.Context, record map[string]string) error {
+	err := f.primary.InsertAuditLogEventFromMap(ctx, record)
+	f.primaryStats.record(err)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, s := range f.secondaries {
+		if f.closed {
+			// Records written during shutdown still reach the primary; only the secondary
+			// sinks miss them.
+			s.stats.drop(errClosed)
+			continue
+		}
+		select {
+		case s.queue <- maps.Clone(record):
+		default:
+			s.stats.drop(errQueueFull)
+			f.logger.Warn("Audit sink is falling behind, record dropped", zap.String("sink", s.stats.snapshot().Name))
+		}
+	}
+	return err
+}
+
+// Statuses reports the primary and every secondary sink, in the order they were added.
+func (f *FanOut) Statuses() []Status {
+	statuses := []Status{f.primaryStats.snapshot()}
+	for _, s := range f.secondaries {
+		statuses = append(statuses, s.stats.snapshot())
+	}
+	return statuses
+}
+
+// Close stops accepting records, lets the workers drain their queues until ctx is done, and
+// closes the secondary sinks. Records written after Close only reach the primary; the secondary
+// sinks count them as dropped. Closing again does nothing.
+func (f *FanOut) Close(ctx context.Context) error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	for _, s := range f.secondaries {
+		close(s.queue)
+	}
+	f.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+
+	var errs []error
+	for _, s := range f.secondaries {
+		errs = append(errs, s.sink.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package auditsink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeSink struct {
+	mu      sync.Mutex
+	records []map[string]string
+	err     error
+	block   chan struct{}
+	closed  bool
+}
+
+func (s *fakeSink) InsertAuditLogEventFromMap(ctx context.Context, record map[string]string) error {
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return s.err
+}
+
+func (s *fakeSink) Close(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestFanOut(t *testing.T) {
+	primary := &fakeSink{}
+	healthy := &fakeSink{}
+	failing := &fakeSink{err: errors.New("connection refused")}
+
+	fanOut := NewFanOut(zap.NewNop(), primary)
+	fanOut.Add("healthy", healthy, 10, time.Second)
+	fanOut.Add("failing", failing, 10, time.Second)
+
+	record := map[string]string{"name": "var.add", "hub_name": "prod"}
+	require.NoError(t, fanOut.InsertAuditLogEventFromMap(t.Context(), record))
+	require.NoError(t, fanOut.InsertAuditLogEventFromMap(t.Context(), record))
+	require.NoError(t, fanOut.Close(t.Context()))
+
+	assert.Len(t, primary.records, 2)
+	assert.Equal(t, []map[string]string{record, record}, healthy.records)
+	assert.True(t, healthy.closed)
+	assert.True(t, failing.closed)
+
+	statuses := fanOut.Statuses()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, Status{Name: "valkey", Written: 2}, statuses[0])
+	assert.Equal(t, Status{Name: "healthy", Written: 2}, statuses[1])
+	assert.Equal(t, "failing", statuses[2].Name)
+	assert.Equal(t, int64(2), statuses[2].Failed)
+	assert.Equal(t, "connection refused", statuses[2].LastError)
+	assert.NotNil(t, statuses[2].LastFailureAt)
+}
+
+func TestFanOut_PrimaryError(t *testing.T) {
+	primary := &fakeSink{err: errors.New("valkey down")}
+	secondary := &fakeSink{}
+
+	fanOut := NewFanOut(zap.NewNop(), primary)
+	fanOut.Add("file", secondary, 10, time.Second)
+
+	require.EqualError(t, fanOut.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.add"}), "valkey down")
+	require.NoError(t, fanOut.Close(t.Context()))
+
+	assert.Len(t, secondary.records, 1, "secondary sinks still receive the record")
+	assert.Equal(t, int64(1), fanOut.Statuses()[0].Failed)
+}
+
+func TestFanOut_SlowSinkDropsRecords(t *testing.T) {
+	slow := &fakeSink{block: make(chan struct{})}
+	fast := &fakeSink{}
+
+	fanOut := NewFanOut(zap.NewNop(), &fakeSink{})
+	fanOut.Add("slow", slow, 1, time.Minute)
+	fanOut.Add("fast", fast, 10, time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 5 {
+			assert.NoError(t, fanOut.InsertAuditLogEventFromMap(context.Background(), map[string]string{"name": "var.add"}))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow sink blocked the writer")
+	}
+
+	close(slow.block)
+	require.NoError(t, fanOut.Close(t.Context()))
+
+	assert.Len(t, fast.records, 5)
+	status := fanOut.Statuses()[1]
+	assert.Equal(t, int64(5), status.Written+status.Dropped)
+	assert.Positive(t, status.Dropped)
+	assert.Equal(t, "queue full, record dropped", status.LastError)
+}
+
+func TestFanOut_WriteDuringClose(t *testing.T) {
+	primary := &fakeSink{}
+	secondary := &fakeSink{}
+	fanOut := NewFanOut(zap.NewNop(), primary)
+	fanOut.Add("secondary", secondary, 100, time.Second)
+
+	// Requests still in flight during shutdown keep writing while the fan-out closes.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				assert.NoError(t, fanOut.InsertAuditLogEventFromMap(context.Background(), map[string]string{"name": "var.add"}))
+			}
+		}()
+	}
+	require.NoError(t, fanOut.Close(t.Context()))
+	wg.Wait()
+	require.NoError(t, fanOut.Close(t.Context()), "closing again does nothing")
+
+	require.NoError(t, fanOut.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.remove"}))
+	assert.Len(t, primary.records, 201, "the primary still receives records after Close")
+
+	status := fanOut.Statuses()[1]
+	assert.Equal(t, int64(201), status.Written+status.Dropped)
+	assert.Equal(t, "fan-out closed, record dropped", status.LastError)
+}
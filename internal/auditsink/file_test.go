@@ -0,0 +1,67 @@
+package auditsink
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readLines(t *testing.T, path string) []map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var records []map[string]string
+	for line := range strings.Lines(string(data)) {
+		var record map[string]string
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	sink, err := NewFileSink(path, 1<<20, 2)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.add", "hub_name": "prod"}))
+	require.NoError(t, sink.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.remove", "hub_name": "prod"}))
+	require.NoError(t, sink.Close(t.Context()))
+
+	assert.Equal(t, []map[string]string{
+		{"name": "var.add", "hub_name": "prod"},
+		{"name": "var.remove", "hub_name": "prod"},
+	}, readLines(t, path))
+
+	// Reopening appends.
+	sink, err = NewFileSink(path, 1<<20, 2)
+	require.NoError(t, err)
+	require.NoError(t, sink.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.set"}))
+	require.NoError(t, sink.Close(t.Context()))
+	assert.Len(t, readLines(t, path), 3)
+}
+
+func TestFileSink_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	record := func(n string) map[string]string { return map[string]string{"n": n} }
+	line, _ := json.Marshal(record("1"))
+
+	// Room for two records per file.
+	sink, err := NewFileSink(path, int64(2*(len(line)+1)), 2)
+	require.NoError(t, err)
+	for _, n := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		require.NoError(t, sink.InsertAuditLogEventFromMap(t.Context(), record(n)))
+	}
+	require.NoError(t, sink.Close(t.Context()))
+
+	assert.Equal(t, []map[string]string{record("7")}, readLines(t, path))
+	assert.Equal(t, []map[string]string{record("5"), record("6")}, readLines(t, path+".1"))
+	assert.Equal(t, []map[string]string{record("3"), record("4")}, readLines(t, path+".2"))
+	assert.NoFileExists(t, path+".3")
+}
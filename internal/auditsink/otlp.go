@@ -0,0 +1,91 @@
+package auditsink
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	otlpScopeName = "github.com/decisiveai/mdai-gateway/internal/auditsink"
+	otlpEventName = "mdai.audit"
+	serviceName   = "mdai-gateway"
+)
+
+// OTLPSink exports every audit record as an OTLP log record whose attributes are the record's
+// fields. The exporter is configured by the standard OTEL_EXPORTER_OTLP_* environment variables.
+type OTLPSink struct {
+	exporter sdklog.Exporter
+	provider *sdklog.LoggerProvider
+	logger   log.Logger
+
+	// mu serializes inserts so that err belongs to the record just emitted.
+	mu  sync.Mutex
+	err error
+}
+
+var _ Sink = (*OTLPSink)(nil)
+
+func NewOTLPSink(ctx context.Context, options ...otlploghttp.Option) (*OTLPSink, error) {
+	exporter, err := otlploghttp.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &OTLPSink{exporter: exporter}
+	s.provider = sdklog.NewLoggerProvider(
+		sdklog.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		sdklog.WithProcessor(exportProcessor{sink: s}),
+	)
+	s.logger = s.provider.Logger(otlpScopeName)
+	return s, nil
+}
+
+// exportProcessor exports each record as it is emitted and keeps the error for the sink, which
+// a batching processor would swallow.
+type exportProcessor struct {
+	sink *OTLPSink
+}
+
+func (p exportProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	p.sink.err = p.sink.exporter.Export(ctx, []sdklog.Record{*record})
+	return p.sink.err
+}
+
+func (exportProcessor) Shutdown(context.Context) error   { return nil }
+func (exportProcessor) ForceFlush(context.Context) error { return nil }
+
+func (s *OTLPSink) InsertAuditLogEventFromMap(ctx context.Context, fields map[string]string) error {
+	var record log.Record
+	record.SetEventName(otlpEventName)
+	record.SetTimestamp(time.Now())
+	record.SetSeverity(log.SeverityInfo)
+	record.SetBody(log.StringValue(cmp.Or(fields["name"], fields["type"])))
+	record.AddAttributes(log.String("mdai-logstream", "audit"))
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		record.AddAttributes(log.String(field, fields[field]))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = nil
+	s.logger.Emit(ctx, record)
+	return s.err
+}
+
+func (s *OTLPSink) Close(ctx context.Context) error {
+	if err := s.provider.Shutdown(ctx); err != nil {
+		return err
+	}
+	return s.exporter.Shutdown(ctx)
+}
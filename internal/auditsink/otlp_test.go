@@ -0,0 +1,45 @@
+package auditsink
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+)
+
+func TestOTLPSink(t *testing.T) {
+	var (
+		requests atomic.Int32
+		status   atomic.Int32
+	)
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), "hub_name")
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	sink, err := NewOTLPSink(t.Context(),
+		otlploghttp.WithEndpointURL(server.URL+"/v1/logs"),
+		otlploghttp.WithRetry(otlploghttp.RetryConfig{Enabled: false}),
+	)
+	require.NoError(t, err)
+
+	record := map[string]string{"name": "var.add", "hub_name": "prod"}
+	require.NoError(t, sink.InsertAuditLogEventFromMap(t.Context(), record))
+
+	status.Store(http.StatusBadRequest)
+	require.Error(t, sink.InsertAuditLogEventFromMap(t.Context(), record), "export failures are reported")
+
+	require.NoError(t, sink.Close(t.Context()))
+	assert.Equal(t, int32(2), requests.Load())
+}
@@ -0,0 +1,58 @@
+package auditsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookSink posts every audit record as a JSON object to a URL. Any response but 2xx is a
+// failure.
+type WebhookSink struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+var _ Sink = (*WebhookSink)(nil)
+
+// NewWebhookSink returns a sink posting to url. A non-empty token is sent as a bearer token.
+func NewWebhookSink(client *http.Client, url, token string) *WebhookSink {
+	return &WebhookSink{client: client, url: url, token: token}
+}
+
+func (s *WebhookSink) InsertAuditLogEventFromMap(ctx context.Context, record map[string]string) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *WebhookSink) Close(_ context.Context) error {
+	s.client.CloseIdleConnections()
+	return nil
+}
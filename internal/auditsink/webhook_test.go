@@ -0,0 +1,44 @@
+package auditsink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		received map[string]string
+		auth     string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		auth = r.Header.Get("Authorization")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.Client(), server.URL, "secret")
+	require.NoError(t, sink.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.add", "hub_name": "prod"}))
+	require.NoError(t, sink.Close(t.Context()))
+
+	assert.Equal(t, map[string]string{"name": "var.add", "hub_name": "prod"}, received)
+	assert.Equal(t, "Bearer secret", auth)
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.Client(), server.URL, "")
+	require.EqualError(t, sink.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.add"}), "webhook responded 502 Bad Gateway")
+}
@@ -0,0 +1,144 @@
+// Package cloudevents implements the parts of the CloudEvents 1.0 JSON format and HTTP binding
+// the gateway uses to receive events and to render its audit records.
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"time"
+)
+
+const (
+	SpecVersion = "1.0"
+
+	// ContentType is the media type of a single event in the structured content mode.
+	ContentType = "application/cloudevents+json"
+	// BatchContentType is the media type of a JSON array of events.
+	BatchContentType = "application/cloudevents-batch+json"
+
+	// PartitionKeyExtension is the partitioning extension, which keys related events.
+	PartitionKeyExtension = "partitionkey"
+	// CorrelationIDExtension carries the correlation ID of MDAI events.
+	CorrelationIDExtension = "correlationid"
+)
+
+var (
+	ErrMissingAttribute = errors.New("missing required attribute")
+
+	// validExtensionName is the form of attribute names the specification allows.
+	validExtensionName = regexp.MustCompile(`^[a-z0-9]+$`)
+
+	// contextAttributes are the attributes of the specification, which are not extensions.
+	contextAttributes = []string{
+		"specversion", "id", "source", "type", "subject", "time", "datacontenttype", "dataschema", "data", "data_base64",
+	}
+)
+
+// Event is a CloudEvent. Data holds JSON data and DataBase64 binary data; at most one is set.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time,omitzero"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+	// Extensions are the other attributes, by name.
+	Extensions map[string]string `json:"-"`
+}
+
+type eventAttributes Event
+
+// MarshalJSON writes the extensions next to the other attributes.
+func (e Event) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(eventAttributes(e))
+	if err != nil || len(e.Extensions) == 0 {
+		return data, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, name := range slices.Sorted(maps.Keys(e.Extensions)) {
+		if slices.Contains(contextAttributes, name) {
+			continue
+		}
+		key, _ := json.Marshal(name)
+		value, _ := json.Marshal(e.Extensions[name])
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads an event in the structured content mode. Extension values that are not
+// strings are kept as JSON.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var attributes eventAttributes
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+
+	*e = Event(attributes)
+	for name, value := range all {
+		if slices.Contains(contextAttributes, name) {
+			continue
+		}
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]string)
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			s = string(value)
+		}
+		e.Extensions[name] = s
+	}
+	return nil
+}
+
+// Validate checks the event against the specification.
+func (e Event) Validate() error {
+	var errs []error
+	if e.SpecVersion != SpecVersion {
+		errs = append(errs, fmt.Errorf("unsupported specversion %q, expected %q", e.SpecVersion, SpecVersion))
+	}
+	for name, value := range map[string]string{"id": e.ID, "source": e.Source, "type": e.Type} {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrMissingAttribute, name))
+		}
+	}
+	if e.Source != "" {
+		if _, err := url.Parse(e.Source); err != nil {
+			errs = append(errs, fmt.Errorf("source must be a URI reference: %w", err))
+		}
+	}
+	if e.DataSchema != "" {
+		if u, err := url.Parse(e.DataSchema); err != nil || !u.IsAbs() {
+			errs = append(errs, fmt.Errorf("dataschema must be an absolute URI: %q", e.DataSchema))
+		}
+	}
+	if e.Data != nil && e.DataBase64 != nil {
+		errs = append(errs, errors.New("data and data_base64 are mutually exclusive"))
+	}
+	for _, name := range slices.Sorted(maps.Keys(e.Extensions)) {
+		if !validExtensionName.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid attribute name %q: only lowercase letters and digits are allowed", name))
+		}
+	}
+	return errors.Join(errs...)
+}
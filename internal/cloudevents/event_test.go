@@ -0,0 +1,210 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const structuredEvent = `{
+	"specversion": "1.0",
+	"id": "evt-1",
+	"source": "/deployments/checkout",
+	"type": "deploy.finished",
+	"subject": "mdaihub-sample",
+	"time": "2025-08-08T15:56:10Z",
+	"datacontenttype": "application/json",
+	"data": {"version": "1.2.3"},
+	"partitionkey": "checkout",
+	"retries": 2
+}`
+
+func TestFromHTTP_Structured(t *testing.T) {
+	header := http.Header{"Content-Type": {ContentType + "; charset=utf-8"}}
+	event, err := FromHTTP(header, []byte(structuredEvent))
+	require.NoError(t, err)
+	require.NoError(t, event.Validate())
+
+	assert.Equal(t, Event{
+		SpecVersion:     "1.0",
+		ID:              "evt-1",
+		Source:          "/deployments/checkout",
+		Type:            "deploy.finished",
+		Subject:         "mdaihub-sample",
+		Time:            time.Date(2025, 8, 8, 15, 56, 10, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"version": "1.2.3"}`),
+		Extensions:      map[string]string{"partitionkey": "checkout", "retries": "2"},
+	}, event)
+
+	// Extensions are written back as attributes.
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"specversion":"1.0","id":"evt-1","source":"/deployments/checkout","type":"deploy.finished",
+		"subject":"mdaihub-sample","time":"2025-08-08T15:56:10Z","datacontenttype":"application/json",
+		"data":{"version":"1.2.3"},"partitionkey":"checkout","retries":"2"}`, string(data))
+}
+
+func TestFromHTTP_Binary(t *testing.T) {
+	header := http.Header{
+		"Ce-Specversion":   {"1.0"},
+		"Ce-Id":            {"evt-2"},
+		"Ce-Source":        {"/deployments/checkout"},
+		"Ce-Type":          {"deploy.finished"},
+		"Ce-Subject":       {"mdaihub-sample"},
+		"Ce-Time":          {"2025-08-08T15:56:10.5Z"},
+		"Ce-Correlationid": {"release%2042"},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantData    json.RawMessage
+		wantBase64  []byte
+	}{
+		{name: "json", contentType: "application/json", body: `{"version":"1.2.3"}`, wantData: json.RawMessage(`{"version":"1.2.3"}`)},
+		{name: "json suffix", contentType: "application/vnd.deploy+json", body: `[1,2]`, wantData: json.RawMessage(`[1,2]`)},
+		{name: "text", contentType: "text/plain", body: "deployed", wantData: json.RawMessage(`"deployed"`)},
+		{name: "binary", contentType: "application/octet-stream", body: "\x00\x01", wantBase64: []byte{0, 1}},
+		{name: "no data", contentType: "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := header.Clone()
+			h.Set("Content-Type", tt.contentType)
+			event, err := FromHTTP(h, []byte(tt.body))
+			require.NoError(t, err)
+			require.NoError(t, event.Validate())
+
+			assert.Equal(t, "evt-2", event.ID)
+			assert.Equal(t, "deploy.finished", event.Type)
+			assert.Equal(t, "mdaihub-sample", event.Subject)
+			assert.Equal(t, time.Date(2025, 8, 8, 15, 56, 10, 5e8, time.UTC), event.Time)
+			assert.Equal(t, tt.contentType, event.DataContentType)
+			assert.Equal(t, map[string]string{"correlationid": "release 42"}, event.Extensions)
+			assert.Equal(t, tt.wantData, event.Data)
+			assert.Equal(t, tt.wantBase64, event.DataBase64)
+		})
+	}
+}
+
+func TestFromHTTP_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    string
+		wantErr error
+		wantMsg string
+	}{
+		{name: "plain JSON", header: http.Header{"Content-Type": {"application/json"}}, body: `{}`, wantErr: ErrNotCloudEvent},
+		{name: "batch", header: http.Header{"Content-Type": {BatchContentType}}, body: `[]`, wantErr: ErrBatchUnsupported},
+		{name: "malformed structured", header: http.Header{"Content-Type": {ContentType}}, body: `{"id":`, wantMsg: "invalid structured CloudEvent"},
+		{
+			name:    "malformed binary data",
+			header:  http.Header{"Content-Type": {"application/json"}, "Ce-Specversion": {"1.0"}},
+			body:    `{"id":`,
+			wantMsg: "data is not valid JSON",
+		},
+		{
+			name:    "malformed binary time",
+			header:  http.Header{"Ce-Specversion": {"1.0"}, "Ce-Time": {"yesterday"}},
+			wantMsg: "invalid time attribute",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromHTTP(tt.header, []byte(tt.body))
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Contains(t, err.Error(), tt.wantMsg)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	event := Event{
+		SpecVersion: "0.3",
+		Source:      "%zz",
+		DataSchema:  "schemas/deploy",
+		Data:        json.RawMessage(`{}`),
+		DataBase64:  []byte{0},
+		Extensions:  map[string]string{"partition_key": "checkout"},
+	}
+
+	err := event.Validate()
+	require.Error(t, err)
+	for _, want := range []string{
+		`unsupported specversion "0.3"`,
+		"missing required attribute: id",
+		"missing required attribute: type",
+		"source must be a URI reference",
+		`dataschema must be an absolute URI: "schemas/deploy"`,
+		"data and data_base64 are mutually exclusive",
+		`invalid attribute name "partition_key"`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestMdaiEvent(t *testing.T) {
+	var ce Event
+	require.NoError(t, json.Unmarshal([]byte(structuredEvent), &ce))
+	ce.Extensions[CorrelationIDExtension] = "release-42"
+
+	event, err := ce.MdaiEvent()
+	require.NoError(t, err)
+	assert.Equal(t, "evt-1", event.ID)
+	assert.Equal(t, "deploy.finished", event.Name)
+	assert.Equal(t, "/deployments/checkout", event.Source)
+	assert.Equal(t, "checkout", event.SourceID)
+	assert.Equal(t, "release-42", event.CorrelationID)
+	assert.Equal(t, "mdaihub-sample", event.HubName)
+	assert.Equal(t, time.Date(2025, 8, 8, 15, 56, 10, 0, time.UTC), event.Timestamp)
+	assert.JSONEq(t, `{"version":"1.2.3"}`, event.Payload)
+	assert.Equal(t, 1, event.Version)
+
+	ce.Data, ce.DataBase64, ce.Time = nil, []byte{0, 1}, time.Time{}
+	event, err = ce.MdaiEvent()
+	require.NoError(t, err)
+	assert.Equal(t, `"AAE="`, event.Payload)
+	assert.False(t, event.Timestamp.IsZero())
+
+	ce.DataBase64 = nil
+	event, err = ce.MdaiEvent()
+	require.NoError(t, err)
+	assert.Equal(t, "null", event.Payload)
+
+	ce.Subject = ""
+	_, err = ce.MdaiEvent()
+	require.ErrorIs(t, err, ErrMissingAttribute)
+	assert.Contains(t, err.Error(), "subject")
+}
+
+func TestFromAuditRecord(t *testing.T) {
+	event, err := FromAuditRecord("1754668570000-0", map[string]string{
+		"timestamp": "2025-08-08T15:56:10Z",
+		"hub_name":  "mdaihub-sample",
+		"name":      "var.set",
+	})
+	require.NoError(t, err)
+	require.NoError(t, event.Validate())
+	assert.Equal(t, "1754668570000-0", event.ID)
+	assert.Equal(t, AuditSource, event.Source)
+	assert.Equal(t, "ai.mydecisive.audit.event", event.Type)
+	assert.Equal(t, "mdaihub-sample", event.Subject)
+	assert.Equal(t, time.Date(2025, 8, 8, 15, 56, 10, 0, time.UTC), event.Time)
+	assert.JSONEq(t, `{"timestamp":"2025-08-08T15:56:10Z","hub_name":"mdaihub-sample","name":"var.set"}`, string(event.Data))
+
+	event, err = FromAuditRecord("2-0", map[string]string{"type": "request_rejected"})
+	require.NoError(t, err)
+	assert.Equal(t, "ai.mydecisive.audit.request_rejected", event.Type)
+	assert.Empty(t, event.Subject)
+	assert.True(t, event.Time.IsZero())
+}
@@ -0,0 +1,107 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// headerPrefix prefixes the attributes of an event in the binary content mode.
+const headerPrefix = "Ce-"
+
+var (
+	// ErrNotCloudEvent is returned for requests in neither content mode.
+	ErrNotCloudEvent = errors.New("request is not a CloudEvent: expected Content-Type " + ContentType + " or ce-specversion header")
+	// ErrBatchUnsupported is returned for requests in the batched content mode.
+	ErrBatchUnsupported = errors.New("batched CloudEvents are not supported")
+)
+
+// FromHTTP reads the event of a request in the structured or binary content mode. The body has
+// already been read by the caller.
+func FromHTTP(header http.Header, body []byte) (Event, error) {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil && header.Get("Content-Type") != "" {
+		return Event{}, fmt.Errorf("invalid Content-Type: %w", err)
+	}
+
+	switch {
+	case mediaType == BatchContentType:
+		return Event{}, ErrBatchUnsupported
+	case mediaType == ContentType:
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			return Event{}, fmt.Errorf("invalid structured CloudEvent: %w", err)
+		}
+		return event, nil
+	case header.Get(headerPrefix+"Specversion") != "":
+		return fromBinary(header, mediaType, body)
+	default:
+		return Event{}, ErrNotCloudEvent
+	}
+}
+
+// fromBinary reads the attributes of an event from the ce- headers and its data from the body.
+func fromBinary(header http.Header, mediaType string, body []byte) (Event, error) {
+	event := Event{DataContentType: header.Get("Content-Type")}
+	for key, values := range header {
+		if len(key) <= len(headerPrefix) || !strings.EqualFold(key[:len(headerPrefix)], headerPrefix) || len(values) == 0 {
+			continue
+		}
+		name := strings.ToLower(key[len(headerPrefix):])
+		value, err := url.PathUnescape(values[0])
+		if err != nil {
+			return Event{}, fmt.Errorf("invalid %s header: %w", key, err)
+		}
+
+		switch name {
+		case "specversion":
+			event.SpecVersion = value
+		case "id":
+			event.ID = value
+		case "source":
+			event.Source = value
+		case "type":
+			event.Type = value
+		case "subject":
+			event.Subject = value
+		case "dataschema":
+			event.DataSchema = value
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return Event{}, fmt.Errorf("invalid time attribute: %w", err)
+			}
+			event.Time = t
+		default:
+			if event.Extensions == nil {
+				event.Extensions = make(map[string]string)
+			}
+			event.Extensions[name] = value
+		}
+	}
+
+	switch {
+	case len(body) == 0:
+	case isJSON(mediaType):
+		if !json.Valid(body) {
+			return Event{}, fmt.Errorf("data is not valid JSON for Content-Type %s", mediaType)
+		}
+		event.Data = body
+	case strings.HasPrefix(mediaType, "text/"):
+		event.Data, _ = json.Marshal(string(body))
+	default:
+		event.DataBase64 = body
+	}
+	return event, nil
+}
+
+// isJSON reports whether data of mediaType is JSON. Data without a content type is assumed to be
+// JSON, as in the structured content mode.
+func isJSON(mediaType string) bool {
+	return mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
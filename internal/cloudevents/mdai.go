@@ -0,0 +1,84 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+)
+
+const (
+	// AuditSource is the source of audit records rendered as events.
+	AuditSource = "/mdai-gateway/audit"
+	// AuditTypePrefix prefixes the audit record type in the type of the rendered events.
+	AuditTypePrefix = "ai.mydecisive.audit."
+
+	// auditEventRecordType is the type of the audit records of published events, which carry no
+	// type field.
+	auditEventRecordType = "event"
+)
+
+// MdaiEvent maps the event to an MdaiEvent: the id, source and time are kept, the type becomes
+// the name and the subject the hub. JSON data is the payload; binary data becomes a JSON string
+// of its base64 encoding and missing data a JSON null.
+func (e Event) MdaiEvent() (eventing.MdaiEvent, error) {
+	if e.Subject == "" {
+		return eventing.MdaiEvent{}, fmt.Errorf("%w: subject, which names the hub", ErrMissingAttribute)
+	}
+
+	payload := "null"
+	switch {
+	case e.Data != nil:
+		payload = string(e.Data)
+	case e.DataBase64 != nil:
+		data, err := json.Marshal(e.DataBase64)
+		if err != nil {
+			return eventing.MdaiEvent{}, err
+		}
+		payload = string(data)
+	}
+
+	event := eventing.MdaiEvent{
+		ID:            e.ID,
+		Name:          e.Type,
+		Timestamp:     e.Time,
+		Payload:       payload,
+		Source:        e.Source,
+		SourceID:      e.Extensions[PartitionKeyExtension],
+		CorrelationID: e.Extensions[CorrelationIDExtension],
+		HubName:       e.Subject,
+	}
+	event.ApplyDefaults()
+	if err := event.Validate(); err != nil {
+		return eventing.MdaiEvent{}, err
+	}
+	return event, nil
+}
+
+// FromAuditRecord renders the audit record with stream ID id as an event. Its type is the record
+// type, "event" for published events, and its subject the hub. The data is the record's fields.
+func FromAuditRecord(id string, fields map[string]string) (Event, error) {
+	recordType := fields["type"]
+	if recordType == "" {
+		recordType = auditEventRecordType
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return Event{}, err
+	}
+
+	event := Event{
+		SpecVersion:     SpecVersion,
+		ID:              id,
+		Source:          AuditSource,
+		Type:            AuditTypePrefix + recordType,
+		Subject:         fields["hub_name"],
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, fields["timestamp"]); err == nil {
+		event.Time = t.UTC()
+	}
+	return event, nil
+}
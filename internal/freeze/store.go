@@ -0,0 +1,141 @@
+package freeze
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/valkey-io/valkey-go"
+)
+
+const (
+	windowsKeyPrefix = "freeze/windows/"
+	heldKeyPrefix    = "freeze/held/"
+	heldHubsKey      = "freeze/held"
+)
+
+var ErrNotFound = errors.New("freeze window not found")
+
+// nextHeldScript pops the oldest held event and, atomically with finding the list empty, drops
+// the hub from the held hubs so a concurrent Hold is never lost.
+var nextHeldScript = valkey.NewLuaScript(`
+local event = redis.call('LPOP', KEYS[1])
+if not event then
+	redis.call('SREM', KEYS[2], ARGV[1])
+end
+return event
+`)
+
+// Store keeps each hub's freeze windows in a Valkey hash, and the events held back while a hub
+// is frozen in a list per hub.
+type Store struct {
+	client valkey.Client
+	now    func() time.Time
+}
+
+func NewStore(client valkey.Client) *Store {
+	return &Store{client: client, now: time.Now}
+}
+
+// List returns the windows of a hub ordered by creation time.
+func (s *Store) List(ctx context.Context, hubName string) ([]Window, error) {
+	docs, err := s.client.Do(ctx, s.client.B().Hgetall().Key(windowsKeyPrefix+hubName).Build()).AsStrMap()
+	if err != nil {
+		return nil, err
+	}
+
+	windows := make([]Window, 0, len(docs))
+	for _, doc := range docs {
+		var w Window
+		if err := json.Unmarshal([]byte(doc), &w); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	slices.SortFunc(windows, func(a, b Window) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return windows, nil
+}
+
+// Create assigns the window its ID and creation time and adds it to the hub.
+func (s *Store) Create(ctx context.Context, hubName string, w *Window) error {
+	w.ID = uuid.NewString()
+	w.CreatedAt = s.now().UTC()
+
+	doc, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return s.client.Do(ctx, s.client.B().Hset().Key(windowsKeyPrefix+hubName).FieldValue().FieldValue(w.ID, string(doc)).Build()).Error()
+}
+
+func (s *Store) Delete(ctx context.Context, hubName, id string) error {
+	removed, err := s.client.Do(ctx, s.client.B().Hdel().Key(windowsKeyPrefix+hubName).Field(id).Build()).AsInt64()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Active returns the window freezing the hub right now and when it ends.
+func (s *Store) Active(ctx context.Context, hubName string) (Window, time.Time, bool, error) {
+	windows, err := s.List(ctx, hubName)
+	if err != nil {
+		return Window{}, time.Time{}, false, err
+	}
+	w, until, ok := Active(windows, s.now())
+	return w, until, ok, nil
+}
+
+// Hold queues an encoded event of a frozen hub until the hub thaws.
+func (s *Store) Hold(ctx context.Context, hubName string, event []byte) error {
+	cmds := valkey.Commands{
+		s.client.B().Rpush().Key(heldKeyPrefix + hubName).Element(string(event)).Build(),
+		s.client.B().Sadd().Key(heldHubsKey).Member(hubName).Build(),
+	}
+	for _, resp := range s.client.DoMulti(ctx, cmds...) {
+		if err := resp.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HeldHubs returns the hubs that may have held events.
+func (s *Store) HeldHubs(ctx context.Context) ([]string, error) {
+	return s.client.Do(ctx, s.client.B().Smembers().Key(heldHubsKey).Build()).AsStrSlice()
+}
+
+// NextHeld removes and returns the oldest held event of a hub. ok is false once none are left,
+// and the hub is then dropped from the held hubs.
+func (s *Store) NextHeld(ctx context.Context, hubName string) ([]byte, bool, error) {
+	event, err := nextHeldScript.Exec(ctx, s.client, []string{heldKeyPrefix + hubName, heldHubsKey}, []string{hubName}).ToString()
+	if valkey.IsValkeyNil(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(event), true, nil
+}
+
+// Requeue puts back an event taken with NextHeld that could not be released. Like Hold, it adds
+// the hub to the held hubs again after pushing the event, since a concurrent NextHeld may have
+// found the list empty and dropped the hub meanwhile.
+func (s *Store) Requeue(ctx context.Context, hubName string, event []byte) error {
+	cmds := valkey.Commands{
+		s.client.B().Lpush().Key(heldKeyPrefix + hubName).Element(string(event)).Build(),
+		s.client.B().Sadd().Key(heldHubsKey).Member(hubName).Build(),
+	}
+	for _, resp := range s.client.DoMulti(ctx, cmds...) {
+		if err := resp.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
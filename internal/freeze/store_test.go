@@ -0,0 +1,112 @@
+package freeze
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+var testNow = time.Date(2026, 12, 25, 12, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) (*Store, *valkeymock.Client) {
+	t.Helper()
+
+	client := valkeymock.NewClient(gomock.NewController(t))
+	store := NewStore(client)
+	store.now = func() time.Time { return testNow }
+	return store, client
+}
+
+func windowDocument(t *testing.T, w Window) valkey.ValkeyMessage {
+	t.Helper()
+
+	doc, err := json.Marshal(w)
+	require.NoError(t, err)
+	return valkeymock.ValkeyBlobString(string(doc))
+}
+
+func TestStore_CreateAndActive(t *testing.T) {
+	store, client := newTestStore(t)
+
+	window := Window{Start: ptr(testNow.Add(-time.Hour)), End: ptr(testNow.Add(time.Hour)), Reason: "holidays"}
+	var stored string
+	client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		if cmd[0] != "HSET" || cmd[1] != "freeze/windows/prod" {
+			return false
+		}
+		stored = cmd[3]
+		return true
+	}, "HSET window")).Return(valkeymock.Result(valkeymock.ValkeyInt64(1)))
+
+	require.NoError(t, store.Create(t.Context(), "prod", &window))
+	assert.NotEmpty(t, window.ID)
+	assert.Equal(t, testNow, window.CreatedAt)
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "freeze/windows/prod")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+			window.ID: valkeymock.ValkeyBlobString(stored),
+			"expired": windowDocument(t, Window{ID: "expired", Start: ptr(testNow.AddDate(0, 0, -2)), End: ptr(testNow.AddDate(0, 0, -1))}),
+		})))
+
+	active, until, frozen, err := store.Active(t.Context(), "prod")
+	require.NoError(t, err)
+	assert.True(t, frozen)
+	assert.Equal(t, window.ID, active.ID)
+	assert.True(t, window.End.Equal(until))
+}
+
+func TestStore_Delete(t *testing.T) {
+	store, client := newTestStore(t)
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("HDEL", "freeze/windows/prod", "w1")).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(1)))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("HDEL", "freeze/windows/prod", "w2")).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(0)))
+
+	require.NoError(t, store.Delete(t.Context(), "prod", "w1"))
+	require.ErrorIs(t, store.Delete(t.Context(), "prod", "w2"), ErrNotFound)
+}
+
+func TestStore_HeldEvents(t *testing.T) {
+	store, client := newTestStore(t)
+
+	client.EXPECT().DoMulti(gomock.Any(),
+		valkeymock.Match("RPUSH", "freeze/held/prod", "event-1"),
+		valkeymock.Match("SADD", "freeze/held", "prod"),
+	).Return([]valkey.ValkeyResult{
+		valkeymock.Result(valkeymock.ValkeyInt64(1)),
+		valkeymock.Result(valkeymock.ValkeyInt64(1)),
+	})
+	require.NoError(t, store.Hold(t.Context(), "prod", []byte("event-1")))
+
+	nextHeld := func(cmd []string) bool {
+		return cmd[0] == "EVALSHA" && cmd[3] == "freeze/held/prod" && cmd[4] == "freeze/held" && cmd[5] == "prod"
+	}
+	gomock.InOrder(
+		client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(nextHeld)).Return(valkeymock.Result(valkeymock.ValkeyBlobString("event-1"))),
+		client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(nextHeld)).Return(valkeymock.Result(valkeymock.ValkeyNil())),
+	)
+
+	event, ok, err := store.NextHeld(t.Context(), "prod")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("event-1"), event)
+
+	_, ok, err = store.NextHeld(t.Context(), "prod")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// A requeued event is released again even when the hub was dropped from the held hubs.
+	client.EXPECT().DoMulti(gomock.Any(),
+		valkeymock.Match("LPUSH", "freeze/held/prod", "event-1"),
+		valkeymock.Match("SADD", "freeze/held", "prod"),
+	).Return([]valkey.ValkeyResult{
+		valkeymock.Result(valkeymock.ValkeyInt64(1)),
+		valkeymock.Result(valkeymock.ValkeyInt64(1)),
+	})
+	require.NoError(t, store.Requeue(t.Context(), "prod", []byte("event-1")))
+}
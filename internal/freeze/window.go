@@ -0,0 +1,164 @@
+package freeze
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OverrideScope lets a caller change the variables of a frozen hub and create and delete freeze
+// windows.
+const OverrideScope = "freeze:override"
+
+const maxRecurrenceDuration = 7 * 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a period during which a hub's manual variables must not change. A window without a
+// recurrence is active between Start and End. A recurring window is active during each of its
+// occurrences, optionally bounded by Start and End.
+type Window struct {
+	ID         string      `json:"id"`
+	Start      *time.Time  `json:"start,omitempty"`
+	End        *time.Time  `json:"end,omitempty"`
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	Reason     string      `json:"reason,omitempty"`
+	CreatedBy  string      `json:"createdBy,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+// Recurrence repeats a window every selected weekday, starting at the given time of day.
+type Recurrence struct {
+	// Weekdays are three-letter day names ("mon", "tue", ...). Empty means every day.
+	Weekdays []string `json:"weekdays,omitempty"`
+	// Start is the time of day the window opens, as HH:MM.
+	Start string `json:"start"`
+	// Duration is how long each occurrence lasts, e.g. "10h".
+	Duration string `json:"duration"`
+	// TimeZone is an IANA zone name. UTC by default.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+func (w Window) Validate() error {
+	if w.Start != nil && w.End != nil && !w.End.After(*w.Start) {
+		return errors.New("end must be after start")
+	}
+	if w.Recurrence == nil {
+		if w.Start == nil || w.End == nil {
+			return errors.New("start and end are required for a window without recurrence")
+		}
+		return nil
+	}
+	_, _, _, err := w.Recurrence.parse()
+	return err
+}
+
+// ActiveAt reports whether the window is active at t and, if so, when the current period ends.
+func (w Window) ActiveAt(t time.Time) (time.Time, bool) {
+	if w.Start != nil && t.Before(*w.Start) {
+		return time.Time{}, false
+	}
+	if w.End != nil && !t.Before(*w.End) {
+		return time.Time{}, false
+	}
+	if w.Recurrence == nil {
+		return *w.End, true
+	}
+
+	until, ok := w.Recurrence.activeAt(t)
+	if ok && w.End != nil && w.End.Before(until) {
+		until = *w.End
+	}
+	return until, ok
+}
+
+func (r Recurrence) activeAt(t time.Time) (time.Time, bool) {
+	days, start, duration, err := r.parse()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := t.In(start.Location())
+	// An occurrence that began on one of the previous days may still be running.
+	for back := 0; time.Duration(back-1)*24*time.Hour < duration; back++ {
+		day := local.AddDate(0, 0, -back)
+		if len(days) > 0 && !slices.Contains(days, day.Weekday()) {
+			continue
+		}
+		begin := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, start.Location())
+		if end := begin.Add(duration); !local.Before(begin) && local.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (r Recurrence) parse() ([]time.Weekday, time.Time, time.Duration, error) {
+	loc := time.UTC
+	if r.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(r.TimeZone); err != nil {
+			return nil, time.Time{}, 0, fmt.Errorf("invalid time zone %q", r.TimeZone)
+		}
+	}
+
+	start, err := time.ParseInLocation("15:04", r.Start, loc)
+	if err != nil {
+		return nil, time.Time{}, 0, fmt.Errorf("invalid recurrence start %q, expected HH:MM", r.Start)
+	}
+
+	duration, err := time.ParseDuration(r.Duration)
+	if err != nil || duration <= 0 || duration > maxRecurrenceDuration {
+		return nil, time.Time{}, 0, fmt.Errorf("invalid recurrence duration %q, expected up to %s", r.Duration, maxRecurrenceDuration)
+	}
+
+	days := make([]time.Weekday, 0, len(r.Weekdays))
+	for _, name := range r.Weekdays {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, time.Time{}, 0, fmt.Errorf("invalid weekday %q", name)
+		}
+		days = append(days, day)
+	}
+	return days, start, duration, nil
+}
+
+// Active returns the window active at t that ends last, if any.
+func Active(windows []Window, t time.Time) (Window, time.Time, bool) {
+	var (
+		active Window
+		until  time.Time
+		found  bool
+	)
+	for _, w := range windows {
+		if end, ok := w.ActiveAt(t); ok && end.After(until) {
+			active, until, found = w, end, true
+		}
+	}
+	return active, until, found
+}
+
+// FrozenError rejects a change to a hub while one of its freeze windows is active.
+type FrozenError struct {
+	HubName string
+	Window  Window
+	Until   time.Time
+}
+
+func (e FrozenError) Error() string {
+	msg := fmt.Sprintf("hub %s is frozen until %s", e.HubName, e.Until.UTC().Format(time.RFC3339))
+	if e.Window.Reason != "" {
+		msg += ": " + e.Window.Reason
+	}
+	return msg
+}
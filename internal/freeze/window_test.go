@@ -0,0 +1,107 @@
+package freeze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestWindow_Validate(t *testing.T) {
+	start := time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		window  Window
+		wantErr string
+	}{
+		{name: "one-off", window: Window{Start: ptr(start), End: ptr(start.Add(time.Hour))}},
+		{name: "one-off without end", window: Window{Start: ptr(start)}, wantErr: "start and end are required for a window without recurrence"},
+		{name: "end before start", window: Window{Start: ptr(start), End: ptr(start)}, wantErr: "end must be after start"},
+		{name: "recurring", window: Window{Recurrence: &Recurrence{Weekdays: []string{"Sat", "sun"}, Start: "22:00", Duration: "10h", TimeZone: "Europe/Berlin"}}},
+		{name: "bad weekday", window: Window{Recurrence: &Recurrence{Weekdays: []string{"someday"}, Start: "22:00", Duration: "1h"}}, wantErr: `invalid weekday "someday"`},
+		{name: "bad start", window: Window{Recurrence: &Recurrence{Start: "10pm", Duration: "1h"}}, wantErr: `invalid recurrence start "10pm", expected HH:MM`},
+		{name: "too long", window: Window{Recurrence: &Recurrence{Start: "22:00", Duration: "200h"}}, wantErr: `invalid recurrence duration "200h", expected up to 168h0m0s`},
+		{name: "bad zone", window: Window{Recurrence: &Recurrence{Start: "22:00", Duration: "1h", TimeZone: "Mars/Olympus"}}, wantErr: `invalid time zone "Mars/Olympus"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.window.Validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWindow_ActiveAt(t *testing.T) {
+	start := time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC)
+	oneOff := Window{Start: ptr(start), End: ptr(start.AddDate(0, 0, 3))}
+	// Friday 22:00 to Saturday 08:00 UTC.
+	weekly := Window{Recurrence: &Recurrence{Weekdays: []string{"fri"}, Start: "22:00", Duration: "10h"}}
+	bounded := Window{End: ptr(time.Date(2026, 10, 24, 2, 0, 0, 0, time.UTC)), Recurrence: weekly.Recurrence}
+
+	tests := []struct {
+		name      string
+		window    Window
+		at        time.Time
+		wantUntil time.Time
+		wantOK    bool
+	}{
+		{name: "before one-off", window: oneOff, at: start.Add(-time.Second)},
+		{name: "inside one-off", window: oneOff, at: start, wantUntil: *oneOff.End, wantOK: true},
+		{name: "end is exclusive", window: oneOff, at: *oneOff.End},
+		{name: "recurring on start day", window: weekly, at: time.Date(2026, 10, 23, 23, 0, 0, 0, time.UTC), wantUntil: time.Date(2026, 10, 24, 8, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "recurring past midnight", window: weekly, at: time.Date(2026, 10, 24, 7, 59, 0, 0, time.UTC), wantUntil: time.Date(2026, 10, 24, 8, 0, 0, 0, time.UTC), wantOK: true},
+		{name: "recurring other day", window: weekly, at: time.Date(2026, 10, 22, 23, 0, 0, 0, time.UTC)},
+		{name: "recurring bounded by end", window: bounded, at: time.Date(2026, 10, 24, 1, 0, 0, 0, time.UTC), wantUntil: *bounded.End, wantOK: true},
+		{name: "recurring after end", window: bounded, at: time.Date(2026, 10, 31, 1, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, ok := tt.window.ActiveAt(tt.at)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.True(t, tt.wantUntil.Equal(until), "until %s, want %s", until, tt.wantUntil)
+		})
+	}
+}
+
+func TestWindow_ActiveAtTimeZone(t *testing.T) {
+	window := Window{Recurrence: &Recurrence{Start: "09:00", Duration: "1h", TimeZone: "America/New_York"}}
+
+	_, ok := window.ActiveAt(time.Date(2026, 10, 19, 13, 30, 0, 0, time.UTC))
+	assert.True(t, ok)
+	_, ok = window.ActiveAt(time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+func TestActive(t *testing.T) {
+	now := time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC)
+	short := Window{ID: "short", Start: ptr(now.Add(-time.Hour)), End: ptr(now.Add(time.Hour))}
+	long := Window{ID: "long", Start: ptr(now.Add(-time.Hour)), End: ptr(now.Add(48 * time.Hour))}
+	past := Window{ID: "past", Start: ptr(now.Add(-48 * time.Hour)), End: ptr(now.Add(-time.Hour))}
+
+	w, until, ok := Active([]Window{short, long, past}, now)
+	require.True(t, ok)
+	assert.Equal(t, "long", w.ID)
+	assert.Equal(t, *long.End, until)
+
+	_, _, ok = Active([]Window{past}, now)
+	assert.False(t, ok)
+}
+
+func TestFrozenError(t *testing.T) {
+	err := FrozenError{
+		HubName: "prod",
+		Window:  Window{Reason: "holiday freeze"},
+		Until:   time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	assert.EqualError(t, err, "hub prod is frozen until 2027-01-02T00:00:00Z: holiday freeze")
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
 	"go.uber.org/zap"
 )
 
@@ -12,6 +13,16 @@ type PrometheusAlertResponse struct {
 	Total      int    `json:"total"`
 	Successful int    `json:"successful"`
 	Skipped    int    `json:"skipped"`
+	// Held counts events queued because their hub is frozen.
+	Held int `json:"held,omitempty"`
+	// Invalid counts alerts that could not be turned into events.
+	Invalid int `json:"invalid,omitempty"`
+	// Failed counts alerts whose events could not be published.
+	Failed int `json:"failed,omitempty"`
+	// Unchanged counts pushed alerts that were already firing and were not processed again.
+	Unchanged int `json:"unchanged,omitempty"`
+	// Results has the outcome of each alert, in the order of the notification.
+	Results []adapter.AlertResult `json:"results,omitempty"`
 }
 
 func WriteJSONResponse(w http.ResponseWriter, logger *zap.Logger, status int, response any) {
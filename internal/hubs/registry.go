@@ -0,0 +1,134 @@
+// Package hubs keeps track of the variables the MdaiHub resources declare.
+package hubs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Resource is the MdaiHub custom resource. A hub is named after its resource and declares its
+// manual, computed and meta variables under spec.variables.
+var Resource = schema.GroupVersionResource{Group: "hub.mydecisive.ai", Version: "v1", Resource: "mdaihubs"}
+
+const resyncPeriod = 10 * time.Minute
+
+// Registry holds the variables declared by each hub.
+type Registry struct {
+	logger *zap.Logger
+
+	mu        sync.RWMutex
+	variables map[string][]string
+}
+
+func NewRegistry(logger *zap.Logger) *Registry {
+	return &Registry{logger: logger, variables: map[string][]string{}}
+}
+
+// Variables returns the keys of the variables hubName declares, or false if the hub is unknown.
+func (r *Registry) Variables(hubName string) ([]string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	variables, ok := r.variables[hubName]
+	return slices.Clone(variables), ok
+}
+
+// Load replaces the hubs with those defined by hubs. A hub defined more than once is taken from
+// the first resource by namespace, so that every replica picks the same one.
+func (r *Registry) Load(hubs []*unstructured.Unstructured) {
+	hubs = slices.Clone(hubs)
+	slices.SortFunc(hubs, func(a, b *unstructured.Unstructured) int {
+		return cmp.Or(cmp.Compare(a.GetNamespace(), b.GetNamespace()), cmp.Compare(a.GetName(), b.GetName()))
+	})
+
+	variables := map[string][]string{}
+	for _, hub := range hubs {
+		if _, ok := variables[hub.GetName()]; ok {
+			r.logger.Warn("Ignoring duplicate hub", zap.String("hubName", hub.GetName()), zap.String("namespace", hub.GetNamespace()))
+			continue
+		}
+		keys, err := variableKeys(hub)
+		if err != nil {
+			r.logger.Error("Ignoring invalid hub", zap.String("hubName", hub.GetName()), zap.String("namespace", hub.GetNamespace()), zap.Error(err))
+			continue
+		}
+		variables[hub.GetName()] = keys
+	}
+
+	r.mu.Lock()
+	r.variables = variables
+	r.mu.Unlock()
+	r.logger.Info("Loaded hub variables", zap.Strings("hubs", slices.Sorted(maps.Keys(variables))))
+}
+
+// variableKeys returns the keys of the variables a hub declares.
+func variableKeys(hub *unstructured.Unstructured) ([]string, error) {
+	declared, _, err := unstructured.NestedSlice(hub.Object, "spec", "variables")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(declared))
+	for i, variable := range declared {
+		fields, ok := variable.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("spec.variables[%d] is not an object", i)
+		}
+		key, ok := fields["key"].(string)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("spec.variables[%d] has no key", i)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Watch keeps the registry in sync with the MdaiHub resources in namespace until ctx is done. It
+// returns once the hubs have been loaded for the first time, or with an error after syncTimeout,
+// e.g. when the resource is not installed, while it keeps watching.
+func (r *Registry) Watch(ctx context.Context, client dynamic.Interface, namespace string, syncTimeout time.Duration) error {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, resyncPeriod, namespace, nil)
+	informer := factory.ForResource(Resource)
+
+	reload := func() {
+		objs, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			r.logger.Error("Failed to list hubs", zap.Error(err))
+			return
+		}
+		hubs := make([]*unstructured.Unstructured, 0, len(objs))
+		for _, obj := range objs {
+			if hub, ok := obj.(*unstructured.Unstructured); ok {
+				hubs = append(hubs, hub)
+			}
+		}
+		r.Load(hubs)
+	}
+	if _, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { reload() },
+		UpdateFunc: func(any, any) { reload() },
+		DeleteFunc: func(any) { reload() },
+	}); err != nil {
+		return fmt.Errorf("failed to watch hubs: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.Informer().HasSynced) {
+		return fmt.Errorf("failed to sync %v informer", Resource)
+	}
+	reload()
+	return nil
+}
@@ -0,0 +1,70 @@
+package hubs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+// hub returns an MdaiHub resource declaring the variables keys.
+func hub(namespace, name string, keys ...string) *unstructured.Unstructured {
+	variables := make([]any, 0, len(keys))
+	for _, key := range keys {
+		variables = append(variables, map[string]any{"key": key, "type": "computed", "dataType": "set"})
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": Resource.GroupVersion().String(),
+		"kind":       "MdaiHub",
+		"metadata":   map[string]any{"namespace": namespace, "name": name},
+		"spec":       map[string]any{"variables": variables},
+	}}
+}
+
+func TestRegistryLoad(t *testing.T) {
+	broken := hub("mdai", "broken")
+	broken.Object["spec"] = map[string]any{"variables": []any{map[string]any{"type": "manual"}}}
+
+	r := NewRegistry(zap.NewNop())
+	r.Load([]*unstructured.Unstructured{
+		hub("other", "sample", "from_other"),
+		hub("mdai", "sample", "service_list", "any_service_alerted"),
+		hub("mdai", "empty"),
+		broken,
+	})
+
+	// The hub first by namespace wins.
+	variables, ok := r.Variables("sample")
+	require.True(t, ok)
+	assert.Equal(t, []string{"service_list", "any_service_alerted"}, variables)
+
+	variables, ok = r.Variables("empty")
+	require.True(t, ok)
+	assert.Empty(t, variables)
+
+	_, ok = r.Variables("broken")
+	assert.False(t, ok)
+	_, ok = r.Variables("missing")
+	assert.False(t, ok)
+}
+
+func TestRegistryWatch(t *testing.T) {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{Resource: "MdaiHubList"},
+		hub("mdai", "sample", "service_list"),
+	)
+
+	r := NewRegistry(zap.NewNop())
+	require.NoError(t, r.Watch(t.Context(), client, corev1.NamespaceAll, time.Minute))
+
+	variables, ok := r.Variables("sample")
+	require.True(t, ok)
+	assert.Equal(t, []string{"service_list"}, variables)
+}
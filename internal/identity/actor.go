@@ -0,0 +1,52 @@
+package identity
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Audit fields identifying who caused an event.
+const (
+	ActorUserAuditField         = "actor_user"
+	ActorRemoteAddrAuditField   = "actor_remote_addr"
+	ActorUserAgentAuditField    = "actor_user_agent"
+	ActorForwardedForAuditField = "actor_forwarded_for"
+)
+
+// Actor describes the caller of a request. User is set for authenticated callers; the
+// connection details identify anonymous ones.
+type Actor struct {
+	User         string
+	RemoteAddr   string
+	UserAgent    string
+	ForwardedFor string
+}
+
+func ActorFromRequest(r *http.Request) Actor {
+	return Actor{
+		User:         User(r),
+		RemoteAddr:   r.RemoteAddr,
+		UserAgent:    r.UserAgent(),
+		ForwardedFor: strings.TrimSpace(r.Header.Get("X-Forwarded-For")),
+	}
+}
+
+// AuditFields returns the authenticated user or, for anonymous callers, the non-empty connection
+// details.
+func (a Actor) AuditFields() map[string]string {
+	if a.User != "" {
+		return map[string]string{ActorUserAuditField: a.User}
+	}
+
+	fields := make(map[string]string, 3)
+	for key, value := range map[string]string{
+		ActorRemoteAddrAuditField:   a.RemoteAddr,
+		ActorUserAgentAuditField:    a.UserAgent,
+		ActorForwardedForAuditField: a.ForwardedFor,
+	} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
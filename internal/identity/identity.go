@@ -0,0 +1,31 @@
+package identity
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	// UserHeader carries the authenticated user as set by the authenticating proxy in front of
+	// the gateway. TrustedProxies.StripUntrusted drops it from requests of anyone else.
+	UserHeader = "X-Forwarded-User"
+	// ScopesHeader carries the scopes granted to the user, separated by spaces or commas.
+	ScopesHeader = "X-Mdai-Scopes"
+)
+
+// User returns the identity of the caller, or "" when the request is anonymous.
+func User(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get(UserHeader))
+}
+
+// Scopes returns the scopes granted to the caller.
+func Scopes(r *http.Request) []string {
+	return strings.FieldsFunc(r.Header.Get(ScopesHeader), func(c rune) bool {
+		return c == ',' || c == ' '
+	})
+}
+
+func HasScope(r *http.Request, scope string) bool {
+	return slices.Contains(Scopes(r), scope)
+}
@@ -0,0 +1,46 @@
+package identity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	assert.Empty(t, User(req))
+
+	req.Header.Set(UserHeader, " alice@example.com ")
+	assert.Equal(t, "alice@example.com", User(req))
+}
+
+func TestScopes(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	assert.Empty(t, Scopes(req))
+	assert.False(t, HasScope(req, "freeze:override"))
+
+	req.Header.Set(ScopesHeader, "variables:write, freeze:override")
+	assert.Equal(t, []string{"variables:write", "freeze:override"}, Scopes(req))
+	assert.True(t, HasScope(req, "freeze:override"))
+	assert.False(t, HasScope(req, "freeze"))
+}
+
+func TestActorAuditFields(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	req.RemoteAddr = "10.0.0.7:52114"
+	req.Header.Set("User-Agent", "curl/8.5.0")
+	req.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+
+	assert.Equal(t, map[string]string{
+		ActorRemoteAddrAuditField:   "10.0.0.7:52114",
+		ActorUserAgentAuditField:    "curl/8.5.0",
+		ActorForwardedForAuditField: "203.0.113.9, 10.0.0.1",
+	}, ActorFromRequest(req).AuditFields())
+
+	req.Header.Set(UserHeader, "alice")
+	assert.Equal(t, map[string]string{ActorUserAuditField: "alice"}, ActorFromRequest(req).AuditFields())
+
+	assert.Empty(t, Actor{}.AuditFields())
+}
@@ -0,0 +1,65 @@
+package identity
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// TrustedProxies are the networks of the proxies allowed to set UserHeader and ScopesHeader.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies reads a comma separated list of CIDRs or addresses, e.g.
+// "10.0.0.0/8, 192.168.1.10".
+func ParseTrustedProxies(s string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", item, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", item, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// Trusts reports whether a request with this remote address comes from a trusted proxy.
+func (t TrustedProxies) Trusts(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return slices.ContainsFunc(t, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+}
+
+// StripUntrusted removes UserHeader and ScopesHeader from requests that do not come from a
+// trusted proxy, so that callers cannot claim an identity or scopes themselves.
+func (t TrustedProxies) StripUntrusted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !t.Trusts(r.RemoteAddr) && (r.Header.Get(UserHeader) != "" || r.Header.Get(ScopesHeader) != "") {
+			r = r.Clone(r.Context())
+			r.Header.Del(UserHeader)
+			r.Header.Del(ScopesHeader)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
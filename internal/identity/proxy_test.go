@@ -0,0 +1,56 @@
+package identity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies(" 10.0.0.0/8, 192.168.1.10,,fd00::1/64 ")
+	require.NoError(t, err)
+	assert.True(t, proxies.Trusts("10.1.2.3:443"))
+	assert.True(t, proxies.Trusts("192.168.1.10:52114"))
+	assert.True(t, proxies.Trusts("[::ffff:192.168.1.10]:52114"))
+	assert.True(t, proxies.Trusts("[fd00::2]:80"))
+	assert.False(t, proxies.Trusts("192.168.1.11:52114"))
+	assert.False(t, proxies.Trusts("not an address"))
+
+	proxies, err = ParseTrustedProxies("")
+	require.NoError(t, err)
+	assert.False(t, proxies.Trusts("127.0.0.1:80"))
+
+	_, err = ParseTrustedProxies("10.0.0.0/33")
+	require.ErrorContains(t, err, `trusted proxy "10.0.0.0/33"`)
+	_, err = ParseTrustedProxies("proxy.local")
+	require.ErrorContains(t, err, `trusted proxy "proxy.local"`)
+}
+
+func TestStripUntrusted(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.1")
+	require.NoError(t, err)
+
+	var user string
+	var scopes []string
+	handler := proxies.StripUntrusted(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		user, scopes = User(r), Scopes(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", http.NoBody)
+	req.Header.Set(UserHeader, "alice")
+	req.Header.Set(ScopesHeader, "audit:admin")
+
+	req.RemoteAddr = "10.0.0.1:52114"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "alice", user)
+	assert.Equal(t, []string{"audit:admin"}, scopes)
+
+	req.RemoteAddr = "10.0.0.2:52114"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Empty(t, user)
+	assert.Empty(t, scopes)
+	assert.Equal(t, "alice", req.Header.Get(UserHeader), "the request of the caller is not modified")
+}
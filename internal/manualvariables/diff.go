@@ -0,0 +1,148 @@
+package manualvariables
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+)
+
+// Change is a single add or remove operation on a manual variable. Data has the same shape
+// the request parsers produce for Command, so it can be published as is.
+type Change struct {
+	Command valkey.CommandType `json:"command"`
+	Data    any                `json:"data"`
+}
+
+// Diff returns the changes that turn the current value of a variable into the desired one.
+// Values are expected in the form valkey.GetValue returns them: []string for sets,
+// map[string]string for maps and string for everything else.
+func Diff(varType valkey.VariableType, current, desired any) ([]Change, error) {
+	switch varType {
+	case valkey.VariableTypeSet:
+		cur, err := asStrings(current)
+		if err != nil {
+			return nil, err
+		}
+		want, err := asStrings(desired)
+		if err != nil {
+			return nil, err
+		}
+		var changes []Change
+		if added := missingFrom(cur, want); len(added) > 0 {
+			changes = append(changes, Change{Command: valkey.CommandAdd, Data: added})
+		}
+		if removed := missingFrom(want, cur); len(removed) > 0 {
+			changes = append(changes, Change{Command: valkey.CommandDel, Data: removed})
+		}
+		return changes, nil
+	case valkey.VariableTypeMap:
+		cur, err := asStringMap(current)
+		if err != nil {
+			return nil, err
+		}
+		want, err := asStringMap(desired)
+		if err != nil {
+			return nil, err
+		}
+		var changes []Change
+		upserts := make(map[string]string)
+		for k, v := range want {
+			if old, ok := cur[k]; !ok || old != v {
+				upserts[k] = v
+			}
+		}
+		if len(upserts) > 0 {
+			changes = append(changes, Change{Command: valkey.CommandAdd, Data: upserts})
+		}
+		var removed []string
+		for k := range cur {
+			if _, ok := want[k]; !ok {
+				removed = append(removed, k)
+			}
+		}
+		if len(removed) > 0 {
+			slices.Sort(removed)
+			changes = append(changes, Change{Command: valkey.CommandDel, Data: removed})
+		}
+		return changes, nil
+	case valkey.VariableTypeStr, valkey.VariableTypeInt, valkey.VariableTypeBool:
+		cur, err := asString(current)
+		if err != nil {
+			return nil, err
+		}
+		want, err := asString(desired)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case cur == want:
+			return nil, nil
+		case want == "":
+			return []Change{{Command: valkey.CommandDel, Data: cur}}, nil
+		default:
+			return []Change{{Command: valkey.CommandAdd, Data: want}}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported variable type %q", varType)
+	}
+}
+
+// Merge returns the changes that apply desired on top of the current value without removing
+// anything: set elements and map entries are added or updated, scalars are overwritten.
+func Merge(varType valkey.VariableType, current, desired any) ([]Change, error) {
+	changes, err := Diff(varType, current, desired)
+	if err != nil {
+		return nil, err
+	}
+	switch varType {
+	case valkey.VariableTypeSet, valkey.VariableTypeMap:
+		return slices.DeleteFunc(changes, func(c Change) bool { return c.Command == valkey.CommandDel }), nil
+	default:
+		return changes, nil
+	}
+}
+
+// missingFrom returns the elements of want that are not in have, keeping the order of want.
+func missingFrom(have, want []string) []string {
+	var missing []string
+	for _, v := range want {
+		if !slices.Contains(have, v) && !slices.Contains(missing, v) {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+func asStrings(v any) ([]string, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("list expected, got %T", v)
+	}
+}
+
+func asStringMap(v any) (map[string]string, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		return t, nil
+	default:
+		return nil, fmt.Errorf("map expected, got %T", v)
+	}
+}
+
+func asString(v any) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	default:
+		return "", fmt.Errorf("string expected, got %T", v)
+	}
+}
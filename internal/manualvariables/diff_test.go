@@ -0,0 +1,111 @@
+package manualvariables
+
+import (
+	"testing"
+
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		varType valkey.VariableType
+		current any
+		desired any
+		want    []Change
+		wantErr bool
+	}{
+		{
+			name:    "set adds and removes",
+			varType: valkey.VariableTypeSet,
+			current: []string{"a", "b"},
+			desired: []string{"b", "c"},
+			want: []Change{
+				{Command: valkey.CommandAdd, Data: []string{"c"}},
+				{Command: valkey.CommandDel, Data: []string{"a"}},
+			},
+		},
+		{
+			name:    "set unchanged ignores order",
+			varType: valkey.VariableTypeSet,
+			current: []string{"a", "b"},
+			desired: []string{"b", "a"},
+		},
+		{
+			name:    "set from empty",
+			varType: valkey.VariableTypeSet,
+			current: nil,
+			desired: []string{"a"},
+			want:    []Change{{Command: valkey.CommandAdd, Data: []string{"a"}}},
+		},
+		{
+			name:    "map upserts and removes",
+			varType: valkey.VariableTypeMap,
+			current: map[string]string{"a": "1", "b": "2", "z": "0"},
+			desired: map[string]string{"a": "1", "b": "3", "c": "4"},
+			want: []Change{
+				{Command: valkey.CommandAdd, Data: map[string]string{"b": "3", "c": "4"}},
+				{Command: valkey.CommandDel, Data: []string{"z"}},
+			},
+		},
+		{
+			name:    "string changed",
+			varType: valkey.VariableTypeStr,
+			current: "old",
+			desired: "new",
+			want:    []Change{{Command: valkey.CommandAdd, Data: "new"}},
+		},
+		{
+			name:    "int cleared",
+			varType: valkey.VariableTypeInt,
+			current: "3",
+			desired: "",
+			want:    []Change{{Command: valkey.CommandDel, Data: "3"}},
+		},
+		{
+			name:    "boolean unchanged",
+			varType: valkey.VariableTypeBool,
+			current: "true",
+			desired: "true",
+		},
+		{
+			name:    "wrong value shape",
+			varType: valkey.VariableTypeSet,
+			current: "a",
+			desired: []string{"a"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			varType: "list",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.varType, tt.current, tt.desired)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	changes, err := Merge(valkey.VariableTypeSet, []string{"a", "b"}, []string{"b", "c"})
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Command: valkey.CommandAdd, Data: []string{"c"}}}, changes)
+
+	changes, err = Merge(valkey.VariableTypeMap, map[string]string{"a": "1", "z": "0"}, map[string]string{"a": "2"})
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Command: valkey.CommandAdd, Data: map[string]string{"a": "2"}}}, changes)
+
+	changes, err = Merge(valkey.VariableTypeStr, "old", "new")
+	require.NoError(t, err)
+	require.Equal(t, []Change{{Command: valkey.CommandAdd, Data: "new"}}, changes)
+}
@@ -0,0 +1,125 @@
+package manualvariables
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+)
+
+// ParseChangeData decodes the data of a recorded change into the shape Apply expects. It is
+// more lenient than the request parsers: recorded int and boolean values are strings, and the
+// operator records set and map removals one element at a time.
+func ParseChangeData(varType valkey.VariableType, command valkey.CommandType, raw json.RawMessage) (any, error) {
+	switch varType {
+	case valkey.VariableTypeSet:
+		return stringsOrString(raw)
+	case valkey.VariableTypeMap:
+		if command == valkey.CommandDel {
+			return stringsOrString(raw)
+		}
+		var m map[string]string
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("map expected: %w", err)
+		}
+		return m, nil
+	case valkey.VariableTypeStr, valkey.VariableTypeInt, valkey.VariableTypeBool:
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s, nil
+		}
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("string expected: %w", err)
+		}
+		switch v.(type) {
+		case float64, bool:
+			return string(raw), nil
+		default:
+			return nil, fmt.Errorf("string expected, got %T", v)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported variable type %q", varType)
+	}
+}
+
+func stringsOrString(raw json.RawMessage) ([]string, error) {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("list expected: %w", err)
+	}
+	return []string{s}, nil
+}
+
+// Apply returns the value of a variable after the change. It is the inverse of Diff: values
+// have the form valkey.GetValue returns them, and current is never modified.
+func Apply(varType valkey.VariableType, current any, change Change) (any, error) {
+	switch varType {
+	case valkey.VariableTypeSet:
+		cur, err := asStrings(current)
+		if err != nil {
+			return nil, err
+		}
+		data, err := asStrings(change.Data)
+		if err != nil {
+			return nil, err
+		}
+		next := append(make([]string, 0, len(cur)), cur...)
+		switch change.Command {
+		case valkey.CommandAdd:
+			return append(next, missingFrom(cur, data)...), nil
+		case valkey.CommandDel:
+			return slices.DeleteFunc(next, func(v string) bool { return slices.Contains(data, v) }), nil
+		}
+	case valkey.VariableTypeMap:
+		cur, err := asStringMap(current)
+		if err != nil {
+			return nil, err
+		}
+		next := maps.Clone(cur)
+		if next == nil {
+			next = make(map[string]string)
+		}
+		switch change.Command {
+		case valkey.CommandAdd:
+			data, err := asStringMap(change.Data)
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(next, data)
+			return next, nil
+		case valkey.CommandDel:
+			keys, err := asStrings(change.Data)
+			if err != nil {
+				return nil, err
+			}
+			for _, k := range keys {
+				delete(next, k)
+			}
+			return next, nil
+		}
+	case valkey.VariableTypeStr, valkey.VariableTypeInt, valkey.VariableTypeBool:
+		if _, err := asString(current); err != nil {
+			return nil, err
+		}
+		data, err := asString(change.Data)
+		if err != nil {
+			return nil, err
+		}
+		switch change.Command {
+		case valkey.CommandAdd:
+			return data, nil
+		case valkey.CommandDel:
+			return "", nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported variable type %q", varType)
+	}
+	return nil, fmt.Errorf("unsupported command %q", change.Command)
+}
@@ -0,0 +1,140 @@
+package manualvariables
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChangeData(t *testing.T) {
+	tests := []struct {
+		name    string
+		varType valkey.VariableType
+		command valkey.CommandType
+		raw     string
+		want    any
+		wantErr bool
+	}{
+		{name: "set list", varType: valkey.VariableTypeSet, command: valkey.CommandAdd, raw: `["a","b"]`, want: []string{"a", "b"}},
+		{name: "set element", varType: valkey.VariableTypeSet, command: valkey.CommandDel, raw: `"a"`, want: []string{"a"}},
+		{name: "map add", varType: valkey.VariableTypeMap, command: valkey.CommandAdd, raw: `{"a":"1"}`, want: map[string]string{"a": "1"}},
+		{name: "map remove", varType: valkey.VariableTypeMap, command: valkey.CommandDel, raw: `["a"]`, want: []string{"a"}},
+		{name: "map add element", varType: valkey.VariableTypeMap, command: valkey.CommandAdd, raw: `"a"`, wantErr: true},
+		{name: "recorded int", varType: valkey.VariableTypeInt, command: valkey.CommandAdd, raw: `"42"`, want: "42"},
+		{name: "raw int", varType: valkey.VariableTypeInt, command: valkey.CommandAdd, raw: `42`, want: "42"},
+		{name: "raw boolean", varType: valkey.VariableTypeBool, command: valkey.CommandAdd, raw: `true`, want: "true"},
+		{name: "string object", varType: valkey.VariableTypeStr, command: valkey.CommandAdd, raw: `{}`, wantErr: true},
+		{name: "unknown type", varType: "list", command: valkey.CommandAdd, raw: `[]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChangeData(tt.varType, tt.command, json.RawMessage(tt.raw))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		varType valkey.VariableType
+		current any
+		change  Change
+		want    any
+		wantErr bool
+	}{
+		{
+			name:    "set add skips members",
+			varType: valkey.VariableTypeSet,
+			current: []string{"a"},
+			change:  Change{Command: valkey.CommandAdd, Data: []string{"a", "b"}},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "set remove",
+			varType: valkey.VariableTypeSet,
+			current: []string{"a", "b"},
+			change:  Change{Command: valkey.CommandDel, Data: []string{"a", "z"}},
+			want:    []string{"b"},
+		},
+		{
+			name:    "set remove from empty",
+			varType: valkey.VariableTypeSet,
+			change:  Change{Command: valkey.CommandDel, Data: []string{"a"}},
+			want:    []string{},
+		},
+		{
+			name:    "map merge",
+			varType: valkey.VariableTypeMap,
+			current: map[string]string{"a": "1", "b": "2"},
+			change:  Change{Command: valkey.CommandAdd, Data: map[string]string{"b": "3", "c": "4"}},
+			want:    map[string]string{"a": "1", "b": "3", "c": "4"},
+		},
+		{
+			name:    "map remove",
+			varType: valkey.VariableTypeMap,
+			current: map[string]string{"a": "1", "b": "2"},
+			change:  Change{Command: valkey.CommandDel, Data: []string{"a"}},
+			want:    map[string]string{"b": "2"},
+		},
+		{
+			name:    "string set",
+			varType: valkey.VariableTypeStr,
+			current: "old",
+			change:  Change{Command: valkey.CommandAdd, Data: "new"},
+			want:    "new",
+		},
+		{
+			name:    "boolean cleared",
+			varType: valkey.VariableTypeBool,
+			current: "true",
+			change:  Change{Command: valkey.CommandDel, Data: "true"},
+			want:    "",
+		},
+		{
+			name:    "unknown command",
+			varType: valkey.VariableTypeInt,
+			change:  Change{Command: "replace", Data: "1"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong data",
+			varType: valkey.VariableTypeMap,
+			change:  Change{Command: valkey.CommandAdd, Data: "a"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(tt.varType, tt.current, tt.change)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApply_DoesNotModifyCurrent(t *testing.T) {
+	set := []string{"a", "b"}
+	_, err := Apply(valkey.VariableTypeSet, set, Change{Command: valkey.CommandDel, Data: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, set)
+
+	m := map[string]string{"a": "1"}
+	_, err = Apply(valkey.VariableTypeMap, m, Change{Command: valkey.CommandDel, Data: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1"}, m)
+}
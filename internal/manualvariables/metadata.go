@@ -0,0 +1,53 @@
+package manualvariables
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ChangeMetadata records why a manual variable was changed. It travels in the event payload
+// and in the audit record of the change.
+type ChangeMetadata struct {
+	Reason      string `json:"reason,omitempty"`
+	Ticket      string `json:"ticket,omitempty"`
+	ExpiresHint string `json:"expiresHint,omitempty"`
+}
+
+func (m ChangeMetadata) IsZero() bool {
+	return m == ChangeMetadata{}
+}
+
+// AuditFields returns the non-empty metadata as audit record fields.
+func (m ChangeMetadata) AuditFields() map[string]string {
+	fields := make(map[string]string, 3)
+	if m.Reason != "" {
+		fields["reason"] = m.Reason
+	}
+	if m.Ticket != "" {
+		fields["ticket"] = m.Ticket
+	}
+	if m.ExpiresHint != "" {
+		fields["expires_hint"] = m.ExpiresHint
+	}
+	return fields
+}
+
+// ChangeMetadataFromRaw extracts the optional metadata fields sent next to "data" in a
+// mutation request body.
+func ChangeMetadataFromRaw(raw map[string]json.RawMessage) (ChangeMetadata, error) {
+	var m ChangeMetadata
+	for key, dst := range map[string]*string{
+		"reason":      &m.Reason,
+		"ticket":      &m.Ticket,
+		"expiresHint": &m.ExpiresHint,
+	} {
+		value, ok := raw[key]
+		if !ok || string(value) == "null" {
+			continue
+		}
+		if err := json.Unmarshal(value, dst); err != nil {
+			return ChangeMetadata{}, fmt.Errorf("%s must be a string", key)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,60 @@
+package manualvariables
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeMetadataFromRaw(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    ChangeMetadata
+		wantErr string
+	}{
+		{
+			name: "none",
+			body: `{"data":"x"}`,
+		},
+		{
+			name: "all",
+			body: `{"data":"x","reason":"noisy service","ticket":"OPS-1","expiresHint":"2026-11-01"}`,
+			want: ChangeMetadata{Reason: "noisy service", Ticket: "OPS-1", ExpiresHint: "2026-11-01"},
+		},
+		{
+			name: "null is ignored",
+			body: `{"data":"x","reason":null}`,
+		},
+		{
+			name:    "not a string",
+			body:    `{"data":"x","ticket":123}`,
+			wantErr: "ticket must be a string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &raw))
+
+			got, err := ChangeMetadataFromRaw(raw)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChangeMetadata_AuditFields(t *testing.T) {
+	assert.Empty(t, ChangeMetadata{}.AuditFields())
+	assert.Equal(t,
+		map[string]string{"reason": "r", "expires_hint": "tomorrow"},
+		ChangeMetadata{Reason: "r", ExpiresHint: "tomorrow"}.AuditFields(),
+	)
+}
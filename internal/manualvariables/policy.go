@@ -0,0 +1,130 @@
+package manualvariables
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/decisiveai/mdai-gateway/internal/redact"
+)
+
+// RequireReasonAnnotation lists, comma separated, the variables of a hub whose mutations must
+// carry a reason. "*" selects every variable of the hub. It is set on the hub's manual
+// variables ConfigMap.
+const RequireReasonAnnotation = "mydecisive.ai/require-reason"
+
+// ProtectedVariablesAnnotation lists, in the same format, the variables whose mutations need the
+// approval of a second identity before they are published.
+const ProtectedVariablesAnnotation = "mydecisive.ai/protected-variables"
+
+// FreezeAlertsAnnotation decides what happens to alert-driven events of a hub while it is
+// frozen: "hold" queues them until the freeze ends, "pass" (the default) publishes them.
+const FreezeAlertsAnnotation = "mydecisive.ai/freeze-alerts"
+
+// AuditRedactionAnnotation lists the redaction rules, in the format of redact.Parse, applied to
+// the alert labels and annotations and the variable values of a hub before they are written to
+// the audit stream or logged.
+const AuditRedactionAnnotation = "mydecisive.ai/redact-audit"
+
+// EventRedactionAnnotation lists, in the same format, the redaction rules applied to the payloads
+// of the events published for a hub. Manual variable events are not redacted: the operator sets
+// the variables to their data.
+const EventRedactionAnnotation = "mydecisive.ai/redact-events"
+
+// AlertFingerprintAnnotation decides what happens to alerts of a hub that arrive without a
+// fingerprint: "compute" derives it from the alert labels, "require" (the default) rejects them.
+const AlertFingerprintAnnotation = "mydecisive.ai/alert-fingerprint"
+
+// AlertAnnotationsAnnotation decides what happens to alerts of a hub whose action_context or
+// relevant_labels annotations are invalid: "flag" (the default) publishes them with the problems
+// listed in the event, "reject" reports them as invalid.
+const AlertAnnotationsAnnotation = "mydecisive.ai/alert-annotations"
+
+const (
+	freezeAlertsHold        = "hold"
+	alertFingerprintCompute = "compute"
+	alertAnnotationsReject  = "reject"
+)
+
+var (
+	ErrReasonRequired = HTTPError{"reason is required for this variable", http.StatusBadRequest}
+	ErrProtected      = HTTPError{"variable is protected, changes must be proposed and approved individually", http.StatusForbidden}
+)
+
+// Policy holds the per-hub rules configured through annotations on the hub's manual variables
+// ConfigMap. The zero value imposes no restrictions.
+type Policy struct {
+	RequireReason []string
+	Protected     []string
+	// HoldAlertsWhenFrozen queues alert-driven events while the hub is frozen.
+	HoldAlertsWhenFrozen bool
+	// ComputeAlertFingerprints derives the fingerprint of alerts sent without one.
+	ComputeAlertFingerprints bool
+	// RejectInvalidAlertAnnotations rejects alerts with invalid action_context or relevant_labels
+	// annotations instead of flagging them.
+	RejectInvalidAlertAnnotations bool
+
+	AuditRedaction redact.Policy
+	EventRedaction redact.Policy
+	// RedactionErr reports invalid redaction rules, which are left out of the policies.
+	RedactionErr error
+}
+
+func PolicyFromAnnotations(annotations map[string]string) Policy {
+	auditRedaction, auditErr := redact.Parse(annotations[AuditRedactionAnnotation])
+	eventRedaction, eventErr := redact.Parse(annotations[EventRedactionAnnotation])
+
+	return Policy{
+		RequireReason: splitList(annotations[RequireReasonAnnotation]),
+		Protected:     splitList(annotations[ProtectedVariablesAnnotation]),
+
+		HoldAlertsWhenFrozen:     strings.EqualFold(strings.TrimSpace(annotations[FreezeAlertsAnnotation]), freezeAlertsHold),
+		ComputeAlertFingerprints: strings.EqualFold(strings.TrimSpace(annotations[AlertFingerprintAnnotation]), alertFingerprintCompute),
+
+		RejectInvalidAlertAnnotations: strings.EqualFold(strings.TrimSpace(annotations[AlertAnnotationsAnnotation]), alertAnnotationsReject),
+
+		AuditRedaction: auditRedaction,
+		EventRedaction: eventRedaction,
+		RedactionErr:   errors.Join(auditErr, eventErr),
+	}
+}
+
+func (p Policy) ReasonRequired(varName string) bool {
+	return matchesVariable(p.RequireReason, varName)
+}
+
+func (p Policy) IsProtected(varName string) bool {
+	return matchesVariable(p.Protected, varName)
+}
+
+// CheckBulkChange verifies that varName may be changed by a bulk operation such as promote or
+// import, which bypass the approval workflow.
+func (p Policy) CheckBulkChange(varName string, metadata ChangeMetadata) error {
+	if p.IsProtected(varName) {
+		return ErrProtected
+	}
+	return p.CheckMetadata(varName, metadata)
+}
+
+// CheckMetadata verifies that the metadata of a change to varName satisfies the policy.
+func (p Policy) CheckMetadata(varName string, metadata ChangeMetadata) error {
+	if p.ReasonRequired(varName) && strings.TrimSpace(metadata.Reason) == "" {
+		return ErrReasonRequired
+	}
+	return nil
+}
+
+func matchesVariable(list []string, varName string) bool {
+	return slices.Contains(list, "*") || slices.Contains(list, varName)
+}
+
+func splitList(s string) []string {
+	var list []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
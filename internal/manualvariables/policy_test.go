@@ -0,0 +1,86 @@
+package manualvariables
+
+import (
+	"testing"
+
+	"github.com/decisiveai/mdai-gateway/internal/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_CheckMetadata(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		varName    string
+		metadata   ChangeMetadata
+		wantErr    bool
+	}{
+		{name: "no policy", varName: "filter"},
+		{name: "listed without reason", annotation: "filter, services", varName: "services", wantErr: true},
+		{name: "listed with reason", annotation: "filter,services", varName: "filter", metadata: ChangeMetadata{Reason: "incident"}},
+		{name: "blank reason", annotation: "filter", varName: "filter", metadata: ChangeMetadata{Reason: "  ", Ticket: "OPS-1"}, wantErr: true},
+		{name: "not listed", annotation: "filter", varName: "services"},
+		{name: "wildcard", annotation: "*", varName: "anything", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := PolicyFromAnnotations(map[string]string{RequireReasonAnnotation: tt.annotation})
+
+			err := policy.CheckMetadata(tt.varName, tt.metadata)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrReasonRequired)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPolicy_CheckBulkChange(t *testing.T) {
+	policy := PolicyFromAnnotations(map[string]string{
+		ProtectedVariablesAnnotation: "drop_all",
+		RequireReasonAnnotation:      "filter",
+	})
+
+	require.ErrorIs(t, policy.CheckBulkChange("drop_all", ChangeMetadata{Reason: "r"}), ErrProtected)
+	require.ErrorIs(t, policy.CheckBulkChange("filter", ChangeMetadata{}), ErrReasonRequired)
+	require.NoError(t, policy.CheckBulkChange("services", ChangeMetadata{}))
+	assert.True(t, policy.IsProtected("drop_all"))
+	assert.False(t, policy.IsProtected("filter"))
+}
+
+func TestPolicyFromAnnotations_FreezeAlerts(t *testing.T) {
+	assert.False(t, PolicyFromAnnotations(nil).HoldAlertsWhenFrozen)
+	assert.False(t, PolicyFromAnnotations(map[string]string{FreezeAlertsAnnotation: "pass"}).HoldAlertsWhenFrozen)
+	assert.True(t, PolicyFromAnnotations(map[string]string{FreezeAlertsAnnotation: " Hold"}).HoldAlertsWhenFrozen)
+}
+
+func TestPolicyFromAnnotations_AlertFingerprint(t *testing.T) {
+	assert.False(t, PolicyFromAnnotations(nil).ComputeAlertFingerprints)
+	assert.False(t, PolicyFromAnnotations(map[string]string{AlertFingerprintAnnotation: "require"}).ComputeAlertFingerprints)
+	assert.True(t, PolicyFromAnnotations(map[string]string{AlertFingerprintAnnotation: "Compute "}).ComputeAlertFingerprints)
+}
+
+func TestPolicyFromAnnotations_AlertAnnotations(t *testing.T) {
+	assert.False(t, PolicyFromAnnotations(nil).RejectInvalidAlertAnnotations)
+	assert.False(t, PolicyFromAnnotations(map[string]string{AlertAnnotationsAnnotation: "flag"}).RejectInvalidAlertAnnotations)
+	assert.True(t, PolicyFromAnnotations(map[string]string{AlertAnnotationsAnnotation: "reject"}).RejectInvalidAlertAnnotations)
+}
+
+func TestPolicyFromAnnotations_Redaction(t *testing.T) {
+	policy := PolicyFromAnnotations(map[string]string{
+		AuditRedactionAnnotation: "label/customer_id=hash, label/bad",
+		EventRedactionAnnotation: "variable/tenants=drop",
+	})
+
+	_, ok := policy.AuditRedaction.ActionFor(redact.Label, "customer_id")
+	assert.True(t, ok)
+	action, ok := policy.EventRedaction.ActionFor(redact.Variable, "tenants")
+	assert.True(t, ok)
+	assert.Equal(t, redact.Drop, action)
+	require.EqualError(t, policy.RedactionErr, `redaction rule "label/bad": expected kind/pattern=action`)
+
+	assert.NoError(t, PolicyFromAnnotations(nil).RedactionErr)
+}
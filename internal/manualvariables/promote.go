@@ -0,0 +1,64 @@
+package manualvariables
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+)
+
+var ErrSameHub = HTTPError{"source and target hub must differ", http.StatusBadRequest}
+
+// PromotableVariables resolves which variables can be copied from sourceHub to targetHub.
+// An empty names list selects every variable declared by the source hub. Each selected
+// variable must be declared by both hubs with the same type; all violations are reported
+// in a single error.
+func PromotableVariables(sourceHub, targetHub string, names []string, hubsVariables ByHub) (map[string]valkey.VariableType, error) {
+	if len(hubsVariables) == 0 {
+		return nil, ErrNoManualVariablesFound
+	}
+	if sourceHub == "" || targetHub == "" {
+		return nil, HTTPError{"source and target hub required", http.StatusBadRequest}
+	}
+	if sourceHub == targetHub {
+		return nil, ErrSameHub
+	}
+
+	sourceVars, ok := hubsVariables[sourceHub]
+	if !ok {
+		return nil, HTTPError{"source hub not found", http.StatusNotFound}
+	}
+	targetVars, ok := hubsVariables[targetHub]
+	if !ok {
+		return nil, HTTPError{"target hub not found", http.StatusNotFound}
+	}
+
+	if len(names) == 0 {
+		names = slices.Sorted(maps.Keys(sourceVars))
+	}
+
+	var problems []string
+	result := make(map[string]valkey.VariableType, len(names))
+	for _, name := range names {
+		sourceType, inSource := sourceVars[name]
+		targetType, inTarget := targetVars[name]
+		switch {
+		case !inSource:
+			problems = append(problems, name+": not declared in source hub")
+		case !inTarget:
+			problems = append(problems, name+": not declared in target hub")
+		case sourceType != targetType:
+			problems = append(problems, fmt.Sprintf("%s: type %s in source hub, %s in target hub", name, sourceType, targetType))
+		default:
+			result[name] = valkey.VariableType(sourceType)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, HTTPError{"incompatible variables: " + strings.Join(problems, "; "), http.StatusUnprocessableEntity}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package manualvariables
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromotableVariables(t *testing.T) {
+	hubs := ByHub{
+		"staging": {"filter": "string", "services": "set", "level": "int", "staging_only": "boolean"},
+		"prod":    {"filter": "string", "services": "set", "level": "string"},
+	}
+
+	tests := []struct {
+		name       string
+		source     string
+		target     string
+		names      []string
+		want       map[string]valkey.VariableType
+		wantStatus int
+		wantMsg    string
+	}{
+		{
+			name:   "selected compatible",
+			source: "staging",
+			target: "prod",
+			names:  []string{"filter", "services"},
+			want: map[string]valkey.VariableType{
+				"filter":   valkey.VariableTypeStr,
+				"services": valkey.VariableTypeSet,
+			},
+		},
+		{
+			name:       "all reports every incompatibility",
+			source:     "staging",
+			target:     "prod",
+			wantStatus: http.StatusUnprocessableEntity,
+			wantMsg:    "incompatible variables: level: type int in source hub, string in target hub; staging_only: not declared in target hub",
+		},
+		{
+			name:       "undeclared in source",
+			source:     "prod",
+			target:     "staging",
+			names:      []string{"missing"},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantMsg:    "incompatible variables: missing: not declared in source hub",
+		},
+		{
+			name:       "same hub",
+			source:     "prod",
+			target:     "prod",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown target",
+			source:     "staging",
+			target:     "dev",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PromotableVariables(tt.source, tt.target, tt.names, hubs)
+			if tt.wantStatus != 0 {
+				var httpErr HTTPError
+				require.True(t, errors.As(err, &httpErr))
+				require.Equal(t, tt.wantStatus, httpErr.HTTPStatus())
+				if tt.wantMsg != "" {
+					require.Equal(t, tt.wantMsg, httpErr.Error())
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
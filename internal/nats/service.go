@@ -3,26 +3,56 @@ package nats
 import (
 	"context"
 	"errors"
+	"slices"
 	"strconv"
+	"strings"
 
-	"github.com/decisiveai/mdai-data-core/audit"
 	"github.com/decisiveai/mdai-data-core/eventing/publisher"
 	"github.com/decisiveai/mdai-gateway/internal/adapter"
 	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
 	"go.uber.org/zap"
 )
 
-func PublishEvents(ctx context.Context, logger *zap.Logger, p publisher.Publisher, eventsPerSubjects []adapter.EventPerSubject, auditAdapter *audit.AuditAdapter) (int, error) {
+// ErrNotAttempted is the error of events left unpublished because the context of the batch ended.
+var ErrNotAttempted = errors.New("not attempted")
+
+// PublishEvents publishes the events in order and writes an audit record for each attempt. It
+// returns how many were published and the errors of the others.
+func PublishEvents(ctx context.Context, logger *zap.Logger, p publisher.Publisher, eventsPerSubjects []adapter.EventPerSubject, auditAdapter auditutils.Inserter) (int, error) {
+	successCount := 0
+	var failures []error
+	for _, err := range PublishEachEvent(ctx, logger, p, eventsPerSubjects, auditAdapter) {
+		switch {
+		case err == nil:
+			successCount++
+		case !errors.Is(err, ErrNotAttempted):
+			failures = append(failures, err)
+		}
+	}
+	return successCount, errors.Join(failures...)
+}
+
+// PublishEachEvent is PublishEvents with the outcome of every event: the error at an event's
+// index is nil once it was published. A cancelled context stops the batch, and the events after
+// it fail with ErrNotAttempted.
+func PublishEachEvent(ctx context.Context, logger *zap.Logger, p publisher.Publisher, eventsPerSubjects []adapter.EventPerSubject, auditAdapter auditutils.Inserter) []error {
 	var (
 		successCount int
-		errs         []error
+		attempted    int
+		failures     []error
 	)
 
-	for _, eventPerSubject := range eventsPerSubjects {
+	errs := make([]error, len(eventsPerSubjects))
+	for i, eventPerSubject := range eventsPerSubjects {
+		attempted++
 		event := eventPerSubject.Event
 		err := p.Publish(ctx, event, eventPerSubject.Subject)
 
-		if auditErr := auditutils.RecordAuditEventFromMdaiEvent(ctx, logger, auditAdapter, event, err == nil); auditErr != nil {
+		audited := event
+		if eventPerSubject.AuditPayload != "" {
+			audited.Payload = eventPerSubject.AuditPayload
+		}
+		if auditErr := auditutils.RecordAuditEventFromMdaiEvent(ctx, logger, auditAdapter, audited, err == nil, eventPerSubject.AuditFields); auditErr != nil {
 			logger.Error("Failed to write audit event for automation step",
 				zap.String("hubName", event.HubName),
 				zap.String("name", event.Name),
@@ -37,13 +67,43 @@ func PublishEvents(ctx context.Context, logger *zap.Logger, p publisher.Publishe
 			continue
 		}
 
+		errs[i] = err
+		failures = append(failures, err)
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			errs = append(errs, err)
+			for j := i + 1; j < len(errs); j++ {
+				errs[j] = ErrNotAttempted
+			}
 			break
 		}
+	}
 
-		errs = append(errs, err)
+	if err := errors.Join(failures...); err != nil && len(eventsPerSubjects) > 1 {
+		recordBatchFailure(ctx, logger, auditAdapter, eventsPerSubjects, successCount, attempted, err)
 	}
+	return errs
+}
 
-	return successCount, errors.Join(errs...)
+// recordBatchFailure writes an audit record summarising a batch of which some events were not
+// published. Each attempted event also has its own record.
+func recordBatchFailure(ctx context.Context, logger *zap.Logger, auditAdapter auditutils.Inserter, eventsPerSubjects []adapter.EventPerSubject, successCount, attempted int, err error) {
+	var hubNames, correlationIDs []string
+	for _, eventPerSubject := range eventsPerSubjects {
+		hubNames = append(hubNames, eventPerSubject.Event.HubName)
+		correlationIDs = append(correlationIDs, eventPerSubject.Event.CorrelationID)
+	}
+	slices.Sort(hubNames)
+	slices.Sort(correlationIDs)
+
+	fields := map[string]string{
+		"reason":          err.Error(),
+		"total":           strconv.Itoa(len(eventsPerSubjects)),
+		"successful":      strconv.Itoa(successCount),
+		"failed":          strconv.Itoa(attempted - successCount),
+		"not_attempted":   strconv.Itoa(len(eventsPerSubjects) - attempted),
+		"hub_name":        strings.Join(slices.Compact(hubNames), ","),
+		"correlation_ids": strings.Join(slices.DeleteFunc(slices.Compact(correlationIDs), func(id string) bool { return id == "" }), ","),
+	}
+	if auditErr := auditutils.RecordAuditRecord(ctx, logger, auditAdapter, auditutils.PublishBatchFailedType, fields); auditErr != nil {
+		logger.Error("Failed to write audit record for partially failed batch", zap.Error(auditErr))
+	}
 }
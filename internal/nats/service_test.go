@@ -10,6 +10,7 @@ import (
 	"github.com/decisiveai/mdai-data-core/audit"
 	"github.com/decisiveai/mdai-data-core/eventing"
 	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
 	"github.com/decisiveai/mdai-gateway/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -151,3 +152,119 @@ func TestPublishEvents(t *testing.T) {
 		mockPub.AssertExpectations(t)
 	})
 }
+
+func TestPublishEvents_RecordsBatchFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	valkeyClient := valkeymock.NewClient(ctrl)
+	var records [][]string
+	valkeyClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).
+		DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			records = append(records, cmd.Commands())
+			return valkeymock.Result(valkeymock.ValkeyString(""))
+		}).Times(3)
+	auditAdapter := audit.NewAuditAdapter(zap.NewNop(), valkeyClient)
+
+	subject := eventing.MdaiEventSubject{Type: "test", Path: "subject"}
+	ok := eventing.MdaiEvent{Name: "var.set", HubName: "hub", CorrelationID: "c-1"}
+	failing := eventing.MdaiEvent{Name: "var.set", HubName: "hub", CorrelationID: "c-2"}
+
+	mockPub := &mocks.MockPublisher{}
+	mockPub.On("Publish", mock.Anything, ok, subject).Return(nil).Once()
+	mockPub.On("Publish", mock.Anything, failing, subject).Return(errors.New("fail")).Once()
+
+	success, err := PublishEvents(t.Context(), zap.NewNop(), mockPub, []adapter.EventPerSubject{
+		{Event: ok, Subject: subject},
+		{Event: failing, Subject: subject},
+	}, auditAdapter)
+	require.Error(t, err)
+	assert.Equal(t, 1, success)
+
+	require.Len(t, records, 3)
+	batch := records[2]
+	field := func(name string) string {
+		i := slices.Index(batch, name)
+		require.NotEqual(t, -1, i, name)
+		return batch[i+1]
+	}
+	assert.Equal(t, auditutils.PublishBatchFailedType, field("type"))
+	assert.Equal(t, "2", field("total"))
+	assert.Equal(t, "1", field("successful"))
+	assert.Equal(t, "1", field("failed"))
+	assert.Equal(t, "0", field("not_attempted"))
+	assert.Equal(t, "hub", field("hub_name"))
+	assert.Equal(t, "c-1,c-2", field("correlation_ids"))
+	assert.Equal(t, "fail", field("reason"))
+}
+
+func TestPublishEvents_AuditPayload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	valkeyClient := valkeymock.NewClient(ctrl)
+	var record []string
+	valkeyClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).
+		DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			record = cmd.Commands()
+			return valkeymock.Result(valkeymock.ValkeyString(""))
+		})
+	auditAdapter := audit.NewAuditAdapter(zap.NewNop(), valkeyClient)
+
+	subject := eventing.MdaiEventSubject{Type: "test", Path: "subject"}
+	event := eventing.MdaiEvent{Name: "var.set", HubName: "hub", Payload: `{"data":"customer-1"}`}
+
+	mockPub := &mocks.MockPublisher{}
+	mockPub.On("Publish", mock.Anything, event, subject).Return(nil).Once()
+
+	_, err := PublishEvents(t.Context(), zap.NewNop(), mockPub, []adapter.EventPerSubject{
+		{Event: event, Subject: subject, AuditPayload: `{"data":"***"}`},
+	}, auditAdapter)
+	require.NoError(t, err)
+
+	mockPub.AssertExpectations(t)
+	i := slices.Index(record, "payload")
+	require.NotEqual(t, -1, i)
+	assert.JSONEq(t, `{"data":"***"}`, record[i+1])
+}
+
+func TestPublishEachEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	valkeyClient := valkeymock.NewClient(ctrl)
+	valkeyClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString(""))).AnyTimes()
+	auditAdapter := audit.NewAuditAdapter(zap.NewNop(), valkeyClient)
+
+	subject := eventing.MdaiEventSubject{Type: "test", Path: "subject"}
+	first := eventing.MdaiEvent{Name: "var.set", HubName: "hub", CorrelationID: "c-1"}
+	failing := eventing.MdaiEvent{Name: "var.set", HubName: "hub", CorrelationID: "c-2"}
+	last := eventing.MdaiEvent{Name: "var.set", HubName: "hub", CorrelationID: "c-3"}
+	events := []adapter.EventPerSubject{
+		{Event: first, Subject: subject},
+		{Event: failing, Subject: subject},
+		{Event: last, Subject: subject},
+	}
+
+	t.Run("failure does not stop the batch", func(t *testing.T) {
+		mockPub := &mocks.MockPublisher{}
+		mockPub.On("Publish", mock.Anything, first, subject).Return(nil).Once()
+		mockPub.On("Publish", mock.Anything, failing, subject).Return(errors.New("fail")).Once()
+		mockPub.On("Publish", mock.Anything, last, subject).Return(nil).Once()
+
+		errs := PublishEachEvent(t.Context(), zap.NewNop(), mockPub, events, auditAdapter)
+		require.Len(t, errs, 3)
+		require.NoError(t, errs[0])
+		require.EqualError(t, errs[1], "fail")
+		require.NoError(t, errs[2])
+		mockPub.AssertExpectations(t)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		mockPub := &mocks.MockPublisher{}
+		mockPub.On("Publish", mock.Anything, first, subject).Return(nil).Once()
+		mockPub.On("Publish", mock.Anything, failing, subject).Run(func(mock.Arguments) { cancel() }).Return(context.Canceled).Once()
+
+		errs := PublishEachEvent(ctx, zap.NewNop(), mockPub, events, auditAdapter)
+		require.Len(t, errs, 3)
+		require.NoError(t, errs[0])
+		require.ErrorIs(t, errs[1], context.Canceled)
+		require.ErrorIs(t, errs[2], ErrNotAttempted)
+		mockPub.AssertExpectations(t)
+	})
+}
@@ -8,11 +8,12 @@ import (
 	"net/http"
 	"slices"
 
-	"github.com/decisiveai/mdai-data-core/audit"
 	"github.com/decisiveai/mdai-data-core/eventing"
 	"github.com/decisiveai/mdai-data-core/eventing/publisher"
 	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
 	"github.com/decisiveai/mdai-gateway/internal/nats"
+	"github.com/google/uuid"
 	"github.com/open-telemetry/opamp-go/protobufs"
 	"github.com/open-telemetry/opamp-go/server"
 	"github.com/open-telemetry/opamp-go/server/types"
@@ -29,11 +30,13 @@ const (
 	hubNameNonIdentifyingAttributeKey           = "hub_name"
 	instanceIDIdentifyingAttributeKey           = "service.instance.id"
 	replayStatusVariableNonIdentifyingAttribute = "replay_status_variable"
+
+	agentInstanceUIDAuditField = "agent_instance_uid"
 )
 
 type OpAMPControlServer struct {
 	logger         *zap.Logger
-	auditAdapter   *audit.AuditAdapter
+	auditAdapter   auditutils.Inserter
 	eventPublisher publisher.Publisher
 
 	connectedAgents *opAMPConnectedAgents
@@ -44,7 +47,7 @@ type OpAMPControlServer struct {
 	ConnContext server.ConnContext
 }
 
-func NewOpAMPControlServer(logger *zap.Logger, auditAdapter *audit.AuditAdapter, eventPublisher publisher.Publisher) (*OpAMPControlServer, error) {
+func NewOpAMPControlServer(logger *zap.Logger, auditAdapter auditutils.Inserter, eventPublisher publisher.Publisher) (*OpAMPControlServer, error) {
 	opampServer := server.New(nil)
 	ctrl := &OpAMPControlServer{
 		logger:          logger,
@@ -198,10 +201,19 @@ func (ctrl *OpAMPControlServer) publishCompletionEvent(ctx context.Context, agen
 	event.ApplyDefaults()
 	eventsPerSubject := []adapter.EventPerSubject{
 		{
-			Event:   event,
-			Subject: subject,
+			Event:       event,
+			Subject:     subject,
+			AuditFields: map[string]string{agentInstanceUIDAuditField: formatInstanceUID(agentID)},
 		},
 	}
 	_, publishErr := nats.PublishEvents(ctx, ctrl.logger, ctrl.eventPublisher, eventsPerSubject, ctrl.auditAdapter)
 	return publishErr
 }
+
+// formatInstanceUID renders a 16 byte agent instance UID as a UUID and passes other values through.
+func formatInstanceUID(uid string) string {
+	if id, err := uuid.FromBytes([]byte(uid)); err == nil {
+		return id.String()
+	}
+	return uid
+}
@@ -2,13 +2,16 @@ package opamp
 
 import (
 	"context"
+	"slices"
 	"testing"
 
 	"github.com/decisiveai/mdai-data-core/audit"
 	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/google/uuid"
 	"github.com/open-telemetry/opamp-go/protobufs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
 	valkeymock "github.com/valkey-io/valkey-go/mock"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.uber.org/mock/gomock"
@@ -336,3 +339,37 @@ func TestDigForCompletionAndExecuteHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPublishCompletionEventAuditsAgentInstanceUID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	valkeyClient := valkeymock.NewClient(ctrl)
+	var audited []string
+	valkeyClient.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "XADD" })).
+		DoAndReturn(func(_ context.Context, cmd valkey.Completed) valkey.ValkeyResult {
+			audited = cmd.Commands()
+			return valkeymock.Result(valkeymock.ValkeyString("1-0"))
+		})
+
+	opampServer, err := NewOpAMPControlServer(zap.NewNop(), audit.NewAuditAdapter(zap.NewNop(), valkeyClient), MockPublisher{received: &[]map[string]string{}})
+	require.NoError(t, err)
+
+	uid := uuid.MustParse("0198f1b2-7c3a-7d4e-8f00-112233445566")
+	agentID := string(uid[:])
+	opampServer.connectedAgents.setAgentDescription(agentID, opAMPAgentInfo{
+		instanceID:           "instance1",
+		replayID:             "replay1",
+		hubName:              "hub1",
+		replayStatusVariable: "replay-status",
+	})
+
+	require.NoError(t, opampServer.publishCompletionEvent(t.Context(), agentID, ingestStatusCompleted))
+	idx := slices.Index(audited, agentInstanceUIDAuditField)
+	require.NotEqual(t, -1, idx)
+	assert.Equal(t, uid.String(), audited[idx+1])
+}
+
+func TestFormatInstanceUID(t *testing.T) {
+	uid := uuid.MustParse("0198f1b2-7c3a-7d4e-8f00-112233445566")
+	assert.Equal(t, uid.String(), formatInstanceUID(string(uid[:])))
+	assert.Equal(t, "agent1", formatInstanceUID("agent1"))
+}
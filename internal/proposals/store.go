@@ -0,0 +1,182 @@
+package proposals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/google/uuid"
+	valkeygo "github.com/valkey-io/valkey-go"
+)
+
+const (
+	keyPrefix  = "proposal/"
+	pendingKey = "proposals/pending"
+
+	// expiredRetention keeps an expired proposal readable long enough for the sweeper to audit it.
+	expiredRetention = time.Hour
+
+	DefaultTTL = 24 * time.Hour
+)
+
+var ErrNotFound = errors.New("proposal not found")
+
+// Proposal is a pending change to a protected manual variable. It is published only once a
+// different identity approves it.
+type Proposal struct {
+	ID         string                         `json:"id"`
+	HubName    string                         `json:"hubName"`
+	VarName    string                         `json:"variable"`
+	VarType    valkey.VariableType            `json:"type"`
+	Command    valkey.CommandType             `json:"command"`
+	Data       any                            `json:"data"`
+	Metadata   manualvariables.ChangeMetadata `json:"metadata"`
+	ProposedBy string                         `json:"proposedBy"`
+	CreatedAt  time.Time                      `json:"createdAt"`
+	ExpiresAt  time.Time                      `json:"expiresAt"`
+}
+
+// Store keeps proposals in Valkey. Each proposal is a JSON document under its own key, and a
+// sorted set scored by expiry indexes the pending ones. Removing a proposal from the index is the
+// single point that decides its outcome, so concurrent approvals cannot both win.
+type Store struct {
+	client valkeygo.Client
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewStore(client valkeygo.Client, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{client: client, ttl: ttl, now: time.Now}
+}
+
+// Create assigns the proposal its ID and timestamps and stores it as pending.
+func (s *Store) Create(ctx context.Context, p *Proposal) error {
+	p.ID = uuid.NewString()
+	p.CreatedAt = s.now().UTC()
+	p.ExpiresAt = p.CreatedAt.Add(s.ttl)
+	return s.save(ctx, *p)
+}
+
+// Restore stores a claimed proposal as pending again with its original expiry, e.g. when its
+// approval could not be published, so that it can be decided again.
+func (s *Store) Restore(ctx context.Context, p Proposal) error {
+	return s.save(ctx, p)
+}
+
+func (s *Store) save(ctx context.Context, p Proposal) error {
+	doc, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	keep := max(p.ExpiresAt.Sub(s.now()), 0) + expiredRetention
+	cmds := valkeygo.Commands{
+		s.client.B().Set().Key(keyPrefix + p.ID).Value(string(doc)).Px(keep).Build(),
+		s.client.B().Zadd().Key(pendingKey).ScoreMember().ScoreMember(float64(p.ExpiresAt.UnixMilli()), p.ID).Build(),
+	}
+	for _, resp := range s.client.DoMulti(ctx, cmds...) {
+		if err := resp.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns a pending proposal.
+func (s *Store) Get(ctx context.Context, id string) (Proposal, error) {
+	score, err := s.client.Do(ctx, s.client.B().Zscore().Key(pendingKey).Member(id).Build()).AsFloat64()
+	if valkeygo.IsValkeyNil(err) || (err == nil && int64(score) <= s.now().UnixMilli()) {
+		return Proposal{}, ErrNotFound
+	}
+	if err != nil {
+		return Proposal{}, err
+	}
+	return s.load(ctx, s.client.B().Get().Key(keyPrefix+id).Build())
+}
+
+// List returns the pending proposals, soonest to expire first.
+func (s *Store) List(ctx context.Context) ([]Proposal, error) {
+	ids, err := s.client.Do(ctx, s.client.B().Zrangebyscore().Key(pendingKey).
+		Min("("+strconv.FormatInt(s.now().UnixMilli(), 10)).Max("+inf").Build()).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	proposals := make([]Proposal, 0, len(ids))
+	for _, id := range ids {
+		p, err := s.load(ctx, s.client.B().Get().Key(keyPrefix+id).Build())
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		proposals = append(proposals, p)
+	}
+	return proposals, nil
+}
+
+// Claim removes a pending proposal and returns it. Only one caller can claim a proposal; the
+// others get ErrNotFound.
+func (s *Store) Claim(ctx context.Context, id string) (Proposal, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return Proposal{}, err
+	}
+	return s.claim(ctx, id)
+}
+
+// ClaimExpired removes and returns every proposal whose expiry has passed.
+func (s *Store) ClaimExpired(ctx context.Context) ([]Proposal, error) {
+	ids, err := s.client.Do(ctx, s.client.B().Zrangebyscore().Key(pendingKey).
+		Min("-inf").Max(strconv.FormatInt(s.now().UnixMilli(), 10)).Build()).AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []Proposal
+	for _, id := range ids {
+		p, err := s.claim(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired = append(expired, p)
+	}
+	return expired, nil
+}
+
+func (s *Store) claim(ctx context.Context, id string) (Proposal, error) {
+	removed, err := s.client.Do(ctx, s.client.B().Zrem().Key(pendingKey).Member(id).Build()).AsInt64()
+	if err != nil {
+		return Proposal{}, err
+	}
+	if removed == 0 {
+		return Proposal{}, ErrNotFound
+	}
+	return s.load(ctx, s.client.B().Getdel().Key(keyPrefix+id).Build())
+}
+
+func (s *Store) load(ctx context.Context, cmd valkeygo.Completed) (Proposal, error) {
+	doc, err := s.client.Do(ctx, cmd).ToString()
+	if valkeygo.IsValkeyNil(err) {
+		return Proposal{}, ErrNotFound
+	}
+	if err != nil {
+		return Proposal{}, err
+	}
+
+	var p Proposal
+	if err := json.Unmarshal([]byte(doc), &p); err != nil {
+		return Proposal{}, err
+	}
+	return p, nil
+}
@@ -0,0 +1,173 @@
+package proposals
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	valkeygo "github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+var testNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func newTestStore(t *testing.T) (*Store, *valkeymock.Client) {
+	t.Helper()
+
+	client := valkeymock.NewClient(gomock.NewController(t))
+	store := NewStore(client, time.Hour)
+	store.now = func() time.Time { return testNow }
+	return store, client
+}
+
+func proposalDocument(t *testing.T, p Proposal) valkeygo.ValkeyResult {
+	t.Helper()
+
+	doc, err := json.Marshal(p)
+	require.NoError(t, err)
+	return valkeymock.Result(valkeymock.ValkeyBlobString(string(doc)))
+}
+
+func TestStore_Create(t *testing.T) {
+	store, client := newTestStore(t)
+
+	var stored Proposal
+	client.EXPECT().DoMulti(gomock.Any(),
+		valkeymock.MatchFn(func(cmd []string) bool {
+			if cmd[0] != "SET" || cmd[3] != "PX" || cmd[4] != strconv.FormatInt((time.Hour+expiredRetention).Milliseconds(), 10) {
+				return false
+			}
+			return json.Unmarshal([]byte(cmd[2]), &stored) == nil && cmd[1] == keyPrefix+stored.ID
+		}, "SET proposal"),
+		valkeymock.MatchFn(func(cmd []string) bool {
+			return cmd[0] == "ZADD" && cmd[1] == pendingKey &&
+				cmd[2] == strconv.FormatInt(testNow.Add(time.Hour).UnixMilli(), 10)
+		}, "ZADD pending"),
+	).Return([]valkeygo.ValkeyResult{
+		valkeymock.Result(valkeymock.ValkeyString("OK")),
+		valkeymock.Result(valkeymock.ValkeyInt64(1)),
+	})
+
+	p := Proposal{
+		HubName:    "prod",
+		VarName:    "drop_all",
+		VarType:    valkey.VariableTypeBool,
+		Command:    valkey.CommandAdd,
+		Data:       "true",
+		Metadata:   manualvariables.ChangeMetadata{Reason: "incident"},
+		ProposedBy: "alice",
+	}
+	require.NoError(t, store.Create(t.Context(), &p))
+
+	assert.NotEmpty(t, p.ID)
+	assert.Equal(t, testNow, p.CreatedAt)
+	assert.Equal(t, testNow.Add(time.Hour), p.ExpiresAt)
+	assert.Equal(t, p, stored)
+}
+
+func TestStore_Get(t *testing.T) {
+	pending := Proposal{ID: "p1", HubName: "prod", VarName: "drop_all", ProposedBy: "alice"}
+
+	tests := []struct {
+		name    string
+		score   valkeygo.ValkeyMessage
+		wantErr error
+	}{
+		{name: "pending", score: valkeymock.ValkeyBlobString(strconv.FormatInt(testNow.Add(time.Minute).UnixMilli(), 10))},
+		{name: "expired", score: valkeymock.ValkeyBlobString(strconv.FormatInt(testNow.UnixMilli(), 10)), wantErr: ErrNotFound},
+		{name: "unknown", score: valkeymock.ValkeyNil(), wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, client := newTestStore(t)
+			client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZSCORE", pendingKey, "p1")).Return(valkeymock.Result(tt.score))
+			if tt.wantErr == nil {
+				client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "proposal/p1")).Return(proposalDocument(t, pending))
+			}
+
+			got, err := store.Get(t.Context(), "p1")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, pending, got)
+		})
+	}
+}
+
+func TestStore_Claim(t *testing.T) {
+	pending := Proposal{ID: "p1", ProposedBy: "alice"}
+	score := valkeymock.Result(valkeymock.ValkeyBlobString(strconv.FormatInt(testNow.Add(time.Minute).UnixMilli(), 10)))
+
+	t.Run("first claim wins", func(t *testing.T) {
+		store, client := newTestStore(t)
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZSCORE", pendingKey, "p1")).Return(score)
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "proposal/p1")).Return(proposalDocument(t, pending))
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZREM", pendingKey, "p1")).Return(valkeymock.Result(valkeymock.ValkeyInt64(1)))
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("GETDEL", "proposal/p1")).Return(proposalDocument(t, pending))
+
+		got, err := store.Claim(t.Context(), "p1")
+		require.NoError(t, err)
+		assert.Equal(t, pending, got)
+	})
+
+	t.Run("concurrent claim loses", func(t *testing.T) {
+		store, client := newTestStore(t)
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZSCORE", pendingKey, "p1")).Return(score)
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "proposal/p1")).Return(proposalDocument(t, pending))
+		client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZREM", pendingKey, "p1")).Return(valkeymock.Result(valkeymock.ValkeyInt64(0)))
+
+		_, err := store.Claim(t.Context(), "p1")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestStore_Restore(t *testing.T) {
+	store, client := newTestStore(t)
+	claimed := Proposal{ID: "p1", ProposedBy: "alice", CreatedAt: testNow.Add(-30 * time.Minute), ExpiresAt: testNow.Add(30 * time.Minute)}
+	doc, err := json.Marshal(claimed)
+	require.NoError(t, err)
+
+	// The proposal keeps its expiry.
+	client.EXPECT().DoMulti(gomock.Any(),
+		valkeymock.Match("SET", "proposal/p1", string(doc), "PX", strconv.FormatInt((30*time.Minute+expiredRetention).Milliseconds(), 10)),
+		valkeymock.Match("ZADD", pendingKey, strconv.FormatInt(claimed.ExpiresAt.UnixMilli(), 10), "p1"),
+	).Return([]valkeygo.ValkeyResult{
+		valkeymock.Result(valkeymock.ValkeyString("OK")),
+		valkeymock.Result(valkeymock.ValkeyInt64(1)),
+	})
+
+	require.NoError(t, store.Restore(t.Context(), claimed))
+}
+
+func TestStore_ListAndClaimExpired(t *testing.T) {
+	store, client := newTestStore(t)
+	now := strconv.FormatInt(testNow.UnixMilli(), 10)
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZRANGEBYSCORE", pendingKey, "("+now, "+inf")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyBlobString("p1"), valkeymock.ValkeyBlobString("gone"))))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "proposal/p1")).Return(proposalDocument(t, Proposal{ID: "p1"}))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "proposal/gone")).Return(valkeymock.Result(valkeymock.ValkeyNil()))
+
+	pending, err := store.List(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []Proposal{{ID: "p1"}}, pending)
+
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZRANGEBYSCORE", pendingKey, "-inf", now)).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyBlobString("p2"), valkeymock.ValkeyBlobString("p3"))))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZREM", pendingKey, "p2")).Return(valkeymock.Result(valkeymock.ValkeyInt64(1)))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("GETDEL", "proposal/p2")).Return(proposalDocument(t, Proposal{ID: "p2"}))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("ZREM", pendingKey, "p3")).Return(valkeymock.Result(valkeymock.ValkeyInt64(0)))
+
+	expired, err := store.ClaimExpired(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []Proposal{{ID: "p2"}}, expired)
+}
@@ -0,0 +1,220 @@
+// Package redact hashes, masks or drops sensitive alert labels, alert annotations and variable
+// values before they are written to the audit stream, to logs or into published events.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Action is what happens to a matching value.
+type Action string
+
+const (
+	// Hash replaces the value with a truncated SHA-256 hash, so equal values stay correlatable.
+	// Hashes of guessable values can be reversed by trying candidates; mask or drop those.
+	Hash Action = "hash"
+	// Mask replaces the value with Masked.
+	Mask Action = "mask"
+	// Drop removes the value.
+	Drop Action = "drop"
+)
+
+// Kind is the kind of key a rule matches.
+type Kind string
+
+const (
+	Label      Kind = "label"
+	Annotation Kind = "annotation"
+	Variable   Kind = "variable"
+)
+
+const (
+	// Masked replaces masked values.
+	Masked = "***"
+
+	hashPrefix = "sha256:"
+	hashLength = 16
+)
+
+// Rule redacts the values of the keys of a kind matching a glob pattern, e.g. label/customer_*.
+type Rule struct {
+	Kind    Kind
+	Pattern string
+	Action  Action
+}
+
+// Policy is an ordered list of rules; the first rule matching a key decides. The zero value
+// redacts nothing.
+type Policy struct {
+	Rules []Rule
+
+	maskAll bool
+}
+
+// MaskAll masks every value. It stands in for a policy that cannot be read.
+var MaskAll = Policy{maskAll: true}
+
+// Parse reads a comma separated list of rules of the form kind/pattern=action, e.g.
+// "label/customer_id=hash, annotation/runbook_*=drop, variable/tenants=mask". Patterns are
+// path.Match globs. Invalid rules are reported, the valid ones are kept.
+func Parse(s string) (Policy, error) {
+	var (
+		policy Policy
+		errs   []error
+	)
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		rule, err := parseRule(item)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy, errors.Join(errs...)
+}
+
+func parseRule(s string) (Rule, error) {
+	target, action, ok := strings.Cut(s, "=")
+	if !ok {
+		return Rule{}, fmt.Errorf("redaction rule %q: expected kind/pattern=action", s)
+	}
+	kind, pattern, ok := strings.Cut(strings.TrimSpace(target), "/")
+	if !ok || pattern == "" {
+		return Rule{}, fmt.Errorf("redaction rule %q: expected kind/pattern=action", s)
+	}
+
+	rule := Rule{Kind: Kind(kind), Pattern: pattern, Action: Action(strings.TrimSpace(action))}
+	switch rule.Kind {
+	case Label, Annotation, Variable:
+	default:
+		return Rule{}, fmt.Errorf("redaction rule %q: unknown kind %q", s, kind)
+	}
+	switch rule.Action {
+	case Hash, Mask, Drop:
+	default:
+		return Rule{}, fmt.Errorf("redaction rule %q: unknown action %q", s, rule.Action)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return Rule{}, fmt.Errorf("redaction rule %q: %w", s, err)
+	}
+	return rule, nil
+}
+
+// Append returns a policy with the rules of other after those of p. Appending to or appending
+// MaskAll gives MaskAll.
+func (p Policy) Append(other Policy) Policy {
+	if p.maskAll || other.maskAll {
+		return MaskAll
+	}
+	return Policy{Rules: slices.Concat(p.Rules, other.Rules)}
+}
+
+func (p Policy) IsZero() bool {
+	return !p.maskAll && len(p.Rules) == 0
+}
+
+// ActionFor returns the action of the first rule matching key, if any.
+func (p Policy) ActionFor(kind Kind, key string) (Action, bool) {
+	if p.maskAll {
+		return Mask, true
+	}
+	for _, rule := range p.Rules {
+		if rule.Kind != kind {
+			continue
+		}
+		if matched, _ := path.Match(rule.Pattern, key); matched {
+			return rule.Action, true
+		}
+	}
+	return "", false
+}
+
+// Map returns a copy of m with the values of matching keys redacted. It returns m itself when no
+// key matches.
+func (p Policy) Map(kind Kind, m map[string]string) map[string]string {
+	var redacted map[string]string
+	for key, value := range m {
+		action, ok := p.ActionFor(kind, key)
+		if !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = maps.Clone(m)
+		}
+		if action == Drop {
+			delete(redacted, key)
+			continue
+		}
+		redacted[key] = apply(action, value)
+	}
+	if redacted == nil {
+		return m
+	}
+	return redacted
+}
+
+// Variable redacts the data of a variable change. Scalars are replaced, and so are the elements
+// of lists and the values of maps; map keys are kept. It returns false when the data is dropped.
+func (p Policy) Variable(name string, data any) (any, bool) {
+	action, ok := p.ActionFor(Variable, name)
+	if !ok {
+		return data, true
+	}
+	if action == Drop {
+		return nil, false
+	}
+	return applyAny(action, data), true
+}
+
+func applyAny(action Action, data any) any {
+	switch v := data.(type) {
+	case nil:
+		return nil
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = applyAny(action, item)
+		}
+		return redacted
+	case []string:
+		redacted := make([]string, len(v))
+		for i, item := range v {
+			redacted[i] = apply(action, item)
+		}
+		return redacted
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, item := range v {
+			redacted[key] = applyAny(action, item)
+		}
+		return redacted
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for key, item := range v {
+			redacted[key] = apply(action, item)
+		}
+		return redacted
+	case string:
+		return apply(action, v)
+	default:
+		return apply(action, fmt.Sprint(v))
+	}
+}
+
+func apply(action Action, value string) string {
+	if action == Hash {
+		sum := sha256.Sum256([]byte(value))
+		return hashPrefix + hex.EncodeToString(sum[:])[:hashLength]
+	}
+	return Masked
+}
@@ -0,0 +1,90 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	policy, err := Parse(" label/customer_*=hash, annotation/runbook=drop,,variable/tenants = mask ")
+	require.NoError(t, err)
+	assert.Equal(t, Policy{Rules: []Rule{
+		{Kind: Label, Pattern: "customer_*", Action: Hash},
+		{Kind: Annotation, Pattern: "runbook", Action: Drop},
+		{Kind: Variable, Pattern: "tenants", Action: Mask},
+	}}, policy)
+
+	policy, err = Parse("label/a=hash, field/b=mask, label/c=erase, label=drop, label/[=mask")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `redaction rule "field/b=mask": unknown kind "field"`)
+	assert.Contains(t, err.Error(), `redaction rule "label/c=erase": unknown action "erase"`)
+	assert.Contains(t, err.Error(), `redaction rule "label=drop": expected kind/pattern=action`)
+	assert.Contains(t, err.Error(), `redaction rule "label/[=mask": syntax error in pattern`)
+	assert.Equal(t, []Rule{{Kind: Label, Pattern: "a", Action: Hash}}, policy.Rules, "valid rules are kept")
+
+	policy, err = Parse("")
+	require.NoError(t, err)
+	assert.True(t, policy.IsZero())
+}
+
+func TestPolicy_Map(t *testing.T) {
+	policy, err := Parse("label/customer_*=hash, label/email=mask, label/tenant=drop, annotation/customer_id=drop")
+	require.NoError(t, err)
+
+	labels := map[string]string{"alertname": "HighRate", "customer_id": "c-42", "email": "a@example.com", "tenant": "acme"}
+	redacted := policy.Map(Label, labels)
+	assert.Equal(t, map[string]string{
+		"alertname":   "HighRate",
+		"customer_id": "sha256:8fb7893be928286e",
+		"email":       Masked,
+	}, redacted)
+	assert.Equal(t, "c-42", labels["customer_id"], "the input is not modified")
+
+	annotations := map[string]string{"summary": "rate too high"}
+	assert.Equal(t, annotations, policy.Map(Annotation, annotations))
+	assert.Equal(t, map[string]string{"summary": "x"}, policy.Map(Annotation, map[string]string{"summary": "x", "customer_id": "c-42"}))
+}
+
+func TestPolicy_Variable(t *testing.T) {
+	policy, err := Parse("variable/tenant_*=hash, variable/secret=drop")
+	require.NoError(t, err)
+
+	data, keep := policy.Variable("tenant_map", map[string]any{"eu": "c-42", "us": []any{"c-42", 7.0}})
+	assert.True(t, keep)
+	assert.Equal(t, map[string]any{"eu": "sha256:8fb7893be928286e", "us": []any{"sha256:8fb7893be928286e", "sha256:7902699be42c8a8e"}}, data)
+
+	data, keep = policy.Variable("tenant_set", []string{"c-42"})
+	assert.True(t, keep)
+	assert.Equal(t, []string{"sha256:8fb7893be928286e"}, data)
+
+	data, keep = policy.Variable("secret", "hunter2")
+	assert.False(t, keep)
+	assert.Nil(t, data)
+
+	data, keep = policy.Variable("service_list", "checkout")
+	assert.True(t, keep)
+	assert.Equal(t, "checkout", data)
+}
+
+func TestMaskAll(t *testing.T) {
+	assert.False(t, MaskAll.IsZero())
+	assert.Equal(t, map[string]string{"alertname": Masked, "mydecisive.ai/hub": Masked},
+		MaskAll.Map(Annotation, map[string]string{"alertname": "HighRate", "mydecisive.ai/hub": "sample"}))
+
+	data, keep := MaskAll.Variable("service_list", []string{"checkout"})
+	assert.True(t, keep)
+	assert.Equal(t, []string{Masked}, data)
+}
+
+func TestPolicy_Append(t *testing.T) {
+	first, err := Parse("label/a=hash")
+	require.NoError(t, err)
+	second, err := Parse("label/b=drop")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Rule{{Kind: Label, Pattern: "a", Action: Hash}, {Kind: Label, Pattern: "b", Action: Drop}}, first.Append(second).Rules)
+	assert.Equal(t, MaskAll, first.Append(MaskAll))
+	assert.Equal(t, MaskAll, MaskAll.Append(second))
+}
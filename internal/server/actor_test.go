@@ -0,0 +1,56 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+)
+
+func TestHandleSetVariables_AuditsActor(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	records := auditRecords(deps.ValkeyClient.(*valkeymock.Client), 1) //nolint:forcetypeassert
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/mdaihub-sample/var/data_string", bytes.NewBufferString(`{"data":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.UserHeader, "alice")
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	require.Len(t, *records, 1)
+	record := (*records)[0]
+	assert.Equal(t, "alice", auditField(record, identity.ActorUserAuditField))
+	assert.NotContains(t, record, identity.ActorRemoteAddrAuditField)
+	assert.NotContains(t, record, identity.ActorForwardedForAuditField)
+}
+
+func TestAlerts_AuditsAnonymousActor(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	records := auditRecords(deps.ValkeyClient.(*valkeymock.Client), 3) //nolint:forcetypeassert
+
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBuffer(readPayloadFromFile(t, alert1)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Alertmanager/0.28.1")
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	req.RemoteAddr = "10.0.0.7:52114"
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	require.Len(t, *records, 3)
+	for _, record := range *records {
+		assert.Equal(t, "10.0.0.7:52114", auditField(record, identity.ActorRemoteAddrAuditField))
+		assert.Equal(t, "Alertmanager/0.28.1", auditField(record, identity.ActorUserAgentAuditField))
+		assert.Equal(t, "203.0.113.9", auditField(record, identity.ActorForwardedForAuditField))
+		assert.NotContains(t, record, identity.ActorUserAuditField)
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/alertpush"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/prometheus/alertmanager/template"
+	"go.uber.org/zap"
+)
+
+var (
+	pushedAlerts  = alertSender{alerts: "pushed alerts", payload: "alerts"}
+	expiredAlerts = alertSender{alerts: "expired pushed alerts", payload: "expired alerts"}
+)
+
+// handleAlertsV2Post receives the alerts Prometheus pushes to its Alertmanagers, so the gateway can
+// be listed as one. The alerts are tracked until they resolve, grouped by hub and processed like
+// the alerts of an Alertmanager notification. As in Alertmanager, invalid alerts are dropped and
+// reported with 400 while the others are processed.
+func handleAlertsV2Post(deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const maxBody = 10 << 20 // 10 MiB
+		r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+		defer r.Body.Close() //nolint:errcheck
+
+		var postable []alertpush.PostableAlert
+		if err := json.NewDecoder(r.Body).Decode(&postable); err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				http.Error(w, "request body too large (max 10MiB)", http.StatusRequestEntityTooLarge)
+				return
+			}
+			deps.Logger.Error("Failed to decode pushed alerts", zap.Error(err))
+			http.Error(w, "invalid alerts payload", http.StatusBadRequest)
+			return
+		}
+
+		valid := make([]alertpush.PostableAlert, 0, len(postable))
+		var rejected []adapter.AlertResult
+		var validationErrs []string
+		for _, alert := range postable {
+			if err := alert.Validate(); err != nil {
+				rejected = append(rejected, adapter.AlertResult{
+					AlertName: alert.Labels["alertname"],
+					Status:    adapter.AlertInvalid,
+					Reason:    err.Error(),
+				})
+				validationErrs = append(validationErrs, err.Error())
+				continue
+			}
+			valid = append(valid, alert)
+		}
+		deps.Logger.Debug("Processing pushed alerts", zap.Int("alertCount", len(postable)), zap.Int("invalid", len(rejected)))
+
+		response := httputil.PrometheusAlertResponse{
+			Message: "Processed " + pushedAlerts.alerts,
+			Total:   len(postable),
+			Invalid: len(rejected),
+		}
+		changed, unchanged, err := deps.AlertPush.Put(r.Context(), valid)
+		if err != nil {
+			deps.Logger.Error("Failed to track pushed alerts", zap.Error(err))
+			http.Error(w, "Failed to track "+pushedAlerts.alerts, http.StatusInternalServerError)
+			return
+		}
+		response.Unchanged = unchanged
+		for _, group := range alertpush.Group(changed) {
+			wrapped := adapter.NewPromAlertWrapper(group, deps.Logger, deps.Deduper)
+			groupResponse, _, err := processAlerts(r.Context(), deps, pushedAlerts, wrapped, identity.ActorFromRequest(r))
+			if err != nil {
+				forgetPushedAlerts(r.Context(), deps, group.Alerts)
+				http.Error(w, "Failed to adapt "+pushedAlerts.alerts+" to MDAI Events", http.StatusInternalServerError)
+				return
+			}
+			forgetPushedAlerts(r.Context(), deps, unprocessedAlerts(group.Alerts, groupResponse.Results))
+			addAlertResponse(&response, groupResponse)
+		}
+		response.Results = append(response.Results, rejected...)
+
+		status := http.StatusOK
+		switch {
+		case response.Failed > 0:
+			response.Message = "Some " + pushedAlerts.alerts + " could not be published"
+			status = http.StatusServiceUnavailable
+		case len(validationErrs) > 0:
+			response.Message = "invalid " + pushedAlerts.payload + ": " + strings.Join(validationErrs, "; ")
+			status = http.StatusBadRequest
+		case response.Total > 0 && response.Invalid == response.Total:
+			response.Message = "invalid " + pushedAlerts.payload + ": " + strings.Join(invalidAlertReasons(response.Results), "; ")
+			status = http.StatusBadRequest
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, status, response)
+	}
+}
+
+// forgetPushedAlerts stops tracking alerts that were not processed. An alert that stays tracked is
+// only processed again once it changes.
+func forgetPushedAlerts(ctx context.Context, deps HandlerDeps, alerts template.Alerts) {
+	if len(alerts) == 0 {
+		return
+	}
+	if err := deps.AlertPush.Forget(ctx, alerts); err != nil {
+		deps.Logger.Error("Failed to forget unprocessed pushed alerts", zap.Int("alertCount", len(alerts)), zap.Error(err))
+	}
+}
+
+// unprocessedAlerts returns the alerts that failed or were invalid, so that they are processed
+// again when they are pushed again. Results are in the order of the alerts.
+func unprocessedAlerts(alerts template.Alerts, results []adapter.AlertResult) template.Alerts {
+	var unprocessed template.Alerts
+	for i, result := range results {
+		if result.Status == adapter.AlertFailed || result.Status == adapter.AlertInvalid {
+			unprocessed = append(unprocessed, alerts[i])
+		}
+	}
+	return unprocessed
+}
+
+// addAlertResponse adds the counts and results of the notification of a group to response.
+func addAlertResponse(response *httputil.PrometheusAlertResponse, group httputil.PrometheusAlertResponse) {
+	response.Successful += group.Successful
+	response.Skipped += group.Skipped
+	response.Held += group.Held
+	response.Invalid += group.Invalid
+	response.Failed += group.Failed
+	response.Results = append(response.Results, group.Results...)
+}
+
+// invalidAlertReasons returns the distinct reasons of the invalid results.
+func invalidAlertReasons(results []adapter.AlertResult) []string {
+	var reasons []string
+	for _, result := range results {
+		if result.Status == adapter.AlertInvalid && !slices.Contains(reasons, result.Reason) {
+			reasons = append(reasons, result.Reason)
+		}
+	}
+	return reasons
+}
+
+// RunAlertPushSweeper resolves the pushed alerts that were not pushed again before their end
+// time, every interval until ctx is done. Every replica runs it on the alerts they share; each
+// expired alert is resolved by the replica that claims it first.
+func RunAlertPushSweeper(ctx context.Context, deps HandlerDeps, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resolveExpiredAlerts(ctx, deps)
+		}
+	}
+}
+
+func resolveExpiredAlerts(ctx context.Context, deps HandlerDeps) {
+	expired, err := deps.AlertPush.Expired(ctx)
+	if err != nil {
+		deps.Logger.Error("Failed to claim expired pushed alerts", zap.Error(err))
+		return
+	}
+	if len(expired) == 0 {
+		return
+	}
+	deps.Logger.Info("Resolving expired pushed alerts", zap.Int("alertCount", len(expired)))
+
+	for _, group := range alertpush.Group(expired) {
+		wrapped := adapter.NewPromAlertWrapper(group, deps.Logger, deps.Deduper)
+		response, _, err := processAlerts(ctx, deps, expiredAlerts, wrapped, identity.Actor{})
+		if err != nil {
+			restoreExpiredAlerts(ctx, deps, group.Alerts)
+			continue
+		}
+
+		// Retry the resolutions that could not be published at the next sweep.
+		var failed template.Alerts
+		for i, result := range response.Results {
+			if result.Status == adapter.AlertFailed {
+				failed = append(failed, group.Alerts[i])
+			}
+		}
+		restoreExpiredAlerts(ctx, deps, failed)
+	}
+}
+
+// restoreExpiredAlerts tracks again the expired alerts whose resolution failed, so that a sweep of
+// any replica retries it.
+func restoreExpiredAlerts(ctx context.Context, deps HandlerDeps, alerts template.Alerts) {
+	if len(alerts) == 0 {
+		return
+	}
+	if err := deps.AlertPush.Restore(ctx, alerts); err != nil {
+		deps.Logger.Error("Failed to restore expired pushed alerts", zap.Int("alertCount", len(alerts)), zap.Error(err))
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAlertsV2(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	var records [][]string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+		records = append(records, cmd.Commands())
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).AnyTimes()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/alerts", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	firing := `[{"labels":{"alertname":"top_talkers","service":"checkout"},"annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample"},
+		"startsAt":"2025-08-08T15:56:10Z","endsAt":"2099-01-01T00:00:00Z","generatorURL":"http://prometheus:9090/graph"}]`
+	rr := post(firing)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"message":"Processed pushed alerts","total":1,"successful":1,"skipped":0,"results":[
+		{"fingerprint":"5feb0ce2139b4971","alertName":"top_talkers","hubName":"mdaihub-sample","status":"published","changeTime":"2025-08-08T15:56:10Z"}]}`, rr.Body.String())
+	require.Len(t, records, 1)
+	assert.Equal(t, "top_talkers.firing", auditField(records[0], "name"))
+	assert.Equal(t, "5feb0ce2139b4971", auditField(records[0], "sourceId"))
+	assert.Equal(t, 1, pushedAlertCount(t, deps))
+
+	// Prometheus pushes the firing alert again at every evaluation, which changes nothing.
+	for range 2 {
+		rr = post(firing)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.JSONEq(t, `{"message":"Processed pushed alerts","total":1,"successful":0,"skipped":0,"unchanged":1}`, rr.Body.String())
+	}
+	require.Len(t, records, 1)
+
+	rr = post(`[{"labels":{"alertname":"top_talkers","service":"checkout"},"annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample"},
+		"startsAt":"2025-08-08T15:56:10Z","endsAt":"2025-08-08T16:10:00Z"}]`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Len(t, records, 2)
+	assert.Equal(t, "top_talkers.resolved", auditField(records[1], "name"))
+	assert.Equal(t, 0, pushedAlertCount(t, deps))
+}
+
+func TestAlertsV2_RetriesFailed(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mockPub := &mocks.MockPublisher{}
+	mockPub.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("nats unavailable")).Once()
+	mockPub.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	deps.EventPublisher = mockPub
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString(""))).Times(2)
+
+	firing := `[{"labels":{"alertname":"top_talkers","service":"checkout"},"annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample"},
+		"startsAt":"2025-08-08T15:56:10Z","endsAt":"2099-01-01T00:00:00Z"}]`
+	for _, want := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/alerts", bytes.NewBufferString(firing))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		require.Equal(t, want, rr.Code, rr.Body.String())
+	}
+	// The alert that could not be published is processed again at its next push.
+	mockPub.AssertExpectations(t)
+	assert.Equal(t, 1, pushedAlertCount(t, deps))
+}
+
+func TestAlertsV2_Invalid(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString(""))).AnyTimes()
+
+	body := `[{"labels":{}},
+		{"labels":{"alertname":"top_talkers"},"annotations":{"hub_name":"mdaihub-sample"},"startsAt":"2025-08-08T15:56:10Z","endsAt":"2025-08-08T15:00:00Z"},
+		{"labels":{"alertname":"top_talkers","service":"checkout"},"annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample"},"startsAt":"2025-08-08T15:56:10Z"}]`
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/alerts", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	// As in Alertmanager, the valid alerts are processed and the invalid ones reported with 400.
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"message":"invalid alerts: at least one label pair required; start time must be before end time","total":3,"successful":1,"skipped":0,"invalid":2,"results":[
+		{"fingerprint":"5feb0ce2139b4971","alertName":"top_talkers","hubName":"mdaihub-sample","status":"published","changeTime":"2025-08-08T15:56:10Z"},
+		{"status":"invalid","reason":"at least one label pair required"},
+		{"alertName":"top_talkers","status":"invalid","reason":"start time must be before end time"}]}`, rr.Body.String())
+}
+
+func TestAlertsV2_ResolveExpired(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	deps.AlertPush = newAlertPushTracker(t, 50*time.Millisecond)
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	var records [][]string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+		records = append(records, cmd.Commands())
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).Times(2)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/alerts", bytes.NewBufferString(
+		`[{"labels":{"alertname":"top_talkers"},"annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample"},"startsAt":"2025-08-08T15:56:10Z"}]`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	resolveExpiredAlerts(t.Context(), deps)
+	require.Len(t, records, 1)
+
+	// The alert was not pushed again within the resolve timeout.
+	time.Sleep(100 * time.Millisecond)
+	resolveExpiredAlerts(t.Context(), deps)
+	require.Len(t, records, 2)
+	assert.Equal(t, "top_talkers.resolved", auditField(records[1], "name"))
+	assert.Equal(t, 0, pushedAlertCount(t, deps))
+}
+
+// pushedAlertCount returns how many pushed alerts are tracked as firing.
+func pushedAlertCount(t *testing.T, deps HandlerDeps) int {
+	t.Helper()
+	n, err := deps.AlertPush.Len(t.Context())
+	require.NoError(t, err)
+	return n
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/alertstate"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/redact"
+	"go.uber.org/zap"
+)
+
+type alertHistoryResponse struct {
+	Fingerprint string                  `json:"fingerprint"`
+	State       *alertstate.State       `json:"state,omitempty"`
+	Transitions []alertstate.Transition `json:"transitions"`
+}
+
+// recordAlertStates records the state of the alerts whose events were published or held, with
+// the labels and annotations redacted like in their audit records. Failing to record a state does
+// not fail the alert, whose event is already out.
+func recordAlertStates(ctx context.Context, deps HandlerDeps, wrapped *adapter.PromAlertWrapper, results []adapter.AlertResult) {
+	policies := make(map[string]redact.Policy)
+	for i, result := range results {
+		if result.Status != adapter.AlertPublished && result.Status != adapter.AlertHeld {
+			continue
+		}
+
+		state := wrapped.AlertState(i)
+		policy, ok := policies[state.HubName]
+		if !ok {
+			policy = hubAuditRedaction(deps, state.HubName)
+			policies[state.HubName] = policy
+		}
+		state.Labels = policy.Map(redact.Label, state.Labels)
+		state.Annotations = policy.Map(redact.Annotation, state.Annotations)
+
+		if _, err := deps.AlertStates.Record(ctx, state, result.EventID); err != nil {
+			deps.Logger.Error("Failed to record alert state", zap.String("fingerprint", result.Fingerprint), zap.Error(err))
+		}
+	}
+}
+
+// handleActiveAlerts returns the firing alerts, optionally only those of a hub and with an alert
+// name.
+func handleActiveAlerts(deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter := alertstate.Filter{
+			HubName:   r.URL.Query().Get("hub"),
+			AlertName: r.URL.Query().Get("alertname"),
+		}
+		active, err := deps.AlertStates.Active(r.Context(), filter)
+		if err != nil {
+			deps.Logger.Error("Failed to read active alerts", zap.Error(err))
+			http.Error(w, "Unable to fetch active alerts from Valkey", http.StatusInternalServerError)
+			return
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, active)
+	}
+}
+
+// handleAlertHistory returns the current state of an alert and its transitions, oldest first.
+func handleAlertHistory(deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fingerprint := r.PathValue("fingerprint")
+
+		state, found, err := deps.AlertStates.Get(r.Context(), fingerprint)
+		if err != nil {
+			deps.Logger.Error("Failed to read alert state", zap.String("fingerprint", fingerprint), zap.Error(err))
+			http.Error(w, "Unable to fetch alert state from Valkey", http.StatusInternalServerError)
+			return
+		}
+		transitions, err := deps.AlertStates.History(r.Context(), fingerprint)
+		if err != nil {
+			deps.Logger.Error("Failed to read alert history", zap.String("fingerprint", fingerprint), zap.Error(err))
+			http.Error(w, "Unable to fetch alert history from Valkey", http.StatusInternalServerError)
+			return
+		}
+		if !found && len(transitions) == 0 {
+			http.Error(w, "no history for alert "+fingerprint, http.StatusNotFound)
+			return
+		}
+
+		response := alertHistoryResponse{Fingerprint: fingerprint, Transitions: transitions}
+		if found {
+			response.State = &state
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/alertstate"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAlertStates(t *testing.T) {
+	cm := manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_set": "set"})
+	cm.Annotations = map[string]string{manualvariables.AuditRedactionAnnotation: "label/service=mask"}
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, cm))
+	stateClient := valkeymock.NewClient(gomock.NewController(t))
+	deps.AlertStates = alertstate.NewStore(stateClient, 0, 0, 0)
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString(""))).Times(1)
+
+	var recorded [][]string
+	stateClient.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "EVALSHA" })).
+		DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			recorded = append(recorded, cmd.Commands())
+			return valkeymock.Result(valkeymock.ValkeyInt64(1))
+		}).Times(1)
+
+	// Only the published alert is recorded.
+	body := `{"receiver":"mdai","status":"firing","alerts":[
+		{"status":"firing","fingerprint":"fp-1","startsAt":"2025-08-08T15:56:10Z","labels":{"alertname":"top_talkers","service":"checkout"},
+		 "annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample"}},
+		{"status":"firing","startsAt":"2025-08-08T15:56:10Z","annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	require.Len(t, recorded, 1)
+	// EVALSHA sha 3 state history active fingerprint sec nsec state ...
+	assert.Equal(t, "alert/state/fp-1", recorded[0][3])
+	var state alertstate.State
+	require.NoError(t, json.Unmarshal([]byte(recorded[0][9]), &state))
+	assert.Equal(t, "top_talkers", state.AlertName)
+	assert.Equal(t, "prometheus", state.Source)
+	assert.Equal(t, time.Date(2025, 8, 8, 15, 56, 10, 0, time.UTC), state.FiringSince)
+	// The labels are redacted like in the audit record.
+	assert.Equal(t, map[string]string{"alertname": "top_talkers", "service": redact.Masked}, state.Labels)
+
+	stateClient.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "alert/active")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+			"fp-1": valkeymock.ValkeyBlobString(recorded[0][9]),
+		}))).Times(2)
+
+	req = httptest.NewRequest(http.MethodGet, "/alerts/active?hub=mdaihub-sample&alertname=top_talkers", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var active []alertstate.State
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &active))
+	require.Len(t, active, 1)
+	assert.Equal(t, "fp-1", active[0].Fingerprint)
+
+	req = httptest.NewRequest(http.MethodGet, "/alerts/active?hub=mdaihub-second", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `[]`, rr.Body.String())
+}
+
+func TestAlertHistory(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	stateClient := valkeymock.NewClient(gomock.NewController(t))
+	deps.AlertStates = alertstate.NewStore(stateClient, 0, 0, 0)
+	mux := NewRouter(t.Context(), deps)
+
+	stateClient.EXPECT().Do(gomock.Any(), valkeymock.Match("HGET", "alert/state/fp-1", "state")).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString(`{"fingerprint":"fp-1","hubName":"mdaihub-sample","status":"resolved","source":"prometheus",
+			"firingSince":"2025-08-08T15:00:00Z","resolvedAt":"2025-08-08T16:00:00Z","lastUpdate":"2025-08-08T16:00:01Z"}`)))
+	stateClient.EXPECT().Do(gomock.Any(), valkeymock.Match("LRANGE", "alert/history/fp-1", "0", "-1")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(
+			valkeymock.ValkeyBlobString(`{"status":"firing","changeTime":"2025-08-08T15:00:00Z","source":"prometheus","eventId":"event-1","recordedAt":"2025-08-08T15:00:01Z"}`),
+			valkeymock.ValkeyBlobString(`{"status":"resolved","changeTime":"2025-08-08T16:00:00Z","source":"prometheus","eventId":"event-2","recordedAt":"2025-08-08T16:00:01Z"}`),
+		)))
+
+	req := httptest.NewRequest(http.MethodGet, "/alerts/fp-1/history", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"fingerprint":"fp-1",
+		"state":{"fingerprint":"fp-1","hubName":"mdaihub-sample","status":"resolved","source":"prometheus",
+			"firingSince":"2025-08-08T15:00:00Z","resolvedAt":"2025-08-08T16:00:00Z","lastUpdate":"2025-08-08T16:00:01Z"},
+		"transitions":[
+			{"status":"firing","changeTime":"2025-08-08T15:00:00Z","source":"prometheus","eventId":"event-1","recordedAt":"2025-08-08T15:00:01Z"},
+			{"status":"resolved","changeTime":"2025-08-08T16:00:00Z","source":"prometheus","eventId":"event-2","recordedAt":"2025-08-08T16:00:01Z"}]}`, rr.Body.String())
+
+	// An alert the gateway never recorded, or whose history expired.
+	stateClient.EXPECT().Do(gomock.Any(), valkeymock.Match("HGET", "alert/state/fp-2", "state")).Return(valkeymock.Result(valkeymock.ValkeyNil()))
+	stateClient.EXPECT().Do(gomock.Any(), valkeymock.Match("LRANGE", "alert/history/fp-2", "0", "-1")).Return(valkeymock.Result(valkeymock.ValkeyArray()))
+
+	req = httptest.NewRequest(http.MethodGet, "/alerts/fp-2/history", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "no history for alert fp-2")
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/auditsink"
+	"github.com/decisiveai/mdai-gateway/internal/cloudevents"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"go.uber.org/zap"
+)
+
+const (
+	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeCSV    = "text/csv"
+
+	// auditExportFlushEvery is the number of records written between flushes of the response.
+	auditExportFlushEvery = 500
+	// auditExportWriteTimeout replaces the server write timeout while the export scans and
+	// flushes, so long exports are not cut off as long as they keep making progress.
+	auditExportWriteTimeout = 30 * time.Second
+	// auditVerifyWriteTimeout bounds a verification of the audit chain.
+	auditVerifyWriteTimeout = 5 * time.Minute
+)
+
+// handleAuditExport streams the audit records matching the filters of GET /v2/audit, oldest
+// first, as NDJSON or CSV. Records are read from the stream in batches and flushed as they are written,
+// so memory use does not depend on the size of the export.
+func handleAuditExport(_ context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		format, err := auditExportFormat(query.Get("format"), r.Header.Get("Accept"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotAcceptable)
+			return
+		}
+		filter, err := auditutils.FilterFromValues(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		contentType, extension := contentTypeNDJSON, format
+		switch format {
+		case auditutils.FormatCSV:
+			contentType = contentTypeCSV
+		case auditutils.FormatCloudEvents:
+			contentType, extension = cloudevents.BatchContentType, "json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit.%s"`, extension))
+
+		var out io.Writer = w
+		if query.Get("gzip") == "true" || strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Add("Vary", "Accept-Encoding")
+			gz := gzip.NewWriter(w)
+			defer gz.Close() //nolint:errcheck
+			out = gz
+		}
+
+		rc := http.NewResponseController(w)
+		// The deadline is extended while scanning too, since a selective filter can scan for
+		// longer than the server write timeout between two matches.
+		var deadline time.Time
+		extendDeadline := func() {
+			if time.Until(deadline) > auditExportWriteTimeout/2 {
+				return
+			}
+			deadline = time.Now().Add(auditExportWriteTimeout)
+			_ = rc.SetWriteDeadline(deadline)
+		}
+		flush := func(records auditutils.RecordWriter) error {
+			if err := records.Flush(); err != nil {
+				return err
+			}
+			if gz, ok := out.(*gzip.Writer); ok {
+				if err := gz.Flush(); err != nil {
+					return err
+				}
+			}
+			extendDeadline()
+			return rc.Flush()
+		}
+
+		records := auditutils.NewRecordWriter(out, format)
+		written := 0
+		extendDeadline()
+		// The request context stops the scan as soon as the client goes away.
+		for record, err := range auditutils.Scan(r.Context(), deps.ValkeyClient, filter, "", false) {
+			if err != nil {
+				// The status line is gone by now; a truncated body is all the client can get.
+				deps.Logger.Error("Failed to read audit stream for export", zap.Error(err))
+				return
+			}
+			extendDeadline()
+			if !filter.Matches(record.Fields) {
+				continue
+			}
+			if err := records.Write(record); err != nil {
+				deps.Logger.Error("Failed to write audit export", zap.Error(err))
+				return
+			}
+			if written++; written%auditExportFlushEvery == 0 {
+				if err := flush(records); err != nil {
+					deps.Logger.Error("Failed to flush audit export", zap.Error(err))
+					return
+				}
+			}
+		}
+
+		if err := records.Close(); err != nil {
+			deps.Logger.Error("Failed to write audit export", zap.Error(err))
+			return
+		}
+		if err := flush(records); err != nil {
+			deps.Logger.Error("Failed to flush audit export", zap.Error(err))
+		}
+	}
+}
+
+// auditExportFormat picks the export format from the format query parameter, falling back to the
+// Accept header. NDJSON is the default.
+func auditExportFormat(param, accept string) (string, error) {
+	switch strings.ToLower(param) {
+	case auditutils.FormatNDJSON:
+		return auditutils.FormatNDJSON, nil
+	case auditutils.FormatCSV:
+		return auditutils.FormatCSV, nil
+	case auditutils.FormatCloudEvents:
+		return auditutils.FormatCloudEvents, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format %q", param)
+	}
+
+	switch {
+	case strings.Contains(accept, contentTypeCSV):
+		return auditutils.FormatCSV, nil
+	case strings.Contains(accept, cloudevents.BatchContentType):
+		return auditutils.FormatCloudEvents, nil
+	}
+	return auditutils.FormatNDJSON, nil
+}
+
+// handleAuditVerify walks a range of the audit stream and reports the first break of its hash
+// chain.
+func handleAuditVerify(_ context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, end, err := auditutils.VerifyRangeFromValues(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Walking a long stream can take longer than the server write timeout allows.
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(auditVerifyWriteTimeout)); err != nil {
+			deps.Logger.Warn("Failed to extend write deadline for audit verification", zap.Error(err))
+		}
+
+		verification, err := auditutils.Verify(r.Context(), deps.ValkeyClient, start, end)
+		if err != nil {
+			deps.Logger.Error("Failed to verify audit chain", zap.Error(err))
+			http.Error(w, "Unable to fetch history from Valkey", http.StatusInternalServerError)
+			return
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, verification)
+	}
+}
+
+// handleAuditSinks reports how many records each audit sink wrote, failed to write or dropped.
+func handleAuditSinks(_ context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		sinks := []auditsink.Status{}
+		if deps.AuditSinks != nil {
+			sinks = deps.AuditSinks.Statuses()
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, map[string]any{"sinks": sinks})
+	}
+}
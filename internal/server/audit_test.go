@@ -0,0 +1,221 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-data-core/audit"
+	"github.com/decisiveai/mdai-gateway/internal/auditsink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func expectAuditStream(t *testing.T, m *valkeymock.Client, start, end string) *gomock.Call {
+	t.Helper()
+
+	return m.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", audit.MdaiHubEventHistoryStreamName, start, end, "COUNT", "200")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(
+			valkeymock.ValkeyArray(valkeymock.ValkeyString("1-0"), valkeymock.ValkeyArray(
+				valkeymock.ValkeyString("hub_name"), valkeymock.ValkeyString("prod"),
+				valkeymock.ValkeyString("name"), valkeymock.ValkeyString("var.add"),
+			)),
+			valkeymock.ValkeyArray(valkeymock.ValkeyString("2-0"), valkeymock.ValkeyArray(
+				valkeymock.ValkeyString("hub_name"), valkeymock.ValkeyString("staging"),
+			)),
+		)))
+}
+
+func TestHandleAuditExport(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "ndjson by default",
+			target:      "/audit/export?hub_name=prod",
+			contentType: contentTypeNDJSON,
+			body:        `{"id":"1-0","fields":{"hub_name":"prod","name":"var.add"}}` + "\n",
+		},
+		{
+			name:        "csv by accept",
+			target:      "/audit/export?hub_name=prod",
+			accept:      "text/csv",
+			contentType: contentTypeCSV,
+			body: "id,timestamp,hub_name,type,name,source,source_id,correlation_id,publish_success,payload,fields\n" +
+				"1-0,,prod,,var.add,,,,,,\n",
+		},
+		{
+			name:        "csv by param",
+			target:      "/audit/export?format=csv&hub_name=staging",
+			accept:      "application/x-ndjson",
+			contentType: contentTypeCSV,
+			body: "id,timestamp,hub_name,type,name,source,source_id,correlation_id,publish_success,payload,fields\n" +
+				"2-0,,staging,,,,,,,,\n",
+		},
+		{
+			name:        "cloudevents by accept",
+			target:      "/audit/export?hub_name=prod",
+			accept:      "application/cloudevents-batch+json",
+			contentType: "application/cloudevents-batch+json",
+			body: "[\n" +
+				`{"specversion":"1.0","id":"1-0","source":"/mdai-gateway/audit","type":"ai.mydecisive.audit.event","subject":"prod",` +
+				`"datacontenttype":"application/json","data":{"hub_name":"prod","name":"var.add"}}` +
+				"\n]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := setupMocks(t, newFakeClientset(t))
+			mux := NewRouter(t.Context(), deps)
+			expectAuditStream(t, deps.ValkeyClient.(*valkeymock.Client), "-", "+") //nolint:forcetypeassert
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.body, rr.Body.String())
+		})
+	}
+}
+
+// deadlineRecorder records the write deadlines the handler sets through its
+// http.ResponseController.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+
+	deadlines []time.Time
+}
+
+func (r *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	r.deadlines = append(r.deadlines, deadline)
+	return nil
+}
+
+func TestHandleAuditExport_WriteDeadline(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	rr := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	// A filter that matches nothing writes nothing, but the deadline must already be extended
+	// while the stream is scanned.
+	expectAuditStream(t, deps.ValkeyClient.(*valkeymock.Client), "-", "+"). //nolint:forcetypeassert
+										Do(func(context.Context, valkey.Completed) {
+			assert.NotEmpty(t, rr.deadlines, "write deadline set before the scan")
+		})
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/export?hub_name=none", http.NoBody)
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	require.NotEmpty(t, rr.deadlines)
+	assert.WithinDuration(t, time.Now().Add(auditExportWriteTimeout), rr.deadlines[0], 5*time.Second)
+}
+
+func TestHandleAuditExport_Gzip(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	expectAuditStream(t, deps.ValkeyClient.(*valkeymock.Client), "1000", "2000") //nolint:forcetypeassert
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/export?since=1970-01-01T00:00:01Z&until=1970-01-01T00:00:02Z", http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+
+	gz, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"id":"1-0","fields":{"hub_name":"prod","name":"var.add"}}`+"\n"+`{"id":"2-0","fields":{"hub_name":"staging"}}`+"\n",
+		string(body))
+}
+
+func TestHandleAuditExport_BadRequest(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+
+	for target, want := range map[string]int{
+		"/audit/export?format=xml":     http.StatusNotAcceptable,
+		"/audit/export?since=tomorrow": http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		rr := httptest.NewRecorder()
+
+		mux.ServeHTTP(rr, req)
+
+		assert.Equal(t, want, rr.Code, target)
+	}
+}
+
+func TestHandleAuditVerify(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	client := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	expectAuditStream(t, client, "1-0", "+")
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "audit/chain/head")).
+		Return(valkeymock.Result(valkeymock.ValkeyNil()))
+	client.EXPECT().Do(gomock.Any(), valkeymock.Match("XREVRANGE", audit.MdaiHubEventHistoryStreamName, "+", "-", "COUNT", "1")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(
+			valkeymock.ValkeyArray(valkeymock.ValkeyString("2-0"), valkeymock.ValkeyArray()),
+		)))
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/verify?start=1-0", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"intact":true,"checked":0,"unchained":2}`, rr.Body.String())
+}
+
+func TestHandleAuditVerify_InvalidRange(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/verify?end=tomorrow", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "invalid stream ID \"tomorrow\"\n", rr.Body.String())
+}
+
+func TestHandleAuditSinks(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	deps.AuditSinks = auditsink.NewFanOut(zap.NewNop(), deps.AuditInserter)
+	deps.AuditInserter = deps.AuditSinks
+	mux := NewRouter(t.Context(), deps)
+	deps.ValkeyClient.(*valkeymock.Client).EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString("1-0"))) //nolint:forcetypeassert
+
+	require.NoError(t, deps.AuditInserter.InsertAuditLogEventFromMap(t.Context(), map[string]string{"name": "var.add"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/sinks", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"sinks":[{"name":"valkey","written":1,"failed":0,"dropped":0}]}`, rr.Body.String())
+}
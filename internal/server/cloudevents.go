@@ -0,0 +1,104 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/decisiveai/mdai-data-core/eventing/config"
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/cloudevents"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"go.uber.org/zap"
+)
+
+// cloudEventResponse reports the outcome of a received CloudEvent. Status is one of the alert
+// statuses published, held or failed.
+type cloudEventResponse struct {
+	Message string `json:"message"`
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// handleCloudEventPost receives a CloudEvent in the structured or binary content mode and
+// publishes it as an MdaiEvent of the hub named by its subject. The events are published on the
+// alert subjects of the hub, keyed by their partition key or else their source, so they reach the
+// hub's event rules and are held like alerts while the hub is frozen.
+func handleCloudEventPost(deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const maxBody = 1 << 20 // 1 MiB
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		_ = r.Body.Close()
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				http.Error(w, "request body too large (max 1MiB)", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		ce, err := cloudevents.FromHTTP(r.Header, body)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, cloudevents.ErrNotCloudEvent) || errors.Is(err, cloudevents.ErrBatchUnsupported) {
+				status = http.StatusUnsupportedMediaType
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if err := ce.Validate(); err != nil {
+			http.Error(w, "invalid CloudEvent: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if isGatewayEventSource(ce.Source) {
+			http.Error(w, fmt.Sprintf("invalid CloudEvent: source %q is reserved for events of the gateway", ce.Source), http.StatusBadRequest)
+			return
+		}
+		event, err := ce.MdaiEvent()
+		if err != nil {
+			http.Error(w, "invalid CloudEvent: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		key := event.SourceID
+		if key == "" {
+			key = event.Source
+		}
+		eventPerSubject := adapter.EventPerSubject{
+			Event: event,
+			Subject: eventing.MdaiEventSubject{
+				Type: eventing.AlertEventType,
+				Path: config.SafeToken(event.HubName) + "." + config.SafeToken(key),
+			},
+			AuditFields: identity.ActorFromRequest(r).AuditFields(),
+		}
+
+		response := cloudEventResponse{ID: event.ID}
+		toPublish, held := holdFrozenAlertEvents(r.Context(), deps, []adapter.EventPerSubject{eventPerSubject})
+		if held > 0 {
+			response.Message, response.Status = "CloudEvent held while the hub is frozen", adapter.AlertHeld
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusAccepted, response)
+			return
+		}
+		if err := publishEachEvent(r.Context(), deps, toPublish)[0]; err != nil {
+			deps.Logger.Error("Failed to publish CloudEvent", zap.String("id", event.ID), zap.String("source", event.Source), zap.Error(err))
+			response.Message, response.Status, response.Reason = "CloudEvent could not be published", adapter.AlertFailed, err.Error()
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusServiceUnavailable, response)
+			return
+		}
+		response.Message, response.Status = "Published CloudEvent", adapter.AlertPublished
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusCreated, response)
+	}
+}
+
+// isGatewayEventSource reports whether the gateway publishes events of source itself, whose
+// payloads it interprets.
+func isGatewayEventSource(source string) bool {
+	return adapter.IsAlertSource(source) || source == eventing.ManualVariablesEventSource
+}
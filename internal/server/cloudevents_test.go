@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCloudEvents(t *testing.T) {
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_set": "set"})))
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	var records [][]string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+		records = append(records, cmd.Commands())
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).Times(2)
+
+	// Structured content mode.
+	req := httptest.NewRequest(http.MethodPost, "/events/cloudevents", bytes.NewBufferString(`{
+		"specversion":"1.0","id":"evt-1","source":"/deployments/checkout","type":"deploy.finished",
+		"subject":"mdaihub-sample","time":"2025-08-08T15:56:10Z","data":{"version":"1.2.3"},"correlationid":"release-42"}`))
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"message":"Published CloudEvent","id":"evt-1","status":"published"}`, rr.Body.String())
+	require.Len(t, records, 1)
+	assert.Equal(t, "deploy.finished", auditField(records[0], "name"))
+	assert.Equal(t, "/deployments/checkout", auditField(records[0], "source"))
+	assert.Equal(t, "mdaihub-sample", auditField(records[0], "hub_name"))
+	assert.Equal(t, "release-42", auditField(records[0], "correlation_id"))
+	assert.JSONEq(t, `{"version":"1.2.3"}`, auditField(records[0], "payload"))
+
+	// Binary content mode.
+	req = httptest.NewRequest(http.MethodPost, "/events/cloudevents", bytes.NewBufferString(`{"version":"1.2.4"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Id", "evt-2")
+	req.Header.Set("Ce-Source", "/deployments/checkout")
+	req.Header.Set("Ce-Type", "deploy.finished")
+	req.Header.Set("Ce-Subject", "mdaihub-sample")
+	req.Header.Set("Ce-Partitionkey", "checkout")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	require.Len(t, records, 2)
+	assert.Equal(t, "checkout", auditField(records[1], "sourceId"))
+	assert.JSONEq(t, `{"version":"1.2.4"}`, auditField(records[1], "payload"))
+}
+
+func TestCloudEvents_Invalid(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:        "not a CloudEvent",
+			contentType: "application/json",
+			body:        `{"id":"evt-1"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantBody:    "request is not a CloudEvent",
+		},
+		{
+			name:        "batch",
+			contentType: "application/cloudevents-batch+json",
+			body:        `[]`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantBody:    "batched CloudEvents are not supported",
+		},
+		{
+			name:        "missing attributes",
+			contentType: "application/cloudevents+json",
+			body:        `{"specversion":"1.0","source":"/deployments/checkout","subject":"mdaihub-sample"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "invalid CloudEvent: missing required attribute",
+		},
+		{
+			name:        "missing subject",
+			contentType: "application/cloudevents+json",
+			body:        `{"specversion":"1.0","id":"evt-1","source":"/deployments/checkout","type":"deploy.finished","data":{}}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "invalid CloudEvent: missing required attribute: subject, which names the hub",
+		},
+		{
+			name:        "reserved source",
+			contentType: "application/cloudevents+json",
+			body:        `{"specversion":"1.0","id":"evt-1","source":"prometheus","type":"top_talkers.firing","subject":"mdaihub-sample","data":{}}`,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `invalid CloudEvent: source "prometheus" is reserved for events of the gateway`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/events/cloudevents", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+}
@@ -0,0 +1,465 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	datacore "github.com/decisiveai/mdai-data-core/variables"
+	"github.com/decisiveai/mdai-gateway/internal/freeze"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/stringutil"
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+
+	contentTypeJSON = "application/json"
+	contentTypeYAML = "application/yaml"
+
+	importModeMerge   = "merge"
+	importModeReplace = "replace"
+
+	importStatusValid   = "valid"
+	importStatusInvalid = "invalid"
+
+	maxImportBody = 10 << 20 // 10 MiB
+)
+
+// exportedVariable is a single variable in the export document.
+type exportedVariable struct {
+	Type  valkey.VariableType `json:"type"`
+	Value any                 `json:"value"`
+}
+
+// skippedVariable is a variable left out of the export document because it could not be read.
+type skippedVariable struct {
+	Hub      string `json:"hub"`
+	Variable string `json:"variable"`
+	Error    string `json:"error"`
+}
+
+// exportedValue returns the value of a variable as written in the export document. Integers and
+// booleans are stored as strings and exported as JSON numbers and booleans, like they are set.
+// ok is false for an unset integer or boolean, which has no value to export.
+func exportedValue(varType valkey.VariableType, value any) (any, bool, error) {
+	stored, isString := value.(string)
+	if !isString || (varType != valkey.VariableTypeInt && varType != valkey.VariableTypeBool) {
+		return value, true, nil
+	}
+	if stored == "" {
+		return nil, false, nil
+	}
+	if varType == valkey.VariableTypeInt {
+		n, err := strconv.Atoi(stored)
+		if err != nil {
+			return nil, false, fmt.Errorf("stored value %q is not an int", stored)
+		}
+		return n, true, nil
+	}
+	b, err := strconv.ParseBool(stored)
+	if err != nil {
+		return nil, false, fmt.Errorf("stored value %q is not a boolean", stored)
+	}
+	return b, true, nil
+}
+
+// importDocument is the import document. It has the same shape as the export document, the
+// values are kept raw so they can be validated with the same parsers as single mutations.
+type importDocument struct {
+	Hubs map[string]map[string]importedVariable `json:"hubs"`
+
+	manualvariables.ChangeMetadata
+}
+
+type importedVariable struct {
+	Type  valkey.VariableType `json:"type"`
+	Value json.RawMessage     `json:"value"`
+}
+
+type importResult struct {
+	Hub      string                   `json:"hub"`
+	Variable string                   `json:"variable"`
+	Status   string                   `json:"status"`
+	Changes  []manualvariables.Change `json:"changes,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+type importResponse struct {
+	Mode          string         `json:"mode"`
+	DryRun        bool           `json:"dryRun"`
+	CorrelationID string         `json:"correlationId,omitempty"`
+	Results       []importResult `json:"results"`
+}
+
+// handleExportVariables streams every hub's declared manual variables with their current values.
+func handleExportVariables(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := requestedFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotAcceptable)
+			return
+		}
+
+		hubsVariables, err := deps.ConfigMapController.GetAllHubsToDataMap()
+		if err != nil {
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, "failed to fetch manual variables")
+			return
+		}
+
+		kv := datacore.NewValkeyAdapter(deps.ValkeyClient, deps.Logger)
+		writer := newSnapshotWriter(w, format)
+		w.WriteHeader(http.StatusOK)
+
+		// The status is sent with the first hub, so variables that cannot be read are listed at
+		// the end of the document instead of failing the export.
+		var skipped []skippedVariable
+		for _, hubName := range slices.Sorted(maps.Keys(hubsVariables)) {
+			variables := make(map[string]exportedVariable, len(hubsVariables[hubName]))
+			for varName, varType := range hubsVariables[hubName] {
+				value, err := valkey.GetValue(ctx, kv, varName, valkey.VariableType(varType), hubName)
+				set := true
+				if err == nil {
+					value, set, err = exportedValue(valkey.VariableType(varType), value)
+				}
+				if err != nil {
+					deps.Logger.Warn("Skipping variable in export",
+						zap.String("hubName", hubName),
+						zap.String("variable", varName),
+						zap.Error(err),
+					)
+					skipped = append(skipped, skippedVariable{Hub: hubName, Variable: varName, Error: err.Error()})
+					continue
+				}
+				if !set {
+					continue
+				}
+				variables[varName] = exportedVariable{Type: valkey.VariableType(varType), Value: value}
+			}
+
+			if err := writer.writeHub(hubName, variables); err != nil {
+				deps.Logger.Error("Failed to write variables export", zap.String("hubName", hubName), zap.Error(err))
+				return
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+
+		slices.SortFunc(skipped, func(a, b skippedVariable) int {
+			return cmp.Or(cmp.Compare(a.Hub, b.Hub), cmp.Compare(a.Variable, b.Variable))
+		})
+		if err := writer.close(skipped); err != nil {
+			deps.Logger.Error("Failed to write variables export", zap.Error(err))
+		}
+	}
+}
+
+// handleImportVariables validates an export document against the declared variable types and
+// applies it. Nothing is published unless every variable in the document is valid.
+func handleImportVariables(ctx context.Context, deps HandlerDeps) http.HandlerFunc { //nolint:funlen
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+		defer r.Body.Close() //nolint:errcheck
+
+		query := r.URL.Query()
+		mode := query.Get("mode")
+		if mode == "" {
+			mode = importModeMerge
+		}
+		if mode != importModeMerge && mode != importModeReplace {
+			http.Error(w, fmt.Sprintf("unsupported import mode %q", mode), http.StatusBadRequest)
+			return
+		}
+		dryRun := query.Get("dryRun") == "true"
+
+		doc, err := decodeImportDocument(r)
+		if err != nil {
+			http.Error(w, "Invalid import document: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hubsVariables, err := deps.ConfigMapController.GetAllHubsToDataMap()
+		if err != nil {
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, "failed to fetch manual variables")
+			return
+		}
+
+		policies := make(map[string]manualvariables.Policy, len(doc.Hubs))
+		freezeErrs := make(map[string]error, len(doc.Hubs))
+		freezeAuditFields := make(map[string]map[string]string, len(doc.Hubs))
+		// A dry run checks the policies and freezes too, so that it reports what the import rejects.
+		for hubName := range doc.Hubs {
+			policy, err := hubPolicy(deps, hubName)
+			if err != nil {
+				writePolicyError(w, deps.Logger, err)
+				return
+			}
+			policies[hubName] = policy
+			fields, err := checkFreeze(ctx, deps, r, hubName)
+			if err != nil && !errors.As(err, new(freeze.FrozenError)) {
+				writeFreezeError(w, deps.Logger, err)
+				return
+			}
+			freezeAuditFields[hubName], freezeErrs[hubName] = fields, err
+		}
+
+		response := importResponse{Mode: mode, DryRun: dryRun}
+		type plannedImport struct {
+			varType valkey.VariableType
+			desired any
+		}
+		planned := make(map[int]plannedImport)
+		for _, hubName := range slices.Sorted(maps.Keys(doc.Hubs)) {
+			for _, varName := range slices.Sorted(maps.Keys(doc.Hubs[hubName])) {
+				result := importResult{Hub: hubName, Variable: varName, Status: importStatusValid}
+				varType, desired, err := validateImportedVariable(hubName, varName, doc.Hubs[hubName][varName], hubsVariables)
+				if err == nil {
+					err = freezeErrs[hubName]
+				}
+				if err == nil {
+					err = policies[hubName].CheckBulkChange(varName, doc.ChangeMetadata)
+				}
+				if err != nil {
+					result.Status = importStatusInvalid
+					result.Error = err.Error()
+				} else {
+					planned[len(response.Results)] = plannedImport{varType: varType, desired: desired}
+				}
+				response.Results = append(response.Results, result)
+			}
+		}
+		if len(planned) < len(response.Results) {
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusUnprocessableEntity, response)
+			return
+		}
+
+		if !dryRun {
+			response.CorrelationID = "import-" + uuid.NewString()
+		}
+
+		plan := manualvariables.Merge
+		if mode == importModeReplace {
+			plan = manualvariables.Diff
+		}
+
+		kv := datacore.NewValkeyAdapter(deps.ValkeyClient, deps.Logger)
+		status := http.StatusOK
+		for i := range response.Results {
+			result, p := &response.Results[i], planned[i]
+
+			current, err := valkey.GetValue(ctx, kv, result.Variable, p.varType, result.Hub)
+			if err != nil {
+				httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if result.Changes, err = plan(p.varType, current, p.desired); err != nil {
+				httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			switch {
+			case len(result.Changes) == 0:
+				result.Status = changeStatusUnchanged
+			case dryRun:
+				result.Status = changeStatusPlanned
+			default:
+				result.Status = changeStatusApplied
+				auditFields := identity.ActorFromRequest(r).AuditFields()
+				maps.Copy(auditFields, freezeAuditFields[result.Hub])
+				if err := publishVariableChanges(ctx, deps, result.Hub, result.Variable, p.varType, result.Changes, response.CorrelationID, doc.ChangeMetadata, auditFields); err != nil {
+					deps.Logger.Error("Failed to import variable",
+						zap.String("hubName", result.Hub),
+						zap.String("variable", result.Variable),
+						zap.Error(err),
+					)
+					result.Status = changeStatusFailed
+					result.Error = err.Error()
+					status = http.StatusInternalServerError
+				}
+			}
+		}
+
+		httputil.WriteJSONResponse(w, deps.Logger, status, response)
+	}
+}
+
+func validateImportedVariable(hubName, varName string, imported importedVariable, hubsVariables manualvariables.ByHub) (valkey.VariableType, any, error) {
+	varType, err := manualvariables.GetVarType(hubName, varName, hubsVariables)
+	if err != nil {
+		return "", nil, err
+	}
+	if imported.Type != "" && imported.Type != varType {
+		return "", nil, fmt.Errorf("type %s does not match declared type %s", imported.Type, varType)
+	}
+	if len(imported.Value) == 0 || string(imported.Value) == "null" {
+		return "", nil, errors.New("value required")
+	}
+
+	raw := imported.Value
+	// Integers and booleans may also be given as strings, e.g. "5" or "true", as they are stored.
+	var str string
+	if (varType == valkey.VariableTypeInt || varType == valkey.VariableTypeBool) && json.Unmarshal(raw, &str) == nil {
+		raw = json.RawMessage(str)
+	}
+
+	parser, err := valkey.GetParser(varType, valkey.CommandAdd)
+	if err != nil {
+		return "", nil, err
+	}
+	desired, err := parser(raw)
+	if err != nil {
+		return "", nil, errors.New(stringutil.UpperFirst(err.Error()))
+	}
+	return varType, desired, nil
+}
+
+func decodeImportDocument(r *http.Request) (importDocument, error) {
+	var doc importDocument
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return doc, err
+	}
+
+	if isYAMLMediaType(r.Header.Get("Content-Type")) {
+		err = yaml.Unmarshal(body, &doc)
+	} else {
+		err = json.Unmarshal(body, &doc)
+	}
+	if err != nil {
+		return doc, err
+	}
+	if len(doc.Hubs) == 0 {
+		return doc, errors.New("no hubs in document")
+	}
+	return doc, nil
+}
+
+// requestedFormat picks the export format from the format query parameter, falling back to the
+// Accept header. JSON is the default.
+func requestedFormat(param, accept string) (string, error) {
+	switch strings.ToLower(param) {
+	case formatJSON:
+		return formatJSON, nil
+	case formatYAML:
+		return formatYAML, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format %q", param)
+	}
+
+	if isYAMLMediaType(accept) {
+		return formatYAML, nil
+	}
+	return formatJSON, nil
+}
+
+func isYAMLMediaType(mediaType string) bool {
+	return strings.Contains(mediaType, "application/yaml") ||
+		strings.Contains(mediaType, "application/x-yaml") ||
+		strings.Contains(mediaType, "text/yaml")
+}
+
+// snapshotWriter writes the export document one hub at a time so the response can be flushed
+// while the remaining hubs are still being read.
+type snapshotWriter struct {
+	w      io.Writer
+	format string
+	hubs   int
+}
+
+func newSnapshotWriter(w http.ResponseWriter, format string) *snapshotWriter {
+	contentType := contentTypeJSON
+	if format == formatYAML {
+		contentType = contentTypeYAML
+	}
+	w.Header().Set("Content-Type", contentType)
+	return &snapshotWriter{w: w, format: format}
+}
+
+func (s *snapshotWriter) writeHub(hubName string, variables map[string]exportedVariable) error {
+	defer func() { s.hubs++ }()
+
+	if s.format == formatYAML {
+		out, err := yaml.Marshal(map[string]any{hubName: variables})
+		if err != nil {
+			return err
+		}
+		if s.hubs == 0 {
+			if _, err := io.WriteString(s.w, "hubs:\n"); err != nil {
+				return err
+			}
+		}
+		for line := range strings.Lines(string(out)) {
+			if _, err := io.WriteString(s.w, "  "+line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	key, err := json.Marshal(hubName)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(variables)
+	if err != nil {
+		return err
+	}
+	prefix := ","
+	if s.hubs == 0 {
+		prefix = `{"hubs":{`
+	}
+	_, err = fmt.Fprintf(s.w, "%s%s:%s", prefix, key, value)
+	return err
+}
+
+// close ends the document, listing the skipped variables after the hubs.
+func (s *snapshotWriter) close(skipped []skippedVariable) error {
+	if s.format == formatYAML {
+		if s.hubs == 0 {
+			if _, err := io.WriteString(s.w, "hubs: {}\n"); err != nil {
+				return err
+			}
+		}
+		if len(skipped) == 0 {
+			return nil
+		}
+		out, err := yaml.Marshal(map[string]any{"skipped": skipped})
+		if err != nil {
+			return err
+		}
+		_, err = s.w.Write(out)
+		return err
+	}
+
+	end := "}"
+	if s.hubs == 0 {
+		end = `{"hubs":{}`
+	}
+	if len(skipped) > 0 {
+		list, err := json.Marshal(skipped)
+		if err != nil {
+			return err
+		}
+		end += `,"skipped":` + string(list)
+	}
+	_, err := io.WriteString(s.w, end+"}\n")
+	return err
+}
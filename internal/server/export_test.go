@@ -0,0 +1,282 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+	"sigs.k8s.io/yaml"
+)
+
+func newTransferDeps(t *testing.T) HandlerDeps {
+	t.Helper()
+
+	clientset := newFakeClientsetWithHubs(t,
+		manualVariablesConfigMap("hub-a", map[string]string{"filter": "string", "services": "set"}),
+		manualVariablesConfigMap("hub-b", map[string]string{"attributes": "map"}),
+	)
+	return setupMocks(t, clientset)
+}
+
+func expectTransferValues(t *testing.T, m *valkeymock.Client) {
+	t.Helper()
+
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "variable/hub-a/filter")).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString("severity>=warn")))
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("SMEMBERS", "variable/hub-a/services")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyBlobString("a"))))
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "variable/hub-b/attributes")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+			"k1": valkeymock.ValkeyBlobString("v1"),
+			"k2": valkeymock.ValkeyBlobString("v2"),
+		})))
+}
+
+const transferDocument = `{"hubs":{
+	"hub-a":{"filter":{"type":"string","value":"severity>=warn"},"services":{"type":"set","value":["a"]}},
+	"hub-b":{"attributes":{"type":"map","value":{"k1":"v1","k2":"v2"}}}
+}}`
+
+func TestHandleExportVariables(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		contentType string
+		decode      func([]byte, any) error
+	}{
+		{name: "json by default", target: "/variables/export", contentType: contentTypeJSON, decode: json.Unmarshal},
+		{name: "yaml by param", target: "/variables/export?format=yaml", contentType: contentTypeYAML, decode: func(b []byte, v any) error { return yaml.Unmarshal(b, v) }},
+		{name: "yaml by accept", target: "/variables/export", accept: "application/yaml", contentType: contentTypeYAML, decode: func(b []byte, v any) error { return yaml.Unmarshal(b, v) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTransferDeps(t)
+			mux := NewRouter(t.Context(), deps)
+			expectTransferValues(t, deps.ValkeyClient.(*valkeymock.Client)) //nolint:forcetypeassert
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.contentType, rr.Header().Get("Content-Type"))
+
+			var got, want map[string]any
+			require.NoError(t, tt.decode(rr.Body.Bytes(), &got), rr.Body.String())
+			require.NoError(t, json.Unmarshal([]byte(transferDocument), &want))
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestHandleExportVariables_UnsupportedFormat(t *testing.T) {
+	deps := newTransferDeps(t)
+	mux := NewRouter(t.Context(), deps)
+
+	req := httptest.NewRequest(http.MethodGet, "/variables/export?format=xml", http.NoBody)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+}
+
+func TestHandleImportVariables_DryRunReplace(t *testing.T) {
+	deps := newTransferDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectTransferValues(t, deps.ValkeyClient.(*valkeymock.Client)) //nolint:forcetypeassert
+
+	body := `
+hubs:
+  hub-a:
+    filter: {type: string, value: "severity>=error"}
+    services: {value: [a, b]}
+  hub-b:
+    attributes: {type: map, value: {k1: v1}}
+`
+	req := httptest.NewRequest(http.MethodPost, "/variables/import?mode=replace&dryRun=true", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/yaml")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{
+		"mode": "replace",
+		"dryRun": true,
+		"results": [
+			{"hub": "hub-a", "variable": "filter", "status": "planned", "changes": [{"command": "add", "data": "severity>=error"}]},
+			{"hub": "hub-a", "variable": "services", "status": "planned", "changes": [{"command": "add", "data": ["b"]}]},
+			{"hub": "hub-b", "variable": "attributes", "status": "planned", "changes": [{"command": "remove", "data": ["k2"]}]}
+		]
+	}`, rr.Body.String())
+}
+
+func TestHandleImportVariables_Merge(t *testing.T) {
+	deps := newTransferDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	expectTransferValues(t, mockClient)
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString(""))).Times(1)
+
+	body := `{"hubs":{
+		"hub-a":{"filter":{"value":"severity>=warn"},"services":{"value":["a"]}},
+		"hub-b":{"attributes":{"value":{"k3":"v3"}}}
+	}}`
+	req := httptest.NewRequest(http.MethodPost, "/variables/import", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp importResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, importModeMerge, resp.Mode)
+	assert.NotEmpty(t, resp.CorrelationID)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, changeStatusUnchanged, resp.Results[0].Status)
+	assert.Equal(t, changeStatusUnchanged, resp.Results[1].Status)
+	assert.Equal(t, changeStatusApplied, resp.Results[2].Status)
+}
+
+func TestHandleImportVariables_Invalid(t *testing.T) {
+	deps := newTransferDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	body := `{"hubs":{
+		"hub-a":{"filter":{"type":"int","value":"x"},"services":{"value":"not-a-list"},"missing":{"value":"x"}},
+		"hub-b":{"attributes":{"value":{"k1":"v1"}}},
+		"hub-c":{"anything":{"value":"x"}}
+	}}`
+	req := httptest.NewRequest(http.MethodPost, "/variables/import?mode=replace", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.JSONEq(t, `{
+		"mode": "replace",
+		"dryRun": false,
+		"results": [
+			{"hub": "hub-a", "variable": "filter", "status": "invalid", "error": "type int does not match declared type string"},
+			{"hub": "hub-a", "variable": "missing", "status": "invalid", "error": "variable not found"},
+			{"hub": "hub-a", "variable": "services", "status": "invalid", "error": "List expected"},
+			{"hub": "hub-b", "variable": "attributes", "status": "valid"},
+			{"hub": "hub-c", "variable": "anything", "status": "invalid", "error": "hub not found"}
+		]
+	}`, rr.Body.String())
+}
+
+func TestHandleImportVariables_BadRequest(t *testing.T) {
+	deps := newTransferDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	for target, body := range map[string]string{
+		"/variables/import?mode=upsert": transferDocument,
+		"/variables/import":             `{"hubs":{}}`,
+		"/variables/import?mode=merge":  `not json`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		mux.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+	}
+}
+
+func TestExportImportVariables_TypedScalars(t *testing.T) {
+	deps := setupMocks(t, newFakeClientsetWithHubs(t,
+		manualVariablesConfigMap("hub-a", map[string]string{"limit": "int", "enabled": "boolean", "unset": "int"}),
+	))
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	expectScalars := func() {
+		mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "variable/hub-a/limit")).
+			Return(valkeymock.Result(valkeymock.ValkeyBlobString("5")))
+		mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "variable/hub-a/enabled")).
+			Return(valkeymock.Result(valkeymock.ValkeyBlobString("true")))
+	}
+	expectScalars()
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "variable/hub-a/unset")).
+		Return(valkeymock.Result(valkeymock.ValkeyNil()))
+
+	req := httptest.NewRequest(http.MethodGet, "/variables/export", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	// Integers and booleans are typed like in set requests, and unset ones are left out.
+	assert.JSONEq(t, `{"hubs":{"hub-a":{"enabled":{"type":"boolean","value":true},"limit":{"type":"int","value":5}}}}`, rr.Body.String())
+
+	// The export imports back without changes.
+	expectScalars()
+	req = httptest.NewRequest(http.MethodPost, "/variables/import?mode=replace&dryRun=true", bytes.NewReader(rr.Body.Bytes()))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"mode":"replace","dryRun":true,"results":[
+		{"hub":"hub-a","variable":"enabled","status":"unchanged"},
+		{"hub":"hub-a","variable":"limit","status":"unchanged"}]}`, rr.Body.String())
+
+	// So do the values as stored, which earlier exports wrote.
+	expectScalars()
+	req = httptest.NewRequest(http.MethodPost, "/variables/import?mode=replace&dryRun=true",
+		bytes.NewBufferString(`{"hubs":{"hub-a":{"enabled":{"value":"true"},"limit":{"value":"5"}}}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), changeStatusPlanned)
+}
+
+func TestHandleExportVariables_Skipped(t *testing.T) {
+	deps := newTransferDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "variable/hub-a/filter")).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString("severity>=warn")))
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("SMEMBERS", "variable/hub-a/services")).
+		Return(valkeymock.ErrorResult(errors.New("connection reset")))
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "variable/hub-b/attributes")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{})))
+
+	req := httptest.NewRequest(http.MethodGet, "/variables/export", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var doc struct {
+		Hubs    map[string]map[string]any `json:"hubs"`
+		Skipped []skippedVariable         `json:"skipped"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc), rr.Body.String())
+	assert.NotContains(t, doc.Hubs["hub-a"], "services")
+	require.Len(t, doc.Skipped, 1)
+	assert.Equal(t, "hub-a", doc.Skipped[0].Hub)
+	assert.Equal(t, "services", doc.Skipped[0].Variable)
+	assert.Contains(t, doc.Skipped[0].Error, "connection reset")
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/freeze"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"go.uber.org/zap"
+)
+
+func handleListFreezes(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		windows, err := deps.Freezes.List(ctx, r.PathValue("hubName"))
+		if err != nil {
+			deps.Logger.Error("Failed to list freeze windows", zap.Error(err))
+			http.Error(w, "Failed to list freeze windows", http.StatusInternalServerError)
+			return
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, windows)
+	}
+}
+
+// handleCreateFreeze adds a freeze window to a hub. Only authenticated callers holding the
+// override scope manage windows, since they could otherwise lift a freeze by deleting it.
+func handleCreateFreeze(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+
+		if !requireFreezeAdmin(w, r) {
+			return
+		}
+
+		hubName := r.PathValue("hubName")
+		hubsVariables, err := deps.ConfigMapController.GetAllHubsToDataMap()
+		if err != nil {
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, "failed to fetch manual variables")
+			return
+		}
+		if _, ok := hubsVariables[hubName]; !ok {
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusNotFound, "Hub not found")
+			return
+		}
+
+		var window freeze.Window
+		if err := json.NewDecoder(r.Body).Decode(&window); err != nil {
+			http.Error(w, "Invalid JSON format in request payload", http.StatusBadRequest)
+			return
+		}
+		if err := window.Validate(); err != nil {
+			http.Error(w, "Invalid freeze window: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		window.CreatedBy = identity.User(r)
+
+		if err := deps.Freezes.Create(ctx, hubName, &window); err != nil {
+			deps.Logger.Error("Failed to store freeze window", zap.Error(err))
+			http.Error(w, "Failed to store freeze window", http.StatusInternalServerError)
+			return
+		}
+		recordFreezeAudit(ctx, deps, r, freezeCreated, hubName, window)
+
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusCreated, window)
+	}
+}
+
+func handleDeleteFreeze(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireFreezeAdmin(w, r) {
+			return
+		}
+
+		hubName, id := r.PathValue("hubName"), r.PathValue("freezeId")
+		err := deps.Freezes.Delete(ctx, hubName, id)
+		switch {
+		case errors.Is(err, freeze.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			deps.Logger.Error("Failed to delete freeze window", zap.Error(err))
+			http.Error(w, "Failed to delete freeze window", http.StatusInternalServerError)
+		default:
+			recordFreezeAudit(ctx, deps, r, freezeDeleted, hubName, freeze.Window{ID: id})
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// requireFreezeAdmin answers 401 without an authenticated user and 403 without the override
+// scope, and returns whether the caller may manage freeze windows.
+func requireFreezeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if identity.User(r) == "" {
+		http.Error(w, "Managing freeze windows requires an authenticated user", http.StatusUnauthorized)
+		return false
+	}
+	if !identity.HasScope(r, freeze.OverrideScope) {
+		http.Error(w, "The "+freeze.OverrideScope+" scope is required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+const (
+	freezeCreated = "created"
+	freezeDeleted = "deleted"
+)
+
+// recordFreezeAudit writes a freeze_changed audit record for a window created or deleted by the
+// caller. Deleted windows are only identified by their ID.
+func recordFreezeAudit(ctx context.Context, deps HandlerDeps, r *http.Request, action, hubName string, window freeze.Window) {
+	fields := map[string]string{
+		"action":    action,
+		"hub_name":  hubName,
+		"freeze_id": window.ID,
+	}
+	if action == freezeCreated {
+		doc, err := json.Marshal(window)
+		if err != nil {
+			deps.Logger.Error("Failed to encode freeze window", zap.String("freezeId", window.ID), zap.Error(err))
+		}
+		fields["window"] = string(doc)
+		if window.Reason != "" {
+			fields["reason"] = window.Reason
+		}
+	}
+	maps.Copy(fields, identity.ActorFromRequest(r).AuditFields())
+
+	if err := auditutils.RecordAuditRecord(ctx, deps.Logger, deps.AuditInserter, auditutils.FreezeChangedType, fields); err != nil {
+		deps.Logger.Error("Failed to audit freeze window change", zap.String("freezeId", window.ID), zap.String("action", action), zap.Error(err))
+	}
+}
+
+// checkFreeze returns a freeze.FrozenError while the hub is frozen, unless the caller holds the
+// override scope. An override is returned as audit fields for the events it lets through.
+func checkFreeze(ctx context.Context, deps HandlerDeps, r *http.Request, hubName string) (map[string]string, error) {
+	window, until, frozen, err := deps.Freezes.Active(ctx, hubName)
+	if err != nil || !frozen {
+		return nil, err
+	}
+	if !identity.HasScope(r, freeze.OverrideScope) {
+		return nil, freeze.FrozenError{HubName: hubName, Window: window, Until: until}
+	}
+
+	deps.Logger.Info("Overriding freeze window",
+		zap.String("hubName", hubName),
+		zap.String("freezeId", window.ID),
+		zap.String("user", identity.User(r)),
+	)
+	return map[string]string{
+		"freeze_override":    window.ID,
+		"freeze_override_by": identity.User(r),
+	}, nil
+}
+
+func writeFreezeError(w http.ResponseWriter, logger *zap.Logger, err error) {
+	var frozen freeze.FrozenError
+	if errors.As(err, &frozen) {
+		http.Error(w, frozen.Error(), http.StatusLocked)
+		return
+	}
+	logger.Error("Failed to read freeze windows", zap.Error(err))
+	http.Error(w, "Failed to read freeze windows", http.StatusInternalServerError)
+}
+
+// holdFrozenAlertEvents queues the events of frozen hubs that hold alerts and returns the
+// events to publish now. Events are passed through when the freeze state cannot be read, and
+// when the hub policy cannot be read, in which case publishing them fails.
+func holdFrozenAlertEvents(ctx context.Context, deps HandlerDeps, events []adapter.EventPerSubject) ([]adapter.EventPerSubject, int) {
+	type hubState struct {
+		hold   bool
+		window freeze.Window
+	}
+	states := make(map[string]hubState)
+
+	toPublish := make([]adapter.EventPerSubject, 0, len(events))
+	held := 0
+	for _, event := range events {
+		hubName := event.Event.HubName
+		state, seen := states[hubName]
+		if !seen {
+			if policy, err := hubPolicy(deps, hubName); err == nil && policy.HoldAlertsWhenFrozen {
+				window, _, frozen, err := deps.Freezes.Active(ctx, hubName)
+				if err != nil {
+					deps.Logger.Error("Failed to read freeze windows, passing alerts through", zap.String("hubName", hubName), zap.Error(err))
+				}
+				state = hubState{hold: frozen, window: window}
+			}
+			states[hubName] = state
+		}
+		if !state.hold {
+			toPublish = append(toPublish, event)
+			continue
+		}
+
+		if event.AuditFields == nil {
+			event.AuditFields = make(map[string]string, 1)
+		}
+		event.AuditFields["held_by_freeze"] = state.window.ID
+		if err := holdEvent(ctx, deps, event); err != nil {
+			deps.Logger.Error("Failed to hold event, publishing it", zap.String("hubName", hubName), zap.Error(err))
+			toPublish = append(toPublish, event)
+			continue
+		}
+		held++
+	}
+	return toPublish, held
+}
+
+func holdEvent(ctx context.Context, deps HandlerDeps, event adapter.EventPerSubject) error {
+	doc, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return deps.Freezes.Hold(ctx, event.Event.HubName, doc)
+}
+
+// RunFreezeReleaser publishes the events held for hubs whose freeze has ended, every interval
+// until ctx is done.
+func RunFreezeReleaser(ctx context.Context, deps HandlerDeps, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			releaseHeldEvents(ctx, deps)
+		}
+	}
+}
+
+func releaseHeldEvents(ctx context.Context, deps HandlerDeps) {
+	hubs, err := deps.Freezes.HeldHubs(ctx)
+	if err != nil {
+		deps.Logger.Error("Failed to list hubs with held events", zap.Error(err))
+		return
+	}
+
+	for _, hubName := range hubs {
+		if _, _, frozen, err := deps.Freezes.Active(ctx, hubName); err != nil || frozen {
+			continue
+		}
+		for {
+			doc, ok, err := deps.Freezes.NextHeld(ctx, hubName)
+			if err != nil {
+				deps.Logger.Error("Failed to read held event", zap.String("hubName", hubName), zap.Error(err))
+				break
+			}
+			if !ok {
+				break
+			}
+
+			var event adapter.EventPerSubject
+			if err := json.Unmarshal(doc, &event); err != nil {
+				deps.Logger.Error("Dropping undecodable held event", zap.String("hubName", hubName), zap.Error(err))
+				continue
+			}
+			if _, err := publishEvents(ctx, deps, []adapter.EventPerSubject{event}); err != nil {
+				deps.Logger.Error("Failed to release held event", zap.String("hubName", hubName), zap.Error(err))
+				if err := deps.Freezes.Requeue(ctx, hubName, doc); err != nil {
+					deps.Logger.Error("Failed to requeue held event", zap.String("hubName", hubName), zap.Error(err))
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/freeze"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func activeFreezeWindows(t *testing.T) map[string]valkey.ValkeyMessage {
+	t.Helper()
+
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	doc, err := json.Marshal(freeze.Window{ID: "w1", Start: &start, End: &end, Reason: "migration"})
+	require.NoError(t, err)
+	return map[string]valkey.ValkeyMessage{"w1": valkeymock.ValkeyBlobString(string(doc))}
+}
+
+func TestHandleSetVariables_Frozen(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	deps.Freezes, _ = newFreezeStore(t, gomock.NewController(t), activeFreezeWindows(t))
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/mdaihub-sample/var/data_string", bytes.NewBufferString(`{"data":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusLocked, rr.Code)
+	assert.Contains(t, rr.Body.String(), "hub mdaihub-sample is frozen until")
+	assert.Contains(t, rr.Body.String(), ": migration")
+}
+
+func TestHandleSetVariables_FrozenOverride(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	deps.Freezes, _ = newFreezeStore(t, gomock.NewController(t), activeFreezeWindows(t))
+	mux := NewRouter(t.Context(), deps)
+	records := auditRecords(deps.ValkeyClient.(*valkeymock.Client), 1) //nolint:forcetypeassert
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/mdaihub-sample/var/data_string", bytes.NewBufferString(`{"data":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.UserHeader, "oncall")
+	req.Header.Set(identity.ScopesHeader, freeze.OverrideScope)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	require.Len(t, *records, 1)
+	assert.Equal(t, "w1", auditField((*records)[0], "freeze_override"))
+	assert.Equal(t, "oncall", auditField((*records)[0], "freeze_override_by"))
+}
+
+func TestHandleFreezes(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	freezeClient := valkeymock.NewClient(gomock.NewController(t))
+	deps.Freezes = freeze.NewStore(freezeClient)
+	mux := NewRouter(t.Context(), deps)
+	records := auditRecords(deps.ValkeyClient.(*valkeymock.Client), 2) //nolint:forcetypeassert
+
+	var stored []string
+	freezeClient.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "HSET" })).
+		DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			stored = cmd.Commands()
+			return valkeymock.Result(valkeymock.ValkeyInt64(1))
+		})
+
+	body := `{"recurrence":{"weekdays":["sat","sun"],"start":"00:00","duration":"24h"},"reason":"weekend"}`
+	req := httptest.NewRequest(http.MethodPost, "/freezes/hub/mdaihub-sample", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.UserHeader, "alice")
+	req.Header.Set(identity.ScopesHeader, freeze.OverrideScope)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created freeze.Window
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "alice", created.CreatedBy)
+	require.Len(t, stored, 4)
+	assert.Equal(t, "freeze/windows/mdaihub-sample", stored[1])
+
+	freezeClient.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "freeze/windows/mdaihub-sample")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{created.ID: valkeymock.ValkeyBlobString(stored[3])})))
+
+	req = httptest.NewRequest(http.MethodGet, "/freezes/hub/mdaihub-sample", http.NoBody)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var listed []freeze.Window
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &listed))
+	assert.Equal(t, []freeze.Window{created}, listed)
+
+	freezeClient.EXPECT().Do(gomock.Any(), valkeymock.Match("HDEL", "freeze/windows/mdaihub-sample", created.ID)).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(1)))
+
+	req = httptest.NewRequest(http.MethodDelete, "/freezes/hub/mdaihub-sample/"+created.ID, http.NoBody)
+	req.Header.Set(identity.UserHeader, "bob")
+	req.Header.Set(identity.ScopesHeader, freeze.OverrideScope)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	// Both changes are audited with the window and the actor.
+	require.Len(t, *records, 2)
+	for i, want := range []struct{ action, actor string }{{freezeCreated, "alice"}, {freezeDeleted, "bob"}} {
+		assert.Equal(t, "freeze_changed", auditField((*records)[i], "type"))
+		assert.Equal(t, want.action, auditField((*records)[i], "action"))
+		assert.Equal(t, created.ID, auditField((*records)[i], "freeze_id"))
+		assert.Equal(t, "mdaihub-sample", auditField((*records)[i], "hub_name"))
+		assert.Equal(t, want.actor, auditField((*records)[i], "actor_user"))
+	}
+	assert.Equal(t, "weekend", auditField((*records)[0], "reason"))
+}
+
+func TestHandleFreezes_Invalid(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	records := expectRejectionAudits(t, deps)
+
+	window := `{"start":"2026-12-24T00:00:00Z","end":"2026-12-27T00:00:00Z"}`
+	tests := []struct {
+		method string
+		target string
+		user   string
+		scopes string
+		body   string
+		want   int
+	}{
+		{method: http.MethodPost, target: "/freezes/hub/mdaihub-sample", scopes: freeze.OverrideScope, body: window, want: http.StatusUnauthorized},
+		{method: http.MethodPost, target: "/freezes/hub/mdaihub-sample", user: "mallory", body: window, want: http.StatusForbidden},
+		{method: http.MethodDelete, target: "/freezes/hub/mdaihub-sample/w1", user: "mallory", want: http.StatusForbidden},
+		{method: http.MethodPost, target: "/freezes/hub/mdaihub-sample", user: "alice", scopes: freeze.OverrideScope, body: `{"start":"2026-12-24T00:00:00Z"}`, want: http.StatusBadRequest},
+		{method: http.MethodPost, target: "/freezes/hub/mdaihub-sample", user: "alice", scopes: freeze.OverrideScope, body: `not json`, want: http.StatusBadRequest},
+		{method: http.MethodPost, target: "/freezes/hub/unknown", user: "alice", scopes: freeze.OverrideScope, body: window, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(identity.UserHeader, tt.user)
+		req.Header.Set(identity.ScopesHeader, tt.scopes)
+		rr := httptest.NewRecorder()
+
+		mux.ServeHTTP(rr, req)
+
+		assert.Equal(t, tt.want, rr.Code, tt.method+" "+tt.target+" "+tt.body)
+	}
+	require.Len(t, *records, len(tests))
+	assert.Equal(t, "w1", auditField((*records)[2], "freeze_id"))
+}
+
+func TestAlerts_HeldWhileFrozen(t *testing.T) {
+	cm := manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_string": "string"})
+	cm.Annotations = map[string]string{manualvariables.FreezeAlertsAnnotation: "hold"}
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, cm))
+	var freezeClient *valkeymock.Client
+	deps.Freezes, freezeClient = newFreezeStore(t, gomock.NewController(t), activeFreezeWindows(t))
+	mux := NewRouter(t.Context(), deps)
+
+	var held []string
+	freezeClient.EXPECT().DoMulti(gomock.Any(),
+		valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "RPUSH" && cmd[1] == "freeze/held/mdaihub-sample" }),
+		valkeymock.Match("SADD", "freeze/held", "mdaihub-sample"),
+	).DoAndReturn(func(_ any, cmds ...valkey.Completed) []valkey.ValkeyResult {
+		held = append(held, cmds[0].Commands()[2])
+		return []valkey.ValkeyResult{
+			valkeymock.Result(valkeymock.ValkeyInt64(1)),
+			valkeymock.Result(valkeymock.ValkeyInt64(1)),
+		}
+	}).Times(2)
+
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBuffer(readPayloadFromFile(t, alert3)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"message":"Processed Prometheus alerts","total":2,"successful":0,"skipped":0,"held":2,"results":[
+		{"fingerprint":"fp-service-a-v2-1","hubName":"mdaihub-sample","status":"held","changeTime":"2018-08-03T10:07:00+02:00"},
+		{"fingerprint":"fp-service-a-v2-2","hubName":"mdaihub-sample","status":"held","changeTime":"2018-08-03T10:08:00+02:00"}]}`, rr.Body.String())
+	require.Len(t, held, 2)
+
+	// Once the freeze is over the held events are published with their original audit fields.
+	releaseClient := valkeymock.NewClient(gomock.NewController(t))
+	deps.Freezes = freeze.NewStore(releaseClient)
+	releaseClient.EXPECT().Do(gomock.Any(), valkeymock.Match("SMEMBERS", "freeze/held")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyBlobString("mdaihub-sample"))))
+	releaseClient.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "freeze/windows/mdaihub-sample")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{})))
+	evalsha := valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "EVALSHA" })
+	gomock.InOrder(
+		releaseClient.EXPECT().Do(gomock.Any(), evalsha).Return(valkeymock.Result(valkeymock.ValkeyBlobString(held[0]))),
+		releaseClient.EXPECT().Do(gomock.Any(), evalsha).Return(valkeymock.Result(valkeymock.ValkeyBlobString(held[1]))),
+		releaseClient.EXPECT().Do(gomock.Any(), evalsha).Return(valkeymock.Result(valkeymock.ValkeyNil())),
+	)
+	records := auditRecords(deps.ValkeyClient.(*valkeymock.Client), 2) //nolint:forcetypeassert
+
+	releaseHeldEvents(t.Context(), deps)
+
+	require.Len(t, *records, 2)
+	for _, record := range *records {
+		assert.Equal(t, "w1", auditField(record, "held_by_freeze"))
+		assert.Equal(t, "true", auditField(record, "publish_success"))
+	}
+}
@@ -6,22 +6,30 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
-	"github.com/decisiveai/mdai-data-core/audit"
 	"github.com/decisiveai/mdai-data-core/eventing"
 	"github.com/decisiveai/mdai-data-core/eventing/config"
-	"github.com/decisiveai/mdai-data-core/eventing/publisher"
+	datacorekube "github.com/decisiveai/mdai-data-core/kube"
 	datacore "github.com/decisiveai/mdai-data-core/variables"
 	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
 	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
 	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
-	"github.com/decisiveai/mdai-gateway/internal/nats"
+	"github.com/decisiveai/mdai-gateway/internal/proposals"
+	"github.com/decisiveai/mdai-gateway/internal/redact"
 	"github.com/decisiveai/mdai-gateway/internal/stringutil"
 	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/decisiveai/mdai-gateway/internal/webhooks"
 	"github.com/prometheus/alertmanager/notify/webhook"
 	"github.com/prometheus/alertmanager/template"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func handleListAllVariables(_ context.Context, deps HandlerDeps) http.HandlerFunc {
@@ -85,12 +93,7 @@ func handleGetVariables(ctx context.Context, deps HandlerDeps) http.HandlerFunc
 
 		varType, err := manualvariables.GetVarType(hubName, varName, hubsVariables)
 		if err != nil {
-			status := http.StatusInternalServerError
-			var httpErr manualvariables.HTTPError
-			if errors.As(err, &httpErr) {
-				status = httpErr.HTTPStatus()
-			}
-			httputil.WriteJSONResponse(w, deps.Logger, status, err.Error())
+			writeVarTypeError(w, deps.Logger, err)
 			return
 		}
 
@@ -128,12 +131,7 @@ func handleSetDeleteVariables(ctx context.Context, deps HandlerDeps) http.Handle
 
 		varType, err := manualvariables.GetVarType(hubName, varName, hubsVariables)
 		if err != nil {
-			status := http.StatusInternalServerError
-			var httpErr manualvariables.HTTPError
-			if errors.As(err, &httpErr) {
-				status = httpErr.HTTPStatus()
-			}
-			httputil.WriteJSONResponse(w, deps.Logger, status, err.Error())
+			writeVarTypeError(w, deps.Logger, err)
 			return
 		}
 
@@ -148,6 +146,27 @@ func handleSetDeleteVariables(ctx context.Context, deps HandlerDeps) http.Handle
 			return
 		}
 
+		metadata, err := manualvariables.ChangeMetadataFromRaw(raw)
+		if err != nil {
+			http.Error(w, "Invalid request payload: "+stringutil.UpperFirst(err.Error()), http.StatusBadRequest)
+			return
+		}
+		policy, err := hubPolicy(deps, hubName)
+		if err != nil {
+			writePolicyError(w, deps.Logger, err)
+			return
+		}
+		if err := policy.CheckMetadata(varName, metadata); err != nil {
+			http.Error(w, "Invalid request payload: "+stringutil.UpperFirst(err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		freezeAuditFields, err := checkFreeze(ctx, deps, r, hubName)
+		if err != nil {
+			writeFreezeError(w, deps.Logger, err)
+			return
+		}
+
 		command := valkey.CommandAdd
 		if r.Method == http.MethodDelete {
 			command = valkey.CommandDel
@@ -165,22 +184,35 @@ func handleSetDeleteVariables(ctx context.Context, deps HandlerDeps) http.Handle
 			return
 		}
 
-		event, err := eventing.NewMdaiEvent(hubName, varName, string(varType), string(command), payload)
+		if policy.IsProtected(varName) {
+			handleProposeChange(ctx, deps, w, r, proposals.Proposal{
+				HubName:  hubName,
+				VarName:  varName,
+				VarType:  varType,
+				Command:  command,
+				Data:     payload,
+				Metadata: metadata,
+			})
+			return
+		}
+
+		eventPerSubject, err := newVariableEvent(hubName, varName, varType, command, payload, metadata)
 		if err != nil {
 			http.Error(w, "Invalid request payload", http.StatusBadRequest)
 			return
 		}
-
-		subject := subjectFromVarsEvent(*event, varName)
+		maps.Copy(eventPerSubject.AuditFields, freezeAuditFields)
+		maps.Copy(eventPerSubject.AuditFields, identity.ActorFromRequest(r).AuditFields())
+		event := eventPerSubject.Event
 
 		deps.Logger.Info("Publishing MdaiEvent",
 			zap.String("id", event.ID),
 			zap.String("name", event.Name),
 			zap.String("source", event.Source),
-			zap.String("subject", subject.String()),
+			zap.String("subject", eventPerSubject.Subject.String()),
 		)
 
-		if _, err := nats.PublishEvents(ctx, deps.Logger, deps.EventPublisher, []adapter.EventPerSubject{{Event: *event, Subject: subject}}, deps.AuditAdapter); err != nil {
+		if _, err := publishEvents(ctx, deps, []adapter.EventPerSubject{eventPerSubject}); err != nil {
 			deps.Logger.Error("Failed to publish MdaiEvent", zap.Error(err))
 			http.Error(w, fmt.Sprintf("Failed to publish event: %v", err), http.StatusInternalServerError)
 			return
@@ -195,8 +227,15 @@ func handleSetDeleteVariables(ctx context.Context, deps HandlerDeps) http.Handle
 	}
 }
 
+// auditSuccessorLink points GET /audit callers to the filtered, paginated GET /v2/audit.
+const auditSuccessorLink = `</v2/audit>; rel="successor-version"`
+
+// handleAuditEventsGet returns the whole audit history, newest first. It ignores query
+// parameters; GET /v2/audit (handleAuditRecordsGet) replaces it for filtering and paging, and the
+// Link header says so.
 func handleAuditEventsGet(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Link", auditSuccessorLink)
 		eventsMap, err := deps.AuditAdapter.HandleEventsGet(ctx)
 		if err != nil {
 			deps.Logger.Error("failed to get events", zap.Error(err))
@@ -208,6 +247,25 @@ func handleAuditEventsGet(ctx context.Context, deps HandlerDeps) http.HandlerFun
 	}
 }
 
+// handleAuditRecordsGet returns a page of the audit records matching the query parameters,
+// always as {records, nextCursor}.
+func handleAuditRecordsGet(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := auditutils.QueryFromValues(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := query.Run(ctx, deps.ValkeyClient)
+		if err != nil {
+			deps.Logger.Error("failed to query events", zap.Error(err))
+			http.Error(w, "Unable to fetch history from Valkey", http.StatusInternalServerError)
+			return
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, page)
+	}
+}
+
 func handlePromAlertsPost(deps HandlerDeps) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const maxBody = 10 << 20 // 10 MiB, TODO make this configurable
@@ -234,43 +292,375 @@ func handlePromAlertsPost(deps HandlerDeps) http.HandlerFunc {
 			return
 		}
 
-		deps.Logger.Debug("Received /alerts/alertmanager POST", zap.Any("msg", msg))
+		if ce := deps.Logger.Check(zap.DebugLevel, "Received /alerts/alertmanager POST"); ce != nil {
+			ce.Write(zap.Any("msg", redactAlertMessage(deps, msg)))
+		}
+
+		handlePrometheusAlerts(r.Context(), deps, w, *msg.Data, identity.ActorFromRequest(r))
+	}
+}
+
+// handleGrafanaAlertsPost receives notifications of the Grafana unified alerting webhook contact
+// point.
+func handleGrafanaAlertsPost(deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const maxBody = 10 << 20 // 10 MiB
+		r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+		defer r.Body.Close() //nolint:errcheck
+
+		// Unknown fields are allowed: Grafana adds fields to the payload over time.
+		var msg adapter.GrafanaMessage
+		dec := json.NewDecoder(r.Body)
+		if err := dec.Decode(&msg); err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				http.Error(w, "request body too large (max 10MiB)", http.StatusRequestEntityTooLarge)
+				return
+			}
+			deps.Logger.Error("Failed to decode Grafana JSON", zap.Error(err))
+			http.Error(w, "invalid Grafana payload", http.StatusBadRequest)
+			return
+		}
+		if err := dec.Decode(&struct{}{}); err != io.EOF {
+			http.Error(w, "request must contain a single JSON object", http.StatusBadRequest)
+			return
+		}
+
+		wrapped := adapter.NewGrafanaAlertWrapper(msg, deps.Logger, deps.Deduper)
+		if ce := deps.Logger.Check(zap.DebugLevel, "Received /alerts/grafana POST"); ce != nil {
+			ce.Write(zap.Any("msg", redactAlertMessage(deps, webhook.Message{Data: wrapped.Data})))
+		}
+		deps.Logger.Debug("Processing Grafana alert",
+			zap.String("receiver", msg.Receiver),
+			zap.String("status", msg.Status),
+			zap.Int("alertCount", len(msg.Alerts)))
+
+		handleAlerts(r.Context(), deps, w, grafanaAlerts, wrapped.PromAlertWrapper, identity.ActorFromRequest(r))
+	}
+}
+
+// handleWebhookPost receives the JSON webhooks of the generic sources declared in the webhook
+// source ConfigMaps and maps them to alerts.
+func handleWebhookPost(deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("source")
+		var source *webhooks.Source
+		if deps.Webhooks != nil {
+			source, _ = deps.Webhooks.Get(name)
+		}
+		if source == nil {
+			http.Error(w, fmt.Sprintf("unknown webhook source %q", name), http.StatusNotFound)
+			return
+		}
+
+		const maxBody = 10 << 20 // 10 MiB
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		_ = r.Body.Close()
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				http.Error(w, "request body too large (max 10MiB)", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		sender := alertSender{alerts: name + " alerts", payload: name + " payload"}
+		items, err := source.Alerts(body, time.Now())
+		if err != nil {
+			deps.Logger.Error("Failed to map webhook payload", zap.String("source", name), zap.Error(err))
+			http.Error(w, "invalid "+sender.payload+": "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		deps.Logger.Debug("Processing webhook", zap.String("source", name), zap.Int("alertCount", len(items)))
+
+		wrapped := adapter.NewGenericAlertWrapper(name, items, deps.Logger, deps.Deduper)
+		handleAlerts(r.Context(), deps, w, sender, wrapped.PromAlertWrapper, identity.ActorFromRequest(r))
+	}
+}
 
-		handlePrometheusAlerts(r.Context(), deps.Logger, w, *msg.Data, deps.EventPublisher, deps.AuditAdapter, deps.Deduper)
+// handleAlertDeduperStats reports the state of the alert deduper.
+func handleAlertDeduperStats(_ context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := deps.Deduper.Stats(r.Context())
+		if err != nil {
+			deps.Logger.Error("Failed to read alert deduper stats", zap.Error(err))
+			http.Error(w, "Failed to read alert deduper stats", http.StatusInternalServerError)
+			return
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, stats)
 	}
 }
 
-// Handle Prometheus Alertmanager alerts.
-func handlePrometheusAlerts(ctx context.Context, logger *zap.Logger, w http.ResponseWriter, alertData template.Data, p publisher.Publisher, auditAdapter *audit.AuditAdapter, deduper *adapter.Deduper) {
-	logger.Debug("Processing Prometheus alert",
+// Handle Prometheus Alertmanager alerts. The audit records of the resulting events are attributed
+// to actor.
+func handlePrometheusAlerts(ctx context.Context, deps HandlerDeps, w http.ResponseWriter, alertData template.Data, actor identity.Actor) {
+	deps.Logger.Debug("Processing Prometheus alert",
 		zap.String("receiver", alertData.Receiver),
 		zap.String("status", alertData.Status),
 		zap.Int("alertCount", len(alertData.Alerts)))
 
-	wrappedAlertData := adapter.NewPromAlertWrapper(alertData, logger, deduper)
-	eventPerSubjects, skipped, err := wrappedAlertData.ToMdaiEvents()
+	handleAlerts(ctx, deps, w, prometheusAlerts, adapter.NewPromAlertWrapper(alertData, deps.Logger, deps.Deduper), actor)
+}
+
+// alertSender names a sender of alert notifications in responses.
+type alertSender struct {
+	alerts  string // e.g. "Prometheus alerts"
+	payload string // e.g. "Alertmanager payload"
+}
+
+var (
+	prometheusAlerts = alertSender{alerts: "Prometheus alerts", payload: "Alertmanager payload"}
+	grafanaAlerts    = alertSender{alerts: "Grafana alerts", payload: "Grafana payload"}
+)
+
+// handleAlerts processes each alert of a notification on its own and reports the outcome of
+// every alert. The status asks the sender to retry only when that can help: 503 when an alert
+// could not be published, 400 when no alert was valid and 201 otherwise.
+func handleAlerts(ctx context.Context, deps HandlerDeps, w http.ResponseWriter, sender alertSender, wrappedAlertData *adapter.PromAlertWrapper, actor identity.Actor) {
+	response, status, err := processAlerts(ctx, deps, sender, wrappedAlertData, actor)
 	if err != nil {
-		logger.Error("Failed to adapt Prometheus Alert to MDAI Events", zap.Error(err))
-		http.Error(w, "Failed to adapt Prometheus Alert to MDAI Events", http.StatusInternalServerError)
+		http.Error(w, "Failed to adapt "+sender.alerts+" to MDAI Events", http.StatusInternalServerError)
 		return
 	}
+	httputil.WriteJSONResponse(w, deps.Logger, status, response)
+}
+
+// processAlerts is handleAlerts without writing the response. It only fails when the alerts
+// cannot be adapted at all.
+func processAlerts(ctx context.Context, deps HandlerDeps, sender alertSender, wrappedAlertData *adapter.PromAlertWrapper, actor identity.Actor) (httputil.PrometheusAlertResponse, int, error) {
+	logger := deps.Logger
+	hubConfigs := make(map[string]adapter.HubConfig)
+	wrappedAlertData.HubConfig = func(hubName string) adapter.HubConfig {
+		config, ok := hubConfigs[hubName]
+		if !ok {
+			config = hubAlertConfig(deps, hubName)
+			hubConfigs[hubName] = config
+		}
+		return config
+	}
+	eventPerSubjects, skipped, err := wrappedAlertData.ToMdaiEvents(ctx)
+	if err != nil {
+		logger.Error("Failed to adapt "+sender.alerts+" to MDAI Events", zap.Error(err))
+		return httputil.PrometheusAlertResponse{}, 0, err
+	}
+	recordSkippedAlerts(ctx, deps, wrappedAlertData.Skipped(), actor)
+
+	for i := range eventPerSubjects {
+		if eventPerSubjects[i].AuditFields == nil {
+			eventPerSubjects[i].AuditFields = make(map[string]string)
+		}
+		maps.Copy(eventPerSubjects[i].AuditFields, actor.AuditFields())
+	}
+	toPublish, held := holdFrozenAlertEvents(ctx, deps, eventPerSubjects)
 
-	successCount, err := nats.PublishEvents(ctx, logger, p, eventPerSubjects, auditAdapter)
+	published := make(map[string]error, len(toPublish))
+	for i, err := range publishEachEvent(ctx, deps, toPublish) {
+		published[toPublish[i].Event.ID] = err
+	}
+
+	results := wrappedAlertData.Results()
+	response := httputil.PrometheusAlertResponse{
+		Message: "Processed " + sender.alerts,
+		Total:   len(wrappedAlertData.Alerts),
+		Skipped: skipped,
+		Held:    held,
+		Results: results,
+	}
+	var invalidReasons []string
+	for i := range results {
+		result := &results[i]
+		if result.Status == adapter.AlertFailed {
+			response.Failed++
+		}
+		if result.Status == adapter.AlertInvalid {
+			response.Invalid++
+			if !slices.Contains(invalidReasons, result.Reason) {
+				invalidReasons = append(invalidReasons, result.Reason)
+			}
+		}
+		if result.EventID == "" {
+			continue
+		}
+
+		// Events that were not published were held because their hub is frozen.
+		err, attempted := published[result.EventID]
+		switch {
+		case !attempted:
+			result.Status = adapter.AlertHeld
+		case err != nil:
+			result.Status, result.Reason = adapter.AlertFailed, err.Error()
+			response.Failed++
+			// Let the retry of the alert through the deduper.
+			if err := deps.Deduper.Forget(ctx, result.Fingerprint, result.ChangeTime); err != nil {
+				logger.Error("Failed to forget unpublished alert", zap.String("fingerprint", result.Fingerprint), zap.Error(err))
+			}
+		default:
+			result.Status = adapter.AlertPublished
+			response.Successful++
+		}
+	}
+
+	recordAlertStates(ctx, deps, wrappedAlertData, results)
+
+	status := http.StatusCreated
 	switch {
-	case err != nil:
-		w.WriteHeader(http.StatusAccepted)
-		_, _ = fmt.Fprintf(w, "Published %d/%d eventPerSubjects; some failed", successCount, len(eventPerSubjects))
-		return
-	default:
-		response := httputil.PrometheusAlertResponse{
-			Message:    "Processed Prometheus alerts",
-			Total:      len(alertData.Alerts),
-			Successful: successCount,
-			Skipped:    skipped,
+	case response.Failed > 0:
+		response.Message = "Some " + sender.alerts + " could not be published"
+		status = http.StatusServiceUnavailable
+	case response.Total > 0 && response.Invalid == response.Total:
+		response.Message = "invalid " + sender.payload + ": " + strings.Join(invalidReasons, "; ")
+		status = http.StatusBadRequest
+	}
+	return response, status, nil
+}
+
+// hubAlertConfig returns the alert settings of a hub. Its declared variables are those of its
+// MdaiHub resource; they are unknown when the resource is not known.
+func hubAlertConfig(deps HandlerDeps, hubName string) adapter.HubConfig {
+	policy, err := hubPolicy(deps, hubName)
+	if err != nil {
+		deps.Logger.Error("Failed to read hub policy, failing its alerts", zap.String("hubName", hubName), zap.Error(err))
+		return adapter.HubConfig{Err: err}
+	}
+	config := adapter.HubConfig{
+		ComputeFingerprint: policy.ComputeAlertFingerprints,
+		InvalidAnnotations: adapter.AnnotationsFlag,
+	}
+	if policy.RejectInvalidAlertAnnotations {
+		config.InvalidAnnotations = adapter.AnnotationsReject
+	}
+
+	if deps.Hubs != nil {
+		if variables, ok := deps.Hubs.Variables(hubName); ok {
+			config.Variables = variables
+		}
+	}
+	return config
+}
+
+// recordSkippedAlerts writes an alert_skipped audit record for each stale alert.
+func recordSkippedAlerts(ctx context.Context, deps HandlerDeps, skipped []adapter.SkippedAlert, actor identity.Actor) {
+	for _, alert := range skipped {
+		fields := map[string]string{
+			"reason":      "stale",
+			"fingerprint": alert.Fingerprint,
+			"alert_name":  alert.AlertName,
+			"hub_name":    alert.HubName,
+			"status":      alert.Status,
+			"change_time": alert.ChangeTime.UTC().Format(time.RFC3339),
+			"last_update": alert.LastUpdate.UTC().Format(time.RFC3339),
+		}
+		maps.Copy(fields, actor.AuditFields())
+		if err := auditutils.RecordAuditRecord(ctx, deps.Logger, deps.AuditInserter, auditutils.AlertSkippedType, fields); err != nil {
+			deps.Logger.Error("Failed to audit skipped alert", zap.String("fingerprint", alert.Fingerprint), zap.Error(err))
 		}
+	}
+}
+
+// variablesActionPayload is the payload of manual variable events: the data-core payload plus
+// the optional metadata describing why the change was made.
+type variablesActionPayload struct {
+	eventing.VariablesActionPayload
+	Metadata *manualvariables.ChangeMetadata `json:"metadata,omitempty"`
+}
+
+// newVariableEvent builds the event for a single manual variable change together with its subject.
+// Non-empty metadata is carried in the payload and in the audit record of the event.
+func newVariableEvent(hubName, varName string, varType valkey.VariableType, command valkey.CommandType, data any, metadata manualvariables.ChangeMetadata) (adapter.EventPerSubject, error) {
+	payload := variablesActionPayload{
+		VariablesActionPayload: eventing.VariablesActionPayload{
+			VariableRef: varName,
+			DataType:    string(varType),
+			Operation:   string(command),
+			Data:        data,
+		},
+	}
+	if !metadata.IsZero() {
+		payload.Metadata = &metadata
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return adapter.EventPerSubject{}, err
+	}
+
+	event := eventing.MdaiEvent{
+		Name:    "var." + string(command),
+		HubName: hubName,
+		Source:  eventing.ManualVariablesEventSource,
+		Payload: string(payloadBytes),
+	}
+	event.ApplyDefaults()
+
+	return adapter.EventPerSubject{
+		Event:       event,
+		Subject:     subjectFromVarsEvent(event, varName),
+		AuditFields: metadata.AuditFields(),
+	}, nil
+}
+
+// hubPolicy returns the policy configured on the hub's manual variables ConfigMap. A hub without
+// one has the unrestricted default policy. It fails when the ConfigMap cannot be read or its
+// redaction rules are invalid: the defaults would skip approvals and redaction.
+func hubPolicy(deps HandlerDeps, hubName string) (manualvariables.Policy, error) {
+	cm, found, err := hubConfigMap(deps.ConfigMapController, hubName)
+	if err != nil {
+		return manualvariables.Policy{}, fmt.Errorf("read policy of hub %s: %w", hubName, err)
+	}
+	if !found {
+		return manualvariables.Policy{}, nil
+	}
+	policy := manualvariables.PolicyFromAnnotations(cm.Annotations)
+	if policy.RedactionErr != nil {
+		return manualvariables.Policy{}, fmt.Errorf("read policy of hub %s: %w", hubName, policy.RedactionErr)
+	}
+	return policy, nil
+}
+
+// hubAuditRedaction returns the audit redaction of a hub, masking every value when the hub's
+// policy cannot be read.
+func hubAuditRedaction(deps HandlerDeps, hubName string) redact.Policy {
+	policy, err := hubPolicy(deps, hubName)
+	if err != nil {
+		deps.Logger.Error("Failed to read hub policy, masking every value", zap.String("hubName", hubName), zap.Error(err))
+		return redact.MaskAll
+	}
+	return policy.AuditRedaction
+}
+
+// writePolicyError writes the response for an error returned by hubPolicy.
+func writePolicyError(w http.ResponseWriter, logger *zap.Logger, err error) {
+	logger.Error("Failed to read hub policy", zap.Error(err))
+	httputil.WriteJSONResponse(w, logger, http.StatusInternalServerError, "failed to read hub policy")
+}
+
+// hubConfigMap returns the ConfigMap of a hub watched by controller. found is false when the hub
+// has none.
+func hubConfigMap(controller *datacorekube.ConfigMapController, hubName string) (*corev1.ConfigMap, bool, error) {
+	objs, err := controller.CmInformer.Informer().GetIndexer().ByIndex(datacorekube.ByHub, hubName)
+	if err != nil {
+		return nil, false, fmt.Errorf("getting hub by index: %w", err)
+	}
+	if len(objs) == 0 {
+		return nil, false, nil
+	}
+	cm, err := controller.GetConfigMapByHubName(hubName)
+	if err != nil {
+		return nil, false, err
+	}
+	return cm, true, nil
+}
 
-		httputil.WriteJSONResponse(w, logger, http.StatusCreated, response)
+// writeVarTypeError writes the response for an error returned by manualvariables.GetVarType.
+func writeVarTypeError(w http.ResponseWriter, logger *zap.Logger, err error) {
+	status := http.StatusInternalServerError
+	var httpErr manualvariables.HTTPError
+	if errors.As(err, &httpErr) {
+		status = httpErr.HTTPStatus()
 	}
+	httputil.WriteJSONResponse(w, logger, status, err.Error())
 }
 
 // subjectFromAlert creates a subject from a mdai event and variable key. Prefix has to be added later at eventing package.
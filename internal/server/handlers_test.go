@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -15,12 +16,20 @@ import (
 	"github.com/decisiveai/mdai-data-core/audit"
 	"github.com/decisiveai/mdai-data-core/eventing"
 	datacorekube "github.com/decisiveai/mdai-data-core/kube"
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
 	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/redact"
+	"github.com/decisiveai/mdai-gateway/internal/webhooks"
+	"github.com/decisiveai/mdai-gateway/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/valkey-io/valkey-go"
 	valkeymock "github.com/valkey-io/valkey-go/mock"
 	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -31,6 +40,9 @@ const (
 	alert3              = "../../testdata/alert_post_body_3.json"
 	alert2              = "../../testdata/alert_post_body_2.json"
 	alert1              = "../../testdata/alert_post_body_1.json"
+	grafanaFiring       = "../../testdata/grafana_alert_firing.json"
+	// alertComputedVariable updates service_list, a computed variable of mdaihub-sample.
+	alertComputedVariable = "../../testdata/alert_test.json"
 )
 
 func TestGetConfiguredManualVariables(t *testing.T) {
@@ -551,6 +563,7 @@ func TestHandleSetVariables_InvalidRequestPayload(t *testing.T) {
 	clientset := newFakeClientset(t)
 	deps := setupMocks(t, clientset)
 	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
 
 	for _, tt := range setTests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -618,6 +631,7 @@ func TestHandleDeleteVariables_InvalidRequestPayload(t *testing.T) {
 	clientset := newFakeClientset(t)
 	deps := setupMocks(t, clientset)
 	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
 
 	for _, tt := range setTests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -684,10 +698,20 @@ func readPayloadFromFile(t *testing.T, fileName string) []byte {
 
 func TestUpdateEventsHandler(t *testing.T) {
 	const (
-		post1Response = `{"message":"Processed Prometheus alerts", "skipped":0, "successful":3, "total":3}` + "\n"
-		post2Response = `{"message":"Processed Prometheus alerts", "skipped":0, "successful":3, "total":3}` + "\n"
-		post3Response = `{"message":"Processed Prometheus alerts", "skipped":0, "successful":2, "total":2}` + "\n"
-		post4Response = `{"message":"Processed Prometheus alerts", "skipped":2, "successful":0, "total":2}` + "\n"
+		post1Response = `{"message":"Processed Prometheus alerts", "skipped":0, "successful":3, "total":3, "results":[
+			{"fingerprint":"fp-service-a-1", "alertName":"logBytesOutTooHighBySvc", "hubName":"mdaihub-sample", "status":"published", "changeTime":"2025-08-03T10:02:26.739266876+02:00"},
+			{"fingerprint":"fp-service-a-2", "alertName":"logBytesOutTooHighBySvc", "hubName":"mdaihub-sample", "status":"published", "changeTime":"2025-08-03T10:05:00+02:00"},
+			{"fingerprint":"fp-service-a-3", "alertName":"logBytesOutTooHighBySvc", "hubName":"mdaihub-sample", "status":"published", "changeTime":"2025-08-03T10:03:30+02:00"}]}` + "\n"
+		post2Response = `{"message":"Processed Prometheus alerts", "skipped":0, "successful":3, "total":3, "results":[
+			{"fingerprint":"fp-service-b-1", "hubName":"mdaihub-sample", "status":"published", "changeTime":"2018-08-03T10:01:00+02:00"},
+			{"fingerprint":"fp-service-b-2", "hubName":"mdaihub-sample", "status":"published", "changeTime":"2018-08-03T10:02:00+02:00"},
+			{"fingerprint":"fp-service-b-3", "hubName":"mdaihub-sample", "status":"published", "changeTime":"2018-08-03T10:03:30+02:00"}]}` + "\n"
+		post3Response = `{"message":"Processed Prometheus alerts", "skipped":0, "successful":2, "total":2, "results":[
+			{"fingerprint":"fp-service-a-v2-1", "hubName":"mdaihub-sample", "status":"published", "changeTime":"2018-08-03T10:07:00+02:00"},
+			{"fingerprint":"fp-service-a-v2-2", "hubName":"mdaihub-sample", "status":"published", "changeTime":"2018-08-03T10:08:00+02:00"}]}` + "\n"
+		post4Response = `{"message":"Processed Prometheus alerts", "skipped":2, "successful":0, "total":2, "results":[
+			{"fingerprint":"fp-service-a-v2-1", "hubName":"mdaihub-sample", "status":"skipped", "reason":"not newer than the state seen at 2018-08-03T10:07:00+02:00", "changeTime":"2018-08-03T10:07:00+02:00"},
+			{"fingerprint":"fp-service-a-v2-2", "hubName":"mdaihub-sample", "status":"skipped", "reason":"not newer than the state seen at 2018-08-03T10:08:00+02:00", "changeTime":"2018-08-03T10:08:00+02:00"}]}` + "\n"
 	)
 
 	alertPostBody1 := readPayloadFromFile(t, alert1)
@@ -732,6 +756,13 @@ func TestUpdateEventsHandler(t *testing.T) {
 	assert.JSONEq(t, post3Response, rr.Body.String())
 
 	// one more with skipped alerts
+	skippedAudits := 0
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		return cmd[0] == "XADD" && slices.Contains(cmd, auditutils.AlertSkippedType)
+	}, "XADD alert_skipped audit record")).DoAndReturn(func(_ any, _ valkey.Completed) valkey.ValkeyResult {
+		skippedAudits++
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).Times(2)
 	req = httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBuffer(alertPostBody3))
 	req.Header.Set("Content-Type", "application/json")
 
@@ -740,12 +771,303 @@ func TestUpdateEventsHandler(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, rr.Code)
 	assert.JSONEq(t, post4Response, rr.Body.String())
+	assert.Equal(t, 2, skippedAudits)
+}
+
+func TestAlerts_MixedBatch(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString(""))).Times(1)
+
+	body := `{"receiver":"mdai","status":"firing","alerts":[
+		{"status":"firing","fingerprint":"fp-ok","startsAt":"2025-08-03T10:00:00Z","annotations":{"alert_name":"DiskUsageHigh","hub_name":"mdaihub-sample"}},
+		{"status":"firing","startsAt":"2025-08-03T10:00:00Z","annotations":{"alert_name":"DiskUsageHigh","hub_name":"mdaihub-sample"}},
+		{"status":"firing","fingerprint":"fp-no-hub","startsAt":"2025-08-03T10:00:00Z","annotations":{"alert_name":"DiskUsageHigh"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"message":"Processed Prometheus alerts","total":3,"successful":1,"skipped":0,"invalid":2,"results":[
+		{"fingerprint":"fp-ok","alertName":"DiskUsageHigh","hubName":"mdaihub-sample","status":"published","changeTime":"2025-08-03T10:00:00Z"},
+		{"alertName":"DiskUsageHigh","hubName":"mdaihub-sample","status":"invalid","reason":"alert fingerprint is required","changeTime":"2025-08-03T10:00:00Z"},
+		{"fingerprint":"fp-no-hub","alertName":"DiskUsageHigh","status":"invalid","reason":"missing required field: hubName","changeTime":"2025-08-03T10:00:00Z"}]}`, rr.Body.String())
+
+	// Invalid alerts are not remembered by the deduper.
+	_, seen, err := deps.Deduper.PeekLast(t.Context(), "fp-no-hub")
+	require.NoError(t, err)
+	assert.False(t, seen)
+}
+
+func TestAlerts_ComputedFingerprint(t *testing.T) {
+	computing := manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_string": "string"})
+	computing.Annotations = map[string]string{manualvariables.AlertFingerprintAnnotation: "compute"}
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, computing, manualVariablesConfigMap("mdaihub-second", map[string]string{"data_string": "string"})))
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	var record []string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+		record = cmd.Commands()
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).Times(1)
+
+	body := `{"receiver":"vmalert","status":"firing","alerts":[
+		{"status":"firing","startsAt":"2025-08-03T10:00:00Z","labels":{"alertname":"DiskUsageHigh","severity":"critical"},"annotations":{"alert_name":"DiskUsageHigh","hub_name":"mdaihub-sample"}},
+		{"status":"firing","startsAt":"2025-08-03T10:00:00Z","labels":{"alertname":"DiskUsageHigh","severity":"critical"},"annotations":{"alert_name":"DiskUsageHigh","hub_name":"mdaihub-second"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"message":"Processed Prometheus alerts","total":2,"successful":1,"skipped":0,"invalid":1,"results":[
+		{"fingerprint":"27700f085fc1d2fc","fingerprintComputed":true,"alertName":"DiskUsageHigh","hubName":"mdaihub-sample","status":"published","changeTime":"2025-08-03T10:00:00Z"},
+		{"alertName":"DiskUsageHigh","hubName":"mdaihub-second","status":"invalid","reason":"alert fingerprint is required","changeTime":"2025-08-03T10:00:00Z"}]}`, rr.Body.String())
+	assert.Equal(t, "27700f085fc1d2fc", auditField(record, "sourceId"))
+	assert.Equal(t, "true", auditField(record, "fingerprint_computed"))
+	assert.Contains(t, auditField(record, "payload"), `"fingerprintComputed":true`)
+}
+
+func TestAlerts_InvalidAnnotations(t *testing.T) {
+	cm := manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_set": "set"})
+	cm.Annotations = map[string]string{manualvariables.AlertAnnotationsAnnotation: "reject"}
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, cm))
+	deps.Hubs = hubRegistry("mdaihub-sample", "data_set", "service_list")
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	var record []string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+		record = cmd.Commands()
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).Times(1)
+
+	body := `{"receiver":"mdai","status":"firing","alerts":[
+		{"status":"firing","fingerprint":"fp-declared","startsAt":"2025-08-03T10:00:00Z","annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample",
+			"action_context":"{\"firing\":{\"variableUpdate\":{\"variableRef\":\"data_set\",\"operation\":\"mdai/add_element\"}}}","relevant_labels":"[\"service_name\"]"}},
+		{"status":"firing","fingerprint":"fp-undeclared","startsAt":"2025-08-03T10:00:00Z","annotations":{"alert_name":"top_talkers","hub_name":"mdaihub-sample",
+			"action_context":"{\"firing\":{\"variableUpdate\":{\"variableRef\":\"services\",\"operation\":\"mdai/add_element\"}}}"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"message":"Processed Prometheus alerts","total":2,"successful":1,"skipped":0,"invalid":1,"results":[
+		{"fingerprint":"fp-declared","alertName":"top_talkers","hubName":"mdaihub-sample","status":"published","changeTime":"2025-08-03T10:00:00Z"},
+		{"fingerprint":"fp-undeclared","alertName":"top_talkers","hubName":"mdaihub-sample","status":"invalid","changeTime":"2025-08-03T10:00:00Z",
+		 "reason":"action_context.firing: variable \"services\" is not declared by the hub"}]}`, rr.Body.String())
+
+	var payload adapter.AlertPayload
+	require.NoError(t, json.Unmarshal([]byte(auditField(record, "payload")), &payload))
+	assert.Equal(t, adapter.ActionContext{
+		"firing": {VariableUpdate: &adapter.VariableUpdate{VariableRef: "data_set", Operation: "mdai/add_element"}},
+	}, payload.ActionContext)
+	assert.Equal(t, []string{"service_name"}, payload.RelevantLabels)
+}
+
+// The computed variables a hub declares are not in its manual variables ConfigMap, and the keys of
+// its hub-variables ConfigMap are the names the variables are exported as, not their keys.
+func TestAlerts_ComputedVariable(t *testing.T) {
+	cm := manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_set": "set"})
+	cm.Annotations = map[string]string{manualvariables.AlertAnnotationsAnnotation: "reject"}
+	hubVariables := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mdaihub-sample-variables",
+			Namespace: "mdai",
+			Labels: map[string]string{
+				datacorekube.ConfigMapTypeLabel: datacorekube.EnvConfigMapType,
+				datacorekube.LabelMdaiHubName:   "mdaihub-sample",
+			},
+		},
+		Data: map[string]string{"SERVICE_LIST_REGEX": "service-a|service-b"},
+	}
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, cm, hubVariables))
+	deps.Hubs = hubRegistry("mdaihub-sample", "data_set", "service_list")
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString(""))).Times(1)
+
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBuffer(readPayloadFromFile(t, alertComputedVariable)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"message":"Processed Prometheus alerts","total":1,"successful":1,"skipped":0,"results":[
+		{"fingerprint":"0a12f1ac0c0c75fe","alertName":"top_talkers","hubName":"mdaihub-sample","status":"published","changeTime":"2018-08-03T09:59:30.739266878+02:00"}]}`, rr.Body.String())
+}
+
+func TestAlerts_PublishFailure(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mockPub := &mocks.MockPublisher{}
+	mockPub.On("Publish", mock.Anything, mock.MatchedBy(func(e eventing.MdaiEvent) bool { return e.SourceID == "fp-service-a-v2-1" }), mock.Anything).
+		Return(errors.New("nats unavailable")).Once()
+	mockPub.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	deps.EventPublisher = mockPub
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.Result(valkeymock.ValkeyString(""))).AnyTimes()
+
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBuffer(readPayloadFromFile(t, alert3)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	// Alertmanager retries on 5xx.
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"message":"Some Prometheus alerts could not be published","total":2,"successful":1,"skipped":0,"failed":1,"results":[
+		{"fingerprint":"fp-service-a-v2-1","hubName":"mdaihub-sample","status":"failed","reason":"nats unavailable","changeTime":"2018-08-03T10:07:00+02:00"},
+		{"fingerprint":"fp-service-a-v2-2","hubName":"mdaihub-sample","status":"published","changeTime":"2018-08-03T10:08:00+02:00"}]}`, rr.Body.String())
+	mockPub.AssertExpectations(t)
+
+	// The failed alert is forgotten so that the retry is not skipped as stale.
+	_, seen, err := deps.Deduper.PeekLast(t.Context(), "fp-service-a-v2-1")
+	require.NoError(t, err)
+	assert.False(t, seen)
+	_, seen, err = deps.Deduper.PeekLast(t.Context(), "fp-service-a-v2-2")
+	require.NoError(t, err)
+	assert.True(t, seen)
+}
+
+func TestGrafanaAlerts(t *testing.T) {
+	cm := manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_set": "set"})
+	cm.Annotations = map[string]string{manualvariables.AuditRedactionAnnotation: "label/mdai_service=mask"}
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, cm))
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+	var records [][]string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+		records = append(records, cmd.Commands())
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).Times(2)
+
+	req := httptest.NewRequest(http.MethodPost, "/alerts/grafana", bytes.NewBuffer(readPayloadFromFile(t, grafanaFiring)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"message":"Processed Grafana alerts","total":2,"successful":2,"skipped":0,"results":[
+		{"fingerprint":"9c5d7f5ea1e7e0b2","alertName":"top_talkers","hubName":"mdaihub-sample","status":"published","changeTime":"2025-08-08T15:56:10Z"},
+		{"fingerprint":"1f3e8b7c66a0d4e9","alertName":"top_talkers","hubName":"mdaihub-sample","status":"published","changeTime":"2025-08-08T15:56:10Z"}]}`, rr.Body.String())
+
+	require.Len(t, records, 2)
+	assert.Equal(t, "grafana", auditField(records[0], "source"))
+	assert.Equal(t, "9c5d7f5ea1e7e0b2", auditField(records[0], "sourceId"))
+	var payload adapter.AlertPayload
+	require.NoError(t, json.Unmarshal([]byte(auditField(records[0], "payload")), &payload))
+	assert.Equal(t, map[string]float64{"A": 57296478.69, "C": 1}, payload.Values)
+	// The value string repeats the redacted label.
+	assert.Equal(t, redact.Masked, payload.Labels["mdai_service"])
+	assert.Equal(t, redact.Masked, payload.ValueString)
+
+	// Resending the notification skips every alert, like on the Alertmanager route.
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		return cmd[0] == "XADD" && slices.Contains(cmd, auditutils.AlertSkippedType)
+	})).Return(valkeymock.Result(valkeymock.ValkeyString(""))).Times(2)
+	req = httptest.NewRequest(http.MethodPost, "/alerts/grafana", bytes.NewBuffer(readPayloadFromFile(t, grafanaFiring)))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"skipped":2`)
+}
+
+func TestGrafanaAlerts_InvalidPayload(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	req := httptest.NewRequest(http.MethodPost, "/alerts/grafana", bytes.NewBufferString(`{"alerts": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "invalid Grafana payload\n", rr.Body.String())
+}
+
+func TestWebhooks(t *testing.T) {
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_set": "set"})))
+	deps.Webhooks = webhooks.NewRegistry(zap.NewNop())
+	mux := NewRouter(t.Context(), deps)
+	mockClient, ok := deps.ValkeyClient.(*valkeymock.Client)
+	require.True(t, ok)
+
+	// Unknown sources are rejected.
+	expectRejectionAudits(t, deps)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/incidents", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "unknown webhook source \"incidents\"\n", rr.Body.String())
+
+	deps.Webhooks.Load([]*corev1.ConfigMap{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "mdai", Name: "webhook-sources"},
+		Data: map[string]string{"incidents": `
+items: "{.incidents[*]}"
+fingerprint: "{.id}"
+hubName: "{.hub}"
+name: "{.title}"
+time: "{.updated_at}"
+payload:
+  count: "{.count}"
+`},
+	}})
+
+	var records [][]string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+		records = append(records, cmd.Commands())
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).AnyTimes()
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/incidents", bytes.NewBufferString(`{"incidents":[
+		{"id":"inc-1","hub":"mdaihub-sample","title":"error_rate","updated_at":"2025-08-08T15:56:10Z","count":3},
+		{"id":"inc-2","hub":"mdaihub-sample","title":"error_rate","updated_at":"yesterday"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var resp httputil.PrometheusAlertResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "Processed incidents alerts", resp.Message)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, adapter.AlertPublished, resp.Results[0].Status)
+	assert.Equal(t, adapter.AlertInvalid, resp.Results[1].Status)
+	assert.Contains(t, resp.Results[1].Reason, "time: ")
+
+	published := slices.IndexFunc(records, func(record []string) bool { return auditField(record, "sourceId") == "inc-1" })
+	require.NotEqual(t, -1, published)
+	assert.Equal(t, "webhook:incidents", auditField(records[published], "source"))
+	var payload adapter.AlertPayload
+	require.NoError(t, json.Unmarshal([]byte(auditField(records[published], "payload")), &payload))
+	assert.Equal(t, map[string]any{"count": float64(3)}, payload.Fields)
+
+	// A body that is not JSON is rejected as a whole.
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/incidents", bytes.NewBufferString(`{"incidents":`))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid incidents payload: invalid JSON")
 }
 
 func TestAlerts_Failuers(t *testing.T) {
 	clientset := newFakeClientset(t)
 	deps := setupMocks(t, clientset)
 	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
 
 	// Prometheus JSON fail
 	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBufferString(`{"receiver":"foo","alerts": true}`))
@@ -798,6 +1120,20 @@ func TestAlerts_NotAllowed(t *testing.T) {
 	}
 }
 
+func TestAlertDeduperStats(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	deps.Deduper = adapter.NewMemoryDeduper(time.Hour, 10)
+	_, _, _ = deps.Deduper.UpdateIfNewer(t.Context(), "fp", time.Now())
+	mux := NewRouter(t.Context(), deps)
+
+	req := httptest.NewRequest(http.MethodGet, "/alerts/deduper", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"backend":"memory","entries":1,"maxEntries":10,"ttl":"1h0m0s"}`, rr.Body.String())
+}
+
 func TestAudit_Success(t *testing.T) {
 	clientset := newFakeClientset(t)
 	deps := setupMocks(t, clientset)
@@ -819,16 +1155,67 @@ func TestAudit_Success(t *testing.T) {
 					}...),
 				}...),
 			),
-		).Times(1)
+		).Times(2)
 
 	req := httptest.NewRequest(http.MethodGet, "/audit", http.NoBody)
 	rr := httptest.NewRecorder()
 	mux.ServeHTTP(rr, req)
 
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `</v2/audit>; rel="successor-version"`, rr.Header().Get("Link"))
+	assert.JSONEq(t, `[{"type":"example_type","value":"{\"foo\":\"bar\"}"}]`+"\n", rr.Body.String())
+
+	// Query parameters do not change the shape; they belong to GET /v2/audit.
+	req = httptest.NewRequest(http.MethodGet, "/audit?hub_name=other", http.NoBody)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.JSONEq(t, `[{"type":"example_type","value":"{\"foo\":\"bar\"}"}]`+"\n", rr.Body.String())
 }
 
+func TestAudit_Query(t *testing.T) {
+	clientset := newFakeClientset(t)
+	deps := setupMocks(t, clientset)
+	mux := NewRouter(t.Context(), deps)
+
+	deps.ValkeyClient.(*valkeymock.Client).EXPECT(). //nolint:forcetypeassert
+								Do(gomock.Any(), valkeymock.Match("XRANGE", audit.MdaiHubEventHistoryStreamName, "-", "+", "COUNT", "200")).
+								Return(valkeymock.Result(valkeymock.ValkeyArray(
+			valkeymock.ValkeyArray(valkeymock.ValkeyString("1718920000000-0"), valkeymock.ValkeyArray(
+				valkeymock.ValkeyString("hub_name"), valkeymock.ValkeyString("mdaihub-sample"),
+			)),
+			valkeymock.ValkeyArray(valkeymock.ValkeyString("1718920000001-0"), valkeymock.ValkeyArray(
+				valkeymock.ValkeyString("hub_name"), valkeymock.ValkeyString("other"),
+			)),
+		))).Times(1)
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/audit?hub_name=mdaihub-sample&order=asc", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"records":[{"id":"1718920000000-0","fields":{"hub_name":"mdaihub-sample"}}]}`, rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/v2/audit?limit=5000", http.NoBody)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "limit must be between 1 and 1000\n", rr.Body.String())
+	// Without parameters, the first page of the newest records.
+	deps.ValkeyClient.(*valkeymock.Client).EXPECT(). //nolint:forcetypeassert
+								Do(gomock.Any(), valkeymock.Match("XREVRANGE", audit.MdaiHubEventHistoryStreamName, "+", "-", "COUNT", "200")).
+								Return(valkeymock.Result(valkeymock.ValkeyArray())).Times(1)
+
+	req = httptest.NewRequest(http.MethodGet, "/v2/audit", http.NoBody)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"records":[]}`, rr.Body.String())
+}
+
 func TestAudit_Fail(t *testing.T) {
 	clientset := newFakeClientset(t)
 	deps := setupMocks(t, clientset)
@@ -850,6 +1237,7 @@ func TestAlets_TrailingJSON(t *testing.T) {
 	clientset := newFakeClientset(t)
 	deps := setupMocks(t, clientset)
 	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
 
 	// trailing JSON after a valid object -> must be rejected
 	req := httptest.NewRequest(
@@ -870,6 +1258,7 @@ func TestAlerts_BodyTooLarge(t *testing.T) {
 	clientset := newFakeClientset(t)
 	deps := setupMocks(t, clientset)
 	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
 	tooBig := strings.Repeat("x", (10<<20)+1) // 10 MiB + 1 byte
 
 	oversizedAlert := map[string]any{
@@ -902,6 +1291,7 @@ func TestAlerts_WrongContentType(t *testing.T) {
 	clientset := newFakeClientset(t)
 	deps := setupMocks(t, clientset)
 	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
 
 	body := strings.NewReader(`{"status":"firing","alerts":[]}`)
 
@@ -4,21 +4,32 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/decisiveai/mdai-data-core/audit"
 	"github.com/decisiveai/mdai-data-core/eventing/publisher"
 	datacorekube "github.com/decisiveai/mdai-data-core/kube"
 	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/alertpush"
+	"github.com/decisiveai/mdai-gateway/internal/alertstate"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/freeze"
+	"github.com/decisiveai/mdai-gateway/internal/hubs"
 	"github.com/decisiveai/mdai-gateway/internal/opamp"
+	"github.com/decisiveai/mdai-gateway/internal/proposals"
 	natsserver "github.com/nats-io/nats-server/v2/server"
 	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
 	valkeymock "github.com/valkey-io/valkey-go/mock"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -29,28 +40,55 @@ import (
 func newFakeClientset(t *testing.T) kubernetes.Interface { //nolint:ireturn
 	t.Helper()
 
+	return newFakeClientsetWithHubs(t, manualVariablesConfigMap("mdaihub-sample", map[string]string{
+		"data_boolean": "boolean",
+		"data_map":     "map",
+		"data_set":     "set",
+		"data_string":  "string",
+		"data_int":     "int",
+	}))
+}
+
+func newFakeClientsetWithHubs(t *testing.T, configMaps ...*corev1.ConfigMap) kubernetes.Interface { //nolint:ireturn
+	t.Helper()
+
 	scheme := runtime.NewScheme()
 	_ = corev1.AddToScheme(scheme)
 
-	configMap := corev1.ConfigMap{
+	objects := make([]runtime.Object, 0, len(configMaps))
+	for _, cm := range configMaps {
+		objects = append(objects, cm)
+	}
+
+	return fake.NewClientset(objects...)
+}
+
+func manualVariablesConfigMap(hubName string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "mdaihub-sample-manual-variables",
+			Name:      hubName + "-manual-variables",
 			Namespace: "mdai",
 			Labels: map[string]string{
 				datacorekube.ConfigMapTypeLabel: datacorekube.ManualEnvConfigMapType,
-				datacorekube.LabelMdaiHubName:   "mdaihub-sample",
+				datacorekube.LabelMdaiHubName:   hubName,
 			},
 		},
-		Data: map[string]string{
-			"data_boolean": "boolean",
-			"data_map":     "map",
-			"data_set":     "set",
-			"data_string":  "string",
-			"data_int":     "int",
-		},
+		Data: data,
 	}
+}
 
-	return fake.NewClientset(&configMap)
+// hubRegistry returns a registry of a single MdaiHub declaring the variables keys.
+func hubRegistry(hubName string, keys ...string) *hubs.Registry {
+	variables := make([]any, 0, len(keys))
+	for _, key := range keys {
+		variables = append(variables, map[string]any{"key": key})
+	}
+	registry := hubs.NewRegistry(zap.NewNop())
+	registry.Load([]*unstructured.Unstructured{{Object: map[string]any{
+		"metadata": map[string]any{"namespace": "mdai", "name": hubName},
+		"spec":     map[string]any{"variables": variables},
+	}}})
+	return registry
 }
 
 func newFakeConfigMapController(t *testing.T, clientset kubernetes.Interface, namespace string) (*datacorekube.ConfigMapController, error) {
@@ -177,10 +215,69 @@ func setupMocks(t *testing.T, clientset kubernetes.Interface) HandlerDeps {
 		Logger:              zap.NewNop(),
 		ValkeyClient:        valkeyClient,
 		AuditAdapter:        auditAdapter,
+		AuditInserter:       auditAdapter,
 		EventPublisher:      eventPublisher,
 		ConfigMapController: cmController,
-		Deduper:             adapter.NewDeduper(),
+		Deduper:             adapter.NewMemoryDeduper(0, 0),
+		AlertPush:           newAlertPushTracker(t, 0),
 		OpAMPServer:         opampServer,
+		Proposals:           proposals.NewStore(valkeyClient, time.Hour),
 	}
+	deps.Freezes, _ = newFreezeStore(t, ctrl, nil)
+	deps.AlertStates, _ = newAlertStateStore(t, ctrl)
 	return deps
 }
+
+// newAlertPushTracker returns a tracker of pushed alerts on an in-process server, since the tracker
+// updates the alerts with scripts that the handlers rely on.
+func newAlertPushTracker(t *testing.T, resolveTimeout time.Duration) *alertpush.Tracker {
+	t.Helper()
+
+	client, err := valkey.NewClient(valkey.ClientOption{InitAddress: []string{miniredis.RunT(t).Addr()}, DisableCache: true})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return alertpush.NewTracker(client, resolveTimeout)
+}
+
+// newAlertStateStore returns an alert state store on its own mock client that accepts every
+// recorded state, so that tests which do not care about alert states need no expectations for it.
+func newAlertStateStore(t *testing.T, ctrl *gomock.Controller) (*alertstate.Store, *valkeymock.Client) {
+	t.Helper()
+
+	client := valkeymock.NewClient(ctrl)
+	client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		return cmd[0] == "EVALSHA" && strings.HasPrefix(cmd[3], "alert/state/")
+	}, "EVALSHA record alert state")).Return(valkeymock.Result(valkeymock.ValkeyInt64(1))).AnyTimes()
+	return alertstate.NewStore(client, 0, 0, 0), client
+}
+
+// newFreezeStore returns a freeze store on its own mock client so that tests which do not care
+// about freezes need no expectations for it. Every hub has the given windows.
+func newFreezeStore(t *testing.T, ctrl *gomock.Controller, windows map[string]valkey.ValkeyMessage) (*freeze.Store, *valkeymock.Client) {
+	t.Helper()
+
+	if windows == nil {
+		windows = map[string]valkey.ValkeyMessage{}
+	}
+	client := valkeymock.NewClient(ctrl)
+	client.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		return cmd[0] == "HGETALL" && strings.HasPrefix(cmd[1], "freeze/windows/")
+	}, "HGETALL freeze windows")).Return(valkeymock.Result(valkeymock.ValkeyMap(windows))).AnyTimes()
+	return freeze.NewStore(client), client
+}
+
+// expectRejectionAudits expects at least one request_rejected audit record and collects every
+// such record written.
+func expectRejectionAudits(t *testing.T, deps HandlerDeps) *[][]string {
+	t.Helper()
+
+	var records [][]string
+	deps.ValkeyClient.(*valkeymock.Client).EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool { //nolint:forcetypeassert
+		return cmd[0] == "XADD" && slices.Contains(cmd, auditutils.RequestRejectedType)
+	}, "XADD request_rejected audit record")).
+		DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			records = append(records, cmd.Commands())
+			return valkeymock.Result(valkeymock.ValkeyString(""))
+		}).MinTimes(1)
+	return &records
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"go.uber.org/zap"
+)
+
+// variableHistoryEntry is a recorded change of a variable. Value is the variable after the
+// change and is only set when the history is reconstructed.
+type variableHistoryEntry struct {
+	auditutils.VariableChange
+	Value any `json:"value,omitempty"`
+	// Error explains why a change could not be applied during reconstruction.
+	Error string `json:"error,omitempty"`
+}
+
+type variableHistoryResponse struct {
+	HubName    string                 `json:"hubName"`
+	Variable   string                 `json:"variable"`
+	Type       valkey.VariableType    `json:"type"`
+	Changes    []variableHistoryEntry `json:"changes"`
+	NextCursor string                 `json:"nextCursor,omitempty"`
+	// Incomplete is set when earlier changes of the variable may be missing: the page does not
+	// start at the beginning of the stream, or the stream was trimmed after the variable's hub
+	// was created. Reconstructed values then start from an empty variable and may be wrong.
+	Incomplete bool `json:"incomplete,omitempty"`
+	// At and Value are the requested point in time and the variable's value after the last change
+	// of the page up to then.
+	At    *time.Time `json:"at,omitempty"`
+	Value any        `json:"value,omitempty"`
+}
+
+// handleVariableHistory returns a page of the changes of a variable recorded in the audit stream,
+// oldest first. With reconstruct=true each change carries the value it produced; with
+// at=<RFC 3339> only the changes up to that time are returned together with the value at that
+// time. since, cursor and limit page through the changes like GET /v2/audit.
+func handleVariableHistory(_ context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hubName := r.PathValue("hubName")
+		varName := r.PathValue("varName")
+		if hubName == "" || varName == "" {
+			http.Error(w, "hub and var name required", http.StatusBadRequest)
+			return
+		}
+
+		reconstruct := false
+		if v := r.URL.Query().Get("reconstruct"); v != "" {
+			var err error
+			if reconstruct, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, "invalid reconstruct "+strconv.Quote(v), http.StatusBadRequest)
+				return
+			}
+		}
+		query := auditutils.VariableHistoryQuery{HubName: hubName, Variable: varName, Limit: auditutils.DefaultLimit}
+		for key, dst := range map[string]*time.Time{"at": &query.Until, "since": &query.Since} {
+			if v := r.URL.Query().Get(key); v != "" {
+				var err error
+				if *dst, err = time.Parse(time.RFC3339, v); err != nil {
+					http.Error(w, "invalid "+key+" "+strconv.Quote(v)+", expected RFC 3339", http.StatusBadRequest)
+					return
+				}
+			}
+		}
+		if !query.Since.IsZero() && !query.Until.IsZero() && query.Until.Before(query.Since) {
+			http.Error(w, "at must not be before since", http.StatusBadRequest)
+			return
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit < 1 || query.Limit > auditutils.MaxLimit {
+				http.Error(w, "limit must be between 1 and "+strconv.Itoa(auditutils.MaxLimit), http.StatusBadRequest)
+				return
+			}
+		}
+		if query.Cursor = r.URL.Query().Get("cursor"); query.Cursor != "" && !auditutils.IsStreamID(query.Cursor) {
+			http.Error(w, "invalid cursor "+strconv.Quote(query.Cursor), http.StatusBadRequest)
+			return
+		}
+		at := query.Until
+
+		hubsVariables, err := deps.ConfigMapController.GetAllHubsToDataMap()
+		if err != nil {
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, "failed to fetch manual variables")
+			return
+		}
+		varType, err := manualvariables.GetVarType(hubName, varName, hubsVariables)
+		if err != nil {
+			writeVarTypeError(w, deps.Logger, err)
+			return
+		}
+
+		history, err := query.Run(r.Context(), deps.ValkeyClient)
+		if err != nil {
+			deps.Logger.Error("Failed to read variable history", zap.String("hubName", hubName), zap.String("varName", varName), zap.Error(err))
+			http.Error(w, "Unable to fetch history from Valkey", http.StatusInternalServerError)
+			return
+		}
+
+		response := variableHistoryResponse{
+			HubName:    hubName,
+			Variable:   varName,
+			Type:       varType,
+			Changes:    make([]variableHistoryEntry, len(history.Changes)),
+			NextCursor: history.NextCursor,
+			Incomplete: !query.FromStart() || historyTrimmed(deps, hubName, history.StreamStart),
+		}
+		for i, change := range history.Changes {
+			response.Changes[i] = variableHistoryEntry{VariableChange: change}
+		}
+		if reconstruct || !at.IsZero() {
+			value := reconstructHistory(varType, response.Changes, reconstruct)
+			if !at.IsZero() {
+				response.At = &at
+				response.Value = value
+			}
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, response)
+	}
+}
+
+// historyTrimmed reports whether the audit stream, which starts at streamStart, was trimmed after
+// the hub's manual variables ConfigMap was created, so that the variable may have changed before
+// its oldest record. It cannot tell when the ConfigMap's creation time is unknown.
+func historyTrimmed(deps HandlerDeps, hubName string, streamStart time.Time) bool {
+	cm, found, err := hubConfigMap(deps.ConfigMapController, hubName)
+	if err != nil {
+		deps.Logger.Warn("Failed to read hub ConfigMap, history may be incomplete", zap.String("hubName", hubName), zap.Error(err))
+		return true
+	}
+	if !found || cm.CreationTimestamp.IsZero() {
+		return false
+	}
+	return streamStart.IsZero() || streamStart.After(cm.CreationTimestamp.Time)
+}
+
+// reconstructHistory replays the changes onto an empty variable and returns the final value.
+// With setValues, every entry gets the value after its change. Changes that cannot be applied
+// leave the value as it was and are marked with the reason.
+func reconstructHistory(varType valkey.VariableType, entries []variableHistoryEntry, setValues bool) any {
+	var value any
+	switch varType {
+	case valkey.VariableTypeSet:
+		value = []string{}
+	case valkey.VariableTypeMap:
+		value = map[string]string{}
+	default:
+		value = ""
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		command := valkey.CommandType(entry.Operation)
+		data, err := manualvariables.ParseChangeData(varType, command, entry.Data)
+		if err == nil {
+			var next any
+			if next, err = manualvariables.Apply(varType, value, manualvariables.Change{Command: command, Data: data}); err == nil {
+				value = next
+			}
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		if setValues {
+			entry.Value = value
+		}
+	}
+	return value
+}
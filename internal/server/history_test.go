@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-data-core/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func historyEntry(id string, kv ...string) valkey.ValkeyMessage {
+	fields := make([]valkey.ValkeyMessage, 0, len(kv))
+	for _, s := range kv {
+		fields = append(fields, valkeymock.ValkeyString(s))
+	}
+	return valkeymock.ValkeyArray(valkeymock.ValkeyString(id), valkeymock.ValkeyArray(fields...))
+}
+
+// expectMapHistory expects a read of the audit stream up to end and returns the first n records
+// of the history of the map variable "attributes".
+func expectMapHistory(m *valkeymock.Client, end string, n int) {
+	entries := []valkey.ValkeyMessage{
+		historyEntry("1000-0", "hub_name", "hub-a", "source", "manual_variables_api", "name", "var.add", "publish_success", "true",
+			"payload", `{"variableRef":"attributes","dataType":"map","operation":"add","data":{"a":"1","b":"2"}}`),
+		historyEntry("2000-0", "hub_name", "hub-a", "type", "variable_updated", "operation", "remove_element",
+			"variable_ref", "attributes", "variable", "a"),
+		historyEntry("3000-0", "hub_name", "hub-a", "source", "manual_variables_api", "name", "var.remove", "publish_success", "true",
+			"payload", `{"variableRef":"attributes","dataType":"map","operation":"remove","data":["b"]}`),
+	}
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", audit.MdaiHubEventHistoryStreamName, "-", end, "COUNT", "200")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(entries[:n]...)))
+}
+
+func TestHandleVariableHistory(t *testing.T) {
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, manualVariablesConfigMap("hub-a", map[string]string{"attributes": "map"})))
+	mux := NewRouter(t.Context(), deps)
+	expectMapHistory(deps.ValkeyClient.(*valkeymock.Client), "+", 3) //nolint:forcetypeassert
+
+	req := httptest.NewRequest(http.MethodGet, "/variables/history/hub/hub-a/var/attributes?reconstruct=true", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{
+		"hubName": "hub-a",
+		"variable": "attributes",
+		"type": "map",
+		"changes": [
+			{"id":"1000-0","timestamp":"1970-01-01T00:00:01Z","source":"manual_api","operation":"add","data":{"a":"1","b":"2"},"value":{"a":"1","b":"2"}},
+			{"id":"2000-0","timestamp":"1970-01-01T00:00:02Z","source":"alert","operation":"remove","data":"a","value":{"b":"2"}},
+			{"id":"3000-0","timestamp":"1970-01-01T00:00:03Z","source":"manual_api","operation":"remove","data":["b"],"value":{}}
+		]
+	}`, rr.Body.String())
+}
+
+func TestHandleVariableHistory_At(t *testing.T) {
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, manualVariablesConfigMap("hub-a", map[string]string{"attributes": "map"})))
+	mux := NewRouter(t.Context(), deps)
+	expectMapHistory(deps.ValkeyClient.(*valkeymock.Client), "2000", 2) //nolint:forcetypeassert
+
+	req := httptest.NewRequest(http.MethodGet, "/variables/history/hub/hub-a/var/attributes?at=1970-01-01T00:00:02Z", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{
+		"hubName": "hub-a",
+		"variable": "attributes",
+		"type": "map",
+		"changes": [
+			{"id":"1000-0","timestamp":"1970-01-01T00:00:01Z","source":"manual_api","operation":"add","data":{"a":"1","b":"2"}},
+			{"id":"2000-0","timestamp":"1970-01-01T00:00:02Z","source":"alert","operation":"remove","data":"a"}
+		],
+		"at": "1970-01-01T00:00:02Z",
+		"value": {"b":"2"}
+	}`, rr.Body.String())
+}
+
+func TestHandleVariableHistory_Incomplete(t *testing.T) {
+	cm := manualVariablesConfigMap("hub-a", map[string]string{"attributes": "map"})
+	cm.CreationTimestamp = metav1.NewTime(time.UnixMilli(500))
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, cm))
+	mux := NewRouter(t.Context(), deps)
+	valkeyClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+
+	// The stream starts after the hub was created, so earlier changes may have been trimmed.
+	expectMapHistory(valkeyClient, "+", 3)
+	req := httptest.NewRequest(http.MethodGet, "/variables/history/hub/hub-a/var/attributes?limit=2", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var page variableHistoryResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	assert.Len(t, page.Changes, 2)
+	assert.Equal(t, "2000-0", page.NextCursor)
+	assert.True(t, page.Incomplete)
+
+	// A later page never starts at the beginning of the variable's history.
+	valkeyClient.EXPECT().Do(gomock.Any(), valkeymock.Match("XRANGE", audit.MdaiHubEventHistoryStreamName, "(2000-0", "+", "COUNT", "200")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray()))
+	req = httptest.NewRequest(http.MethodGet, "/variables/history/hub/hub-a/var/attributes?cursor=2000-0", http.NoBody)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"hubName":"hub-a","variable":"attributes","type":"map","changes":[],"incomplete":true}`, rr.Body.String())
+}
+
+func TestHandleVariableHistory_BadRequest(t *testing.T) {
+	deps := setupMocks(t, newFakeClientsetWithHubs(t, manualVariablesConfigMap("hub-a", map[string]string{"attributes": "map"})))
+	mux := NewRouter(t.Context(), deps)
+
+	for url, want := range map[string]int{
+		"/variables/history/hub/hub-a/var/attributes?reconstruct=maybe":                                  http.StatusBadRequest,
+		"/variables/history/hub/hub-a/var/attributes?at=yesterday":                                       http.StatusBadRequest,
+		"/variables/history/hub/hub-a/var/attributes?since=yesterday":                                    http.StatusBadRequest,
+		"/variables/history/hub/hub-a/var/attributes?limit=0":                                            http.StatusBadRequest,
+		"/variables/history/hub/hub-a/var/attributes?cursor=last":                                        http.StatusBadRequest,
+		"/variables/history/hub/hub-a/var/attributes?since=1970-01-01T00:00:02Z&at=1970-01-01T00:00:01Z": http.StatusBadRequest,
+		"/variables/history/hub/hub-a/var/missing":                                                       http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, url, http.NoBody)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		assert.Equal(t, want, rr.Code, url)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newReasonRequiredDeps(t *testing.T) HandlerDeps {
+	t.Helper()
+
+	cm := manualVariablesConfigMap("prod", map[string]string{"filter": "string", "services": "set"})
+	cm.Annotations = map[string]string{manualvariables.RequireReasonAnnotation: "filter"}
+	return setupMocks(t, newFakeClientsetWithHubs(t, cm))
+}
+
+func TestHandleSetVariables_Metadata(t *testing.T) {
+	deps := newReasonRequiredDeps(t)
+	mux := NewRouter(t.Context(), deps)
+
+	var audited []string
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).
+		DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			audited = cmd.Commands()
+			return valkeymock.Result(valkeymock.ValkeyString(""))
+		}).Times(1)
+
+	body := `{"data":"severity>=error","reason":"incident noise","ticket":"OPS-42"}`
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/prod/var/filter", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var result eventing.MdaiEvent
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.JSONEq(t, `{
+		"variableRef": "filter", "dataType": "string", "operation": "add", "data": "severity>=error",
+		"metadata": {"reason": "incident noise", "ticket": "OPS-42"}
+	}`, result.Payload)
+
+	i := slices.Index(audited, "reason")
+	require.NotEqual(t, -1, i, "audit record must carry the reason")
+	assert.Equal(t, "incident noise", audited[i+1])
+	i = slices.Index(audited, "ticket")
+	require.NotEqual(t, -1, i, "audit record must carry the ticket")
+	assert.Equal(t, "OPS-42", audited[i+1])
+	assert.NotContains(t, audited, "expires_hint")
+}
+
+func TestHandleSetVariables_ReasonRequired(t *testing.T) {
+	deps := newReasonRequiredDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected int
+	}{
+		{name: "missing reason", path: "/variables/hub/prod/var/filter", body: `{"data":"x","ticket":"OPS-1"}`, expected: http.StatusBadRequest},
+		{name: "invalid metadata", path: "/variables/hub/prod/var/services", body: `{"data":["a"],"reason":42}`, expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+		})
+	}
+}
+
+func TestHandlePromoteVariables_ReasonRequired(t *testing.T) {
+	clientset := newFakeClientsetWithHubs(t,
+		manualVariablesConfigMap("staging", map[string]string{"filter": "string"}),
+		func() *corev1.ConfigMap {
+			cm := manualVariablesConfigMap("prod", map[string]string{"filter": "string"})
+			cm.Annotations = map[string]string{manualvariables.RequireReasonAnnotation: "*"}
+			return cm
+		}(),
+	)
+	deps := setupMocks(t, clientset)
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	body := `{"sourceHub":"staging","targetHub":"prod","apply":true}`
+	req := httptest.NewRequest(http.MethodPost, "/variables/promote", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `"reason is required for this variable"`, rr.Body.String())
+}
+
+func TestHandleImportVariables_ReasonRequired(t *testing.T) {
+	deps := newReasonRequiredDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	// A dry run reports the variables the import rejects.
+	for _, dryRun := range []bool{false, true} {
+		body := `{"hubs":{"prod":{"filter":{"value":"x"},"services":{"value":["a"]}}}}`
+		req := httptest.NewRequest(http.MethodPost, "/variables/import?dryRun="+strconv.FormatBool(dryRun), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		mux.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.JSONEq(t, `{
+			"mode": "merge",
+			"dryRun": `+strconv.FormatBool(dryRun)+`,
+			"results": [
+				{"hub": "prod", "variable": "filter", "status": "invalid", "error": "reason is required for this variable"},
+				{"hub": "prod", "variable": "services", "status": "valid"}
+			]
+		}`, rr.Body.String())
+	}
+}
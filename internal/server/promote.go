@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+
+	datacore "github.com/decisiveai/mdai-data-core/variables"
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	promotedFromHubAuditField = "promoted_from_hub"
+
+	changeStatusUnchanged = "unchanged"
+	changeStatusPlanned   = "planned"
+	changeStatusApplied   = "applied"
+	changeStatusFailed    = "failed"
+)
+
+type promoteRequest struct {
+	SourceHub string   `json:"sourceHub"`
+	TargetHub string   `json:"targetHub"`
+	Variables []string `json:"variables,omitempty"`
+	// Apply publishes the changes; without it only the diff is returned.
+	Apply bool `json:"apply"`
+
+	manualvariables.ChangeMetadata
+}
+
+type promotedVariable struct {
+	Name    string                   `json:"name"`
+	Type    valkey.VariableType      `json:"type"`
+	Source  any                      `json:"source"`
+	Target  any                      `json:"target"`
+	Changes []manualvariables.Change `json:"changes"`
+	Status  string                   `json:"status"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+type promoteResponse struct {
+	SourceHub     string             `json:"sourceHub"`
+	TargetHub     string             `json:"targetHub"`
+	Applied       bool               `json:"applied"`
+	CorrelationID string             `json:"correlationId,omitempty"`
+	Variables     []promotedVariable `json:"variables"`
+}
+
+// handlePromoteVariables copies manual variable values from one hub to another. It always
+// returns the per-variable diff and only publishes the target hub events when apply is set.
+func handlePromoteVariables(ctx context.Context, deps HandlerDeps) http.HandlerFunc { //nolint:funlen
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+
+		var req promoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON format in request payload", http.StatusBadRequest)
+			return
+		}
+
+		hubsVariables, err := deps.ConfigMapController.GetAllHubsToDataMap()
+		if err != nil {
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, "failed to fetch manual variables")
+			return
+		}
+
+		varTypes, err := manualvariables.PromotableVariables(req.SourceHub, req.TargetHub, req.Variables, hubsVariables)
+		if err != nil {
+			writeVarTypeError(w, deps.Logger, err)
+			return
+		}
+
+		var freezeAuditFields map[string]string
+		if req.Apply {
+			if freezeAuditFields, err = checkFreeze(ctx, deps, r, req.TargetHub); err != nil {
+				writeFreezeError(w, deps.Logger, err)
+				return
+			}
+
+			policy, err := hubPolicy(deps, req.TargetHub)
+			if err != nil {
+				writePolicyError(w, deps.Logger, err)
+				return
+			}
+			for _, varName := range slices.Sorted(maps.Keys(varTypes)) {
+				if err := policy.CheckBulkChange(varName, req.ChangeMetadata); err != nil {
+					writeVarTypeError(w, deps.Logger, err)
+					return
+				}
+			}
+		}
+
+		kv := datacore.NewValkeyAdapter(deps.ValkeyClient, deps.Logger)
+		response := promoteResponse{
+			SourceHub: req.SourceHub,
+			TargetHub: req.TargetHub,
+			Applied:   req.Apply,
+			Variables: make([]promotedVariable, 0, len(varTypes)),
+		}
+		if req.Apply {
+			response.CorrelationID = "promote-" + uuid.NewString()
+		}
+
+		status := http.StatusOK
+		for _, varName := range slices.Sorted(maps.Keys(varTypes)) {
+			varType := varTypes[varName]
+			result := promotedVariable{Name: varName, Type: varType}
+
+			if result.Source, err = valkey.GetValue(ctx, kv, varName, varType, req.SourceHub); err != nil {
+				httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if result.Target, err = valkey.GetValue(ctx, kv, varName, varType, req.TargetHub); err != nil {
+				httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if result.Changes, err = manualvariables.Diff(varType, result.Target, result.Source); err != nil {
+				httputil.WriteJSONResponse(w, deps.Logger, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			switch {
+			case len(result.Changes) == 0:
+				result.Status = changeStatusUnchanged
+			case !req.Apply:
+				result.Status = changeStatusPlanned
+			default:
+				result.Status = changeStatusApplied
+				auditFields := map[string]string{promotedFromHubAuditField: req.SourceHub}
+				maps.Copy(auditFields, freezeAuditFields)
+				maps.Copy(auditFields, identity.ActorFromRequest(r).AuditFields())
+				if err := publishVariableChanges(ctx, deps, req.TargetHub, varName, varType, result.Changes, response.CorrelationID, req.ChangeMetadata, auditFields); err != nil {
+					deps.Logger.Error("Failed to promote variable",
+						zap.String("sourceHub", req.SourceHub),
+						zap.String("targetHub", req.TargetHub),
+						zap.String("variable", varName),
+						zap.Error(err),
+					)
+					result.Status = changeStatusFailed
+					result.Error = err.Error()
+					status = http.StatusInternalServerError
+				}
+			}
+
+			response.Variables = append(response.Variables, result)
+		}
+
+		httputil.WriteJSONResponse(w, deps.Logger, status, response)
+	}
+}
+
+// publishVariableChanges publishes one event per change of a single variable. All events share
+// correlationID and metadata, and carry auditFields into their audit records.
+func publishVariableChanges(ctx context.Context, deps HandlerDeps, hubName, varName string, varType valkey.VariableType, changes []manualvariables.Change, correlationID string, metadata manualvariables.ChangeMetadata, auditFields map[string]string) error {
+	events := make([]adapter.EventPerSubject, 0, len(changes))
+	for _, change := range changes {
+		eventPerSubject, err := newVariableEvent(hubName, varName, varType, change.Command, change.Data, metadata)
+		if err != nil {
+			return err
+		}
+		eventPerSubject.Event.CorrelationID = correlationID
+		maps.Copy(eventPerSubject.AuditFields, auditFields)
+		events = append(events, eventPerSubject)
+	}
+
+	_, err := publishEvents(ctx, deps, events)
+	return err
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func newPromoteDeps(t *testing.T) HandlerDeps {
+	t.Helper()
+
+	clientset := newFakeClientsetWithHubs(t,
+		manualVariablesConfigMap("staging", map[string]string{"filter": "string", "services": "set", "level": "int"}),
+		manualVariablesConfigMap("prod", map[string]string{"filter": "string", "services": "set", "level": "string"}),
+	)
+	return setupMocks(t, clientset)
+}
+
+func expectPromoteValues(t *testing.T, m *valkeymock.Client) {
+	t.Helper()
+
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "variable/staging/filter")).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString("severity>=warn")))
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "variable/prod/filter")).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString("severity>=error")))
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("SMEMBERS", "variable/staging/services")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyBlobString("a"), valkeymock.ValkeyBlobString("b"))))
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("SMEMBERS", "variable/prod/services")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyBlobString("b"), valkeymock.ValkeyBlobString("c"))))
+}
+
+func TestHandlePromoteVariables_DryRun(t *testing.T) {
+	deps := newPromoteDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectPromoteValues(t, deps.ValkeyClient.(*valkeymock.Client)) //nolint:forcetypeassert
+
+	body := `{"sourceHub":"staging","targetHub":"prod","variables":["services","filter"]}`
+	req := httptest.NewRequest(http.MethodPost, "/variables/promote", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{
+		"sourceHub": "staging",
+		"targetHub": "prod",
+		"applied": false,
+		"variables": [
+			{
+				"name": "filter", "type": "string", "status": "planned",
+				"source": "severity>=warn", "target": "severity>=error",
+				"changes": [{"command": "add", "data": "severity>=warn"}]
+			},
+			{
+				"name": "services", "type": "set", "status": "planned",
+				"source": ["a", "b"], "target": ["b", "c"],
+				"changes": [{"command": "add", "data": ["a"]}, {"command": "remove", "data": ["c"]}]
+			}
+		]
+	}`, rr.Body.String())
+}
+
+func TestHandlePromoteVariables_Apply(t *testing.T) {
+	deps := newPromoteDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	expectPromoteValues(t, mockClient)
+
+	var audited [][]string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).
+		DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			audited = append(audited, cmd.Commands())
+			return valkeymock.Result(valkeymock.ValkeyString(""))
+		}).Times(3)
+
+	body := `{"sourceHub":"staging","targetHub":"prod","variables":["filter","services"],"apply":true}`
+	req := httptest.NewRequest(http.MethodPost, "/variables/promote", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp promoteResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.True(t, resp.Applied)
+	assert.NotEmpty(t, resp.CorrelationID)
+	require.Len(t, resp.Variables, 2)
+	for _, v := range resp.Variables {
+		assert.Equal(t, changeStatusApplied, v.Status)
+	}
+
+	require.Len(t, audited, 3)
+	for _, cmd := range audited {
+		i := slices.Index(cmd, promotedFromHubAuditField)
+		require.NotEqual(t, -1, i, "audit record must carry the source hub")
+		assert.Equal(t, "staging", cmd[i+1])
+		assert.Contains(t, cmd, resp.CorrelationID)
+	}
+}
+
+func TestHandlePromoteVariables_Incompatible(t *testing.T) {
+	deps := newPromoteDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	body := `{"sourceHub":"staging","targetHub":"prod","variables":["level"],"apply":true}`
+	req := httptest.NewRequest(http.MethodPost, "/variables/promote", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.JSONEq(t, `"incompatible variables: level: type int in source hub, string in target hub"`, rr.Body.String())
+}
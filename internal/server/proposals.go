@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/decisiveai/mdai-gateway/internal/proposals"
+	"go.uber.org/zap"
+)
+
+const (
+	proposalProposed = "proposed"
+	proposalApproved = "approved"
+	proposalRejected = "rejected"
+	proposalExpired  = "expired"
+	// proposalPublishFailed records an approval whose change could not be published. The
+	// proposal stays pending.
+	proposalPublishFailed = "publish_failed"
+)
+
+// handleProposeChange stores a change to a protected variable as a proposal instead of
+// publishing it.
+func handleProposeChange(ctx context.Context, deps HandlerDeps, w http.ResponseWriter, r *http.Request, proposal proposals.Proposal) {
+	proposal.ProposedBy = identity.User(r)
+	if proposal.ProposedBy == "" {
+		http.Error(w, "Changes to protected variables require an authenticated user", http.StatusUnauthorized)
+		return
+	}
+
+	if err := deps.Proposals.Create(ctx, &proposal); err != nil {
+		deps.Logger.Error("Failed to store proposal", zap.Error(err))
+		http.Error(w, "Failed to store proposal", http.StatusInternalServerError)
+		return
+	}
+	recordProposalAudit(ctx, deps, proposal, proposalProposed, identity.ActorFromRequest(r))
+
+	httputil.WriteJSONResponse(w, deps.Logger, http.StatusAccepted, proposal)
+}
+
+func handleListProposals(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expireProposals(ctx, deps)
+
+		pending, err := deps.Proposals.List(ctx)
+		if err != nil {
+			deps.Logger.Error("Failed to list proposals", zap.Error(err))
+			http.Error(w, "Failed to list proposals", http.StatusInternalServerError)
+			return
+		}
+
+		if hubName := r.URL.Query().Get("hub"); hubName != "" {
+			filtered := pending[:0]
+			for _, p := range pending {
+				if p.HubName == hubName {
+					filtered = append(filtered, p)
+				}
+			}
+			pending = filtered
+		}
+
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, pending)
+	}
+}
+
+func handleGetProposal(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		proposal, err := deps.Proposals.Get(ctx, r.PathValue("id"))
+		if err != nil {
+			writeProposalError(w, deps.Logger, err)
+			return
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, proposal)
+	}
+}
+
+// handleDecideProposal approves or rejects a pending proposal. The decision must come from an
+// identity other than the proposer's; approval publishes the proposed change and is only recorded
+// once it is published. A proposal whose change could not be published stays pending.
+func handleDecideProposal(ctx context.Context, deps HandlerDeps, decision string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		decidedBy := identity.User(r)
+		if decidedBy == "" {
+			http.Error(w, "Deciding on a proposal requires an authenticated user", http.StatusUnauthorized)
+			return
+		}
+
+		id := r.PathValue("id")
+		proposal, err := deps.Proposals.Get(ctx, id)
+		if err != nil {
+			writeProposalError(w, deps.Logger, err)
+			return
+		}
+		if proposal.ProposedBy == decidedBy {
+			http.Error(w, fmt.Sprintf("A proposal cannot be %s by its proposer", decision), http.StatusForbidden)
+			return
+		}
+
+		var freezeAuditFields map[string]string
+		if decision == proposalApproved {
+			if freezeAuditFields, err = checkFreeze(ctx, deps, r, proposal.HubName); err != nil {
+				writeFreezeError(w, deps.Logger, err)
+				return
+			}
+		}
+
+		// Claiming settles races between concurrent decisions and the expiry sweeper.
+		if decision == proposalRejected {
+			if proposal, err = deps.Proposals.Claim(ctx, id); err != nil {
+				writeProposalError(w, deps.Logger, err)
+				return
+			}
+			recordProposalAudit(ctx, deps, proposal, decision, identity.ActorFromRequest(r))
+			httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, proposal)
+			return
+		}
+
+		eventPerSubject, err := newVariableEvent(proposal.HubName, proposal.VarName, proposal.VarType, proposal.Command, proposal.Data, proposal.Metadata)
+		if err != nil {
+			http.Error(w, "Invalid proposal payload", http.StatusInternalServerError)
+			return
+		}
+		eventPerSubject.Event.CorrelationID = "proposal-" + proposal.ID
+		maps.Copy(eventPerSubject.AuditFields, map[string]string{
+			"proposal_id": proposal.ID,
+			"proposed_by": proposal.ProposedBy,
+			"approved_by": decidedBy,
+		})
+		maps.Copy(eventPerSubject.AuditFields, freezeAuditFields)
+		maps.Copy(eventPerSubject.AuditFields, identity.ActorFromRequest(r).AuditFields())
+
+		if proposal, err = deps.Proposals.Claim(ctx, id); err != nil {
+			writeProposalError(w, deps.Logger, err)
+			return
+		}
+		if _, err := publishEvents(ctx, deps, []adapter.EventPerSubject{eventPerSubject}); err != nil {
+			deps.Logger.Error("Failed to publish approved proposal", zap.String("proposalId", proposal.ID), zap.Error(err))
+			// Keep the proposal pending so that the approval can be retried.
+			if restoreErr := deps.Proposals.Restore(ctx, proposal); restoreErr != nil {
+				deps.Logger.Error("Failed to restore proposal", zap.String("proposalId", proposal.ID), zap.Error(restoreErr))
+			}
+			recordProposalAudit(ctx, deps, proposal, proposalPublishFailed, identity.ActorFromRequest(r))
+			http.Error(w, fmt.Sprintf("Failed to publish event: %v", err), http.StatusInternalServerError)
+			return
+		}
+		recordProposalAudit(ctx, deps, proposal, decision, identity.ActorFromRequest(r))
+
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, eventPerSubject.Event)
+	}
+}
+
+// RunProposalSweeper expires overdue proposals every interval until ctx is done.
+func RunProposalSweeper(ctx context.Context, deps HandlerDeps, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expireProposals(ctx, deps)
+		}
+	}
+}
+
+func expireProposals(ctx context.Context, deps HandlerDeps) {
+	expired, err := deps.Proposals.ClaimExpired(ctx)
+	if err != nil {
+		deps.Logger.Error("Failed to expire proposals", zap.Error(err))
+	}
+	for _, proposal := range expired {
+		recordProposalAudit(ctx, deps, proposal, proposalExpired, identity.Actor{})
+	}
+}
+
+func recordProposalAudit(ctx context.Context, deps HandlerDeps, proposal proposals.Proposal, action string, actor identity.Actor) {
+	redacted, _ := hubAuditRedaction(deps, proposal.HubName).Variable(proposal.VarName, proposal.Data)
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		deps.Logger.Error("Failed to encode proposal data", zap.String("proposalId", proposal.ID), zap.Error(err))
+	}
+
+	fields := map[string]string{
+		"action":       action,
+		"actor":        actor.User,
+		"proposal_id":  proposal.ID,
+		"hub_name":     proposal.HubName,
+		"variable_ref": proposal.VarName,
+		"operation":    string(proposal.Command),
+		"data":         string(data),
+		"proposed_by":  proposal.ProposedBy,
+		"expires_at":   proposal.ExpiresAt.Format(time.RFC3339),
+	}
+	maps.Copy(fields, proposal.Metadata.AuditFields())
+	maps.Copy(fields, actor.AuditFields())
+
+	if err := auditutils.RecordAuditRecord(ctx, deps.Logger, deps.AuditInserter, auditutils.VariableProposalType, fields); err != nil {
+		deps.Logger.Error("Failed to audit proposal", zap.String("proposalId", proposal.ID), zap.String("action", action), zap.Error(err))
+	}
+}
+
+func writeProposalError(w http.ResponseWriter, logger *zap.Logger, err error) {
+	if errors.Is(err, proposals.ErrNotFound) {
+		http.Error(w, "Proposal not found or no longer pending", http.StatusNotFound)
+		return
+	}
+	logger.Error("Failed to read proposal", zap.Error(err))
+	http.Error(w, "Failed to read proposal", http.StatusInternalServerError)
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/proposals"
+	"github.com/decisiveai/mdai-gateway/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func newProtectedDeps(t *testing.T) HandlerDeps {
+	t.Helper()
+
+	cm := manualVariablesConfigMap("prod", map[string]string{"drop_all": "boolean", "filter": "string"})
+	cm.Annotations = map[string]string{manualvariables.ProtectedVariablesAnnotation: "drop_all"}
+	return setupMocks(t, newFakeClientsetWithHubs(t, cm))
+}
+
+// auditRecords captures every audit record written through the mock.
+func auditRecords(m *valkeymock.Client, times int) *[][]string {
+	var records [][]string
+	m.EXPECT().Do(gomock.Any(), XaddMatcher{}).
+		DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+			records = append(records, cmd.Commands())
+			return valkeymock.Result(valkeymock.ValkeyString(""))
+		}).Times(times)
+	return &records
+}
+
+func auditField(record []string, field string) string {
+	if i := slices.Index(record, field); i != -1 && i+1 < len(record) {
+		return record[i+1]
+	}
+	return ""
+}
+
+func expectPendingProposal(t *testing.T, m *valkeymock.Client, p proposals.Proposal) {
+	t.Helper()
+
+	doc, err := json.Marshal(p)
+	require.NoError(t, err)
+	score := strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)
+
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("ZSCORE", "proposals/pending", p.ID)).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString(score))).AnyTimes()
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "proposal/"+p.ID)).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString(string(doc)))).AnyTimes()
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("ZREM", "proposals/pending", p.ID)).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(1))).MaxTimes(1)
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("GETDEL", "proposal/"+p.ID)).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString(string(doc)))).MaxTimes(1)
+}
+
+var pendingDropAll = proposals.Proposal{
+	ID:         "p1",
+	HubName:    "prod",
+	VarName:    "drop_all",
+	VarType:    "boolean",
+	Command:    "add",
+	Data:       "true",
+	Metadata:   manualvariables.ChangeMetadata{Reason: "incident"},
+	ProposedBy: "alice",
+}
+
+func TestHandleSetVariables_ProtectedCreatesProposal(t *testing.T) {
+	deps := newProtectedDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+
+	mockClient.EXPECT().DoMulti(gomock.Any(),
+		valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "SET" }),
+		valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "ZADD" }),
+	).Return([]valkey.ValkeyResult{
+		valkeymock.Result(valkeymock.ValkeyString("OK")),
+		valkeymock.Result(valkeymock.ValkeyInt64(1)),
+	})
+	records := auditRecords(mockClient, 1)
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/prod/var/drop_all", bytes.NewBufferString(`{"data":true,"reason":"incident"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.UserHeader, "alice")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	var proposal proposals.Proposal
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &proposal))
+	assert.NotEmpty(t, proposal.ID)
+	assert.Equal(t, "alice", proposal.ProposedBy)
+	assert.Equal(t, "true", proposal.Data)
+
+	require.Len(t, *records, 1)
+	record := (*records)[0]
+	assert.Equal(t, "variable_proposal", auditField(record, "type"))
+	assert.Equal(t, proposalProposed, auditField(record, "action"))
+	assert.Equal(t, proposal.ID, auditField(record, "proposal_id"))
+	assert.Equal(t, "incident", auditField(record, "reason"))
+}
+
+func TestHandleSetVariables_ProtectedRequiresIdentity(t *testing.T) {
+	deps := newProtectedDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/prod/var/drop_all", bytes.NewBufferString(`{"data":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestHandleDecideProposal_Approve(t *testing.T) {
+	deps := newProtectedDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	expectPendingProposal(t, mockClient, pendingDropAll)
+	records := auditRecords(mockClient, 2)
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/proposals/p1/approve", http.NoBody)
+	req.Header.Set(identity.UserHeader, "bob")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var event eventing.MdaiEvent
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &event))
+	assert.Equal(t, "proposal-p1", event.CorrelationID)
+	assert.JSONEq(t, `{"variableRef":"drop_all","dataType":"boolean","operation":"add","data":"true","metadata":{"reason":"incident"}}`, event.Payload)
+
+	// The approval is recorded once the change is published.
+	require.Len(t, *records, 2)
+	assert.Equal(t, "alice", auditField((*records)[0], "proposed_by"))
+	assert.Equal(t, "bob", auditField((*records)[0], "approved_by"))
+	assert.Equal(t, proposalApproved, auditField((*records)[1], "action"))
+	assert.Equal(t, "bob", auditField((*records)[1], "actor"))
+}
+
+func TestHandleDecideProposal_PublishFailed(t *testing.T) {
+	deps := newProtectedDeps(t)
+	mockPub := &mocks.MockPublisher{}
+	mockPub.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("nats unavailable")).Once()
+	deps.EventPublisher = mockPub
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	expectPendingProposal(t, mockClient, pendingDropAll)
+	doc, err := json.Marshal(pendingDropAll)
+	require.NoError(t, err)
+	var actions []string
+	mockClient.EXPECT().Do(gomock.Any(), XaddMatcher{}).DoAndReturn(func(_ any, cmd valkey.Completed) valkey.ValkeyResult {
+		if action := auditField(cmd.Commands(), "action"); action != "" {
+			actions = append(actions, action)
+		}
+		return valkeymock.Result(valkeymock.ValkeyString(""))
+	}).AnyTimes()
+	// The proposal is stored as pending again.
+	mockClient.EXPECT().DoMulti(gomock.Any(),
+		valkeymock.Match("SET", "proposal/p1", string(doc), "PX", strconv.FormatInt(time.Hour.Milliseconds(), 10)),
+		valkeymock.Match("ZADD", "proposals/pending", strconv.FormatInt(pendingDropAll.ExpiresAt.UnixMilli(), 10), "p1"),
+	).Return([]valkey.ValkeyResult{
+		valkeymock.Result(valkeymock.ValkeyString("OK")),
+		valkeymock.Result(valkeymock.ValkeyInt64(1)),
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/proposals/p1/approve", http.NoBody)
+	req.Header.Set(identity.UserHeader, "bob")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code, rr.Body.String())
+	mockPub.AssertExpectations(t)
+	assert.Contains(t, actions, proposalPublishFailed)
+	assert.NotContains(t, actions, proposalApproved)
+}
+
+func TestHandleDecideProposal_Reject(t *testing.T) {
+	deps := newProtectedDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	expectPendingProposal(t, mockClient, pendingDropAll)
+	records := auditRecords(mockClient, 1)
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/proposals/p1/reject", http.NoBody)
+	req.Header.Set(identity.UserHeader, "bob")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Len(t, *records, 1)
+	assert.Equal(t, proposalRejected, auditField((*records)[0], "action"))
+}
+
+func TestHandleDecideProposal_Forbidden(t *testing.T) {
+	deps := newProtectedDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+	expectPendingProposal(t, deps.ValkeyClient.(*valkeymock.Client), pendingDropAll) //nolint:forcetypeassert
+
+	for user, want := range map[string]int{"": http.StatusUnauthorized, "alice": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/variables/proposals/p1/approve", http.NoBody)
+		req.Header.Set(identity.UserHeader, user)
+		rr := httptest.NewRecorder()
+
+		mux.ServeHTTP(rr, req)
+
+		assert.Equal(t, want, rr.Code, user)
+	}
+}
+
+func TestHandleListProposals_ExpiresOverdue(t *testing.T) {
+	deps := newProtectedDeps(t)
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+
+	overdue, err := json.Marshal(proposals.Proposal{ID: "old", HubName: "prod", VarName: "drop_all", ProposedBy: "carol"})
+	require.NoError(t, err)
+	pending, err := json.Marshal(pendingDropAll)
+	require.NoError(t, err)
+
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "ZRANGEBYSCORE" && cmd[2] == "-inf" })).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyBlobString("old"))))
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("ZREM", "proposals/pending", "old")).
+		Return(valkeymock.Result(valkeymock.ValkeyInt64(1)))
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("GETDEL", "proposal/old")).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString(string(overdue))))
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool { return cmd[0] == "ZRANGEBYSCORE" && cmd[3] == "+inf" })).
+		Return(valkeymock.Result(valkeymock.ValkeyArray(valkeymock.ValkeyBlobString("p1"))))
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("GET", "proposal/p1")).
+		Return(valkeymock.Result(valkeymock.ValkeyBlobString(string(pending))))
+	records := auditRecords(mockClient, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/variables/proposals?hub=prod", http.NoBody)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var got []proposals.Proposal
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "p1", got[0].ID)
+
+	require.Len(t, *records, 1)
+	assert.Equal(t, proposalExpired, auditField((*records)[0], "action"))
+	assert.Equal(t, "old", auditField((*records)[0], "proposal_id"))
+}
+
+func TestHandlePromoteVariables_Protected(t *testing.T) {
+	target := manualVariablesConfigMap("prod", map[string]string{"drop_all": "boolean"})
+	target.Annotations = map[string]string{manualvariables.ProtectedVariablesAnnotation: "drop_all"}
+	deps := setupMocks(t, newFakeClientsetWithHubs(t,
+		manualVariablesConfigMap("staging", map[string]string{"drop_all": "boolean"}),
+		target,
+	))
+	mux := NewRouter(t.Context(), deps)
+	expectRejectionAudits(t, deps)
+
+	body := `{"sourceHub":"staging","targetHub":"prod","apply":true}`
+	req := httptest.NewRequest(http.MethodPost, "/variables/promote", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/nats"
+	"github.com/decisiveai/mdai-gateway/internal/redact"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"go.uber.org/zap"
+)
+
+// publishEvents publishes events with the event redaction of their hub applied to the payloads
+// and writes their audit records with the audit redaction applied instead. Nothing is published
+// when the policy of a hub cannot be read.
+func publishEvents(ctx context.Context, deps HandlerDeps, events []adapter.EventPerSubject) (int, error) {
+	redacted, errs := redactEvents(deps, events)
+	if err := errors.Join(errs...); err != nil {
+		return 0, err
+	}
+	return nats.PublishEvents(ctx, deps.Logger, deps.EventPublisher, redacted, deps.AuditInserter)
+}
+
+// publishEachEvent is publishEvents with the outcome of every event. Only the events whose hub
+// policy cannot be read fail without being published.
+func publishEachEvent(ctx context.Context, deps HandlerDeps, events []adapter.EventPerSubject) []error {
+	redacted, errs := redactEvents(deps, events)
+	toPublish := make([]adapter.EventPerSubject, 0, len(events))
+	indexes := make([]int, 0, len(events))
+	for i, event := range redacted {
+		if errs[i] == nil {
+			toPublish = append(toPublish, event)
+			indexes = append(indexes, i)
+		}
+	}
+	for i, err := range nats.PublishEachEvent(ctx, deps.Logger, deps.EventPublisher, toPublish, deps.AuditInserter) {
+		errs[indexes[i]] = err
+	}
+	return errs
+}
+
+// redactEvents redacts each event by the policy of its hub. The error at an event's index is set
+// when that policy cannot be read.
+func redactEvents(deps HandlerDeps, events []adapter.EventPerSubject) ([]adapter.EventPerSubject, []error) {
+	type hubPolicyResult struct {
+		policy manualvariables.Policy
+		err    error
+	}
+	policies := make(map[string]hubPolicyResult)
+	redacted := make([]adapter.EventPerSubject, len(events))
+	errs := make([]error, len(events))
+	for i, event := range events {
+		result, ok := policies[event.Event.HubName]
+		if !ok {
+			result.policy, result.err = hubPolicy(deps, event.Event.HubName)
+			if result.err != nil {
+				deps.Logger.Error("Failed to read hub policy, not publishing its events", zap.String("hubName", event.Event.HubName), zap.Error(result.err))
+			}
+			policies[event.Event.HubName] = result
+		}
+		if result.err != nil {
+			errs[i] = result.err
+			continue
+		}
+		redacted[i] = redactEvent(deps.Logger, result.policy, event)
+	}
+	return redacted, errs
+}
+
+func redactEvent(logger *zap.Logger, policy manualvariables.Policy, event adapter.EventPerSubject) adapter.EventPerSubject {
+	if policy.AuditRedaction.IsZero() && policy.EventRedaction.IsZero() {
+		return event
+	}
+
+	payload := event.Event.Payload
+	auditPayload, err := redactPayload(policy.AuditRedaction, event.Event.Source, payload)
+	if err != nil {
+		logger.Error("Failed to redact event payload, masking it in the audit record", zap.String("eventId", event.Event.ID), zap.Error(err))
+		auditPayload = redact.Masked
+	}
+	// The operator sets manual variables to the data of their events, so it is never redacted.
+	eventPayload := payload
+	if event.Event.Source != eventing.ManualVariablesEventSource {
+		eventPayload, err = redactPayload(policy.EventRedaction, event.Event.Source, payload)
+		if err != nil {
+			logger.Error("Failed to redact event payload, masking it", zap.String("eventId", event.Event.ID), zap.Error(err))
+			eventPayload = redact.Masked
+		}
+	}
+
+	event.Event.Payload = eventPayload
+	if auditPayload != eventPayload {
+		event.AuditPayload = auditPayload
+	}
+	return event
+}
+
+// redactPayload applies policy to the payload of an alert or manual variable event. Payloads of
+// other sources are returned unchanged.
+func redactPayload(policy redact.Policy, source, payload string) (string, error) {
+	if policy.IsZero() {
+		return payload, nil
+	}
+
+	var redacted any
+	switch {
+	case adapter.IsAlertSource(source):
+		var alert adapter.AlertPayload
+		if err := json.Unmarshal([]byte(payload), &alert); err != nil {
+			return "", fmt.Errorf("decode alert payload: %w", err)
+		}
+		redacted = redactAlertPayload(policy, alert)
+	case source == eventing.ManualVariablesEventSource:
+		var variable variablesActionPayload
+		if err := json.Unmarshal([]byte(payload), &variable); err != nil {
+			return "", fmt.Errorf("decode variable payload: %w", err)
+		}
+		variable.Data, _ = policy.Variable(variable.VariableRef, variable.Data)
+		redacted = variable
+	default:
+		return payload, nil
+	}
+
+	data, err := json.Marshal(redacted)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func redactAlertPayload(policy redact.Policy, alert adapter.AlertPayload) adapter.AlertPayload {
+	labels := policy.Map(redact.Label, alert.Labels)
+	if alert.ValueString != "" && !maps.Equal(labels, alert.Labels) {
+		// The value string repeats the labels.
+		alert.ValueString = redact.Masked
+	}
+	alert.Labels = labels
+	alert.Annotations = policy.Map(redact.Annotation, alert.Annotations)
+	if alert.Value != "" {
+		// The value is the current_value annotation.
+		value := policy.Map(redact.Annotation, map[string]string{adapter.CurrentValue: alert.Value})
+		alert.Value = value[adapter.CurrentValue]
+	}
+	return alert
+}
+
+// redactAlertMessage returns a copy of msg for logging with the audit redaction of each alert's
+// hub applied. Labels and annotations common to all alerts are redacted by the rules of every
+// hub in the message.
+func redactAlertMessage(deps HandlerDeps, msg webhook.Message) webhook.Message {
+	if msg.Data == nil {
+		return msg
+	}
+
+	data := *msg.Data
+	data.Alerts = make(template.Alerts, len(msg.Data.Alerts))
+	var combined redact.Policy
+	policies := make(map[string]redact.Policy)
+	for i, alert := range msg.Data.Alerts {
+		hubName := alert.Annotations[adapter.HubName]
+		policy, ok := policies[hubName]
+		if !ok {
+			policy = hubAuditRedaction(deps, hubName)
+			policies[hubName] = policy
+			combined = combined.Append(policy)
+		}
+		alert.Labels = template.KV(policy.Map(redact.Label, alert.Labels))
+		alert.Annotations = template.KV(policy.Map(redact.Annotation, alert.Annotations))
+		data.Alerts[i] = alert
+	}
+	data.GroupLabels = template.KV(combined.Map(redact.Label, data.GroupLabels))
+	data.CommonLabels = template.KV(combined.Map(redact.Label, data.CommonLabels))
+	data.CommonAnnotations = template.KV(combined.Map(redact.Annotation, data.CommonAnnotations))
+
+	msg.Data = &data
+	return msg
+}
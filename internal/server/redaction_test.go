@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/decisiveai/mdai-data-core/eventing"
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/manualvariables"
+	"github.com/decisiveai/mdai-gateway/internal/redact"
+	"github.com/decisiveai/mdai-gateway/internal/valkey"
+	"github.com/decisiveai/mdai-gateway/mocks"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/zap"
+)
+
+func redactingClientset(t *testing.T, annotations map[string]string) HandlerDeps {
+	t.Helper()
+
+	cm := manualVariablesConfigMap("mdaihub-sample", map[string]string{"data_string": "string", "data_map": "map"})
+	cm.Annotations = annotations
+	return setupMocks(t, newFakeClientsetWithHubs(t, cm))
+}
+
+func TestAlerts_AuditRedaction(t *testing.T) {
+	deps := redactingClientset(t, map[string]string{
+		manualvariables.AuditRedactionAnnotation: "label/instance=hash, annotation/description=drop",
+	})
+	mux := NewRouter(t.Context(), deps)
+	records := auditRecords(deps.ValkeyClient.(*valkeymock.Client), 2) //nolint:forcetypeassert
+
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBuffer(readPayloadFromFile(t, alert3)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	require.Len(t, *records, 2)
+	for _, record := range *records {
+		var payload adapter.AlertPayload
+		require.NoError(t, json.Unmarshal([]byte(auditField(record, "payload")), &payload))
+		assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, payload.Labels["instance"])
+		assert.Equal(t, "service-a", payload.Labels["service_name"])
+		assert.NotContains(t, payload.Annotations, "description")
+		assert.Equal(t, "mdaihub-sample", payload.Annotations["hub_name"])
+	}
+}
+
+func TestRedactEvent(t *testing.T) {
+	event, err := newVariableEvent("mdaihub-sample", "data_map", valkey.VariableTypeMap, valkey.CommandAdd,
+		map[string]string{"eu": "customer-1"}, manualvariables.ChangeMetadata{})
+	require.NoError(t, err)
+	original := event.Event.Payload
+
+	auditOnly := manualvariables.PolicyFromAnnotations(map[string]string{
+		manualvariables.AuditRedactionAnnotation: "variable/data_map=mask",
+	})
+	redacted := redactEvent(zap.NewNop(), auditOnly, event)
+	assert.Equal(t, original, redacted.Event.Payload, "events keep the values")
+	assert.JSONEq(t, `{"variableRef":"data_map","dataType":"map","operation":"add","data":{"eu":"***"}}`, redacted.AuditPayload)
+
+	// The operator sets the variable to the data of the event.
+	both := manualvariables.PolicyFromAnnotations(map[string]string{
+		manualvariables.AuditRedactionAnnotation: "variable/data_map=drop",
+		manualvariables.EventRedactionAnnotation: "variable/data_map=hash",
+	})
+	redacted = redactEvent(zap.NewNop(), both, event)
+	assert.Equal(t, original, redacted.Event.Payload, "events keep the values")
+	assert.JSONEq(t, `{"variableRef":"data_map","dataType":"map","operation":"add","data":null}`, redacted.AuditPayload)
+
+	assert.Equal(t, event, redactEvent(zap.NewNop(), manualvariables.Policy{}, event))
+}
+
+func TestRedactAlertMessage(t *testing.T) {
+	deps := redactingClientset(t, map[string]string{
+		manualvariables.AuditRedactionAnnotation: "label/dc=mask",
+	})
+
+	var msg webhook.Message
+	require.NoError(t, json.Unmarshal(readPayloadFromFile(t, alert3), &msg))
+	redacted := redactAlertMessage(deps, msg)
+
+	assert.Equal(t, redact.Masked, redacted.Data.Alerts[0].Labels["dc"])
+	assert.Equal(t, redact.Masked, redacted.Data.CommonLabels["dc"])
+	assert.Equal(t, "eu-west-1", msg.Data.Alerts[0].Labels["dc"], "the message itself is not modified")
+	assert.Equal(t, "eu-west-1", msg.Data.CommonLabels["dc"])
+}
+
+func TestUnreadableHubPolicy(t *testing.T) {
+	// Publishing without the redaction the hub asked for would leak the values.
+	deps := redactingClientset(t, map[string]string{
+		manualvariables.AuditRedactionAnnotation: "label/instance=erase",
+	})
+	mux := NewRouter(t.Context(), deps)
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/mdaihub-sample/var/data_string", bytes.NewBufferString(`{"data":"value"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), "failed to read hub policy")
+
+	req = httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBuffer(readPayloadFromFile(t, alert3)))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+	var response struct {
+		Failed  int `json:"failed"`
+		Results []adapter.AlertResult
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Failed)
+	for _, result := range response.Results {
+		assert.Equal(t, adapter.AlertFailed, result.Status)
+		assert.Contains(t, result.Reason, `unknown action "erase"`)
+	}
+
+	event, err := newVariableEvent("mdaihub-sample", "data_string", valkey.VariableTypeStr, valkey.CommandAdd, "value", manualvariables.ChangeMetadata{})
+	require.NoError(t, err)
+	published, err := publishEvents(t.Context(), deps, []adapter.EventPerSubject{event})
+	require.Error(t, err)
+	assert.Zero(t, published)
+}
+
+func TestSetVariable_EventRedaction(t *testing.T) {
+	deps := redactingClientset(t, map[string]string{
+		manualvariables.AuditRedactionAnnotation: "variable/data_string=mask",
+		manualvariables.EventRedactionAnnotation: "variable/data_string=hash",
+	})
+	var published eventing.MdaiEvent
+	mockPub := &mocks.MockPublisher{}
+	mockPub.On("Publish", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { published = args.Get(1).(eventing.MdaiEvent) }). //nolint:forcetypeassert
+		Return(nil).Once()
+	deps.EventPublisher = mockPub
+	mux := NewRouter(t.Context(), deps)
+	records := auditRecords(deps.ValkeyClient.(*valkeymock.Client), 1) //nolint:forcetypeassert
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/mdaihub-sample/var/data_string", bytes.NewBufferString(`{"data":"customer-1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	mockPub.AssertExpectations(t)
+	// The operator sets the variable to the published value.
+	assert.JSONEq(t, `{"variableRef":"data_string","dataType":"string","operation":"add","data":"customer-1"}`, published.Payload)
+	require.Len(t, *records, 1)
+	assert.JSONEq(t, `{"variableRef":"data_string","dataType":"string","operation":"add","data":"***"}`, auditField((*records)[0], "payload"))
+}
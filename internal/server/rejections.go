@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"go.uber.org/zap"
+)
+
+// maxRejectionReason bounds the part of a rejection response kept as the audit reason.
+const maxRejectionReason = 1024
+
+// auditRejections writes a request_rejected audit record for every request next answers with a
+// client error, so that refused changes leave a trace next to the published ones.
+func auditRejections(ctx context.Context, deps HandlerDeps, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &rejectionRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status < http.StatusBadRequest || rec.status >= http.StatusInternalServerError {
+			return
+		}
+
+		fields := map[string]string{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"status": strconv.Itoa(rec.status),
+			"reason": rejectionReason(rec.body.Bytes()),
+		}
+		for field, pathValue := range map[string]string{"hub_name": "hubName", "variable_ref": "varName", "proposal_id": "id", "freeze_id": "freezeId"} {
+			if v := r.PathValue(pathValue); v != "" {
+				fields[field] = v
+			}
+		}
+		maps.Copy(fields, identity.ActorFromRequest(r).AuditFields())
+
+		if err := auditutils.RecordAuditRecord(ctx, deps.Logger, deps.AuditInserter, auditutils.RequestRejectedType, fields); err != nil {
+			deps.Logger.Error("Failed to audit rejected request", zap.String("path", r.URL.Path), zap.Error(err))
+		}
+	})
+}
+
+// rejectionReason turns an error response body into a single line. JSON string bodies, as written
+// by httputil.WriteJSONResponse, are unquoted.
+func rejectionReason(body []byte) string {
+	body = bytes.TrimSpace(body)
+	var s string
+	if err := json.Unmarshal(body, &s); err == nil {
+		return s
+	}
+	return strings.Join(strings.Fields(string(body)), " ")
+}
+
+// rejectionRecorder keeps the status and the beginning of the body of a response.
+type rejectionRecorder struct {
+	http.ResponseWriter
+
+	status int
+	body   bytes.Buffer
+}
+
+func (r *rejectionRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *rejectionRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if n := min(len(p), maxRejectionReason-r.body.Len()); r.status >= http.StatusBadRequest && n > 0 {
+		r.body.Write(p[:n])
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *rejectionRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRejections_InvalidPayload(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	records := expectRejectionAudits(t, deps)
+
+	req := httptest.NewRequest(http.MethodPost, "/variables/hub/mdaihub-sample/var/data_int", bytes.NewBufferString(`{"data":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.UserHeader, "alice")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Len(t, *records, 1)
+	record := (*records)[0]
+	assert.Equal(t, auditutils.RequestRejectedType, auditField(record, "type"))
+	assert.Equal(t, "400", auditField(record, "status"))
+	assert.Equal(t, "Invalid request payload: Int expected", auditField(record, "reason"))
+	assert.Equal(t, http.MethodPost, auditField(record, "method"))
+	assert.Equal(t, "mdaihub-sample", auditField(record, "hub_name"))
+	assert.Equal(t, "data_int", auditField(record, "variable_ref"))
+	assert.Equal(t, "alice", auditField(record, identity.ActorUserAuditField))
+}
+
+func TestAuditRejections_UnknownHub(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	records := expectRejectionAudits(t, deps)
+
+	req := httptest.NewRequest(http.MethodDelete, "/variables/hub/nope/var/data_int", bytes.NewBufferString(`{"data":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Len(t, *records, 1)
+	assert.Equal(t, "404", auditField((*records)[0], "status"))
+	assert.Equal(t, "nope", auditField((*records)[0], "hub_name"))
+	assert.NotEmpty(t, auditField((*records)[0], "reason"))
+}
+
+func TestAlerts_MissingFingerprint(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	records := expectRejectionAudits(t, deps)
+
+	body := `{"receiver":"mdai","status":"firing","alerts":[{"status":"firing","annotations":{"alert_name":"DiskUsageHigh","hub_name":"mdaihub-sample"}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/alerts/alertmanager", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Len(t, *records, 1)
+	assert.Contains(t, auditField((*records)[0], "reason"), `"message":"invalid Alertmanager payload: alert fingerprint is required"`)
+	assert.Contains(t, rr.Body.String(), `"status":"invalid"`)
+	assert.Equal(t, "/alerts/alertmanager", auditField((*records)[0], "path"))
+}
+
+func TestRejectionReason(t *testing.T) {
+	assert.Equal(t, "variable not found", rejectionReason([]byte(`"variable not found"`+"\n")))
+	assert.Equal(t, "Invalid JSON format in request payload", rejectionReason([]byte("Invalid JSON format in request payload\n")))
+	assert.Equal(t, `{"errors": ["a"]}`, rejectionReason([]byte("{\"errors\":\n [\"a\"]}")))
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"strconv"
+	"time"
+
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/httputil"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"go.uber.org/zap"
+)
+
+// retentionPolicyResponse shows a retention policy. Source is "valkey" for a policy set at
+// runtime and "deployment" for the retention the gateway was started with. EffectiveMaxAge is set
+// when records are trimmed sooner than MaxAge: data-core writers keep trimming by the
+// deployment's retention.
+type retentionPolicyResponse struct {
+	MaxAge          string     `json:"maxAge"`
+	MaxLen          int64      `json:"maxLen"`
+	Source          string     `json:"source"`
+	EffectiveMaxAge string     `json:"effectiveMaxAge,omitempty"`
+	UpdatedBy       string     `json:"updatedBy,omitempty"`
+	UpdatedAt       *time.Time `json:"updatedAt,omitempty"`
+}
+
+func newRetentionPolicyResponse(policy auditutils.RetentionPolicy, deploymentMaxAge time.Duration) retentionPolicyResponse {
+	resp := retentionPolicyResponse{
+		MaxAge:    auditutils.FormatRetention(policy.MaxAge),
+		MaxLen:    policy.MaxLen,
+		Source:    "valkey",
+		UpdatedBy: policy.UpdatedBy,
+	}
+	if policy.Default {
+		resp.Source = "deployment"
+	} else if deploymentMaxAge < policy.MaxAge {
+		resp.EffectiveMaxAge = auditutils.FormatRetention(deploymentMaxAge)
+	}
+	if !policy.UpdatedAt.IsZero() {
+		resp.UpdatedAt = &policy.UpdatedAt
+	}
+	return resp
+}
+
+// requireAuditAdmin answers 403 and returns false unless the caller holds the audit admin scope.
+func requireAuditAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if identity.HasScope(r, auditutils.AdminScope) {
+		return true
+	}
+	http.Error(w, "The "+auditutils.AdminScope+" scope is required", http.StatusForbidden)
+	return false
+}
+
+func handleAuditStats(_ context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := auditutils.Stats(r.Context(), deps.ValkeyClient)
+		if err != nil {
+			deps.Logger.Error("Failed to read audit stream stats", zap.Error(err))
+			http.Error(w, "Unable to fetch history from Valkey", http.StatusInternalServerError)
+			return
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, stats)
+	}
+}
+
+func handleGetAuditRetention(_ context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		policy, err := auditutils.GetRetentionPolicy(r.Context(), deps.ValkeyClient, deps.AuditRetention)
+		if err != nil {
+			deps.Logger.Error("Failed to read audit retention policy", zap.Error(err))
+			http.Error(w, "Failed to read audit retention policy", http.StatusInternalServerError)
+			return
+		}
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, newRetentionPolicyResponse(policy, deps.AuditRetention))
+	}
+}
+
+// handleSetAuditRetention stores a retention policy that every gateway applies to its next write.
+func handleSetAuditRetention(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+
+		if !requireAuditAdmin(w, r) {
+			return
+		}
+
+		var update auditutils.RetentionPolicyUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Invalid JSON format in request payload", http.StatusBadRequest)
+			return
+		}
+		policy, err := update.Parse()
+		if err != nil {
+			http.Error(w, "Invalid retention policy: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		previous, err := auditutils.GetRetentionPolicy(ctx, deps.ValkeyClient, deps.AuditRetention)
+		if err != nil {
+			deps.Logger.Error("Failed to read audit retention policy", zap.Error(err))
+			http.Error(w, "Failed to read audit retention policy", http.StatusInternalServerError)
+			return
+		}
+
+		policy.UpdatedBy = identity.User(r)
+		policy.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+		if err := auditutils.SetRetentionPolicy(ctx, deps.ValkeyClient, policy); err != nil {
+			deps.Logger.Error("Failed to store audit retention policy", zap.Error(err))
+			http.Error(w, "Failed to store audit retention policy", http.StatusInternalServerError)
+			return
+		}
+
+		recordRetentionChange(ctx, deps, r, previous, policy)
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, newRetentionPolicyResponse(policy, deps.AuditRetention))
+	}
+}
+
+// handleResetAuditRetention removes the stored policy, so the deployment's retention applies again.
+func handleResetAuditRetention(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAuditAdmin(w, r) {
+			return
+		}
+
+		previous, err := auditutils.GetRetentionPolicy(ctx, deps.ValkeyClient, deps.AuditRetention)
+		if err != nil {
+			deps.Logger.Error("Failed to read audit retention policy", zap.Error(err))
+			http.Error(w, "Failed to read audit retention policy", http.StatusInternalServerError)
+			return
+		}
+		if err := auditutils.ResetRetentionPolicy(ctx, deps.ValkeyClient); err != nil {
+			deps.Logger.Error("Failed to reset audit retention policy", zap.Error(err))
+			http.Error(w, "Failed to reset audit retention policy", http.StatusInternalServerError)
+			return
+		}
+
+		policy := auditutils.RetentionPolicy{MaxAge: deps.AuditRetention, Default: true}
+		recordRetentionChange(ctx, deps, r, previous, policy)
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, newRetentionPolicyResponse(policy, deps.AuditRetention))
+	}
+}
+
+func recordRetentionChange(ctx context.Context, deps HandlerDeps, r *http.Request, previous, policy auditutils.RetentionPolicy) {
+	fields := map[string]string{
+		"max_age":          auditutils.FormatRetention(policy.MaxAge),
+		"max_len":          strconv.FormatInt(policy.MaxLen, 10),
+		"previous_max_age": auditutils.FormatRetention(previous.MaxAge),
+		"previous_max_len": strconv.FormatInt(previous.MaxLen, 10),
+		"reset":            strconv.FormatBool(policy.Default),
+	}
+	maps.Copy(fields, identity.ActorFromRequest(r).AuditFields())
+
+	if err := auditutils.RecordAuditRecord(ctx, deps.Logger, deps.AuditInserter, auditutils.RetentionChangedType, fields); err != nil {
+		deps.Logger.Error("Failed to audit retention policy change", zap.Error(err))
+	}
+}
+
+// handleAuditTrim trims the audit stream on demand. The trim is recorded in the stream before it
+// is carried out, and not carried out when it cannot be recorded.
+func handleAuditTrim(ctx context.Context, deps HandlerDeps) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+
+		if !requireAuditAdmin(w, r) {
+			return
+		}
+
+		var req auditutils.TrimRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON format in request payload", http.StatusBadRequest)
+			return
+		}
+		var invalid auditutils.InvalidTrimError
+		if err := req.Validate(); errors.As(err, &invalid) {
+			http.Error(w, invalid.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fields := make(map[string]string)
+		if req.OlderThan != "" {
+			fields["older_than"] = req.OlderThan
+		}
+		if req.MaxLen != nil {
+			fields["max_len"] = strconv.FormatInt(*req.MaxLen, 10)
+		}
+		maps.Copy(fields, identity.ActorFromRequest(r).AuditFields())
+		if err := auditutils.RecordAuditRecord(ctx, deps.Logger, deps.AuditInserter, auditutils.AuditTrimmedType, fields); err != nil {
+			deps.Logger.Error("Failed to audit audit stream trim, not trimming", zap.Error(err))
+			http.Error(w, "Failed to record the trim in the audit stream", http.StatusInternalServerError)
+			return
+		}
+
+		removed, err := auditutils.Trim(ctx, deps.ValkeyClient, req, time.Now())
+		if err != nil {
+			deps.Logger.Error("Failed to trim audit stream", zap.Error(err))
+			http.Error(w, "Failed to trim audit stream", http.StatusInternalServerError)
+			return
+		}
+		deps.Logger.Info("Trimmed audit stream", zap.Int64("removed", removed))
+
+		httputil.WriteJSONResponse(w, deps.Logger, http.StatusOK, map[string]int64{"removed": removed})
+	}
+}
+
+// RunAuditRetentionTrimmer applies the stored retention policy to the audit stream every
+// interval, so that it holds even when no record is written, until ctx ends.
+func RunAuditRetentionTrimmer(ctx context.Context, deps HandlerDeps, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := auditutils.ApplyRetentionPolicy(ctx, deps.ValkeyClient)
+			if err != nil {
+				deps.Logger.Error("Failed to apply audit retention policy", zap.Error(err))
+				continue
+			}
+			if removed > 0 {
+				deps.Logger.Info("Trimmed audit stream by the retention policy", zap.Int64("removed", removed))
+			}
+		}
+	}
+}
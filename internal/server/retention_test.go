@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleAuditStats(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	m := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("XLEN", "mdai_hub_event_history")).Return(valkeymock.Result(valkeymock.ValkeyInt64(0)))
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("MEMORY", "USAGE", "mdai_hub_event_history")).Return(valkeymock.Result(valkeymock.ValkeyNil()))
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/stats", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"length":0,"memoryBytes":0}`, rr.Body.String())
+}
+
+func TestHandleGetAuditRetention(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	deps.AuditRetention = 30 * 24 * time.Hour
+	mux := NewRouter(t.Context(), deps)
+	m := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "audit/retention")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{})))
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/retention", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"maxAge":"30d","maxLen":0,"source":"deployment"}`, rr.Body.String())
+}
+
+func TestHandleSetAuditRetention(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	deps.AuditRetention = 30 * 24 * time.Hour
+	mux := NewRouter(t.Context(), deps)
+	m := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "audit/retention")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{})))
+	m.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		return cmd[0] == "HSET" && cmd[1] == "audit/retention" && auditField(cmd, "max_age_ms") == "604800000" && auditField(cmd, "max_len") == "5000"
+	})).Return(valkeymock.Result(valkeymock.ValkeyInt64(4)))
+	records := auditRecords(m, 1)
+
+	req := httptest.NewRequest(http.MethodPut, "/audit/retention", bytes.NewBufferString(`{"maxAge":"7d","maxLen":5000}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.UserHeader, "alice")
+	req.Header.Set(identity.ScopesHeader, auditutils.AdminScope)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"maxAge":"7d","maxLen":5000,"source":"valkey","updatedBy":"alice"`)
+	require.Len(t, *records, 1)
+	record := (*records)[0]
+	assert.Equal(t, auditutils.RetentionChangedType, auditField(record, "type"))
+	assert.Equal(t, "7d", auditField(record, "max_age"))
+	assert.Equal(t, "30d", auditField(record, "previous_max_age"))
+	assert.Equal(t, "alice", auditField(record, identity.ActorUserAuditField))
+}
+
+func TestHandleAuditTrim(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	m := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	records := auditRecords(m, 1)
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("XTRIM", "mdai_hub_event_history", "MAXLEN", "1000")).
+		DoAndReturn(func(any, valkey.Completed) valkey.ValkeyResult {
+			assert.Len(t, *records, 1, "the trim is recorded first")
+			return valkeymock.Result(valkeymock.ValkeyInt64(42))
+		})
+
+	req := httptest.NewRequest(http.MethodPost, "/audit/trim", bytes.NewBufferString(`{"maxLen":1000}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.UserHeader, "alice")
+	req.Header.Set(identity.ScopesHeader, auditutils.AdminScope)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"removed":42}`, rr.Body.String())
+	require.Len(t, *records, 1)
+	record := (*records)[0]
+	assert.Equal(t, auditutils.AuditTrimmedType, auditField(record, "type"))
+	assert.Equal(t, "1000", auditField(record, "max_len"))
+	assert.Equal(t, "alice", auditField(record, identity.ActorUserAuditField))
+}
+
+func TestHandleAuditTrim_AuditFailure(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	m := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	m.EXPECT().Do(gomock.Any(), XaddMatcher{}).Return(valkeymock.ErrorResult(errors.New("connection reset")))
+
+	req := httptest.NewRequest(http.MethodPost, "/audit/trim", bytes.NewBufferString(`{"olderThan":"7d"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(identity.ScopesHeader, auditutils.AdminScope)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	// Without its record, nothing is trimmed.
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "Failed to record the trim in the audit stream\n", rr.Body.String())
+}
+
+func TestHandleGetAuditRetention_LongerThanDeployment(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	deps.AuditRetention = 30 * 24 * time.Hour
+	mux := NewRouter(t.Context(), deps)
+	m := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	m.EXPECT().Do(gomock.Any(), valkeymock.Match("HGETALL", "audit/retention")).
+		Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+			"max_age_ms": valkeymock.ValkeyBlobString("7776000000"),
+			"max_len":    valkeymock.ValkeyBlobString("0"),
+		})))
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/retention", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"maxAge":"90d","maxLen":0,"source":"valkey","effectiveMaxAge":"30d"}`, rr.Body.String())
+}
+
+func TestRunAuditRetentionTrimmer(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	m := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+	ctx, cancel := context.WithCancel(t.Context())
+	m.EXPECT().Do(gomock.Any(), valkeymock.MatchFn(func(cmd []string) bool {
+		return cmd[0] == "EVALSHA" && cmd[3] == "mdai_hub_event_history" && cmd[4] == "audit/retention"
+	})).DoAndReturn(func(any, valkey.Completed) valkey.ValkeyResult {
+		cancel()
+		return valkeymock.Result(valkeymock.ValkeyInt64(3))
+	}).MinTimes(1)
+
+	RunAuditRetentionTrimmer(ctx, deps, time.Millisecond)
+}
+
+func TestAuditAdminEndpoints_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		scopes string
+		status int
+		reason string
+	}{
+		{"trim without scope", http.MethodPost, "/audit/trim", `{"maxLen":1}`, "", http.StatusForbidden, "The audit:admin scope is required"},
+		{"policy without scope", http.MethodPut, "/audit/retention", `{"maxAge":"1d"}`, "freeze:override", http.StatusForbidden, "The audit:admin scope is required"},
+		{"reset without scope", http.MethodDelete, "/audit/retention", "", "", http.StatusForbidden, "The audit:admin scope is required"},
+		{"invalid trim", http.MethodPost, "/audit/trim", `{}`, auditutils.AdminScope, http.StatusBadRequest, "invalid trim: set olderThan or maxLen"},
+		{"invalid policy", http.MethodPut, "/audit/retention", `{"maxAge":"forever"}`, auditutils.AdminScope, http.StatusBadRequest, `Invalid retention policy: invalid retention "forever"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := setupMocks(t, newFakeClientset(t))
+			mux := NewRouter(t.Context(), deps)
+			records := expectRejectionAudits(t, deps)
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(identity.ScopesHeader, tt.scopes)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			require.Len(t, *records, 1)
+			assert.Equal(t, tt.reason, auditField((*records)[0], "reason"))
+		})
+	}
+}
@@ -4,12 +4,21 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/decisiveai/mdai-data-core/audit"
 	"github.com/decisiveai/mdai-data-core/eventing/publisher"
 	datacorekube "github.com/decisiveai/mdai-data-core/kube"
 	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/decisiveai/mdai-gateway/internal/alertpush"
+	"github.com/decisiveai/mdai-gateway/internal/alertstate"
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/auditsink"
+	"github.com/decisiveai/mdai-gateway/internal/freeze"
+	"github.com/decisiveai/mdai-gateway/internal/hubs"
 	"github.com/decisiveai/mdai-gateway/internal/opamp"
+	"github.com/decisiveai/mdai-gateway/internal/proposals"
+	"github.com/decisiveai/mdai-gateway/internal/webhooks"
 	"github.com/valkey-io/valkey-go"
 	"go.uber.org/zap"
 )
@@ -18,22 +27,59 @@ type HandlerDeps struct {
 	Logger              *zap.Logger
 	ValkeyClient        valkey.Client
 	AuditAdapter        *audit.AuditAdapter
+	AuditInserter       auditutils.Inserter // writes audit records; AuditAdapter only reads them
+	AuditSinks          *auditsink.FanOut   // reports per-sink writes, may be nil
+	AuditRetention      time.Duration       // deployment default, replaced by a policy stored in Valkey
 	EventPublisher      publisher.Publisher
 	ConfigMapController *datacorekube.ConfigMapController
-	Deduper             *adapter.Deduper
+	Hubs                *hubs.Registry // variables declared by the MdaiHub resources, may be nil
+	Deduper             adapter.Deduper
+	Webhooks            *webhooks.Registry // generic webhook sources, may be nil
+	AlertPush           *alertpush.Tracker // alerts pushed to /api/v2/alerts
+	AlertStates         *alertstate.Store  // current state and transitions of alerts
 	OpAMPServer         *opamp.OpAMPControlServer
+	Proposals           *proposals.Store
+	Freezes             *freeze.Store
 }
 
 func NewRouter(ctx context.Context, deps HandlerDeps) *http.ServeMux {
 	router := http.NewServeMux()
 
 	router.HandleFunc("GET /audit", handleAuditEventsGet(ctx, deps))
-	router.Handle("POST /alerts/alertmanager", requireJSON(handlePromAlertsPost(deps)))
+	router.HandleFunc("GET /v2/audit", handleAuditRecordsGet(ctx, deps))
+	router.HandleFunc("GET /audit/export", handleAuditExport(ctx, deps))
+	router.HandleFunc("GET /audit/verify", handleAuditVerify(ctx, deps))
+	router.HandleFunc("GET /audit/tail", handleAuditTail(ctx, deps))
+	router.HandleFunc("GET /audit/sinks", handleAuditSinks(ctx, deps))
+	router.HandleFunc("GET /audit/stats", handleAuditStats(ctx, deps))
+	router.HandleFunc("GET /audit/retention", handleGetAuditRetention(ctx, deps))
+	router.Handle("PUT /audit/retention", auditRejections(ctx, deps, requireJSON(handleSetAuditRetention(ctx, deps))))
+	router.Handle("DELETE /audit/retention", auditRejections(ctx, deps, handleResetAuditRetention(ctx, deps)))
+	router.Handle("POST /audit/trim", auditRejections(ctx, deps, requireJSON(handleAuditTrim(ctx, deps))))
+	router.Handle("POST /alerts/alertmanager", auditRejections(ctx, deps, requireJSON(handlePromAlertsPost(deps))))
+	router.Handle("POST /alerts/grafana", auditRejections(ctx, deps, requireJSON(handleGrafanaAlertsPost(deps))))
+	router.Handle("POST /api/v2/alerts", auditRejections(ctx, deps, requireJSON(handleAlertsV2Post(deps))))
+	router.Handle("POST /events/cloudevents", auditRejections(ctx, deps, handleCloudEventPost(deps)))
+	router.Handle("POST /webhooks/{source}", auditRejections(ctx, deps, requireJSON(handleWebhookPost(deps))))
+	router.HandleFunc("GET /alerts/deduper", handleAlertDeduperStats(ctx, deps))
+	router.HandleFunc("GET /alerts/active", handleActiveAlerts(deps))
+	router.HandleFunc("GET /alerts/{fingerprint}/history", handleAlertHistory(deps))
 	router.Handle("GET /variables/list", handleListAllVariables(ctx, deps))
 	router.Handle("GET /variables/list/hub/{hubName}", handleListHubVariables(ctx, deps))
 	router.Handle("GET /variables/values/hub/{hubName}/var/{varName}", handleGetVariables(ctx, deps))
-	router.Handle("POST /variables/hub/{hubName}/var/{varName}", handleSetDeleteVariables(ctx, deps))
-	router.Handle("DELETE /variables/hub/{hubName}/var/{varName}", handleSetDeleteVariables(ctx, deps))
+	router.Handle("GET /variables/history/hub/{hubName}/var/{varName}", handleVariableHistory(ctx, deps))
+	router.Handle("POST /variables/hub/{hubName}/var/{varName}", auditRejections(ctx, deps, handleSetDeleteVariables(ctx, deps)))
+	router.Handle("DELETE /variables/hub/{hubName}/var/{varName}", auditRejections(ctx, deps, handleSetDeleteVariables(ctx, deps)))
+	router.Handle("POST /variables/promote", auditRejections(ctx, deps, requireJSON(handlePromoteVariables(ctx, deps))))
+	router.Handle("GET /variables/export", handleExportVariables(ctx, deps))
+	router.Handle("POST /variables/import", auditRejections(ctx, deps, handleImportVariables(ctx, deps)))
+	router.Handle("GET /variables/proposals", handleListProposals(ctx, deps))
+	router.Handle("GET /variables/proposals/{id}", handleGetProposal(ctx, deps))
+	router.Handle("POST /variables/proposals/{id}/approve", auditRejections(ctx, deps, handleDecideProposal(ctx, deps, proposalApproved)))
+	router.Handle("POST /variables/proposals/{id}/reject", auditRejections(ctx, deps, handleDecideProposal(ctx, deps, proposalRejected)))
+	router.Handle("GET /freezes/hub/{hubName}", handleListFreezes(ctx, deps))
+	router.Handle("POST /freezes/hub/{hubName}", auditRejections(ctx, deps, requireJSON(handleCreateFreeze(ctx, deps))))
+	router.Handle("DELETE /freezes/hub/{hubName}/{freezeId}", auditRejections(ctx, deps, handleDeleteFreeze(ctx, deps)))
 	router.Handle("POST /opamp", deps.OpAMPServer.HandlerFunc)
 
 	return router
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	auditutils "github.com/decisiveai/mdai-gateway/internal/audit"
+	"github.com/decisiveai/mdai-gateway/internal/cloudevents"
+	"go.uber.org/zap"
+)
+
+const (
+	// auditTailBlock is how long a single read waits for new records. A keepalive comment is sent
+	// when none arrive.
+	auditTailBlock = 15 * time.Second
+	// auditTailBatch bounds the records read at once.
+	auditTailBatch = 100
+	// auditTailWriteTimeout is how long a client may take to accept one write. Clients that read
+	// slower are disconnected.
+	auditTailWriteTimeout = 10 * time.Second
+	// auditTailMaxDuration ends every tail after a while; clients reconnect with Last-Event-ID.
+	auditTailMaxDuration = time.Hour
+	// maxAuditTails bounds the concurrent tails of a gateway.
+	maxAuditTails = 64
+)
+
+// handleAuditTail streams new audit records as server-sent events. Each event carries the record
+// as JSON, or as a structured CloudEvent with format=cloudevents, and its stream ID as the event
+// ID, so clients can resume with Last-Event-ID or the after query parameter. The filters of
+// GET /v2/audit apply, except for the time range.
+func handleAuditTail(_ context.Context, deps HandlerDeps) http.HandlerFunc {
+	tails := make(chan struct{}, maxAuditTails)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter, err := auditutils.FilterFromValues(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		encode := func(record auditutils.Record) ([]byte, error) { return json.Marshal(record) }
+		switch format := query.Get("format"); format {
+		case "", "json":
+		case auditutils.FormatCloudEvents:
+			encode = func(record auditutils.Record) ([]byte, error) {
+				event, err := cloudevents.FromAuditRecord(record.ID, record.Fields)
+				if err != nil {
+					return nil, err
+				}
+				return json.Marshal(event)
+			}
+		default:
+			http.Error(w, fmt.Sprintf("unsupported format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		after := r.Header.Get("Last-Event-ID")
+		if v := query.Get("after"); v != "" {
+			after = v
+		}
+		if after != "" {
+			if err := auditutils.ValidateStreamID(after); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		select {
+		case tails <- struct{}{}:
+			defer func() { <-tails }()
+		default:
+			http.Error(w, "Too many audit tails, try again later", http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), auditTailMaxDuration)
+		defer cancel()
+
+		if after == "" {
+			if after, err = auditutils.LastID(ctx, deps.ValkeyClient); err != nil {
+				deps.Logger.Error("Failed to read audit stream for tail", zap.Error(err))
+				http.Error(w, "Unable to fetch history from Valkey", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+
+		rc := http.NewResponseController(w)
+		send := func(event string) error {
+			_ = rc.SetWriteDeadline(time.Now().Add(auditTailWriteTimeout))
+			if _, err := fmt.Fprint(w, event); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+
+		if err := send(": tailing audit records\n\n"); err != nil {
+			return
+		}
+		for ctx.Err() == nil {
+			records, err := auditutils.ReadAfter(ctx, deps.ValkeyClient, after, auditTailBlock, auditTailBatch)
+			if err != nil {
+				if ctx.Err() == nil {
+					deps.Logger.Error("Failed to read audit stream for tail", zap.Error(err))
+				}
+				return
+			}
+			if len(records) == 0 {
+				if err := send(": keepalive\n\n"); err != nil {
+					return
+				}
+				continue
+			}
+
+			for _, record := range records {
+				after = record.ID
+				if !filter.Matches(record.Fields) {
+					continue
+				}
+				data, err := encode(record)
+				if err != nil {
+					deps.Logger.Error("Failed to encode audit record for tail", zap.String("id", record.ID), zap.Error(err))
+					continue
+				}
+				if err := send(fmt.Sprintf("id: %s\nevent: audit\ndata: %s\n\n", record.ID, data)); err != nil {
+					deps.Logger.Debug("Audit tail client went away", zap.Error(err))
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/decisiveai/mdai-data-core/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+	valkeymock "github.com/valkey-io/valkey-go/mock"
+	"go.uber.org/mock/gomock"
+)
+
+func expectTailRead(m *valkeymock.Client, after string) *gomock.Call {
+	return m.EXPECT().Do(gomock.Any(), valkeymock.Match("XREAD", "COUNT", strconv.Itoa(auditTailBatch),
+		"BLOCK", strconv.FormatInt(auditTailBlock.Milliseconds(), 10), "STREAMS", audit.MdaiHubEventHistoryStreamName, after))
+}
+
+func TestHandleAuditTail(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	gomock.InOrder(
+		expectTailRead(mockClient, "7-1").Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+			audit.MdaiHubEventHistoryStreamName: valkeymock.ValkeyArray(
+				valkeymock.ValkeyArray(valkeymock.ValkeyString("8-0"), valkeymock.ValkeyArray(
+					valkeymock.ValkeyString("hub_name"), valkeymock.ValkeyString("prod"),
+				)),
+				valkeymock.ValkeyArray(valkeymock.ValkeyString("9-0"), valkeymock.ValkeyArray(
+					valkeymock.ValkeyString("hub_name"), valkeymock.ValkeyString("staging"),
+				)),
+			),
+		}))),
+		// The next read continues after the filtered out record; the client leaves while it waits.
+		expectTailRead(mockClient, "9-0").DoAndReturn(func(_ any, _ valkey.Completed) valkey.ValkeyResult {
+			cancel()
+			return valkeymock.Result(valkeymock.ValkeyNil())
+		}),
+	)
+
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/audit/tail?hub_name=prod", http.NoBody)
+	req.Header.Set("Last-Event-ID", "7-1")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+	assert.Equal(t, ": tailing audit records\n\n"+
+		"id: 8-0\nevent: audit\ndata: {\"id\":\"8-0\",\"fields\":{\"hub_name\":\"prod\"}}\n\n"+
+		": keepalive\n\n", rr.Body.String())
+}
+
+func TestHandleAuditTail_CloudEvents(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	gomock.InOrder(
+		expectTailRead(mockClient, "7-1").Return(valkeymock.Result(valkeymock.ValkeyMap(map[string]valkey.ValkeyMessage{
+			audit.MdaiHubEventHistoryStreamName: valkeymock.ValkeyArray(
+				valkeymock.ValkeyArray(valkeymock.ValkeyString("8-0"), valkeymock.ValkeyArray(
+					valkeymock.ValkeyString("hub_name"), valkeymock.ValkeyString("prod"),
+					valkeymock.ValkeyString("timestamp"), valkeymock.ValkeyString("2025-08-08T15:56:10Z"),
+				)),
+			),
+		}))),
+		expectTailRead(mockClient, "8-0").DoAndReturn(func(_ any, _ valkey.Completed) valkey.ValkeyResult {
+			cancel()
+			return valkeymock.Result(valkeymock.ValkeyNil())
+		}),
+	)
+
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/audit/tail?after=7-1&format=cloudevents", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, ": tailing audit records\n\n"+
+		"id: 8-0\nevent: audit\ndata: "+
+		`{"specversion":"1.0","id":"8-0","source":"/mdai-gateway/audit","type":"ai.mydecisive.audit.event","subject":"prod",`+
+		`"time":"2025-08-08T15:56:10Z","datacontenttype":"application/json","data":{"hub_name":"prod","timestamp":"2025-08-08T15:56:10Z"}}`+
+		"\n\n: keepalive\n\n", rr.Body.String())
+}
+
+func TestHandleAuditTail_FromNow(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+	mockClient := deps.ValkeyClient.(*valkeymock.Client) //nolint:forcetypeassert
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	mockClient.EXPECT().Do(gomock.Any(), valkeymock.Match("XREVRANGE", audit.MdaiHubEventHistoryStreamName, "+", "-", "COUNT", "1")).
+		Return(valkeymock.Result(valkeymock.ValkeyArray()))
+	expectTailRead(mockClient, "0-0").DoAndReturn(func(_ any, _ valkey.Completed) valkey.ValkeyResult {
+		cancel()
+		return valkeymock.Result(valkeymock.ValkeyNil())
+	})
+
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/audit/tail", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestHandleAuditTail_InvalidResumeID(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/tail?after=latest", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "invalid stream ID \"latest\"\n", rr.Body.String())
+}
+
+func TestHandleAuditTail_UnsupportedFormat(t *testing.T) {
+	deps := setupMocks(t, newFakeClientset(t))
+	mux := NewRouter(t.Context(), deps)
+
+	req := httptest.NewRequest(http.MethodGet, "/audit/tail?format=csv", http.NoBody)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "unsupported format \"csv\"\n", rr.Body.String())
+}
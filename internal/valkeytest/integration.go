@@ -0,0 +1,31 @@
+//go:build integration
+
+package valkeytest
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+// addrEnvVarKey names the Valkey server the integration tests run against, e.g.
+// "localhost:6379". Its databases 1 to 15 are flushed by the tests.
+const addrEnvVarKey = "VALKEY_TEST_ADDR"
+
+var lastDB atomic.Int32
+
+// addr returns the Valkey server under test and a database of its own for each test, since tests
+// run in parallel and reuse keys. Packages must be tested one at a time, as each numbers the
+// databases from 1.
+func addr(t *testing.T) (string, int) {
+	t.Helper()
+	addr := os.Getenv(addrEnvVarKey)
+	if addr == "" {
+		t.Fatal(addrEnvVarKey + " must be set for the integration tests")
+	}
+	db := int(lastDB.Add(1))
+	if db > 15 {
+		t.Fatal("more tests use Valkey than it has databases")
+	}
+	return addr, db
+}
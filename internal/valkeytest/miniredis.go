@@ -0,0 +1,16 @@
+//go:build !integration
+
+package valkeytest
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// addr starts an in-process server that runs the Lua scripts of the stores as Valkey would. Build
+// with the integration tag to run the tests against a real Valkey instead.
+func addr(t *testing.T) (string, int) {
+	t.Helper()
+	return miniredis.RunT(t).Addr(), 0
+}
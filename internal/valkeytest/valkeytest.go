@@ -0,0 +1,20 @@
+// Package valkeytest provides the Valkey server the tests of the Valkey-backed stores run against.
+package valkeytest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/valkey-io/valkey-go"
+)
+
+// New returns a client of an empty database on the server returned by addr.
+func New(t *testing.T) valkey.Client {
+	t.Helper()
+	addr, db := addr(t)
+	client, err := valkey.NewClient(valkey.ClientOption{InitAddress: []string{addr}, SelectDB: db, DisableCache: true})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	require.NoError(t, client.Do(t.Context(), client.B().Flushdb().Build()).Error())
+	return client
+}
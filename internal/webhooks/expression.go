@@ -0,0 +1,115 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// expression extracts a value from a webhook item. Expressions starting with "{{" are Go
+// templates, all others JSONPath templates in the syntax of kubectl, e.g. "{.labels.hub}". Text
+// outside of braces is literal, so "firing" is a constant.
+type expression struct {
+	raw  string
+	tmpl *template.Template
+}
+
+func compileExpression(name, raw string) (*expression, error) {
+	e := &expression{raw: raw}
+	if strings.HasPrefix(strings.TrimSpace(raw), "{{") {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		e.tmpl = tmpl
+		return e, nil
+	}
+	// JSONPath templates keep state while they are evaluated, so they are parsed again for every
+	// evaluation; this only checks the syntax.
+	if _, err := e.jsonPath(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return e, nil
+}
+
+func (e *expression) jsonPath() (*jsonpath.JSONPath, error) {
+	path := jsonpath.New(e.raw).AllowMissingKeys(true)
+	if err := path.Parse(e.raw); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// Value returns the value the expression selects. A JSONPath expression made of a single field
+// selection returns the selected value, nil when there is none and a list when there are
+// several. All other expressions return text.
+func (e *expression) Value(item any) (any, error) {
+	if e.tmpl != nil {
+		return e.String(item)
+	}
+	path, err := e.jsonPath()
+	if err != nil {
+		return nil, err
+	}
+	results, err := path.FindResults(item)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != 1 {
+		return e.String(item)
+	}
+	switch values := results[0]; len(values) {
+	case 0:
+		return nil, nil
+	case 1:
+		return values[0].Interface(), nil
+	default:
+		list := make([]any, len(values))
+		for i, v := range values {
+			list[i] = v.Interface()
+		}
+		return list, nil
+	}
+}
+
+// String returns the text of the expression. Selected strings and numbers are written as they
+// are, other values as JSON.
+func (e *expression) String(item any) (string, error) {
+	var buf bytes.Buffer
+	if e.tmpl != nil {
+		if err := e.tmpl.Execute(&buf, item); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	path, err := e.jsonPath()
+	if err != nil {
+		return "", err
+	}
+	results, err := path.FindResults(item)
+	if err != nil {
+		return "", err
+	}
+	for _, values := range results {
+		for _, v := range values {
+			switch value := v.Interface().(type) {
+			case string:
+				buf.WriteString(value)
+			case json.Number:
+				buf.WriteString(value.String())
+			default:
+				data, err := json.Marshal(value)
+				if err != nil {
+					return "", err
+				}
+				buf.Write(data)
+			}
+		}
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,227 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/prometheus/alertmanager/template"
+	"sigs.k8s.io/yaml"
+)
+
+var errMissingExpression = errors.New("expression is required")
+
+// validSourceName is the form of source names, which appear in the route and event source.
+var validSourceName = regexp.MustCompile(`^[a-z0-9]([a-z0-9_-]*[a-z0-9])?$`)
+
+// Mapping declares how the JSON body of a webhook is turned into alerts. Every field but Items
+// is an expression evaluated on each item, see expression.
+type Mapping struct {
+	// Items selects the list of items, e.g. "{.alerts[*]}". The whole body is a single item
+	// when it is empty.
+	Items       string `json:"items,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Status is "firing" or "resolved"; "firing" when empty.
+	Status  string `json:"status,omitempty"`
+	HubName string `json:"hubName"`
+	// Name is the alert name; events are named "<name>.<status>".
+	Name string `json:"name"`
+	// Time is when the item changed, in RFC 3339. It orders the states of an item for
+	// deduplication; the time of receipt is used when it is empty.
+	Time string `json:"time,omitempty"`
+	// Labels selects an object of labels, from which the fingerprint can be computed.
+	Labels string `json:"labels,omitempty"`
+	// Payload lists the fields added to the event payload.
+	Payload map[string]string `json:"payload,omitempty"`
+}
+
+// Source is a compiled Mapping.
+type Source struct {
+	Name string
+
+	items       *expression
+	fingerprint *expression
+	status      *expression
+	hubName     *expression
+	name        *expression
+	time        *expression
+	labels      *expression
+	payload     map[string]*expression
+}
+
+// ParseSource parses the YAML or JSON mapping of a source.
+func ParseSource(name, data string) (*Source, error) {
+	if !validSourceName.MatchString(name) {
+		return nil, fmt.Errorf("invalid source name %q", name)
+	}
+	var mapping Mapping
+	if err := yaml.UnmarshalStrict([]byte(data), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid mapping: %w", err)
+	}
+	return Compile(name, mapping)
+}
+
+// Compile checks and compiles the expressions of a mapping.
+func Compile(name string, mapping Mapping) (*Source, error) {
+	s := &Source{Name: name, payload: make(map[string]*expression, len(mapping.Payload))}
+
+	var errs []error
+	compile := func(field, raw string, required bool) *expression {
+		if raw == "" {
+			if required {
+				errs = append(errs, fmt.Errorf("%s: %w", field, errMissingExpression))
+			}
+			return nil
+		}
+		e, err := compileExpression(field, raw)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return e
+	}
+	s.items = compile("items", mapping.Items, false)
+	s.fingerprint = compile("fingerprint", mapping.Fingerprint, false)
+	s.status = compile("status", mapping.Status, false)
+	s.hubName = compile("hubName", mapping.HubName, true)
+	s.name = compile("name", mapping.Name, true)
+	s.time = compile("time", mapping.Time, false)
+	s.labels = compile("labels", mapping.Labels, false)
+	for _, field := range slices.Sorted(maps.Keys(mapping.Payload)) {
+		s.payload[field] = compile("payload."+field, mapping.Payload[field], true)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Alerts maps the items of a webhook body to alerts. Items that cannot be mapped carry the
+// reason; only a body that is not JSON or whose items cannot be selected is an error.
+func (s *Source) Alerts(body []byte, received time.Time) ([]adapter.GenericAlert, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	items := []any{doc}
+	if s.items != nil {
+		selected, err := s.items.Value(doc)
+		if err != nil {
+			return nil, fmt.Errorf("items: %w", err)
+		}
+		switch selected := selected.(type) {
+		case nil:
+			items = nil
+		case []any:
+			items = selected
+		default:
+			items = []any{selected}
+		}
+	}
+
+	alerts := make([]adapter.GenericAlert, len(items))
+	for i, item := range items {
+		alerts[i] = s.alert(item, received)
+	}
+	return alerts, nil
+}
+
+func (s *Source) alert(item any, received time.Time) adapter.GenericAlert {
+	var (
+		alert adapter.GenericAlert
+		errs  []error
+	)
+	text := func(field string, e *expression) string {
+		if e == nil {
+			return ""
+		}
+		value, err := e.String(item)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		return strings.TrimSpace(value)
+	}
+
+	alert.Fingerprint = text("fingerprint", s.fingerprint)
+	alert.Status = strings.ToLower(text("status", s.status))
+	if alert.Status == "" {
+		alert.Status = "firing"
+	}
+	alert.Annotations = template.KV{
+		adapter.HubName:   text("hubName", s.hubName),
+		adapter.AlertName: text("name", s.name),
+	}
+
+	changed := received
+	if value := text("time", s.time); value != "" {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("time: %w", err))
+		}
+		changed = t
+	}
+	if alert.Status == "resolved" {
+		alert.EndsAt = changed
+	} else {
+		alert.StartsAt = changed
+	}
+
+	if s.labels != nil {
+		labels, err := s.labelsOf(item)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("labels: %w", err))
+		}
+		alert.Labels = labels
+	}
+
+	if len(s.payload) > 0 {
+		alert.Fields = make(map[string]any, len(s.payload))
+		for field, e := range s.payload {
+			value, err := e.Value(item)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("payload.%s: %w", field, err))
+			}
+			alert.Fields[field] = value
+		}
+	}
+
+	alert.Err = errors.Join(errs...)
+	return alert
+}
+
+func (s *Source) labelsOf(item any) (template.KV, error) {
+	value, err := s.labels.Value(item)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("object expected, got %T", value)
+	}
+	labels := make(template.KV, len(object))
+	for name, v := range object {
+		switch v := v.(type) {
+		case string:
+			labels[name] = v
+		case json.Number:
+			labels[name] = v.String()
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			labels[name] = string(data)
+		}
+	}
+	return labels, nil
+}
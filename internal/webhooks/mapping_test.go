@@ -0,0 +1,122 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/decisiveai/mdai-gateway/internal/adapter"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const incidentMapping = `
+items: "{.incidents[*]}"
+fingerprint: "{.id}"
+status: '{{ if eq .state "closed" }}resolved{{ else }}firing{{ end }}'
+hubName: "{.tags.hub}"
+name: "{.title}"
+time: "{.updated_at}"
+labels: "{.tags}"
+payload:
+  service: "{.tags.service}"
+  count: "{.count}"
+  url: "https://incidents.example.com/{.id}"
+`
+
+func TestSourceAlerts(t *testing.T) {
+	source, err := ParseSource("incidents", incidentMapping)
+	require.NoError(t, err)
+
+	received := time.Date(2025, 8, 8, 16, 0, 0, 0, time.UTC)
+	alerts, err := source.Alerts([]byte(`{"incidents":[
+		{"id":"inc-1","state":"open","title":"error_rate","updated_at":"2025-08-08T15:56:10Z","count":3,"tags":{"hub":"mdaihub-sample","service":"checkout"}},
+		{"id":"inc-2","state":"closed","title":"error_rate","updated_at":"2025-08-08T15:57:00Z","tags":{"hub":"mdaihub-sample","service":"cart"}},
+		{"id":"inc-3","state":"open","title":"error_rate","updated_at":"yesterday","tags":{"hub":"mdaihub-sample"}}
+	]}`), received)
+	require.NoError(t, err)
+	require.Len(t, alerts, 3)
+
+	assert.Equal(t, adapter.GenericAlert{
+		Alert: template.Alert{
+			Status:      "firing",
+			Labels:      template.KV{"hub": "mdaihub-sample", "service": "checkout"},
+			Annotations: template.KV{adapter.HubName: "mdaihub-sample", adapter.AlertName: "error_rate"},
+			StartsAt:    time.Date(2025, 8, 8, 15, 56, 10, 0, time.UTC),
+			Fingerprint: "inc-1",
+		},
+		Fields: map[string]any{"service": "checkout", "count": json.Number("3"), "url": "https://incidents.example.com/inc-1"},
+	}, alerts[0])
+
+	assert.Equal(t, "resolved", alerts[1].Status)
+	assert.Equal(t, time.Date(2025, 8, 8, 15, 57, 0, 0, time.UTC), alerts[1].EndsAt)
+	assert.True(t, alerts[1].StartsAt.IsZero())
+	assert.Nil(t, alerts[1].Fields["count"])
+	require.NoError(t, alerts[1].Err)
+
+	require.Error(t, alerts[2].Err)
+	assert.Contains(t, alerts[2].Err.Error(), "time: ")
+}
+
+func TestSourceAlerts_SingleItem(t *testing.T) {
+	source, err := Compile("deploys", Mapping{HubName: "mdaihub-sample", Name: "{.event}"})
+	require.NoError(t, err)
+
+	received := time.Date(2025, 8, 8, 16, 0, 0, 0, time.UTC)
+	alerts, err := source.Alerts([]byte(`{"event":"deploy_started"}`), received)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "firing", alerts[0].Status)
+	assert.Equal(t, "deploy_started", alerts[0].Annotations[adapter.AlertName])
+	assert.Equal(t, "mdaihub-sample", alerts[0].Annotations[adapter.HubName])
+	assert.Equal(t, received, alerts[0].StartsAt)
+	assert.Empty(t, alerts[0].Fingerprint)
+
+	_, err = source.Alerts([]byte(`{"event":`), received)
+	assert.ErrorContains(t, err, "invalid JSON")
+}
+
+func TestParseSource_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		mapping  string
+		wantErrs []string
+	}{
+		{
+			name:     "source name",
+			source:   "Incidents/v1",
+			mapping:  `{hubName: h, name: n}`,
+			wantErrs: []string{`invalid source name "Incidents/v1"`},
+		},
+		{
+			name:     "unknown field",
+			source:   "incidents",
+			mapping:  `{hubName: h, name: n, hub: h}`,
+			wantErrs: []string{"invalid mapping", `unknown field "hub"`},
+		},
+		{
+			name:     "missing expressions",
+			source:   "incidents",
+			mapping:  `{payload: {service: ""}}`,
+			wantErrs: []string{"hubName: expression is required", "name: expression is required", "payload.service: expression is required"},
+		},
+		{
+			name:     "malformed expressions",
+			source:   "incidents",
+			mapping:  `{hubName: "{.tags.hub", name: "{{ .title "}`,
+			wantErrs: []string{"hubName: ", "name: "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSource(tt.source, tt.mapping)
+			require.Error(t, err)
+			for _, want := range tt.wantErrs {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package webhooks
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	datacorekube "github.com/decisiveai/mdai-data-core/kube"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ConfigMapType labels the ConfigMaps declaring webhook sources. Every key of their data is the
+// name of a source and its value the YAML mapping of the source.
+const ConfigMapType = "gateway-webhook-sources"
+
+const resyncPeriod = 10 * time.Minute
+
+// Registry holds the webhook sources currently declared.
+type Registry struct {
+	logger *zap.Logger
+
+	mu      sync.RWMutex
+	sources map[string]*Source
+}
+
+func NewRegistry(logger *zap.Logger) *Registry {
+	return &Registry{logger: logger, sources: map[string]*Source{}}
+}
+
+// Get returns the source named name, or false if no valid mapping declares it.
+func (r *Registry) Get(name string) (*Source, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.sources[name]
+	return s, ok
+}
+
+// Load replaces the sources with those declared by configMaps. Invalid mappings are logged and
+// left out. A source declared more than once is taken from the first ConfigMap by namespace and
+// name, so that every replica picks the same one.
+func (r *Registry) Load(configMaps []*corev1.ConfigMap) {
+	configMaps = slices.Clone(configMaps)
+	slices.SortFunc(configMaps, func(a, b *corev1.ConfigMap) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	sources := map[string]*Source{}
+	declaredBy := map[string]string{}
+	for _, cm := range configMaps {
+		ref := cm.Namespace + "/" + cm.Name
+		for _, name := range slices.Sorted(maps.Keys(cm.Data)) {
+			if first, ok := declaredBy[name]; ok {
+				r.logger.Warn("Ignoring duplicate webhook source",
+					zap.String("source", name), zap.String("configmap", ref), zap.String("declaredBy", first))
+				continue
+			}
+			source, err := ParseSource(name, cm.Data[name])
+			if err != nil {
+				r.logger.Error("Ignoring invalid webhook source",
+					zap.String("source", name), zap.String("configmap", ref), zap.Error(err))
+				continue
+			}
+			sources[name] = source
+			declaredBy[name] = ref
+		}
+	}
+
+	r.mu.Lock()
+	r.sources = sources
+	r.mu.Unlock()
+	r.logger.Info("Loaded webhook sources", zap.Strings("sources", slices.Sorted(maps.Keys(sources))))
+}
+
+// Watch keeps the registry in sync with the ConfigMaps of ConfigMapType in namespace until ctx is
+// done. It returns once the ConfigMaps have been loaded for the first time.
+func (r *Registry) Watch(ctx context.Context, clientset kubernetes.Interface, namespace string) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, resyncPeriod,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = fmt.Sprintf("%s=%s", datacorekube.ConfigMapTypeLabel, ConfigMapType)
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps()
+
+	reload := func() {
+		configMaps, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			r.logger.Error("Failed to list webhook source ConfigMaps", zap.Error(err))
+			return
+		}
+		r.Load(configMaps)
+	}
+	if _, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { reload() },
+		UpdateFunc: func(any, any) { reload() },
+		DeleteFunc: func(any) { reload() },
+	}); err != nil {
+		return fmt.Errorf("failed to watch webhook source ConfigMaps: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("failed to sync %v informer", typ)
+		}
+	}
+	reload()
+	return nil
+}
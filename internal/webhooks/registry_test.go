@@ -0,0 +1,80 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	datacorekube "github.com/decisiveai/mdai-data-core/kube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func sourcesConfigMap(namespace, name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{datacorekube.ConfigMapTypeLabel: ConfigMapType},
+		},
+		Data: data,
+	}
+}
+
+func TestRegistryLoad(t *testing.T) {
+	r := NewRegistry(zap.NewNop())
+	r.Load([]*corev1.ConfigMap{
+		sourcesConfigMap("mdai", "b", map[string]string{"incidents": `{hubName: "{.hub}", name: "{.b}"}`}),
+		sourcesConfigMap("mdai", "a", map[string]string{
+			"incidents": `{hubName: "{.hub}", name: "{.a}"}`,
+			"broken":    `{name: "{.a}"}`,
+		}),
+	})
+
+	_, ok := r.Get("broken")
+	assert.False(t, ok)
+
+	// The ConfigMap first by name wins.
+	source, ok := r.Get("incidents")
+	require.True(t, ok)
+	alerts, err := source.Alerts([]byte(`{"hub":"h","a":"from_a","b":"from_b"}`), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "from_a", alerts[0].Annotations["alert_name"])
+}
+
+func TestRegistryWatch(t *testing.T) {
+	clientset := fake.NewClientset(
+		sourcesConfigMap("mdai", "sources", map[string]string{"incidents": `{hubName: "{.hub}", name: "{.name}"}`}),
+		// Not labelled as webhook sources.
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "mdai", Name: "other"},
+			Data:       map[string]string{"deploys": `{hubName: "{.hub}", name: "{.name}"}`},
+		},
+	)
+
+	r := NewRegistry(zap.NewNop())
+	require.NoError(t, r.Watch(t.Context(), clientset, corev1.NamespaceAll))
+
+	_, ok := r.Get("incidents")
+	assert.True(t, ok)
+	_, ok = r.Get("deploys")
+	assert.False(t, ok)
+
+	cms := clientset.CoreV1().ConfigMaps("mdai")
+	_, err := cms.Update(t.Context(), sourcesConfigMap("mdai", "sources", map[string]string{"deploys": `{hubName: "{.hub}", name: "{.name}"}`}), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, deploys := r.Get("deploys")
+		_, incidents := r.Get("incidents")
+		return deploys && !incidents
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, cms.Delete(t.Context(), "sources", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		_, ok := r.Get("deploys")
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+}